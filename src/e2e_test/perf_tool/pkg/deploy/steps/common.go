@@ -61,13 +61,14 @@ func (r *renderedYAML) deploy(clusterCtx *cluster.Context) error {
 	if err := createNamespaceIfNotExists(clusterCtx, ns); err != nil {
 		return err
 	}
-	return k8s.ApplyYAML(
+	_, err = k8s.ApplyYAML(
 		clusterCtx.Clientset(),
 		clusterCtx.RestConfig(),
 		ns,
 		bytes.NewReader(r.yaml),
 		true,
 	)
+	return err
 }
 
 const kustomizeForPatchesTmpl = `
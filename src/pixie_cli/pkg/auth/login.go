@@ -29,17 +29,16 @@ import (
 	"net/url"
 	"os"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/lestrrat-go/jwx/jwt"
-	"github.com/segmentio/analytics-go/v3"
 	log "github.com/sirupsen/logrus"
 	"github.com/skratchdot/open-golang/open"
-	"golang.org/x/term"
 	"google.golang.org/grpc/metadata"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
 	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
@@ -55,20 +54,22 @@ var localServerRedirectURL = "http://localhost:8085/auth_complete"
 var localServerPort = int32(8085)
 var sentSegmentAlias = false
 
-// SaveRefreshToken saves the refresh token in default spot.
+// authCredentialName is the name that the refresh token is stored under in the OS keychain.
+const authCredentialName = "auth"
+
+// SaveRefreshToken saves the refresh token in the OS keychain, or in the default spot if credentials are stored in
+// files.
 func SaveRefreshToken(token *RefreshToken) error {
 	pixieAuthFilePath, err := utils.EnsureDefaultAuthFilePath()
 	if err != nil {
 		return err
 	}
 
-	f, err := os.OpenFile(pixieAuthFilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	b, err := json.Marshal(token)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	return json.NewEncoder(f).Encode(token)
+	return pxconfig.SaveCredential(authCredentialName, pixieAuthFilePath, append(b, '\n'))
 }
 
 // LoadDefaultCredentials loads the default credentials for the user.
@@ -77,14 +78,13 @@ func LoadDefaultCredentials() (*RefreshToken, error) {
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Open(pixieAuthFilePath)
+	b, err := pxconfig.LoadCredential(authCredentialName, pixieAuthFilePath)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
 
 	token := &RefreshToken{}
-	if err := json.NewDecoder(f).Decode(token); err != nil {
+	if err := json.Unmarshal(b, token); err != nil {
 		return nil, err
 	}
 
@@ -92,10 +92,7 @@ func LoadDefaultCredentials() (*RefreshToken, error) {
 		userID := srvutils.GetUserID(parsed)
 		if userID != "" && !sentSegmentAlias {
 			// Associate UserID with AnalyticsID.
-			_ = pxanalytics.Client().Enqueue(&analytics.Alias{
-				UserId:     pxconfig.Cfg().UniqueClientID,
-				PreviousId: userID,
-			})
+			pxanalytics.Alias(userID)
 			sentSegmentAlias = true
 		}
 	}
@@ -108,7 +105,6 @@ func LoadDefaultCredentials() (*RefreshToken, error) {
 // existing credentials and whether those are actually valid.
 func IsAuthenticated(cloudAddr string) bool {
 	creds := MustLoadDefaultCredentials()
-	client := http.Client{}
 	req, err := http.NewRequest("GET", fmt.Sprintf("https://%s/api/authorized", cloudAddr), nil)
 	if err != nil {
 		return false
@@ -116,7 +112,7 @@ func IsAuthenticated(cloudAddr string) bool {
 
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", creds.Token))
 
-	resp, err := client.Do(req)
+	resp, err := utils.HTTPClient().Do(req)
 	if err != nil {
 		return false
 	}
@@ -134,14 +130,14 @@ func IsAuthenticated(cloudAddr string) bool {
 func MustLoadDefaultCredentials() *RefreshToken {
 	token, err := LoadDefaultCredentials()
 
-	if err != nil && os.IsNotExist(err) {
+	if err != nil && errors.Is(err, os.ErrNotExist) {
 		utils.Error("You must be logged in to perform this operation. Please run `px auth login`.")
 	} else if err != nil {
 		utils.Errorf("Failed to get auth credentials: %s", err.Error())
 	}
 
 	if err != nil {
-		os.Exit(1)
+		exitcodes.ExitWith(exitcodes.Auth)
 	}
 
 	return token
@@ -190,20 +186,17 @@ func (p *PixieCloudLogin) Run() (*RefreshToken, error) {
 			return refreshToken, nil
 		case errUserNotRegistered:
 			utils.Error("Failed to authenticate. Please refer to UI for further instructions.")
-			os.Exit(1)
+			exitcodes.ExitWith(exitcodes.Auth)
 		case errUserChallengeTimeout:
 			utils.Error("Timeout waiting for response from browser. Perhaps try --manual mode.")
-			os.Exit(1)
+			exitcodes.ExitWith(exitcodes.Auth)
 		case errBrowserFailed:
 			fallthrough
 		default:
 			utils.WithError(err).Info("Failed to perform browser based auth. Will try manual auth")
 		}
 	}
-	_ = pxanalytics.Client().Enqueue(&analytics.Track{
-		UserId: pxconfig.Cfg().UniqueClientID,
-		Event:  "Manual Auth",
-	})
+	pxanalytics.Track("Manual Auth", nil)
 	// Try to request using manual mode
 	accessToken, err := p.getAuthStringManually()
 	if err != nil {
@@ -235,23 +228,19 @@ func sendError(w http.ResponseWriter, err error) {
 }
 
 func (p *PixieCloudLogin) doAPIKeyAuth() (*RefreshToken, error) {
-	fmt.Print("\nEnter API Key (won't echo): ")
-	apiKey, err := term.ReadPassword(syscall.Stdin)
 	fmt.Print("\n")
+	apiKey, err := components.SecretPrompt("Enter API Key")
 	if err != nil {
 		return nil, err
 	}
 
-	return p.getRefreshToken("", string(apiKey))
+	return p.getRefreshToken("", apiKey)
 }
 
 func (p *PixieCloudLogin) tryBrowserAuth() (*RefreshToken, error) {
 	// Browser auth starts up a server on localhost to do the user challenge
 	// and get the authentication token.
-	_ = pxanalytics.Client().Enqueue(&analytics.Track{
-		UserId: pxconfig.Cfg().UniqueClientID,
-		Event:  "Browser Auth",
-	})
+	pxanalytics.Track("Browser Auth", nil)
 	authURL := p.getAuthURL()
 	q := authURL.Query()
 	q.Set("redirect_uri", localServerRedirectURL)
@@ -320,10 +309,7 @@ func (p *PixieCloudLogin) tryBrowserAuth() (*RefreshToken, error) {
 		utils.Info("Starting browser... (if browser-based login fails, try running `px auth login --manual` for headless login)")
 		err := open.Run(authURL.String())
 		if err != nil {
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Browser Open Failed",
-			})
+			pxanalytics.Track("Browser Open Failed", nil)
 			results <- result{nil, errBrowserFailed}
 		}
 	}()
@@ -340,16 +326,10 @@ func (p *PixieCloudLogin) tryBrowserAuth() (*RefreshToken, error) {
 			return nil, errUserChallengeTimeout
 		case res, ok := <-results:
 			if !ok {
-				_ = pxanalytics.Client().Enqueue(&analytics.Track{
-					UserId: pxconfig.Cfg().UniqueClientID,
-					Event:  "Auth Failure",
-				})
+				pxanalytics.Track("Auth Failure", nil)
 				return nil, errUserChallengeTimeout
 			}
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Auth Success",
-			})
+			pxanalytics.Track("Auth Success", nil)
 			// TODO(zasgar): This is a hack, figure out why this function takes so long to exit.
 			utils.Info("Fetching refresh token ...")
 			return res.Token, res.err
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// builtinAliases are the short forms of the most common demo commands. Aliases in the config file override them.
+var builtinAliases = map[string]string{
+	"dd": "demo deploy",
+	"dl": "demo list",
+	"ds": "demo status",
+	"dx": "demo delete",
+}
+
+func init() {
+	AliasCmd.AddCommand(listAliasesCmd)
+	AliasCmd.AddCommand(setAliasCmd)
+	AliasCmd.AddCommand(unsetAliasCmd)
+}
+
+// AliasCmd is the "alias" command.
+var AliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage the short names of commands",
+	Long: `Manage the short names of commands.
+
+An alias is replaced by its command line when it is the first argument of px, followed by the rest of the arguments.
+For example, after px alias set dd demo deploy -y, px dd px-sock-shop runs px demo deploy -y px-sock-shop. Aliases
+aren't expanded within the command line of other aliases, and can't have the name of a built-in command.
+
+The following aliases are built in, and can be overridden:
+  dd  demo deploy
+  dl  demo list
+  ds  demo status
+  dx  demo delete`,
+}
+
+var listAliasesCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the aliases",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		userAliases := pxconfig.Cfg().Aliases
+		all := aliases()
+		names := make([]string, 0, len(all))
+		for name := range all {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := components.CreateStreamWriter(outputFormat(), os.Stdout)
+		defer w.Finish()
+		w.SetHeader("aliases", []string{"Name", "Command", "Source"})
+		for _, name := range names {
+			source := "built-in"
+			if _, ok := userAliases[name]; ok {
+				source = "config"
+			}
+			_ = w.Write([]interface{}{name, all[name], source})
+		}
+	},
+}
+
+var setAliasCmd = &cobra.Command{
+	Use:   "set NAME COMMAND...",
+	Short: "Make an alias run the given command line",
+	Example: `  px alias set dd demo deploy -y
+  px alias set sock demo deploy px-sock-shop --wait`,
+	Args: cobra.MinimumNArgs(2),
+	// The command line of the alias may contain flags, which are for the aliased command.
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, command := args[0], args[1:]
+		if err := validateAlias(name, command); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Invalid alias")
+		}
+		if err := pxconfig.SetAlias(name, strings.Join(command, " ")); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to save the alias")
+		}
+		utils.Infof("px %s now runs px %s", name, strings.Join(command, " "))
+	},
+}
+
+var unsetAliasCmd = &cobra.Command{
+	Use:   "unset NAME",
+	Short: "Remove an alias from the config file, which restores the built-in alias of the name if there is one",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pxconfig.UnsetAlias(args[0]); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to remove the alias")
+		}
+		utils.Infof("Removed alias %s", args[0])
+	},
+}
+
+// validateAlias returns an error if the alias would never be expanded, or would expand to something that isn't a
+// command.
+func validateAlias(name string, command []string) error {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("alias %q must be a single word that doesn't start with -", name)
+	}
+	if isCommandName(name) {
+		return fmt.Errorf("alias %q has the name of a built-in command", name)
+	}
+	if _, _, err := RootCmd.Find(command); err != nil {
+		if _, _, ok := lookupPlugin(command); !ok {
+			return fmt.Errorf("px %s isn't a command", strings.Join(command, " "))
+		}
+	}
+	return nil
+}
+
+// isCommandName returns whether the name is a built-in top-level command, or one of their aliases.
+func isCommandName(name string) bool {
+	for _, c := range RootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// aliases returns the built-in aliases, overridden by the aliases in the config file.
+func aliases() map[string]string {
+	all := make(map[string]string, len(builtinAliases))
+	for name, command := range builtinAliases {
+		all[name] = command
+	}
+	for name, command := range pxconfig.Cfg().Aliases {
+		all[name] = command
+	}
+	return all
+}
+
+// ExpandAliases replaces an alias in the command line of px with its command line. It must be called before Execute,
+// which parses the flags of os.Args.
+func ExpandAliases() {
+	if expanded, ok := expandAliases(os.Args, aliases()); ok {
+		os.Args = expanded
+	}
+}
+
+// expandAliases replaces the alias that is the first argument of the command line with its command line. For shell
+// completions, the alias follows the completion command. It returns false if there is no alias to expand.
+func expandAliases(args []string, aliases map[string]string) ([]string, bool) {
+	i := 1
+	if len(args) > 1 && (args[1] == cobra.ShellCompRequestCmd || args[1] == cobra.ShellCompNoDescRequestCmd) {
+		i = 2
+	}
+	if len(args) <= i || isCommandName(args[i]) {
+		return nil, false
+	}
+	command, ok := aliases[args[i]]
+	if !ok {
+		return nil, false
+	}
+	expanded := append([]string{}, args[:i]...)
+	expanded = append(expanded, strings.Fields(command)...)
+	return append(expanded, args[i+1:]...), true
+}
@@ -22,14 +22,11 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
-	"syscall"
 
 	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"golang.org/x/term"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
@@ -50,8 +47,6 @@ func init() {
 
 	DeleteAPIKeyCmd.Flags().StringP("id", "i", "", "The API key to delete")
 
-	ListAPIKeyCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
-
 	LookupAPIKeyCmd.Flags().StringP("key", "k", "", "Value of the key. Leave blank to be prompted.")
 }
 
@@ -123,13 +118,9 @@ var DeleteAPIKeyCmd = &cobra.Command{
 var ListAPIKeyCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all API key metadata",
-	PreRun: func(cmd *cobra.Command, args []string) {
-		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
-	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := outputFormat()
 
 		keys, err := listAPIKeyMetadatas(cloudAddr)
 		if err != nil {
@@ -153,16 +144,14 @@ var LookupAPIKeyCmd = &cobra.Command{
 	Short: "Lookup API key based on the value of the key",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := outputFormat()
 		apiKey, err := cmd.Flags().GetString("key")
 		if err != nil || len(apiKey) == 0 {
-			fmt.Print("\nEnter API Key (won't echo): ")
-			k, err := term.ReadPassword(syscall.Stdin)
+			fmt.Print("\n")
+			apiKey, err = components.SecretPrompt("Enter API Key")
 			if err != nil {
 				log.WithError(err).Fatal("Failed to read API Key")
 			}
-			apiKey = string(k)
 		}
 
 		k, err := lookupAPIKey(cloudAddr, apiKey)
@@ -185,8 +174,7 @@ var GetAPIKeyCmd = &cobra.Command{
 	Short: "Get API key details for a specific key",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := outputFormat()
 
 		if len(args) != 1 {
 			utils.Fatal("Expected a single argument 'key id'.")
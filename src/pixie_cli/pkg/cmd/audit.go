@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func init() {
+	listAuditCmd.Flags().Duration("since", 0, "Only show the changes made within the given duration, for example: 24h")
+	listAuditCmd.Flags().String("kube_context", "", "Only show the changes made with the given kubeconfig context")
+	listAuditCmd.Flags().StringP("namespace", "n", "", "Only show the changes made to the given namespace")
+	listAuditCmd.Flags().Bool("failed", false, "Only show the changes that the cluster rejected or that failed")
+
+	AuditCmd.AddCommand(listAuditCmd)
+}
+
+// AuditCmd is the "audit" command.
+var AuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the changes that the CLI made to clusters",
+	Long: `Show the changes that the CLI made to clusters.
+
+Every request of the CLI that changes a cluster, such as creating a namespace or applying or deleting an object, is
+recorded in an append-only audit log under ~/.local/state/pixie, along with the user, the kubeconfig context and the
+command that made it. Dry runs aren't recorded.`,
+}
+
+var listAuditCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the changes that the CLI made to clusters, from the oldest to the most recent",
+	Example: `  px audit list --since 24h
+  px audit list --kube_context prod -n px-sock-shop -o json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := utils.ReadAuditLog()
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to read the audit log")
+		}
+		since, _ := cmd.Flags().GetDuration("since")
+		kubeContext, _ := cmd.Flags().GetString("kube_context")
+		namespace, _ := cmd.Flags().GetString("namespace")
+		failed, _ := cmd.Flags().GetBool("failed")
+
+		w := components.CreateStreamWriter(outputFormat(), os.Stdout)
+		defer w.Finish()
+		w.SetHeader("audit", []string{"Time", "User", "Context", "Command", "Verb", "Resource", "Namespace", "Name", "Result"})
+		for _, e := range entries {
+			if since > 0 && time.Since(e.Time) > since {
+				continue
+			}
+			if kubeContext != "" && e.Context != kubeContext {
+				continue
+			}
+			if namespace != "" && e.Namespace != namespace && !(e.Resource == "namespaces" && e.Name == namespace) {
+				continue
+			}
+			if failed && e.Succeeded() {
+				continue
+			}
+			_ = w.Write([]interface{}{
+				e.Time.Local().Format(time.RFC3339), e.User, e.Context, e.Command, e.Verb, e.Resource, e.Namespace,
+				e.Name, auditResult(e),
+			})
+		}
+	},
+}
+
+// auditResult describes whether the change of the audit entry was made.
+func auditResult(e *utils.AuditEntry) string {
+	switch {
+	case e.Error != "":
+		return e.Error
+	case e.Succeeded():
+		return "OK"
+	default:
+		return fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+	}
+}
@@ -20,14 +20,12 @@ package cmd
 
 import (
 	"github.com/lestrrat-go/jwx/jwt"
-	"github.com/segmentio/analytics-go/v3"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
-	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	srvutils "px.dev/pixie/src/shared/services/utils"
 )
@@ -81,10 +79,7 @@ var LoginCmd = &cobra.Command{
 			userID := srvutils.GetUserID(token)
 			if userID != "" {
 				// Associate UserID with AnalyticsID.
-				_ = pxanalytics.Client().Enqueue(&analytics.Alias{
-					UserId:     pxconfig.Cfg().UniqueClientID,
-					PreviousId: userID,
-				})
+				pxanalytics.Alias(userID)
 			}
 		}
 		utils.Info("Authentication Successful")
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// completionCmdName is the name of the command that cobra adds to generate the completion scripts of each shell:
+// px completion bash|zsh|fish|powershell.
+const completionCmdName = "completion"
+
+func init() {
+	// Demo commands that take the name of a demo app as their argument.
+	for _, c := range []*cobra.Command{
+		interactDemoCmd, deleteDemoCmd, deployDemoCmd, diffDemoCmd, logsDemoCmd, portForwardDemoCmd, sizeDemoCmd,
+		statusDemoCmd, validateDemoCmd,
+	} {
+		c.ValidArgsFunction = completeDemoApps
+	}
+
+	_ = RootCmd.RegisterFlagCompletionFunc("context", completeKubeContexts)
+	for _, c := range []*cobra.Command{getConfigCmd, setConfigCmd, listConfigCmd, unsetConfigCmd} {
+		_ = c.RegisterFlagCompletionFunc("cluster", completeKubeContexts)
+	}
+}
+
+// IsCompletion returns whether the CLI was run with the given args to generate shell completions.
+func IsCompletion(args []string) bool {
+	if len(args) < 2 {
+		return false
+	}
+	switch args[1] {
+	case completionCmdName, cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	default:
+		return false
+	}
+}
+
+// isCompletionCmd returns whether the command generates shell completions.
+func isCompletionCmd(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case completionCmdName, cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return true
+		}
+	}
+	return false
+}
+
+// completeDemoApps completes the names of the demo apps, from the cached manifest if it was downloaded before.
+func completeDemoApps(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// The demo command doesn't run its persistent pre run for completions.
+	bindDemoFlags(cmd)
+	applyConfigSettings()
+	manifest, err := newDemoClient().CachedManifest()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var apps []string
+	for app, spec := range manifest {
+		if spec == nil || spec.Deprecated != nil {
+			continue
+		}
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	return apps, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeKubeContexts completes the names of the contexts in the kubeconfig.
+func completeKubeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	contexts, err := k8s.ListContexts()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return contexts, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,314 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func init() {
+	for _, c := range []*cobra.Command{getConfigCmd, setConfigCmd, listConfigCmd, unsetConfigCmd} {
+		c.Flags().String("cluster", "", "The kubeconfig context of a cluster, to manage the settings that override the settings for all clusters when commands work with it")
+	}
+
+	ConfigCmd.AddCommand(getConfigCmd)
+	ConfigCmd.AddCommand(setConfigCmd)
+	ConfigCmd.AddCommand(listConfigCmd)
+	ConfigCmd.AddCommand(unsetConfigCmd)
+	ConfigCmd.AddCommand(exportConfigCmd)
+	ConfigCmd.AddCommand(importConfigCmd)
+	ConfigCmd.AddCommand(validateConfigCmd)
+	ConfigCmd.AddCommand(encryptConfigCmd)
+	ConfigCmd.AddCommand(decryptConfigCmd)
+	ConfigCmd.AddCommand(resetClientIDCmd)
+}
+
+// ConfigCmd is the config sub-command of the CLI.
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the settings of the CLI",
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Info("Nothing here... Please execute one of the subcommands")
+		cmd.Help()
+	},
+}
+
+var getConfigCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting, for example: px config get analytics.enabled",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := pxconfig.LookupSetting(args[0])
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to get setting")
+		}
+		value := s.Get(configForCluster(cmd))
+		// Print the bare value by default, so that it can be used in scripts.
+		format := outputFormat()
+		if format == "" {
+			fmt.Println(value)
+			return
+		}
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("settings", []string{"Key", "Value"})
+		_ = w.Write([]interface{}{s.Key, value})
+	},
+}
+
+var listConfigCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the settings and their values",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := configForCluster(cmd)
+		format := outputFormat()
+		if format == "" {
+			format = "table"
+		}
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("settings", []string{"Key", "Value", "Description"})
+		for _, s := range pxconfig.Settings() {
+			_ = w.Write([]interface{}{s.Key, s.Get(cfg), s.Description})
+		}
+	},
+}
+
+var unsetConfigCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Restore the default of a setting, for example: px config unset kube.context",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		if cluster, _ := cmd.Flags().GetString("cluster"); cluster != "" {
+			err = pxconfig.UnsetForCluster(cluster, args[0])
+		} else {
+			err = pxconfig.Unset(args[0])
+		}
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatalf("Failed to unset %s", args[0])
+		}
+		utils.Infof("Unset %s", args[0])
+	},
+}
+
+var setConfigCmd = &cobra.Command{
+	Use:   "set <key>=<value>",
+	Short: "Change a setting, for example: px config set analytics.enabled=false",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, value, ok := strings.Cut(args[0], "=")
+		if len(args) == 2 {
+			key, value, ok = args[0], args[1], true
+		}
+		if !ok {
+			utils.WithExitCode(exitcodes.Usage).Fatal("Settings must be specified through the following format: <key>=<value>")
+		}
+		var err error
+		cluster, _ := cmd.Flags().GetString("cluster")
+		if cluster != "" {
+			err = pxconfig.SetForCluster(cluster, key, value)
+		} else {
+			err = pxconfig.Set(key, value)
+		}
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatalf("Failed to set %s", key)
+		}
+		// Print the value as it was stored, since some settings normalize it.
+		s, _ := pxconfig.LookupSetting(key)
+		utils.Infof("Set %s to %s", key, s.Get(configForCluster(cmd)))
+	},
+}
+
+var exportConfigCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export the settings that can be shared with a team, to stdout or a file",
+	Long: `Export the settings that can be shared with a team, to stdout or a file, so that others can apply them with
+px config import.
+
+Settings that are specific to each user or secret, such as analytics.enabled and analytics.write_key, aren't exported.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		b, err := json.MarshalIndent(pxconfig.Export(), "", "  ")
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to export settings")
+		}
+		b = append(b, '\n')
+		if len(args) == 0 || args[0] == "-" {
+			os.Stdout.Write(b)
+			return
+		}
+		if err := os.WriteFile(args[0], b, 0644); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to export settings")
+		}
+		utils.Infof("Exported settings to %s", args[0])
+	},
+}
+
+var importConfigCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Apply the settings exported by px config export, from a file or - for stdin",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var b []byte
+		var err error
+		if args[0] == "-" {
+			b, err = io.ReadAll(os.Stdin)
+		} else {
+			b, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to read settings")
+		}
+		values := make(map[string]string)
+		if err := json.Unmarshal(b, &values); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to parse settings")
+		}
+		if err := pxconfig.Import(values); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to import settings")
+		}
+		utils.Infof("Imported %d settings", len(values))
+	},
+}
+
+var validateConfigCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for unknown keys, invalid values and missing files, URLs and kubeconfig contexts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		problems, err := pxconfig.Validate()
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to validate the config")
+		}
+		if len(problems) == 0 {
+			utils.Info("The config is valid")
+			return
+		}
+
+		format := outputFormat()
+		if format == "" {
+			format = "table"
+		}
+		w := components.CreateStreamWriter(format, os.Stdout)
+		errs := 0
+		w.SetHeader("problems", []string{"Key", "Severity", "Problem"})
+		for _, p := range problems {
+			severity := "warning"
+			if !p.Warning {
+				severity = "error"
+				errs++
+			}
+			_ = w.Write([]interface{}{p.Key, severity, p.Message})
+		}
+		w.Finish()
+		switch {
+		case errs == 1:
+			utils.WithExitCode(exitcodes.Config).Fatal("Found 1 error in the config")
+		case errs > 1:
+			utils.WithExitCode(exitcodes.Config).Fatalf("Found %d errors in the config", errs)
+		}
+	},
+}
+
+var encryptConfigCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the config file with a passphrase",
+	Long: `Encrypt the config file with a passphrase, so that no settings, such as analytics endpoints and keys, are
+stored in plaintext.
+
+The passphrase is read from the ` + pxconfig.PassphraseEnvVar + ` env var, or prompted for. Every command then
+needs it to read the config file. Store credentials in the OS keychain with px config set credentials.store=keychain
+to also keep them off disk.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		passphrase := os.Getenv(pxconfig.PassphraseEnvVar)
+		if passphrase == "" {
+			var err error
+			if passphrase, err = components.SecretPrompt("New passphrase"); err != nil {
+				utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to read the passphrase")
+			}
+			confirmation, err := components.SecretPrompt("Repeat the passphrase")
+			if err != nil {
+				utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to read the passphrase")
+			}
+			if passphrase != confirmation {
+				utils.WithExitCode(exitcodes.Config).Fatal("The passphrases don't match")
+			}
+		}
+		if err := pxconfig.EncryptConfig(passphrase); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to encrypt the config file")
+		}
+		utils.Infof("Encrypted the config file. Set %s to its passphrase to use px non-interactively.",
+			pxconfig.PassphraseEnvVar)
+	},
+}
+
+var decryptConfigCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Store the config file in plaintext again, after px config encrypt",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !pxconfig.ConfigEncrypted() {
+			utils.Info("The config file isn't encrypted")
+			return
+		}
+		if err := pxconfig.DecryptConfig(); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to decrypt the config file")
+		}
+		utils.Info("Decrypted the config file")
+	},
+}
+
+var resetClientIDCmd = &cobra.Command{
+	Use:   "reset-client-id",
+	Short: "Assign a new anonymous ID to the CLI, so that later usage analytics can't be linked to earlier ones",
+	Long: `Assign a new anonymous ID to the CLI, so that later usage analytics can't be linked to earlier ones.
+
+The ID is also reset when analytics are turned back on with px config set analytics.enabled=true. Logging in with
+px auth login links the current ID to your account.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pxconfig.ResetClientID(); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Config).Fatal("Failed to reset the client ID")
+		}
+		utils.Info("Reset the client ID")
+	},
+}
+
+// configForCluster returns the config with the overrides for the cluster of the --cluster flag applied, or the config
+// for all clusters if the flag isn't given.
+func configForCluster(cmd *cobra.Command) *pxconfig.ConfigInfo {
+	if cluster, _ := cmd.Flags().GetString("cluster"); cluster != "" {
+		return pxconfig.ClusterCfg(cluster)
+	}
+	return pxconfig.Cfg()
+}
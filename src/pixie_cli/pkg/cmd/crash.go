@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+const crashReportConsentMessage = `The crash report contains the version of px, your OS, the command that crashed with its arguments
+removed, the panic message with names, paths and addresses removed, and the stack trace.
+You can change whether you are asked with px config set crash_reports.submit=<ask|always|never>.`
+
+// crashReportSubmitter submits the crash reports that the user agreed to submit. It's nil in builds that can't
+// submit them, such as builds with the notel tag.
+var crashReportSubmitter func(r *utils.CrashReport) error
+
+// SetCrashReportSubmitter sets the function that submits the crash reports that the user agreed to submit.
+func SetCrashReportSubmitter(submit func(r *utils.CrashReport) error) {
+	crashReportSubmitter = submit
+}
+
+// RecoverCrash handles a panic of px: it writes a crash report, offers to submit it, and exits. It must be deferred by
+// the function that runs the command, and by main for the code that runs before the command. Panics of the tasks that
+// the task runners run in other goroutines are re-raised by the runners, so that they are handled too.
+func RecoverCrash() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	report := utils.NewCrashReport(recovered, ScrubbedArgs(os.Args))
+	// Logged at the debug level, so that the log file has the stack trace, but the error hooks don't submit it.
+	log.Debugf("px crashed: %s\n%s", report.Panic, report.Stack)
+
+	hint := &components.ErrorHint{
+		Code:  exitcodes.CodeCrash,
+		Cause: "This is a bug in px.",
+	}
+	path, err := utils.WriteCrashReport(report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write the crash report: %s\n%s\n", err.Error(), report.Stack)
+		hint.NextSteps = []string{"Report it at https://github.com/pixie-io/pixie/issues, with the stack trace above"}
+	} else {
+		hint.NextSteps = []string{fmt.Sprintf("Report it at https://github.com/pixie-io/pixie/issues, with the crash report in %s", path)}
+	}
+	components.RenderError(os.Stderr, "px crashed unexpectedly", exitcodes.Wrap(exitcodes.Crash, fmt.Errorf("panic: %s", report.Panic)), hint)
+
+	offerCrashReport(report)
+	exitcodes.ExitWith(exitcodes.Crash)
+}
+
+// offerCrashReport submits the crash report if the user agrees to, or agreed to with the crash_reports.submit
+// setting. Nothing is submitted if the user can't be asked.
+func offerCrashReport(report *utils.CrashReport) {
+	if crashReportSubmitter == nil {
+		return
+	}
+	switch pxconfig.Cfg().CrashReports.Submit {
+	case pxconfig.CrashReportsNever:
+		return
+	case pxconfig.CrashReportsAlways:
+	default:
+		// -y accepts the default answers, so it can't consent to submitting the report.
+		if viper.GetBool("y") || !components.IsTerminal(os.Stdin) || !components.IsTerminal(os.Stderr) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, crashReportConsentMessage)
+		submit, err := components.YNPromptE("Submit the crash report to the Pixie team?", false)
+		if err != nil || !submit {
+			return
+		}
+	}
+
+	scrubbed := *report
+	scrubbed.Panic = pxanalytics.Scrub(report.Panic)
+	if err := crashReportSubmitter(&scrubbed); err != nil {
+		utils.WithError(err).Error("Failed to submit the crash report")
+		return
+	}
+	utils.Info("Submitted the crash report, thank you")
+}
+
+func init() {
+	DebugCmd.AddCommand(debugCrashCmd)
+}
+
+// debugCrashCmd panics, to test how crashes are reported.
+var debugCrashCmd = &cobra.Command{
+	Use:   "crash",
+	Short: "Crash px, to test how crashes are reported",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		panic(errors.New("crash test"))
+	},
+}
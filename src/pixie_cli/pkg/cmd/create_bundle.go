@@ -32,7 +32,7 @@ func init() {
 	CreateBundle.Flags().StringArrayP("search_path", "s", []string{},
 		"The paths to search for the pxl files")
 	CreateBundle.MarkFlagRequired("search_path")
-	CreateBundle.Flags().StringP("out", "o", "-", "The output file")
+	CreateBundle.Flags().String("out", "-", "The output file")
 }
 
 // CreateBundle is the 'create-bundle' command. It's used to create a script bundle that can be used by the UI/CLI.
@@ -178,7 +178,7 @@ var DebugPodsCmd = &cobra.Command{
 		if err != nil {
 			utils.WithError(err).Fatal("Could not fetch Vizier pods")
 		}
-		w := components.CreateStreamWriter("table", os.Stdout)
+		w := components.CreateStreamWriter(outputFormat(), os.Stdout)
 		defer w.Finish()
 		w.SetHeader("pods", []string{"Name", "Phase", "Restarts", "Message", "Reason", "Start Time"})
 		for _, pod := range pods {
@@ -201,7 +201,7 @@ var DebugContainersCmd = &cobra.Command{
 		if err != nil {
 			utils.WithError(err).Fatal("Could not fetch Vizier pods")
 		}
-		w := components.CreateStreamWriter("table", os.Stdout)
+		w := components.CreateStreamWriter(outputFormat(), os.Stdout)
 		defer w.Finish()
 		w.SetHeader("containers", []string{"Name", "Pod", "State", "Restarts", "Message", "Reason", "Start Time"})
 		for _, pod := range pods {
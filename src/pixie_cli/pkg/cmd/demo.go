@@ -19,43 +19,64 @@
 package cmd
 
 import (
-	"archive/tar"
-	"bytes"
-	"compress/gzip"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/cenkalti/backoff/v4"
 	"github.com/fatih/color"
-	"github.com/segmentio/analytics-go/v3"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	v1 "k8s.io/api/core/v1"
-	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
-	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/utils/shared/k8s"
 )
 
-const manifestFile = "manifest.json"
-
-var errNamespaceAlreadyExists = errors.New("namespace already exists")
-var errCertMgrDoesNotExist = errors.New("cert-manager does not exist")
+// defaultVizierNamespace is the namespace Pixie is deployed to by px deploy.
+const defaultVizierNamespace = "pl"
 
 func init() {
-	DemoCmd.PersistentFlags().String("artifacts", "https://storage.googleapis.com/pixie-prod-artifacts/prod-demo-apps", "The path to the demo apps")
+	DemoCmd.PersistentFlags().String("artifacts", demo.DefaultArtifactsURL, "The location of the demo apps. Supports http(s)://, gs://<bucket>/<path>, s3://<bucket>/<path>, az://<account>/<container>/<path> and file://<path>. A comma-separated list of mirrors may be given, which are tried in order.")
+	DemoCmd.PersistentFlags().String("channel", "stable", "The release channel of the demo apps to use (stable, beta, dev)")
+	DemoCmd.PersistentFlags().String("manifest_overrides", "", "Path to a JSON file that is deep-merged into the downloaded demo manifest. Defaults to ~/.config/pixie/demo-overrides.json if it exists.")
+	DemoCmd.PersistentFlags().StringArray("artifacts_header", []string{}, "A header added to all HTTP(S) artifact requests, in the format \"Name: value\". May be repeated.")
+	DemoCmd.PersistentFlags().String("artifacts_sa_key", "", "Path to a GCP service account key used to read gs:// artifacts. If unset, application default credentials are used.")
+	DemoCmd.PersistentFlags().String("max_download_rate", "", "The maximum rate, per second, that artifacts are downloaded at, for example: 2MB or 500KiB. Unlimited by default.")
+
+	deployDemoCmd.Flags().String("arch", "", "The node architecture to deploy the demo for (amd64, arm64). Detected from the cluster nodes by default.")
+	deployDemoCmd.Flags().String("registry", "", "A registry prefix that all demo container images are rewritten to, for example: registry.corp/px-demos")
+	deployDemoCmd.Flags().String("node_selector", "", "A node selector added to every demo pod, for example: pool=demos,disk=ssd")
+	deployDemoCmd.Flags().StringArray("toleration", []string{}, "A toleration added to every demo pod, in the format key[=value][:Effect]. May be repeated.")
+	deployDemoCmd.Flags().Duration("ttl", 0, "How long the demo app should live for, for example: 4h. Expired demo apps are removed by px demo delete --expired.")
+	deployDemoCmd.Flags().Bool("wait", false, "Wait for the demo app's workloads to become ready")
+	deployDemoCmd.Flags().Duration("wait_timeout", 5*time.Minute, "How long to wait for the demo app to become ready with --wait")
+	deployDemoCmd.Flags().Bool("open", false, "Open the demo app's frontend in a browser once it is ready. Implies --wait.")
+	deployDemoCmd.Flags().Int("local_port", 8080, "The local port to forward the demo frontend to with --open, if it isn't exposed through a LoadBalancer")
+	deployDemoCmd.Flags().Bool("suffix", false, "Deploy into a suffixed namespace such as <app>-2 if the app's namespace already exists and was not created by px")
+	deployDemoCmd.Flags().Bool("force", false, "Re-apply the demo YAMLs into the existing namespace instead of failing, for example to repair a broken demo")
+	deployDemoCmd.Flags().Bool("force_conflicts", false, "Take ownership of fields of the demo resources that were changed by other tools, such as kubectl, instead of failing")
+	deployDemoCmd.Flags().String("openshift_scc", "anyuid", "On OpenShift, the SecurityContextConstraints to grant the demo app's service accounts, since the demo images run as fixed users. Set to empty to skip")
+	deployDemoCmd.Flags().Bool("prune", true, "With --force, delete resources left behind by a previous deploy of the demo app that are no longer part of it")
+	deployDemoCmd.Flags().Bool("require_pixie", false, "Fail instead of offering to deploy Pixie if it is not installed on the cluster")
+	deployDemoCmd.Flags().Bool("print_images", false, "Print the images that need to be mirrored to --registry and exit without deploying")
+	deployDemoCmd.Flags().Bool("resume", false, "Resume a failed deploy of the demo app, skipping the steps that already completed")
+	deployDemoCmd.Flags().Bool("rollback_on_interrupt", false, "Whether a deploy interrupted with Ctrl+C deletes what it already created, rather than keeping it to be resumed. Asks if unset.")
+
+	deleteDemoCmd.Flags().String("namespace", "", "The namespace the demo app was deployed to. Defaults to the name of the app, or the suffixed namespace px deployed it to.")
+	deleteDemoCmd.Flags().Bool("expired", false, "Delete all demo apps whose --ttl has passed")
+	deleteDemoCmd.Flags().Bool("dry_run", false, "Print the steps that would delete the demo app without running them")
+
+	listDemoCmd.Flags().Bool("show_deprecated", false, "Whether to include deprecated demo apps in the list")
 
 	DemoCmd.AddCommand(interactDemoCmd)
 	DemoCmd.AddCommand(listDemoCmd)
@@ -68,13 +89,14 @@ var DemoCmd = &cobra.Command{
 	Use:   "demo",
 	Short: "Manage demo apps",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		// This pre run might be run from a subcommand. To bind the correct flag, we should check
-		// the persistent flags on both the current command and the parent.
-		if cmd.PersistentFlags().Lookup("artifacts") != nil {
-			viper.BindPFlag("artifacts", cmd.PersistentFlags().Lookup("artifacts"))
-			return
+		bindDemoFlags(cmd)
+		applyGlobalFlags(cmd, args)
+		promptForAnalyticsConsent(cmd)
+		trackKubernetesVersion()
+		if isJSONOutput(demoOutputFormat()) {
+			// Machine-readable task events go to stderr, so that they don't mix with the JSON output on stdout.
+			utils.SetTaskEventWriter(os.Stderr)
 		}
-		viper.BindPFlag("artifacts", cmd.Parent().PersistentFlags().Lookup("artifacts"))
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		utils.Info("Nothing here... Please execute one of the subcommands")
@@ -88,16 +110,10 @@ var interactDemoCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run:   interactCmd,
 	PreRun: func(cmd *cobra.Command, args []string) {
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Print Interact Instructions",
-		})
+		pxanalytics.Track("Demo Print Interact Instructions", nil)
 	},
 	PostRun: func(cmd *cobra.Command, args []string) {
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Print Interact Instructions Complete",
-		})
+		pxanalytics.Track("Demo Print Interact Instructions Complete", nil)
 	},
 }
 
@@ -106,65 +122,149 @@ var listDemoCmd = &cobra.Command{
 	Short: "List available demo apps",
 	Run:   listCmd,
 	PreRun: func(cmd *cobra.Command, args []string) {
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo List Apps",
-		})
+		pxanalytics.Track("Demo List Apps", nil)
 	},
 	PostRun: func(cmd *cobra.Command, args []string) {
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo List Apps Complete",
-		})
+		pxanalytics.Track("Demo List Apps Complete", nil)
 	},
 }
 
 var deleteDemoCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete demo app",
-	Args:  cobra.ExactArgs(1),
-	Run:   deleteCmd,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if expired, _ := cmd.Flags().GetBool("expired"); expired {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: deleteCmd,
 	PreRun: func(cmd *cobra.Command, args []string) {
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Delete App",
-			Properties: analytics.NewProperties().
-				Set("app", args[0]),
-		})
+		pxanalytics.Track("Demo Delete App", pxanalytics.NewProperties().
+			Set("app", strings.Join(args, "")))
 	},
 	PostRun: func(cmd *cobra.Command, args []string) {
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Delete App Complete",
-			Properties: analytics.NewProperties().
-				Set("app", args[0]),
-		})
+		pxanalytics.Track("Demo Delete App Complete", pxanalytics.NewProperties().
+			Set("app", strings.Join(args, "")))
 	},
 }
 
 var deployDemoCmd = &cobra.Command{
 	Use:   "deploy",
-	Short: "Deploy demo app",
-	Args:  cobra.ExactArgs(1),
+	Short: "Deploy demo app. Offers a list of the available apps if no app is given.",
+	Args:  cobra.MaximumNArgs(1),
 	Run:   deployCmd,
 	PreRun: func(cmd *cobra.Command, args []string) {
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Deploy App",
-			Properties: analytics.NewProperties().
-				Set("app", args[0]),
-		})
+		pxanalytics.Track("Demo Deploy App", pxanalytics.NewProperties().
+			Set("app", demoAppArg(args)))
 	},
 	PostRun: func(cmd *cobra.Command, args []string) {
-		defer pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Deploy App Complete",
-			Properties: analytics.NewProperties().
-				Set("app", args[0]),
-		})
+		defer pxanalytics.Track("Demo Deploy App Complete", pxanalytics.NewProperties().
+			Set("app", demoAppArg(args)))
 	},
 }
 
+// bindDemoFlags binds the persistent flags of the demo command to viper.
+func bindDemoFlags(cmd *cobra.Command) {
+	// This might be run from a subcommand. To bind the correct flag, we should check
+	// the persistent flags on both the current command and the parent.
+	flags := cmd.PersistentFlags()
+	if flags.Lookup("artifacts") == nil {
+		flags = cmd.Parent().PersistentFlags()
+	}
+	viper.BindPFlag("artifacts", flags.Lookup("artifacts"))
+	viper.BindPFlag("artifacts_header", flags.Lookup("artifacts_header"))
+	viper.BindPFlag("artifacts_sa_key", flags.Lookup("artifacts_sa_key"))
+	viper.BindPFlag("channel", flags.Lookup("channel"))
+	viper.BindPFlag("manifest_overrides", flags.Lookup("manifest_overrides"))
+	viper.BindPFlag("max_download_rate", flags.Lookup("max_download_rate"))
+}
+
+// pickedDemoApp is the demo app that the user picked, when none was given as an argument.
+var pickedDemoApp string
+
+// demoAppArg returns the demo app given as an argument. If there is none, the user picks one of the apps in the
+// manifest.
+func demoAppArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	if pickedDemoApp != "" {
+		return pickedDemoApp
+	}
+
+	manifest, err := newDemoClient().Manifest()
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatal("Could not download manifest file")
+	}
+	var apps []string
+	for app, appSpec := range manifest {
+		if appSpec == nil || appSpec.Deprecated != nil {
+			continue
+		}
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	pickedDemoApp, err = demoPrompter.Select("Which demo app do you want to deploy?", apps, "")
+	if err != nil {
+		utils.WithError(err).Fatal("No demo app selected. Pass the app to deploy as an argument, see px demo list.")
+	}
+	return pickedDemoApp
+}
+
+// demoTransforms returns the transforms that the demo app's YAMLs are deployed with, from the --registry,
+// --node_selector and --toleration flags. If pinArch is set, the pods are also scheduled on nodes of that
+// architecture only. Images rewritten to --registry are added to images.
+func demoTransforms(cmd *cobra.Command, appName string, archSpec *demo.ArchSpec, pinArch string, images map[string]string) []demoResourceTransform {
+	transforms := []demoResourceTransform{
+		labelTransform(map[string]string{
+			demo.ResourceLabel: appName,
+		}),
+	}
+	if archSpec != nil && len(archSpec.Images) != 0 {
+		transforms = append(transforms, imageReplaceTransform(archSpec.Images))
+	}
+	if registry, _ := cmd.Flags().GetString("registry"); registry != "" {
+		transforms = append(transforms, imageRewriteTransform(registry, images))
+	}
+
+	if pinArch != "" {
+		transforms = append(transforms, nodeSelectorTransform(map[string]string{v1.LabelArchStable: pinArch}))
+	}
+	nodeSelectorStr, _ := cmd.Flags().GetString("node_selector")
+	if nodeSelectorStr != "" {
+		nodeSelector, err := k8s.KeyValueStringToMap(nodeSelectorStr)
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("--node_selector must be specified through the following format: key1=value1,key2=value2")
+		}
+		transforms = append(transforms, nodeSelectorTransform(nodeSelector))
+	}
+
+	tolerationStrs, _ := cmd.Flags().GetStringArray("toleration")
+	if len(tolerationStrs) != 0 {
+		tolerations := make([]map[string]interface{}, len(tolerationStrs))
+		for i, t := range tolerationStrs {
+			var err error
+			tolerations[i], err = parseToleration(t)
+			if err != nil {
+				utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("--toleration must be specified through the following format: key[=value][:Effect]")
+			}
+		}
+		transforms = append(transforms, tolerationsTransform(tolerations))
+	}
+	return transforms
+}
+
+// demoOutputFormat returns the format that demo commands write tables in, which is a table by default.
+func demoOutputFormat() string {
+	if format := outputFormat(); format != "" {
+		return format
+	}
+	return "table"
+}
+
 func interactCmd(cmd *cobra.Command, args []string) {
 	appName := args[0]
 
@@ -173,31 +273,23 @@ func interactCmd(cmd *cobra.Command, args []string) {
 		if err == nil {
 			return
 		}
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Print Interact Instructions Error",
-			Properties: analytics.NewProperties().
-				Set("error", err.Error()),
-		})
+		pxanalytics.Track("Demo Print Interact Instructions Error", pxanalytics.NewProperties().
+			Set("error", err.Error()))
 	}()
 
-	manifest, err := downloadManifest(viper.GetString("artifacts"))
+	manifest, err := newDemoClient().Manifest()
 	if err != nil {
 		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 		log.WithError(err).Fatal("Could not download manifest file")
 	}
-	appSpec, ok := manifest[appName]
-	// When a demo app is deprecated, its contents will be set to null in manifest.json.
-	if !ok || appSpec == nil {
-		utils.Fatalf("%s is not a supported demo app", appName)
-	}
+	appSpec := getAppSpec(manifest, appName)
 	instructions := strings.Join(appSpec.Instructions, "\n")
 
 	p := func(s string, a ...interface{}) {
 		fmt.Fprintf(os.Stderr, s, a...)
 	}
 	p(color.CyanString("Post-deploy instructions for %s demo app:\n\n", args[0]))
-	p(instructions + "\n\n")
+	p(components.RenderMarkdown(instructions) + "\n\n")
 }
 
 func listCmd(cmd *cobra.Command, args []string) {
@@ -206,36 +298,60 @@ func listCmd(cmd *cobra.Command, args []string) {
 		if err == nil {
 			return
 		}
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo List Apps Error",
-			Properties: analytics.NewProperties().
-				Set("error", err.Error()),
-		})
+		pxanalytics.Track("Demo List Apps Error", pxanalytics.NewProperties().
+			Set("error", err.Error()))
 	}()
 
-	manifest, err := downloadManifest(viper.GetString("artifacts"))
+	manifest, err := newDemoClient().Manifest()
 	if err != nil {
 		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 		log.WithError(err).Fatal("Could not download manifest file")
 	}
 
-	w := components.CreateStreamWriter("table", os.Stdout)
+	showDeprecated, _ := cmd.Flags().GetBool("show_deprecated")
+	deployed := deployedDemoChannels()
+
+	utils.Infof("Showing demo apps from the %s channel", demoChannel())
+	w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
 	defer w.Finish()
-	w.SetHeader("demo_list", []string{"Name", "Description"})
+	w.SetHeader("demo_list", []string{"Name", "Description", "Deployed"})
+	listed, listedDeployed := 0, 0
 	for app, appSpec := range manifest {
-		// When a demo app is deprecated, its contents will be set to null in manifest.json.
-		if appSpec != nil {
-			err = w.Write([]interface{}{app, appSpec.Description})
-			if err != nil {
-				log.WithError(err).Error("Failed to write demo app")
+		description := ""
+		switch {
+		// When a demo app is deprecated without metadata, its contents will be set to null in manifest.json.
+		case appSpec == nil:
+			if !showDeprecated {
+				continue
+			}
+			description = "DEPRECATED"
+		case appSpec.Deprecated != nil:
+			if !showDeprecated {
 				continue
 			}
+			description = "DEPRECATED: " + appSpec.Deprecated.Guidance()
+		default:
+			description = appSpec.Description
+		}
+		err = w.Write([]interface{}{app, description, deployed[app]})
+		if err != nil {
+			log.WithError(err).Error("Failed to write demo app")
+			continue
+		}
+		listed++
+		if deployed[app] != "" {
+			listedDeployed++
 		}
 	}
+	_ = components.SetFooter(w, fmt.Sprintf("%d apps", listed), "", fmt.Sprintf("%d deployed", listedDeployed))
 }
 
 func deleteCmd(cmd *cobra.Command, args []string) {
+	dryRun, _ := cmd.Flags().GetBool("dry_run")
+	if expired, _ := cmd.Flags().GetBool("expired"); expired {
+		deleteExpiredDemoApps(dryRun)
+		return
+	}
 	appName := args[0]
 
 	var err error
@@ -243,16 +359,12 @@ func deleteCmd(cmd *cobra.Command, args []string) {
 		if err == nil {
 			return
 		}
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Delete App Error",
-			Properties: analytics.NewProperties().
-				Set("app", appName).
-				Set("error", err.Error()),
-		})
+		pxanalytics.Track("Demo Delete App Error", pxanalytics.NewProperties().
+			Set("app", appName).
+			Set("error", err.Error()))
 	}()
 
-	manifest, err := downloadManifest(viper.GetString("artifacts"))
+	manifest, err := newDemoClient().Manifest()
 	if err != nil {
 		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 		log.WithError(err).Fatal("Could not download manifest file")
@@ -264,303 +376,371 @@ func deleteCmd(cmd *cobra.Command, args []string) {
 	kubeAPIConfig := k8s.GetClientAPIConfig()
 	currentCluster := kubeAPIConfig.CurrentContext
 	utils.Infof("Deleting demo app %s from the following cluster: %s", appName, currentCluster)
-	clusterOk := components.YNPrompt("Is the cluster correct?", true)
+	clusterOk := demoPrompter.YNPrompt("Is the cluster correct?", true)
 	if !clusterOk {
-		utils.Fatal("Cluster is not correct. Aborting.")
+		utils.WithExitCode(exitcodes.Aborted).Fatal("Cluster is not correct. Aborting.")
 	}
 
-	if !namespaceExists(appName) {
-		utils.Fatalf("Namespace %s does not exist on cluster %s", appName, currentCluster)
+	namespace := demoAppNamespace(cmd, appName)
+	if !demoClusterClient().NamespaceExists(namespace) {
+		utils.Fatalf("Namespace %s does not exist on cluster %s", namespace, currentCluster)
 	}
 
-	if err = deleteDemoApp(appName); err != nil {
+	err = newDemoClient().Delete(appName, &demo.DeleteOptions{Namespace: namespace, DryRun: dryRun})
+	if err != nil {
+		var timeoutErr *utils.TimeoutError
+		if errors.As(err, &timeoutErr) {
+			components.RenderError(os.Stderr, fmt.Sprintf("Error deleting demo app %s", appName), err, &components.ErrorHint{
+				Cause: fmt.Sprintf("Namespace %s is stuck terminating, usually because a resource in it has a finalizer that can't complete.", namespace),
+				NextSteps: []string{
+					fmt.Sprintf("Run kubectl get all -n %s to find the remaining resources.", namespace),
+					"Remove the finalizers of the stuck resources, then run px demo delete again.",
+				},
+			})
+			exitcodes.ExitWith(exitcodes.Cluster)
+		}
 		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 		log.WithError(err).Fatalf("Error deleting demo app %s from cluster %s", appName, currentCluster)
-	} else {
+	} else if !dryRun {
+		removeDemoCheckpoint(appName)
 		utils.Infof("Successfully deleted demo app %s from cluster %s", appName, currentCluster)
 	}
 }
 
 func deployCmd(cmd *cobra.Command, args []string) {
-	appName := args[0]
+	appName := demoAppArg(args)
 
 	var err error
 	defer func() {
 		if err == nil {
 			return
 		}
-		pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Demo Deploy App Error",
-			Properties: analytics.NewProperties().
-				Set("app", appName).
-				Set("error", err.Error()),
-		})
+		pxanalytics.Track("Demo Deploy App Error", pxanalytics.NewProperties().
+			Set("app", appName).
+			Set("error", err.Error()))
 	}()
 
-	manifest, err := downloadManifest(viper.GetString("artifacts"))
+	client := newDemoClient()
+	manifest, err := client.Manifest()
 	if err != nil {
 		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 		log.WithError(err).Fatal("Could not download manifest file")
 	}
 
-	appSpec, ok := manifest[appName]
-	// When a demo app is deprecated, its contents will be set to null in manifest.json.
-	if !ok || appSpec == nil {
-		utils.Fatalf("%s is not a supported demo app", appName)
-	}
+	appSpec := getDeployableAppSpec(manifest, appName)
 	instructions := strings.Join(appSpec.Instructions, "\n")
 
-	yamls, err := downloadDemoAppYAMLs(appName, viper.GetString("artifacts"))
+	registry, _ := cmd.Flags().GetString("registry")
+	printImages, _ := cmd.Flags().GetBool("print_images")
+
+	// The cluster is confirmed before anything is read from it, which only printing the images of a given --arch
+	// doesn't do.
+	kubeAPIConfig := k8s.GetClientAPIConfig()
+	currentCluster := kubeAPIConfig.CurrentContext
+	if archFlag, _ := cmd.Flags().GetString("arch"); !printImages || archFlag == "" {
+		utils.Infof("Deploying demo app %s from the %s channel to the following cluster: %s", appName, demoChannel(), currentCluster)
+		clusterOk := demoPrompter.YNPrompt("Is the cluster correct?", true)
+		if !clusterOk {
+			utils.WithExitCode(exitcodes.Aborted).Fatal("Cluster is not correct. Aborting.")
+		}
+	}
+
+	arch, pinArch := demoArch(cmd)
+	yamls, err := client.FetchBundle(appSpec.BundleName(appName, arch))
 	if err != nil {
 		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 		log.WithError(err).Fatalf("Could not download demo yaml apps for app '%s'", appName)
 	}
 
-	kubeAPIConfig := k8s.GetClientAPIConfig()
-	currentCluster := kubeAPIConfig.CurrentContext
-	utils.Infof("Deploying demo app %s to the following cluster: %s", appName, currentCluster)
-	clusterOk := components.YNPrompt("Is the cluster correct?", true)
-	if !clusterOk {
-		utils.Error("Cluster is not correct. Aborting.")
+	images := make(map[string]string)
+	transforms := demoTransforms(cmd, appName, appSpec.Architectures[arch], pinArch, images)
+
+	yamls, err = transformDemoYAMLs(yamls, transforms...)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to prepare YAMLs for demo app %s", appName)
+	}
+
+	if printImages {
+		if registry == "" {
+			utils.WithExitCode(exitcodes.Usage).Fatal("--print_images requires --registry to be set")
+		}
+		w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+		w.SetHeader("demo_images", []string{"Source", "Mirror"})
+		for src, dst := range images {
+			if err := w.Write([]interface{}{src, dst}); err != nil {
+				log.WithError(err).Error("Failed to write image")
+			}
+		}
+		w.Finish()
 		return
 	}
 
-	err = setupDemoApp(appName, yamls, appSpec.Dependencies)
+	footprint, err := computeDemoFootprint(yamls)
 	if err != nil {
-		if errors.Is(err, errNamespaceAlreadyExists) {
-			utils.Error("Failed to deploy demo application: namespace already exists.")
-			return
-		} else if errors.Is(err, errCertMgrDoesNotExist) {
-			utils.Error("Failed to deploy demo application: cert-manager needs to be installed. To deploy, please follow instructions at https://cert-manager.io/docs/getting-started/")
-			return
+		utils.WithError(err).Fatalf("Failed to parse YAMLs for demo app %s", appName)
+	}
+	printDemoFootprint(appName, footprint)
+	capacity, capacityErr := getClusterCapacity()
+	if capacityErr != nil {
+		utils.WithError(capacityErr).Error("Failed to get the cluster's capacity, skipping capacity check")
+	} else if !compareDemoFootprint(footprint, capacity) {
+		if !demoPrompter.YNPrompt("The demo app may not fit on the cluster. Continue anyway?", false) {
+			utils.WithExitCode(exitcodes.Aborted).Fatal("Aborting.")
 		}
-		// Using log.Errorf rather than CLI log in order to track this unexpected error in Sentry.
-		log.WithError(err).Errorf("Error deploying demo application, deleting namespace %s", appName)
-		// Note: If you can specify the namespace for the demo app in the future, we shouldn't delete the namespace.
-		if err = deleteDemoApp(appName); err != nil {
-			// Using log.Errorf rather than CLI log in order to track this unexpected error in Sentry.
-			log.WithError(err).Errorf("Error deleting namespace %s", appName)
+	}
+
+	requirePixie, _ := cmd.Flags().GetBool("require_pixie")
+	ensurePixieDeployed(requirePixie)
+
+	ttl, _ := cmd.Flags().GetDuration("ttl")
+	force, _ := cmd.Flags().GetBool("force")
+	prune, _ := cmd.Flags().GetBool("prune")
+	forceConflicts, _ := cmd.Flags().GetBool("force_conflicts")
+	scc, _ := cmd.Flags().GetString("openshift_scc")
+	suffix, _ := cmd.Flags().GetBool("suffix")
+	resume, _ := cmd.Flags().GetBool("resume")
+	namespace, checkpoint := demoDeployCheckpoint(appName, yamls, resume, func() string {
+		return resolveDemoNamespace(appName, force, suffix)
+	})
+	applied, err := client.Deploy(appName, yamls, &demo.DeployOptions{
+		Namespace:            namespace,
+		NamespaceAnnotations: demoNamespaceAnnotations(ttl),
+		Dependencies:         appSpec.Dependencies,
+		Force:                force,
+		Prune:                prune,
+		ForceConflicts:       forceConflicts,
+		SCC:                  scc,
+		MultiNamespace:       appSpec.MultiNamespace,
+		Checkpoint:           checkpoint,
+		ConfirmRollback:      demoRollbackConfirmer(cmd, appName),
+	})
+	printApplyWarnings(applied)
+	if err != nil {
+		var conflictErr *k8s.ApplyConflictError
+		switch {
+		case errors.Is(err, demo.ErrNamespaceAlreadyExists), errors.Is(err, demo.ErrCertManagerMissing):
+			// Nothing was deployed, so there is nothing to roll back or resume.
+			components.PrintError("Failed to deploy demo application", err)
+			exitcodes.Exit(err)
+		case errors.Is(err, utils.ErrInterrupted):
+			utils.Errorf("Deploy of demo app %s was interrupted", appName)
+		case errors.As(err, &conflictErr):
+			printApplyConflicts(conflictErr)
+			components.RenderError(os.Stderr, "Failed to deploy demo application", err, applyConflictHint(appName))
+		case errors.Is(err, demo.ErrSCCGrantFailed), errors.Is(err, k8s.ErrForbidden):
+			// Missing RBAC permissions are expected on locked down clusters, so they aren't tracked in Sentry.
+			components.PrintError("Failed to deploy demo application", err)
+		case force:
+			// Using log.Error rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Errorf("Error redeploying demo application into namespace %s", namespace)
+		default:
+			// Using log.Error rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Error("Failed to deploy demo application")
 		}
-		utils.Fatal("Failed to deploy demo application.")
+		// The deploy was rolled back, unless a step completed and it was kept to be resumed.
+		printDemoResumeHint(appName, namespace, checkpoint)
+		exitcodes.Exit(err)
+	}
+
+	utils.Infof("Successfully deployed demo app %s to namespace %s on cluster %s: %s.", appName, namespace, currentCluster, k8s.SummarizeAppliedResources(applied))
+	printAppliedResources(applied)
+	if ttl > 0 {
+		utils.Infof("Demo app %s expires in %s. Run %s to clean up expired demo apps.", appName, ttl, color.GreenString("px demo delete --expired"))
 	}
 
-	utils.Infof("Successfully deployed demo app %s to cluster %s.", args[0], currentCluster)
+	wait, _ := cmd.Flags().GetBool("wait")
+	openFrontend, _ := cmd.Flags().GetBool("open")
+	if wait || openFrontend {
+		waitTimeout, _ := cmd.Flags().GetDuration("wait_timeout")
+		if err := waitForDemoApp(namespace, waitTimeout); err != nil {
+			printDemoWarningEvents(namespace)
+			components.FatalError(fmt.Sprintf("Demo app %s did not become ready", appName), err)
+		}
+	}
 
 	p := func(s string, a ...interface{}) {
 		fmt.Fprintf(os.Stderr, s, a...)
 	}
 	b := color.New(color.Bold)
 	p(color.CyanString("==> ") + b.Sprint("Next Steps:\n\n"))
-	p(instructions)
-}
-
-type manifestAppSpec struct {
-	Description  string          `json:"description"`
-	Instructions []string        `json:"instructions"`
-	Dependencies map[string]bool `json:"dependencies"`
-}
+	p(components.RenderMarkdown(instructions))
 
-type manifest = map[string]*manifestAppSpec
+	if appSpec.LiveView != nil && appSpec.LiveView.Script != "" {
+		scriptArgs := appSpec.LiveView.ScriptArgs(namespace)
+		invocation := "px live " + appSpec.LiveView.Script
+		if len(scriptArgs) != 0 {
+			invocation += " -- " + strings.Join(scriptArgs, " ")
+		}
+		p("\n\nTo see the data Pixie collects from %s, run: %s\n", appName, color.GreenString("%s", invocation))
+		if !openFrontend && demoPrompter.YNPrompt("Run the live view now?", false) {
+			LiveCmd.Run(LiveCmd, append([]string{appSpec.LiveView.Script}, scriptArgs...))
+			return
+		}
+	}
 
-func downloadGCSFileFromHTTP(dirURL, filename string) ([]byte, error) {
-	// Get the data
-	resp, err := http.Get(fmt.Sprintf("%s/%s", dirURL, filename))
-	if err != nil {
-		return nil, err
+	if openFrontend {
+		if appSpec.Frontend == nil || appSpec.Frontend.Service == "" {
+			utils.Errorf("Demo app %s does not declare a web frontend to open", appName)
+			return
+		}
+		localPort, _ := cmd.Flags().GetInt("local_port")
+		p("\n")
+		openDemoFrontend(namespace, appSpec.Frontend, localPort)
 	}
-	defer resp.Body.Close()
-	return io.ReadAll(resp.Body)
 }
 
-func downloadManifest(artifacts string) (manifest, error) {
-	jsonBytes, err := downloadGCSFileFromHTTP(artifacts, manifestFile)
+// deployedDemoChannels returns the release channel of each demo app deployed on the current cluster.
+// Errors are ignored, since listing demo apps should not require a cluster.
+func deployedDemoChannels() map[string]string {
+	channels := make(map[string]string)
+	kubeConfig, err := k8s.LoadConfig()
 	if err != nil {
-		return nil, err
+		return channels
 	}
-
-	jsonManifest := make(manifest)
-	err = json.Unmarshal(jsonBytes, &jsonManifest)
+	kubeConfig.Timeout = 5 * time.Second
+	clientset := k8s.GetClientset(kubeConfig)
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{LabelSelector: demo.ChannelLabel})
 	if err != nil {
-		return nil, err
+		return channels
 	}
-	return jsonManifest, nil
-}
-
-func deleteDemoApp(appName string) error {
-	deleteDemo := []utils.Task{
-		newTaskWrapper(fmt.Sprintf("Deleting demo app %s", appName), func() error {
-			kubeConfig := k8s.GetConfig()
-			clientset := k8s.GetClientset(kubeConfig)
-
-			// Resources labeled as "pixie-demo-initial-cleanup" should be cleaned up first.
-			od := k8s.ObjectDeleter{
-				Clientset:  clientset,
-				RestConfig: kubeConfig,
-				Timeout:    2 * time.Minute,
-			}
-
-			_, err := od.DeleteByLabel(fmt.Sprintf("pixie-demo-initial-cleanup=true,pixie-demo=%s", appName))
-			if err != nil {
-				return err
-			}
-
-			// Delete the remaining resources before namespace deletion.
-			od = k8s.ObjectDeleter{
-				Clientset:  clientset,
-				RestConfig: kubeConfig,
-				Timeout:    2 * time.Minute,
-			}
-
-			_, err = od.DeleteByLabel(fmt.Sprintf("pixie-demo=%s", appName))
-			if err != nil {
-				return err
-			}
-
-			err = clientset.CoreV1().Namespaces().Delete(context.Background(), appName, metav1.DeleteOptions{})
-			if err != nil {
-				return err
-			}
-			t := time.NewTimer(180 * time.Second)
-			defer t.Stop()
-
-			s := time.NewTicker(5 * time.Second)
-			defer s.Stop()
-
-			for {
-				select {
-				case <-t.C:
-					return errors.New("timeout waiting for namespace deletion")
-				default:
-					_, err := clientset.CoreV1().Namespaces().Get(context.Background(), appName, metav1.GetOptions{})
-					if k8s_errors.IsNotFound(err) {
-						return nil
-					}
-					if err != nil {
-						return err
-					}
-					<-s.C
-				}
-			}
-		}),
+	for _, ns := range namespaces.Items {
+		appName := ns.Labels[demo.AppLabel]
+		if appName == "" {
+			appName = ns.Name
+		}
+		channels[appName] = ns.Labels[demo.ChannelLabel]
 	}
-	tr := utils.NewSerialTaskRunner(deleteDemo)
-	return tr.RunAndMonitor()
+	return channels
 }
 
-func downloadDemoAppYAMLs(appName, artifacts string) (map[string][]byte, error) {
-	targzBytes, err := downloadGCSFileFromHTTP(artifacts, fmt.Sprintf("%s.tar.gz", appName))
-	if err != nil {
-		return nil, err
+// demoArch returns the architecture to deploy the demo app for, from --arch or else detected from the cluster. It
+// also returns the architecture that the pods must be scheduled on, if the detection fell back to amd64 on a cluster
+// with nodes of mixed architectures, or else an empty string.
+func demoArch(cmd *cobra.Command) (string, string) {
+	if arch, _ := cmd.Flags().GetString("arch"); arch != "" {
+		return arch, ""
 	}
-	gzipReader, err := gzip.NewReader(bytes.NewReader(targzBytes))
+	arch, mixed, err := detectClusterArch()
 	if err != nil {
-		return nil, err
+		utils.WithError(err).Fatal("Failed to detect the cluster architecture, please specify --arch")
 	}
-	defer gzipReader.Close()
-
-	tarReader := tar.NewReader(gzipReader)
-	outputYAMLs := map[string][]byte{}
-
-	for {
-		hdr, err := tarReader.Next()
-		if err == io.EOF {
-			break // End of archive
-		}
-		if err != nil {
-			return nil, err
-		}
+	if mixed {
+		return arch, arch
+	}
+	return arch, ""
+}
 
-		if !strings.HasSuffix(hdr.Name, ".yaml") {
-			continue
-		}
+// detectClusterArch returns the node architecture of the current cluster, and whether the cluster has nodes of mixed
+// architectures. Mixed clusters fall back to amd64, which is what the default demo artifacts are built for.
+func detectClusterArch() (string, bool, error) {
+	nodes, err := demoKube.Clientset().CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return "", false, err
+	}
 
-		contents, err := io.ReadAll(tarReader)
-		if err != nil {
-			return nil, err
+	archs := make(map[string]bool)
+	for _, n := range nodes.Items {
+		archs[n.Status.NodeInfo.Architecture] = true
+	}
+	if len(archs) != 1 {
+		if len(archs) > 1 {
+			utils.Infof("Cluster has nodes with mixed architectures, deploying amd64 artifacts to the amd64 nodes, "+
+				"with a %s node selector. Use --arch to override.", v1.LabelArchStable)
+			return "amd64", true, nil
 		}
-		outputYAMLs[hdr.Name] = contents
+		return "amd64", false, nil
 	}
-	return outputYAMLs, nil
+	for arch := range archs {
+		return arch, false, nil
+	}
+	return "amd64", false, nil
 }
 
-func namespaceExists(namespace string) bool {
-	kubeConfig := k8s.GetConfig()
-	clientset := k8s.GetClientset(kubeConfig)
-	_, err := clientset.CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
-	return err == nil
-}
+// ensurePixieDeployed checks whether Pixie is deployed on the current cluster, and if not, either
+// fails or offers to deploy it, since the demo apps are only useful with Pixie observing them.
+func ensurePixieDeployed(requirePixie bool) {
+	if pixieDeployed() {
+		return
+	}
+	if requirePixie {
+		utils.Fatalf("Pixie is not deployed on the current cluster. Run %s first.", color.GreenString("px deploy"))
+	}
 
-func createNamespace(namespace string) error {
-	kubeConfig := k8s.GetConfig()
-	clientset := k8s.GetClientset(kubeConfig)
-	_, err := clientset.CoreV1().Namespaces().Create(context.Background(), &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, metav1.CreateOptions{})
-	return err
+	utils.Info("Pixie is not deployed on the current cluster, so no data will be collected from the demo app.")
+	if !demoPrompter.YNPrompt("Deploy Pixie before deploying the demo app?", true) {
+		utils.Infof("Skipping Pixie deploy. Run %s to deploy Pixie later.", color.GreenString("px deploy"))
+		return
+	}
+	runPixieDeploy()
 }
 
-func certManagerExists() (bool, error) {
-	kubeConfig := k8s.GetConfig()
-	clientset := k8s.GetClientset(kubeConfig)
-
-	deps, err := clientset.AppsV1().Deployments("").List(context.Background(), metav1.ListOptions{})
+// pixieDeployed returns whether Pixie is deployed on the current cluster, in any namespace, by looking for the pods
+// of Vizier or its operator. If the pods can't be listed across namespaces, such as without RBAC permissions to, it
+// falls back to checking for the namespace that px deploy uses by default.
+func pixieDeployed() bool {
+	selector := k8s.VizierLabelSelector()
+	pods, err := demoKube.Clientset().CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(&selector),
+		Limit:         1,
+	})
 	if err != nil {
-		return false, err
+		log.WithError(err).Debug("Failed to list the pods of Pixie")
+		return demoClusterClient().NamespaceExists(defaultVizierNamespace)
 	}
+	return len(pods.Items) > 0
+}
 
-	for _, d := range deps.Items {
-		if d.Name == "cert-manager" {
-			return true, nil
-		}
+// runPixieDeploy runs px deploy with its default flags, like cobra does when it is run from the command line.
+func runPixieDeploy() {
+	// Parsing the flags also merges the persistent flags of the root command, such as --context, into the flags of
+	// px deploy, which it reads.
+	if err := DeployCmd.ParseFlags(nil); err != nil {
+		utils.WithError(err).Fatal("Failed to parse the flags of px deploy")
 	}
-
-	return false, err
+	args := DeployCmd.Flags().Args()
+	if err := DeployCmd.ValidateArgs(args); err != nil {
+		utils.WithError(err).Fatal("Invalid arguments for px deploy")
+	}
+	DeployCmd.PreRun(DeployCmd, args)
+	DeployCmd.Run(DeployCmd, args)
+	DeployCmd.PostRun(DeployCmd, args)
 }
 
-func setupDemoApp(appName string, yamls map[string][]byte, deps map[string]bool) error {
-	kubeConfig := k8s.GetConfig()
-	clientset := k8s.GetClientset(kubeConfig)
-
-	// Check deps.
-	if deps["cert-manager"] {
-		certMgrExists, err := certManagerExists()
-		if err != nil && !k8s_errors.IsNotFound(err) {
-			return err
-		}
-
-		if !certMgrExists || k8s_errors.IsNotFound(err) {
-			return errCertMgrDoesNotExist
+func printApplyConflicts(err *k8s.ApplyConflictError) {
+	utils.Errorf("%s %s has fields that are managed by other tools:", err.Kind, err.Name)
+	w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+	defer w.Finish()
+	w.SetHeader("demo_apply_conflicts", []string{"Field", "Conflict"})
+	for _, c := range err.Conflicts {
+		if err := w.Write([]interface{}{c.Field, c.Message}); err != nil {
+			log.WithError(err).Error("Failed to write apply conflict")
 		}
 	}
+}
 
-	if namespaceExists(appName) {
-		fmt.Printf("%s: namespace %s already exists. If created with px, run %s to remove\n",
-			color.RedString("Error"), color.RedString(appName), color.GreenString(fmt.Sprintf("px demo delete %s", appName)))
-		return errNamespaceAlreadyExists
+// printApplyWarnings prints the warnings the API server returned for the applied resources, such as
+// deprecated API versions that will stop working after a cluster upgrade.
+func printApplyWarnings(applied []*k8s.AppliedResource) {
+	for _, r := range applied {
+		for _, w := range r.Warnings {
+			utils.WithColor(color.New(color.FgYellow)).Infof("Warning: %s %s: %s", r.Kind, r.Name, w)
+		}
 	}
+}
 
-	tasks := []utils.Task{
-		newTaskWrapper(fmt.Sprintf("Creating namespace %s", appName), func() error {
-			return createNamespace(appName)
-		}),
-		newTaskWrapper(fmt.Sprintf("Deploying %s YAMLs", appName), func() error {
-			for _, yamlBytes := range yamls {
-				yamlBytes := yamlBytes
-				bo := backoff.NewExponentialBackOff()
-				bo.MaxElapsedTime = 5 * time.Minute
-
-				op := func() error {
-					return k8s.ApplyYAML(clientset, kubeConfig, appName, bytes.NewReader(yamlBytes), false)
-				}
-
-				err := backoff.Retry(op, bo)
-				if err != nil {
-					return err
-				}
-			}
-			return nil
-		}),
+func printAppliedResources(applied []*k8s.AppliedResource) {
+	w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+	defer w.Finish()
+	w.SetHeader("demo_resources", []string{"Kind", "Name", "Namespace", "Status", "Error", "Warnings"})
+	components.SetWideColumns(w, "Error", "Warnings")
+	for _, r := range applied {
+		errMsg := ""
+		if r.Err != nil {
+			errMsg = r.Err.Error()
+		}
+		if err := w.Write([]interface{}{r.Kind, r.Name, r.Namespace, r.Status, errMsg, strings.Join(r.Warnings, "; ")}); err != nil {
+			log.WithError(err).Error("Failed to write applied resource")
+		}
 	}
-
-	tr := utils.NewSerialTaskRunner(tasks)
-	return tr.RunAndMonitor()
+	_ = components.SetFooter(w, "Total", fmt.Sprintf("%d resources", len(applied)), "", k8s.SummarizeAppliedResources(applied), "", "")
 }
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// demoMaxConcurrency is the number of workloads of a demo app that are waited on at a time, which bounds the
+// number of concurrent watches on the API server.
+const demoMaxConcurrency = 8
+
+// waitForDemoApp waits until the rollouts of all Deployments, StatefulSets and DaemonSets of the demo app
+// complete, showing the progress of each workload.
+func waitForDemoApp(namespace string, timeout time.Duration) error {
+	clientset := demoKube.Clientset()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	workloads, err := k8s.ListRolloutStatuses(ctx, clientset, namespace)
+	if err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	tasks := make([]utils.Task, len(workloads))
+	for i, w := range workloads {
+		w := w
+		tasks[i] = newContextTaskWrapper(fmt.Sprintf("Waiting for %s %s", strings.ToLower(w.Kind), w.Name), func(taskCtx context.Context) error {
+			taskCtx, cancel := context.WithDeadline(taskCtx, deadline)
+			defer cancel()
+			return k8s.WaitForRollout(taskCtx, clientset, w.Kind, w.Namespace, w.Name, 5*time.Second, nil)
+		})
+	}
+	return utils.NewParallelTaskRunner(tasks, demoMaxConcurrency).RunAndMonitor()
+}
+
+// printDemoWarningEvents prints the recent warning events of the demo namespace, which usually explain why
+// the demo app's pods don't come up.
+func printDemoWarningEvents(namespace string) {
+	clientset := demoKube.Clientset()
+	events, err := k8s.GetNamespaceEvents(context.Background(), clientset, namespace, &k8s.NamespaceEventsOptions{
+		WarningsOnly: true,
+		Since:        time.Hour,
+		Limit:        20,
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to get events")
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	utils.Infof("Recent warning events in namespace %s:", namespace)
+	w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+	defer w.Finish()
+	w.SetHeader("demo_events", []string{"Last Seen", "Reason", "Object", "Count", "Message"})
+	for _, e := range events {
+		if err := w.Write([]interface{}{humanize.Time(e.LastSeen), e.Reason, e.Object, e.Count, e.Message}); err != nil {
+			log.WithError(err).Error("Failed to write event")
+		}
+	}
+}
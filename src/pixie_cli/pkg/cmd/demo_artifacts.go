@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// The px demo commands reach the cluster, the artifacts and the user through the following, rather than directly, so
+// that they can be replaced, for example with the fakes of the demo/fake package in tests.
+var (
+	// demoKube is the cluster that the demo commands work with.
+	demoKube demo.Kube = demo.SharedKube()
+	// demoSource fetches the demo apps. If it's nil, they are fetched from the configured artifacts URL.
+	demoSource demo.Source
+	// demoPrompter asks the questions of the demo commands.
+	demoPrompter components.UserPrompter = components.TerminalPrompter
+)
+
+// maxDownloadRate returns the maximum rate, in bytes per second, that artifacts are downloaded at, or 0 if it's
+// unlimited. It exits if --max_download_rate is invalid.
+func maxDownloadRate() int64 {
+	s := viper.GetString("max_download_rate")
+	if s == "" {
+		return 0
+	}
+	n, err := humanize.ParseBytes(s)
+	if err != nil {
+		utils.WithError(err).WithExitCode(exitcodes.Usage).Fatalf("Invalid --max_download_rate %s", s)
+	}
+	return int64(n)
+}
+
+// demoChannel returns the configured release channel, exiting if it is unknown.
+func demoChannel() string {
+	return releaseChannel(viper.GetString("channel"))
+}
+
+// releaseChannel returns the given release channel, or the stable channel if it's empty, exiting if it is unknown.
+func releaseChannel(channel string) string {
+	if channel == "" {
+		return "stable"
+	}
+	if !demo.IsChannel(channel) {
+		utils.WithExitCode(exitcodes.Usage).Fatalf("Unknown channel %s, must be one of stable, beta or dev", channel)
+	}
+	return channel
+}
+
+// demoArtifactsURL returns the configured artifacts URL(s) for the configured release channel.
+func demoArtifactsURL() string {
+	return demo.ChannelArtifactsURL(viper.GetString("artifacts"), demoChannel())
+}
+
+// artifactSourceOptions returns the options that artifacts are fetched with, from the demo flags.
+func artifactSourceOptions() *demo.SourceOptions {
+	return &demo.SourceOptions{
+		Headers:            viper.GetStringSlice("artifacts_header"),
+		GCSCredentialsFile: viper.GetString("artifacts_sa_key"),
+		MaxDownloadRate:    maxDownloadRate(),
+	}
+}
+
+// newArtifactSource returns the source for the given artifacts URL, configured by the demo flags.
+func newArtifactSource(artifacts string) (demo.Source, error) {
+	return demo.NewSource(artifacts, artifactSourceOptions())
+}
+
+// newDemoClient returns the client for the demo apps of the configured artifacts URL and release channel.
+func newDemoClient() *demo.Client {
+	client := &demo.Client{
+		Artifacts:             demoArtifactsURL(),
+		SourceOptions:         *artifactSourceOptions(),
+		Channel:               demoChannel(),
+		ManifestOverridesFile: manifestOverridesPath(),
+		Source:                demoSource,
+		Kube:                  demoKube,
+	}
+	if cacheDir, err := utils.EnsureDefaultCacheDirPath(); err == nil {
+		client.CacheDir = cacheDir
+	}
+	return client
+}
+
+// demoClusterClient returns a client for the demo apps deployed on the cluster, for commands that don't fetch
+// artifacts.
+func demoClusterClient() *demo.Client {
+	return &demo.Client{Kube: demoKube}
+}
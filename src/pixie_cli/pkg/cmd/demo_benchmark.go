@@ -0,0 +1,333 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// benchmarkSampleInterval is how often the resource usage of a benchmarked demo app is sampled. The metrics API
+// doesn't refresh much faster than this.
+const benchmarkSampleInterval = 15 * time.Second
+
+// benchmarkTTLMargin is added to the TTL of benchmarked demo apps, so that an interrupted benchmark is cleaned up
+// by px demo delete --expired, but a running one isn't.
+const benchmarkTTLMargin = 15 * time.Minute
+
+// errMetricsUnavailable is returned when the cluster doesn't serve the metrics API, for example because
+// metrics-server isn't installed.
+var errMetricsUnavailable = errors.New("the metrics API (metrics.k8s.io) is not available on the cluster")
+
+func init() {
+	benchmarkDemoCmd.Flags().String("arch", "", "The node architecture to deploy the demo for (amd64, arm64). Detected from the cluster nodes by default.")
+	benchmarkDemoCmd.Flags().String("registry", "", "A registry prefix that all demo container images are rewritten to, for example: registry.corp/px-demos")
+	benchmarkDemoCmd.Flags().String("node_selector", "", "A node selector added to every demo pod, for example: pool=demos,disk=ssd")
+	benchmarkDemoCmd.Flags().StringArray("toleration", []string{}, "A toleration added to every demo pod, in the format key[=value][:Effect]. May be repeated.")
+	benchmarkDemoCmd.Flags().String("openshift_scc", "anyuid", "On OpenShift, the SecurityContextConstraints to grant the demo app's service accounts. Set to empty to skip")
+	benchmarkDemoCmd.Flags().Int("runs", 1, "How many times to deploy and tear down the demo app")
+	benchmarkDemoCmd.Flags().Duration("wait_timeout", 10*time.Minute, "How long to wait for the demo app to become ready")
+	benchmarkDemoCmd.Flags().Duration("sample_duration", time.Minute, "How long to sample the resource usage of the demo app for once it is ready. Set to 0 to take a single sample")
+	benchmarkDemoCmd.Flags().Bool("keep", false, "Leave the demo app deployed after the last run instead of tearing it down")
+
+	DemoCmd.AddCommand(benchmarkDemoCmd)
+}
+
+var benchmarkDemoCmd = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Measure how long a demo app takes to deploy and how many resources it uses",
+	Long: `Deploy a demo app into a new namespace, measure how long it takes to be applied and to become ready,
+sample the CPU and memory used by the pods in its namespace, then tear it down and report the results.
+
+The resource usage is read from the metrics API, which requires metrics-server to be installed on the cluster.
+Use -o json to get the results in a machine-readable format.`,
+	Args: cobra.ExactArgs(1),
+	Run:  benchmarkCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Benchmark App", pxanalytics.NewProperties().
+			Set("app", args[0]))
+	},
+}
+
+// demoBenchmark is the result of a single benchmark run of a demo app.
+type demoBenchmark struct {
+	Run       int
+	Namespace string
+	// Applied is how long it took to apply the demo app's resources.
+	Applied time.Duration
+	// Ready is how long it took from the start of the deploy until all of the demo app's workloads were ready.
+	Ready    time.Duration
+	Teardown time.Duration
+	Pods     int
+	Restarts int32
+	// Usage is the peak usage of the pods in the namespace, or nil if the metrics API isn't available.
+	Usage *demoUsage
+}
+
+// demoUsage is the CPU and memory used by the pods in a namespace.
+type demoUsage struct {
+	CPU    resource.Quantity
+	Memory resource.Quantity
+}
+
+func (b *demoBenchmark) row(f *demoFootprint) []interface{} {
+	cpu, memory := "n/a", "n/a"
+	if b.Usage != nil {
+		cpu, memory = b.Usage.CPU.String(), b.Usage.Memory.String()
+	}
+	teardown := "-"
+	if b.Teardown > 0 {
+		teardown = formatStatsDuration(b.Teardown)
+	}
+	return []interface{}{
+		b.Run,
+		b.Namespace,
+		formatStatsDuration(b.Applied),
+		formatStatsDuration(b.Ready),
+		teardown,
+		b.Pods,
+		b.Restarts,
+		cpu,
+		memory,
+		f.CPURequests.String(),
+		f.MemoryRequests.String(),
+	}
+}
+
+// podMetricsList is the subset of the metrics.k8s.io PodMetricsList that is needed to sum the usage of pods.
+type podMetricsList struct {
+	Items []struct {
+		Containers []struct {
+			Usage map[string]resource.Quantity `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// getNamespaceUsage returns the current CPU and memory used by the pods in the namespace, from the metrics API.
+func getNamespaceUsage(ctx context.Context, clientset kubernetes.Interface, namespace string) (*demoUsage, error) {
+	data, err := clientset.CoreV1().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", namespace, "pods").
+		DoRaw(ctx)
+	if err != nil {
+		if k8serrors.IsNotFound(err) || k8serrors.IsServiceUnavailable(err) {
+			return nil, errMetricsUnavailable
+		}
+		return nil, err
+	}
+	var metrics podMetricsList
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, err
+	}
+	usage := &demoUsage{}
+	for _, p := range metrics.Items {
+		for _, c := range p.Containers {
+			usage.CPU.Add(c.Usage["cpu"])
+			usage.Memory.Add(c.Usage["memory"])
+		}
+	}
+	return usage, nil
+}
+
+// sampleNamespaceUsage samples the usage of the pods in the namespace for the given duration, and returns the peak
+// CPU and memory usage.
+func sampleNamespaceUsage(ctx context.Context, clientset kubernetes.Interface, namespace string, duration time.Duration) (*demoUsage, error) {
+	peak := &demoUsage{}
+	deadline := time.Now().Add(duration)
+	for {
+		usage, err := getNamespaceUsage(ctx, clientset, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if usage.CPU.Cmp(peak.CPU) > 0 {
+			peak.CPU = usage.CPU
+		}
+		if usage.Memory.Cmp(peak.Memory) > 0 {
+			peak.Memory = usage.Memory
+		}
+		if !time.Now().Add(benchmarkSampleInterval).Before(deadline) {
+			return peak, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(benchmarkSampleInterval):
+		}
+	}
+}
+
+// countPodRestarts returns the number of pods in the namespace and how often their containers restarted.
+func countPodRestarts(ctx context.Context, clientset kubernetes.Interface, namespace string) (int, int32, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, 0, err
+	}
+	var restarts int32
+	for _, p := range pods.Items {
+		for _, s := range p.Status.ContainerStatuses {
+			restarts += s.RestartCount
+		}
+	}
+	return len(pods.Items), restarts, nil
+}
+
+// benchmarkNamespace returns a namespace for the demo app that doesn't exist yet, so that a benchmark never
+// touches an existing deploy of the demo app.
+func benchmarkNamespace(appName string) string {
+	client := demoClusterClient()
+	exists, _, err := client.NamespaceState(appName)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to check namespace %s", appName)
+	}
+	if !exists {
+		return appName
+	}
+	namespace, err := client.NextFreeNamespace(appName)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to find a free namespace")
+	}
+	return namespace
+}
+
+func benchmarkCmd(cmd *cobra.Command, args []string) {
+	appName := args[0]
+	runs, _ := cmd.Flags().GetInt("runs")
+	keep, _ := cmd.Flags().GetBool("keep")
+	waitTimeout, _ := cmd.Flags().GetDuration("wait_timeout")
+	sampleDuration, _ := cmd.Flags().GetDuration("sample_duration")
+	scc, _ := cmd.Flags().GetString("openshift_scc")
+	if runs < 1 {
+		utils.WithExitCode(exitcodes.Usage).Fatal("--runs must be at least 1")
+	}
+
+	client := newDemoClient()
+	manifest, err := client.Manifest()
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatal("Could not download manifest file")
+	}
+	appSpec := getDeployableAppSpec(manifest, appName)
+
+	arch, pinArch := demoArch(cmd)
+	yamls, err := client.FetchBundle(appSpec.BundleName(appName, arch))
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatalf("Could not download demo yaml apps for app '%s'", appName)
+	}
+	transforms := demoTransforms(cmd, appName, appSpec.Architectures[arch], pinArch, make(map[string]string))
+	yamls, err = transformDemoYAMLs(yamls, transforms...)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to prepare YAMLs for demo app %s", appName)
+	}
+	footprint, err := computeDemoFootprint(yamls)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to parse YAMLs for demo app %s", appName)
+	}
+
+	currentCluster := k8s.GetClientAPIConfig().CurrentContext
+	utils.Infof("Benchmarking demo app %s from the %s channel with %d run(s) on the following cluster: %s", appName, demoChannel(), runs, currentCluster)
+	if !demoPrompter.YNPrompt("Is the cluster correct?", true) {
+		utils.WithExitCode(exitcodes.Aborted).Fatal("Cluster is not correct. Aborting.")
+	}
+
+	w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+	defer w.Finish()
+	w.SetHeader("demo_benchmark", []string{"Run", "Namespace", "Applied", "Ready", "Teardown", "Pods", "Restarts",
+		"CPU (peak)", "Memory (peak)", "CPU requests", "Memory requests"})
+
+	clientset := demoKube.Clientset()
+	metricsAvailable := true
+	for run := 1; run <= runs; run++ {
+		namespace := benchmarkNamespace(appName)
+		b := &demoBenchmark{Run: run, Namespace: namespace}
+		utils.Infof("Run %d/%d: deploying demo app %s to namespace %s", run, runs, appName, namespace)
+
+		start := time.Now()
+		_, err := client.Deploy(appName, yamls, &demo.DeployOptions{
+			Namespace:            namespace,
+			NamespaceAnnotations: demoNamespaceAnnotations(waitTimeout + sampleDuration + benchmarkTTLMargin),
+			Dependencies:         appSpec.Dependencies,
+			SCC:                  scc,
+			MultiNamespace:       appSpec.MultiNamespace,
+		})
+		if err != nil {
+			// The deploy was rolled back, so there is nothing to tear down.
+			components.FatalError(fmt.Sprintf("Failed to deploy demo app %s", appName), err)
+		}
+		b.Applied = time.Since(start)
+
+		if err := waitForDemoApp(namespace, waitTimeout); err != nil {
+			printDemoWarningEvents(namespace)
+			teardownBenchmark(client, appName, namespace)
+			components.FatalError(fmt.Sprintf("Demo app %s did not become ready", appName), err)
+		}
+		b.Ready = time.Since(start)
+
+		ctx := context.Background()
+		if metricsAvailable {
+			utils.Infof("Sampling the resource usage of namespace %s for %s", namespace, sampleDuration)
+			b.Usage, err = sampleNamespaceUsage(ctx, clientset, namespace, sampleDuration)
+			if errors.Is(err, errMetricsUnavailable) {
+				utils.WithError(err).Error("Skipping resource usage, install metrics-server to collect it")
+				metricsAvailable = false
+			} else if err != nil {
+				utils.WithError(err).Error("Failed to sample the resource usage of the demo app")
+			}
+		}
+		if b.Pods, b.Restarts, err = countPodRestarts(ctx, clientset, namespace); err != nil {
+			utils.WithError(err).Error("Failed to count the pods of the demo app")
+		}
+
+		if keep && run == runs {
+			utils.Infof("Leaving demo app %s deployed in namespace %s", appName, namespace)
+		} else {
+			start = time.Now()
+			teardownBenchmark(client, appName, namespace)
+			b.Teardown = time.Since(start)
+		}
+
+		if err := w.Write(b.row(footprint)); err != nil {
+			log.WithError(err).Error("Failed to write demo benchmark")
+		}
+	}
+}
+
+// teardownBenchmark deletes the benchmarked demo app, exiting if that fails since later runs would be skewed by it.
+func teardownBenchmark(client *demo.Client, appName, namespace string) {
+	utils.Infof("Tearing down demo app %s in namespace %s", appName, namespace)
+	if err := client.Delete(appName, &demo.DeleteOptions{Namespace: namespace}); err != nil {
+		utils.WithError(err).Fatalf("Failed to delete demo app %s from namespace %s, delete it with: px demo delete %s --namespace %s", appName, namespace, appName, namespace)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pmezard/go-difflib/difflib"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	DemoCmd.AddCommand(diffDemoCmd)
+}
+
+var diffDemoCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show the differences between a deployed demo app and its artifacts",
+	Args:  cobra.ExactArgs(1),
+	Run:   diffCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Diff App", pxanalytics.NewProperties().
+			Set("app", args[0]))
+	},
+}
+
+func diffCmd(cmd *cobra.Command, args []string) {
+	appName := args[0]
+
+	if !demoClusterClient().NamespaceExists(appName) {
+		utils.Fatalf("Demo app %s is not deployed on the current cluster", appName)
+	}
+
+	yamls, err := newDemoClient().AppYAMLs(appName)
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatalf("Could not download demo yaml apps for app '%s'", appName)
+	}
+
+	kubeConfig := demoKube.Config()
+	clientset := demoKube.Clientset()
+
+	names := make([]string, 0, len(yamls))
+	for name := range yamls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	drifted := 0
+	failed := 0
+	var diffErr error
+	for _, name := range names {
+		diffs, err := k8s.Diff(clientset, kubeConfig, appName, bytes.NewReader(yamls[name]))
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to diff demo app %s", appName)
+		}
+
+		for _, d := range diffs {
+			if d.Err != nil {
+				utils.WithError(d.Err).Errorf("Failed to diff %s %s", d.Kind, d.Name)
+				failed++
+				diffErr = d.Err
+				continue
+			}
+			if !d.Changed() {
+				continue
+			}
+			drifted++
+			printUnifiedDiff(d.Diff)
+		}
+	}
+
+	switch {
+	case failed > 0:
+		utils.WithError(diffErr).Fatalf("Failed to diff %d resource(s) of demo app %s, %d resource(s) differ from its artifacts",
+			failed, appName, drifted)
+	case drifted == 0:
+		utils.Infof("Demo app %s matches its artifacts", appName)
+	default:
+		utils.Infof("%d resource(s) in demo app %s differ from its artifacts", drifted, appName)
+	}
+}
+
+func printUnifiedDiff(diff string) {
+	header := color.New(color.Bold)
+	added := color.New(color.FgGreen)
+	removed := color.New(color.FgRed)
+	hunk := color.New(color.FgCyan)
+	for _, line := range difflib.SplitLines(diff) {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			header.Fprint(os.Stdout, line)
+		case strings.HasPrefix(line, "+"):
+			added.Fprint(os.Stdout, line)
+		case strings.HasPrefix(line, "-"):
+			removed.Fprint(os.Stdout, line)
+		case strings.HasPrefix(line, "@@"):
+			hunk.Fprint(os.Stdout, line)
+		default:
+			fmt.Fprint(os.Stdout, line)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// applyConflictHint is the hint for demo apps that fail to deploy because their fields are managed by other tools.
+func applyConflictHint(appName string) *components.ErrorHint {
+	return &components.ErrorHint{
+		Code:  "apply_conflict",
+		Cause: "Another tool, such as kubectl or a controller, manages fields of the demo app's resources.",
+		NextSteps: []string{
+			fmt.Sprintf("Run %s to take ownership of these fields.", fmt.Sprintf("px demo deploy %s --force --force_conflicts", appName)),
+		},
+	}
+}
+
+func init() {
+	components.RegisterErrorHinter(func(err error) *components.ErrorHint {
+		var manifestErr *demo.ManifestError
+		if !errors.As(err, &manifestErr) {
+			return nil
+		}
+		return &components.ErrorHint{
+			Code:  "manifest_unavailable",
+			Cause: fmt.Sprintf("The manifest of the demo apps couldn't be downloaded from %s.", manifestErr.Artifacts),
+			NextSteps: []string{
+				"Check that --artifacts and --channel point to a location that has a manifest.json.",
+				"Run px doctor to check the connection to the artifacts.",
+			},
+		}
+	})
+	components.RegisterErrorHinter(func(err error) *components.ErrorHint {
+		var nsErr *demo.NamespaceError
+		if !errors.As(err, &nsErr) {
+			return nil
+		}
+		return &components.ErrorHint{
+			Code:  "namespace_exists",
+			Cause: "The demo app is already deployed, or another app uses its namespace.",
+			NextSteps: []string{
+				fmt.Sprintf("If the demo app was deployed with px, run px demo delete %s to remove it, or px demo deploy %s --force to redeploy it.", nsErr.App, nsErr.App),
+				fmt.Sprintf("Otherwise, run px demo deploy %s --suffix to deploy into another namespace.", nsErr.App),
+			},
+		}
+	})
+	components.RegisterErrorHint(demo.ErrCertManagerMissing, &components.ErrorHint{
+		Code:  "cert_manager_missing",
+		Cause: "The demo app needs cert-manager, which isn't installed on the cluster.",
+		NextSteps: []string{
+			"Install cert-manager by following the instructions at https://cert-manager.io/docs/getting-started/, then redeploy.",
+		},
+	})
+	components.RegisterErrorHinter(func(err error) *components.ErrorHint {
+		var sccErr *demo.SCCGrantError
+		if !errors.As(err, &sccErr) {
+			return nil
+		}
+		return &components.ErrorHint{
+			Code:  "scc_grant_failed",
+			Cause: fmt.Sprintf("The demo app needs the %s SecurityContextConstraints on OpenShift, and you lack permission to grant them.", sccErr.SCC),
+			NextSteps: []string{
+				fmt.Sprintf("Ask a cluster admin to run %s, then redeploy with --openshift_scc=\"\".", k8s.SCCGrantCommand(sccErr.Namespace, sccErr.SCC)),
+			},
+		}
+	})
+	components.RegisterErrorHinter(func(err error) *components.ErrorHint {
+		if !errors.Is(err, k8s.ErrForbidden) {
+			return nil
+		}
+		hint := &components.ErrorHint{
+			Code:      exitcodes.CodeRBACDenied,
+			Cause:     "Your kubeconfig user lacks an RBAC permission that the command needs.",
+			NextSteps: []string{"Ask a cluster admin for the missing permission."},
+		}
+		var apiErr *k8s.APIError
+		if errors.As(err, &apiErr) && apiErr.Verb != "" && apiErr.Resource != "" {
+			check := fmt.Sprintf("kubectl auth can-i %s %s", apiErr.Verb, apiErr.Resource)
+			if apiErr.Namespace != "" {
+				check += " -n " + apiErr.Namespace
+			}
+			hint.NextSteps = append(hint.NextSteps, fmt.Sprintf("Run %s to check the permission.", check))
+		}
+		return hint
+	})
+	components.RegisterErrorHint(k8s.ErrTimeout, &components.ErrorHint{
+		Code:  exitcodes.CodeTimeout,
+		Cause: "The cluster didn't finish the operation in time. Images may still be pulling, or the cluster may lack capacity.",
+		NextSteps: []string{
+			"Run px demo status to see which workloads aren't ready.",
+			"Retry with a longer --wait_timeout.",
+		},
+	})
+}
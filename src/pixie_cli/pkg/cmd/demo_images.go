@@ -0,0 +1,209 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// resolveDigestTimeout bounds how long the digest of a single image is looked up for.
+const resolveDigestTimeout = 30 * time.Second
+
+func init() {
+	imagesDemoCmd.Flags().String("arch", "", "Only list the images of the given architecture (amd64, arm64). The images of all architectures of the app are listed by default")
+	imagesDemoCmd.Flags().Bool("resolve_digests", false, "Look up the digests of images that aren't pinned to one in their registries, which must be public")
+	DemoCmd.AddCommand(imagesDemoCmd)
+}
+
+var imagesDemoCmd = &cobra.Command{
+	Use:   "images",
+	Short: "List the container images of a demo app",
+	Long: `List every container image that the YAMLs of a demo app reference, with its tag and digest, so that the images
+can be scanned before the app is deployed.
+
+Images that aren't pinned to a digest in the YAMLs only show one with --resolve_digests, which looks up the digest
+that their tag currently points to. Tags can be moved, so pin the resolved digests if the scanned images must be the
+deployed ones.`,
+	Args: cobra.ExactArgs(1),
+	Run:  imagesCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Images", pxanalytics.NewProperties().
+			Set("app", args[0]))
+	},
+}
+
+// demoImage is a container image of a demo app, for one architecture.
+type demoImage struct {
+	Arch string
+	// Reference is the image as referenced by the YAMLs, after the images of the architecture were replaced.
+	Reference string
+	Ref       *demo.ImageRef
+	// Pinned is whether the reference has a digest. The digest of other images is resolved from their registry.
+	Pinned bool
+	// Resources are the resources whose containers use the image, as Kind/name.
+	Resources []string
+}
+
+// demoAppArchs returns the architectures that the demo app has artifacts for: amd64, which the default bundle is
+// built for, and the architectures of the manifest.
+func demoAppArchs(appSpec *demo.AppSpec) []string {
+	archs := []string{"amd64"}
+	for arch := range appSpec.Architectures {
+		if arch != "amd64" {
+			archs = append(archs, arch)
+		}
+	}
+	sort.Strings(archs[1:])
+	return archs
+}
+
+// listDemoImages returns the distinct images of the YAMLs of the demo app for the architecture, sorted by reference.
+func listDemoImages(arch string, archSpec *demo.ArchSpec, yamls map[string][]byte) ([]*demoImage, error) {
+	byReference := make(map[string]*demoImage)
+	for _, r := range demoImages(yamls) {
+		reference := r.Image
+		if archSpec != nil {
+			if replaced, ok := archSpec.Images[reference]; ok {
+				reference = replaced
+			}
+		}
+		img, ok := byReference[reference]
+		if !ok {
+			ref, err := demo.ParseImageRef(reference)
+			if err != nil {
+				return nil, err
+			}
+			img = &demoImage{Arch: arch, Reference: reference, Ref: ref, Pinned: ref.Digest != ""}
+			byReference[reference] = img
+		}
+		// A resource can run the image in several containers.
+		if n := len(img.Resources); n == 0 || img.Resources[n-1] != r.Resource {
+			img.Resources = append(img.Resources, r.Resource)
+		}
+	}
+
+	images := make([]*demoImage, 0, len(byReference))
+	for _, img := range byReference {
+		images = append(images, img)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Reference < images[j].Reference })
+	return images, nil
+}
+
+// resolveDemoImageDigests looks up the digests of the images that aren't pinned to one. Images that can't be resolved
+// keep an empty digest, and the number of them is returned with the first error.
+func resolveDemoImageDigests(images []*demoImage) (int, error) {
+	// The same image is often used by several architectures.
+	resolved := make(map[string]string)
+	failed := 0
+	var firstErr error
+	for _, img := range images {
+		if img.Pinned {
+			continue
+		}
+		name := img.Ref.String()
+		digest, ok := resolved[name]
+		if !ok {
+			ctx, cancel := context.WithTimeout(context.Background(), resolveDigestTimeout)
+			var err error
+			digest, err = demo.ResolveDigest(ctx, img.Ref)
+			cancel()
+			if err != nil {
+				utils.WithError(err).Errorf("Failed to resolve the digest of %s", img.Reference)
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			resolved[name] = digest
+		}
+		img.Ref.Digest = digest
+	}
+	return failed, firstErr
+}
+
+func imagesCmd(cmd *cobra.Command, args []string) {
+	appName := args[0]
+	arch, _ := cmd.Flags().GetString("arch")
+	resolveDigests, _ := cmd.Flags().GetBool("resolve_digests")
+
+	client := newDemoClient()
+	manifest, err := client.Manifest()
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatal("Could not download manifest file")
+	}
+	appSpec := getAppSpec(manifest, appName)
+
+	archs := demoAppArchs(appSpec)
+	if arch != "" {
+		if arch != "amd64" && appSpec.Architectures[arch] == nil {
+			utils.WithExitCode(exitcodes.Usage).Fatalf("Demo app %s has no artifacts for architecture %s, must be one of: %s",
+				appName, arch, strings.Join(archs, ", "))
+		}
+		archs = []string{arch}
+	}
+
+	var images []*demoImage
+	for _, a := range archs {
+		yamls, err := client.FetchBundle(appSpec.BundleName(appName, a))
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatalf("Could not download demo yaml apps for app '%s'", appName)
+		}
+		archImages, err := listDemoImages(a, appSpec.Architectures[a], yamls)
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to list the images of demo app %s", appName)
+		}
+		images = append(images, archImages...)
+	}
+
+	var failed int
+	var resolveErr error
+	if resolveDigests {
+		failed, resolveErr = resolveDemoImageDigests(images)
+	}
+
+	w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+	w.SetHeader("demo_images", []string{"Arch", "Reference", "Image", "Tag", "Digest", "Pinned", "Resources"})
+	for _, img := range images {
+		if err := w.Write([]interface{}{img.Arch, img.Reference, img.Ref.Name(), img.Ref.Tag, img.Ref.Digest, img.Pinned,
+			strings.Join(img.Resources, ", ")}); err != nil {
+			log.WithError(err).Error("Failed to write demo app image")
+		}
+	}
+	w.Finish()
+
+	if resolveErr != nil {
+		utils.WithError(resolveErr).Fatalf("Failed to resolve the digests of %d images", failed)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	logsDemoCmd.Flags().StringP("selector", "l", "", "Label selector to filter the demo pods, for example: name=frontend")
+	logsDemoCmd.Flags().BoolP("follow", "f", false, "Whether to keep streaming new logs")
+	logsDemoCmd.Flags().Int64("tail", -1, "The number of recent lines to show for each container. Defaults to all lines.")
+
+	DemoCmd.AddCommand(logsDemoCmd)
+}
+
+var logsDemoCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Stream logs from the pods of a deployed demo app",
+	Args:  cobra.ExactArgs(1),
+	Run:   logsCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Logs", pxanalytics.NewProperties().
+			Set("app", args[0]))
+	},
+}
+
+var logPrefixColors = []color.Attribute{
+	color.FgCyan, color.FgGreen, color.FgMagenta, color.FgYellow, color.FgBlue, color.FgRed,
+}
+
+func logsCmd(cmd *cobra.Command, args []string) {
+	appName := args[0]
+	selector, _ := cmd.Flags().GetString("selector")
+	follow, _ := cmd.Flags().GetBool("follow")
+	tail, _ := cmd.Flags().GetInt64("tail")
+
+	if !demoClusterClient().NamespaceExists(appName) {
+		utils.Fatalf("Demo app %s is not deployed on the current cluster", appName)
+	}
+
+	ctx, cleanup := utils.WithSignalCancellable(context.Background())
+	defer cleanup()
+
+	clientset := demoKube.Clientset()
+	pods, err := clientset.CoreV1().Pods(appName).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to list pods for demo app %s", appName)
+	}
+	if len(pods.Items) == 0 {
+		utils.Fatalf("No pods found for demo app %s", appName)
+	}
+
+	opts := &k8s.StreamLogsOptions{Follow: follow}
+	if tail >= 0 {
+		opts.TailLines = &tail
+	}
+	lines, err := k8s.StreamLogs(ctx, clientset, appName, selector, opts)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to stream logs for demo app %s", appName)
+	}
+
+	prefixes := make(map[string]string)
+	for l := range lines {
+		if l.Err != nil {
+			utils.WithError(l.Err).Errorf("Failed to stream logs for %s/%s", l.Pod, l.Container)
+			continue
+		}
+		key := fmt.Sprintf("%s/%s", l.Pod, l.Container)
+		prefix, ok := prefixes[key]
+		if !ok {
+			prefix = color.New(logPrefixColors[len(prefixes)%len(logPrefixColors)]).Sprintf("[%s]", key)
+			prefixes[key] = prefix
+		}
+		fmt.Fprintf(os.Stdout, "%s %s\n", prefix, l.Line)
+	}
+}
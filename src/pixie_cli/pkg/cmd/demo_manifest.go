@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// manifestOverridesPath returns the path of the overrides file to use, or an empty string if there is none.
+func manifestOverridesPath() string {
+	if p := viper.GetString("manifest_overrides"); p != "" {
+		return p
+	}
+	p, err := utils.EnsureDefaultDemoOverridesFilePath()
+	if err != nil {
+		return ""
+	}
+	if _, err := os.Stat(p); err != nil {
+		return ""
+	}
+	return p
+}
+
+// getAppSpec returns the spec of the given app, exiting with guidance if the app is unknown. Deprecated apps are
+// returned, so that the ones that are still deployed can be used; see getDeployableAppSpec.
+func getAppSpec(m demo.Manifest, appName string) *demo.AppSpec {
+	appSpec, ok := m[appName]
+	if !ok {
+		utils.WithExitCode(exitcodes.Usage).Fatalf("%s is not a supported demo app", appName)
+	}
+	// When a demo app is deprecated without metadata, its contents will be set to null in manifest.json.
+	if appSpec == nil {
+		utils.WithExitCode(exitcodes.Usage).Fatalf("%s is a deprecated demo app and is no longer supported", appName)
+	}
+	return appSpec
+}
+
+// getDeployableAppSpec returns the spec of the given app, like getAppSpec, but also exits with guidance if the app is
+// deprecated, since deprecated apps can't be deployed anymore.
+func getDeployableAppSpec(m demo.Manifest, appName string) *demo.AppSpec {
+	appSpec := getAppSpec(m, appName)
+	if appSpec.Deprecated != nil {
+		utils.Errorf("%s is a deprecated demo app. %s", appName, appSpec.Deprecated.Guidance())
+		if appSpec.Deprecated.Replacement != "" {
+			utils.WithExitCode(exitcodes.Usage).Fatalf("Run %s to deploy the replacement.",
+				color.GreenString("px demo deploy %s", appSpec.Deprecated.Replacement))
+		}
+		exitcodes.ExitWith(exitcodes.Usage)
+	}
+	return appSpec
+}
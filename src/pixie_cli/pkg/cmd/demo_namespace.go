@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// resolveDemoNamespace returns the namespace to deploy the demo app to. If the app's namespace already
+// exists but was not created by px, a suffixed namespace is used instead, either automatically when
+// suffix is set or after confirming with the user.
+func resolveDemoNamespace(appName string, force, suffix bool) string {
+	client := demoClusterClient()
+	exists, managed, err := client.NamespaceState(appName)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to check namespace %s", appName)
+	}
+	if !exists || managed || force {
+		return appName
+	}
+
+	candidate, err := client.NextFreeNamespace(appName)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to find a free namespace")
+	}
+	if suffix {
+		utils.Infof("Namespace %s already exists and was not created by px, deploying into %s", appName, candidate)
+		return candidate
+	}
+	if demoPrompter.YNPrompt(fmt.Sprintf("Namespace %s already exists and was not created by px. Deploy into %s instead?", appName, candidate), true) {
+		return candidate
+	}
+	return appName
+}
+
+// demoAppNamespace returns the namespace the demo app is deployed to: the --namespace flag if set, otherwise the
+// namespace named after the app, otherwise the only suffixed namespace that px deployed the app to.
+func demoAppNamespace(cmd *cobra.Command, appName string) string {
+	if namespace, _ := cmd.Flags().GetString("namespace"); namespace != "" {
+		return namespace
+	}
+	client := demoClusterClient()
+	if client.NamespaceExists(appName) {
+		return appName
+	}
+	namespaces, err := client.AppNamespaces(appName)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to find the namespaces of demo app %s", appName)
+	}
+	if len(namespaces) > 1 {
+		utils.WithExitCode(exitcodes.Usage).Fatalf("Demo app %s is deployed to several namespaces (%s), pick one with --namespace",
+			appName, strings.Join(namespaces, ", "))
+	}
+	if len(namespaces) == 1 {
+		return namespaces[0]
+	}
+	return appName
+}
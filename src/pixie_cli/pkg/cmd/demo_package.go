@@ -0,0 +1,427 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	packageDemoCmd.Flags().String("name", "", "The name of the demo app. Defaults to the name of the directory.")
+	packageDemoCmd.Flags().String("metadata", "", "Path to the metadata file of the app, in the format of a manifest entry. Defaults to metadata.yaml, metadata.yml or metadata.json in the directory.")
+	packageDemoCmd.Flags().String("output_dir", ".", "The directory to write <app>.tar.gz and <app>.manifest.json to")
+
+	DemoCmd.AddCommand(packageDemoCmd)
+}
+
+var packageDemoCmd = &cobra.Command{
+	Use:   "package DIR",
+	Short: "Package a directory of YAMLs and a metadata file into a demo app bundle and manifest entry",
+	Long: `Package a directory of YAMLs and a metadata file into a demo app bundle and manifest entry.
+
+All .yaml files in the directory are added to <app>.tar.gz. The metadata file holds the
+manifest entry of the app, for example:
+
+  description: An online shop
+  instructions:
+    - Run px live px/http_data to see its requests.
+  dependencies:
+    cert-manager: false
+  frontend:
+    service: front-end
+    port: 80
+
+The YAMLs, the container images that they reference, and the metadata are checked before the
+bundle is written. To publish the app, copy <app>.tar.gz next to the manifest.json of the demo
+artifacts and add the entry in <app>.manifest.json to it.`,
+	Args: cobra.ExactArgs(1),
+	Run:  packageCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Package App", nil)
+	},
+}
+
+// demoMetadataFiles are the files that packageCmd looks for the app's metadata in, in order.
+var demoMetadataFiles = []string{"metadata.yaml", "metadata.yml", "metadata.json"}
+
+// knownDemoDependencies are the dependencies that px demo deploy knows how to check for.
+var knownDemoDependencies = map[string]bool{"cert-manager": true}
+
+// knownDemoArchitectures are the node architectures that apps may have overrides for.
+var knownDemoArchitectures = map[string]bool{"amd64": true, "arm64": true, "ppc64le": true, "s390x": true}
+
+// imageReferenceRegexp matches a container image reference: [registry[:port]/]repository[:tag][@digest].
+var imageReferenceRegexp = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[\w][\w.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// readDemoMetadata reads the manifest entry of an app from a YAML or JSON file. Unknown fields are rejected, since
+// they are most likely misspelled.
+func readDemoMetadata(path string) (*demo.AppSpec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec := &demo.AppSpec{}
+	if err := yaml.UnmarshalStrict(b, spec); err != nil {
+		return nil, fmt.Errorf("invalid metadata in %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// findDemoMetadata returns the path of the metadata file in the given directory.
+func findDemoMetadata(dir string) (string, error) {
+	for _, name := range demoMetadataFiles {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("none of %s exist in %s, use --metadata to set the metadata file",
+		strings.Join(demoMetadataFiles, ", "), dir)
+}
+
+// readDemoYAMLDir reads the YAMLs in the given directory and its subdirectories, keyed by their slash-separated path
+// relative to it. The metadata file is skipped. Files with a .yml extension are reported as problems, because only
+// .yaml files are deployed.
+func readDemoYAMLDir(dir, metadataPath string) (map[string][]byte, []*demoValidationProblem, error) {
+	yamls := make(map[string][]byte)
+	var problems []*demoValidationProblem
+	metadataAbs, _ := filepath.Abs(metadataPath)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if abs, _ := filepath.Abs(path); abs == metadataAbs {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch filepath.Ext(path) {
+		case ".yaml":
+		case ".yml":
+			problems = append(problems, &demoValidationProblem{
+				File: rel, Severity: "error", Message: "only .yaml files are deployed, rename the file to .yaml",
+			})
+			return nil
+		default:
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		yamls[rel] = b
+		return nil
+	})
+	return yamls, problems, err
+}
+
+// demoImageRef is a container image referenced by a resource in a demo app's YAMLs.
+type demoImageRef struct {
+	File     string
+	Resource string
+	Image    string
+}
+
+// demoImages returns the container images referenced by the given YAMLs. Files that can't be parsed are skipped,
+// since they are reported by validateDemoYAMLs.
+func demoImages(yamls map[string][]byte) []*demoImageRef {
+	names := make([]string, 0, len(yamls))
+	for name := range yamls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var refs []*demoImageRef
+	for _, name := range names {
+		resources, err := k8s.GetResourcesFromNamedYAML(name, bytes.NewReader(yamls[name]))
+		if err != nil {
+			continue
+		}
+		for _, r := range resources {
+			obj := r.Object
+			resourceName := fmt.Sprintf("%s/%s", obj.GetKind(), obj.GetName())
+			_ = forEachPodSpec(obj, func(spec map[string]interface{}) error {
+				return forEachContainer(spec, func(container map[string]interface{}) error {
+					image, _ := container["image"].(string)
+					refs = append(refs, &demoImageRef{File: name, Resource: resourceName, Image: image})
+					return nil
+				})
+			})
+		}
+	}
+	return refs
+}
+
+// checkImageReference returns the severity and description of the problem with the given image reference, if any.
+func checkImageReference(image string) (string, string) {
+	if image == "" {
+		return "error", "container image is not set"
+	}
+	if !imageReferenceRegexp.MatchString(image) {
+		return "error", fmt.Sprintf("invalid image reference %q", image)
+	}
+	if strings.Contains(image, "@") {
+		return "", ""
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	tag := ""
+	if idx := strings.Index(name, ":"); idx != -1 {
+		tag = name[idx+1:]
+	}
+	switch tag {
+	case "":
+		return "warning", fmt.Sprintf("image %s has no tag, pin a tag or digest so that the app doesn't change", image)
+	case "latest":
+		return "warning", fmt.Sprintf("image %s uses the latest tag, pin a tag or digest so that the app doesn't change", image)
+	}
+	return "", ""
+}
+
+// checkDemoPackage checks that the given YAMLs and metadata make up a demo app that px demo deploy can deploy.
+func checkDemoPackage(appName, metadataFile string, spec *demo.AppSpec, yamls map[string][]byte) []*demoValidationProblem {
+	var problems []*demoValidationProblem
+	addProblem := func(severity, format string, args ...interface{}) {
+		problems = append(problems, &demoValidationProblem{
+			File: metadataFile, Severity: severity, Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	// The app name is the default namespace of the app.
+	for _, msg := range validation.IsDNS1123Label(appName) {
+		addProblem("error", "app name %q is not a valid namespace name: %s", appName, msg)
+	}
+
+	if spec.Description == "" {
+		addProblem("warning", "description is not set, it is shown by px demo list")
+	}
+	if len(spec.Instructions) == 0 {
+		addProblem("error", "instructions are not set, they are shown once the app is deployed")
+	}
+	for i, instruction := range spec.Instructions {
+		if strings.TrimSpace(instruction) == "" {
+			addProblem("error", "instruction %d is empty", i+1)
+		}
+	}
+
+	deps := make([]string, 0, len(spec.Dependencies))
+	for dep := range spec.Dependencies {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	for _, dep := range deps {
+		if !knownDemoDependencies[dep] {
+			addProblem("error", "unknown dependency %q", dep)
+		}
+	}
+
+	if spec.LiveView != nil && spec.LiveView.Script == "" {
+		addProblem("error", "live_view.script is not set")
+	}
+
+	services := make(map[string]bool)
+	refs := demoImages(yamls)
+	images := make(map[string]bool)
+	for _, ref := range refs {
+		images[ref.Image] = true
+	}
+	for name, b := range yamls {
+		resources, err := k8s.GetResourcesFromNamedYAML(name, bytes.NewReader(b))
+		if err != nil {
+			continue
+		}
+		for _, r := range resources {
+			if r.Object.GetKind() == "Service" {
+				services[r.Object.GetName()] = true
+			}
+		}
+	}
+	if spec.Frontend != nil {
+		if spec.Frontend.Service == "" {
+			addProblem("error", "frontend.service is not set")
+		} else if !services[spec.Frontend.Service] {
+			addProblem("error", "frontend.service %s is not a Service in the YAMLs", spec.Frontend.Service)
+		}
+		if spec.Frontend.Port <= 0 || spec.Frontend.Port > 65535 {
+			addProblem("error", "frontend.port %d is not a valid port", spec.Frontend.Port)
+		}
+	}
+
+	archs := make([]string, 0, len(spec.Architectures))
+	for arch := range spec.Architectures {
+		archs = append(archs, arch)
+	}
+	sort.Strings(archs)
+	for _, arch := range archs {
+		archSpec := spec.Architectures[arch]
+		if !knownDemoArchitectures[arch] {
+			addProblem("warning", "architecture %q is not a known node architecture", arch)
+		}
+		if archSpec == nil {
+			continue
+		}
+		if archSpec.Bundle != "" {
+			if !strings.HasSuffix(archSpec.Bundle, ".tar.gz") {
+				addProblem("error", "architectures.%s.bundle %s must be a .tar.gz file", arch, archSpec.Bundle)
+			}
+			addProblem("warning", "architectures.%s.bundle %s is not created by px demo package, it must be published separately", arch, archSpec.Bundle)
+		}
+		froms := make([]string, 0, len(archSpec.Images))
+		for from := range archSpec.Images {
+			froms = append(froms, from)
+		}
+		sort.Strings(froms)
+		for _, from := range froms {
+			to := archSpec.Images[from]
+			if !images[from] {
+				addProblem("error", "architectures.%s.images: image %s is not used by the YAMLs", arch, from)
+			}
+			if severity, msg := checkImageReference(to); severity == "error" {
+				addProblem(severity, "architectures.%s.images: %s", arch, msg)
+			}
+		}
+	}
+
+	if len(yamls) == 0 {
+		addProblem("error", "there are no .yaml files to package")
+	}
+	problems = append(problems, validateDemoYAMLs(yamls, nil)...)
+	for _, ref := range refs {
+		if severity, msg := checkImageReference(ref.Image); severity != "" {
+			problems = append(problems, &demoValidationProblem{
+				File: ref.File, Resource: ref.Resource, Severity: severity, Message: msg,
+			})
+		}
+	}
+	return problems
+}
+
+func packageCmd(cmd *cobra.Command, args []string) {
+	dir := args[0]
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		utils.WithExitCode(exitcodes.Usage).Fatalf("%s is not a directory", dir)
+	}
+
+	appName, _ := cmd.Flags().GetString("name")
+	if appName == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to resolve %s", dir)
+		}
+		appName = filepath.Base(abs)
+	}
+
+	metadataPath, _ := cmd.Flags().GetString("metadata")
+	if metadataPath == "" {
+		var err error
+		metadataPath, err = findDemoMetadata(dir)
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Failed to find the metadata of the demo app")
+		}
+	}
+	spec, err := readDemoMetadata(metadataPath)
+	if err != nil {
+		utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Failed to read the metadata of the demo app")
+	}
+
+	yamls, problems, err := readDemoYAMLDir(dir, metadataPath)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to read the YAMLs in %s", dir)
+	}
+	problems = append(problems, checkDemoPackage(appName, filepath.Base(metadataPath), spec, yamls)...)
+
+	errCount := 0
+	if len(problems) > 0 {
+		w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+		w.SetHeader("demo_validate", []string{"File", "Resource", "Severity", "Problem"})
+		for _, p := range problems {
+			if p.Severity == "error" {
+				errCount++
+			}
+			if err := w.Write([]interface{}{p.File, p.Resource, p.Severity, p.Message}); err != nil {
+				log.WithError(err).Error("Failed to write validation problem")
+			}
+		}
+		w.Finish()
+	}
+	if errCount > 0 {
+		utils.Fatalf("Demo app %s has %d error(s), not packaging it", appName, errCount)
+	}
+
+	outputDir, _ := cmd.Flags().GetString("output_dir")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		utils.WithError(err).Fatalf("Failed to create %s", outputDir)
+	}
+	bundlePath := filepath.Join(outputDir, appName+".tar.gz")
+	var bundle bytes.Buffer
+	if err := demo.WriteBundle(&bundle, yamls); err == nil {
+		err = os.WriteFile(bundlePath, bundle.Bytes(), 0644)
+	}
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to write %s", bundlePath)
+	}
+	if spec.Dependencies == nil {
+		spec.Dependencies = make(map[string]bool)
+	}
+	entry, err := json.MarshalIndent(demo.Manifest{appName: spec}, "", "  ")
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to encode the manifest entry")
+	}
+	entryPath := filepath.Join(outputDir, appName+".manifest.json")
+	if err := os.WriteFile(entryPath, append(entry, '\n'), 0644); err != nil {
+		utils.WithError(err).Fatalf("Failed to write %s", entryPath)
+	}
+
+	utils.Infof("Packaged demo app %s with %d YAML file(s) into %s", appName, len(yamls), bundlePath)
+	utils.Infof("Copy it next to the manifest.json of the demo artifacts, and add the entry in %s to it", entryPath)
+	pxanalytics.Track("Demo Package App Complete", pxanalytics.NewProperties().
+		Set("files", len(yamls)).
+		Set("warnings", len(problems)))
+}
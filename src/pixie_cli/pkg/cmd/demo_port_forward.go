@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skratchdot/open-golang/open"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	portForwardDemoCmd.Flags().Int("local_port", 8080, "The local port to forward the demo frontend to")
+
+	DemoCmd.AddCommand(portForwardDemoCmd)
+}
+
+var portForwardDemoCmd = &cobra.Command{
+	Use:   "port-forward",
+	Short: "Forward a local port to the web frontend of a deployed demo app",
+	Args:  cobra.ExactArgs(1),
+	Run:   portForwardCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Port Forward", pxanalytics.NewProperties().
+			Set("app", args[0]))
+	},
+}
+
+func portForwardCmd(cmd *cobra.Command, args []string) {
+	appName := args[0]
+	localPort, _ := cmd.Flags().GetInt("local_port")
+
+	manifest, err := newDemoClient().Manifest()
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatal("Could not download manifest file")
+	}
+	appSpec := getAppSpec(manifest, appName)
+	if appSpec.Frontend == nil || appSpec.Frontend.Service == "" {
+		utils.Fatalf("Demo app %s does not declare a web frontend", appName)
+	}
+	if !demoClusterClient().NamespaceExists(appName) {
+		utils.Fatalf("Demo app %s is not deployed on the current cluster", appName)
+	}
+
+	fw, err := startFrontendPortForward(appName, appSpec.Frontend, localPort)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to port-forward the frontend of demo app %s", appName)
+	}
+	defer fw.Close()
+
+	utils.Infof("Forwarding the %s frontend to %s. Press Ctrl+C to stop.", appName, components.URL(fmt.Sprintf("http://localhost:%d", fw.LocalPort())))
+	waitForPortForward(fw, appName)
+}
+
+// startFrontendPortForward forwards the given local port to the demo frontend.
+func startFrontendPortForward(namespace string, frontend *demo.Frontend, localPort int) (*k8s.PortForwarder, error) {
+	kubeConfig := demoKube.Config()
+	clientset := demoKube.Clientset()
+	fw, err := k8s.NewServicePortForwarder(context.Background(), clientset, kubeConfig, namespace, frontend.Service, localPort, frontend.Port)
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Start(); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// waitForPortForward blocks until the user interrupts the command or the port-forward stops.
+func waitForPortForward(fw *k8s.PortForwarder, appName string) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	select {
+	case <-sig:
+	case err := <-fw.Done():
+		if err != nil {
+			utils.WithError(err).Errorf("Port-forward to the frontend of demo app %s stopped", appName)
+		}
+	}
+}
+
+// frontendLoadBalancerURL returns the external URL of the demo frontend, if its Service is exposed
+// through a LoadBalancer that has been assigned an address.
+func frontendLoadBalancerURL(appName string, frontend *demo.Frontend) (string, bool) {
+	clientset := demoKube.Clientset()
+	svc, err := clientset.CoreV1().Services(appName).Get(context.Background(), frontend.Service, metav1.GetOptions{})
+	if err != nil || svc.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return "", false
+	}
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		host := ingress.Hostname
+		if host == "" {
+			host = ingress.IP
+		}
+		if host != "" {
+			return fmt.Sprintf("http://%s", net.JoinHostPort(host, strconv.Itoa(frontend.Port))), true
+		}
+	}
+	return "", false
+}
+
+// openDemoFrontend opens the demo frontend in a browser. If the frontend isn't exposed through a
+// LoadBalancer, it is port-forwarded to the given local port until the user interrupts the command.
+func openDemoFrontend(appName string, frontend *demo.Frontend, localPort int) {
+	if url, ok := frontendLoadBalancerURL(appName, frontend); ok {
+		utils.Infof("Opening the %s frontend at %s", appName, components.URL(url))
+		if err := open.Run(url); err != nil {
+			utils.WithError(err).Errorf("Failed to open a browser, visit %s instead", components.URL(url))
+		}
+		return
+	}
+
+	fw, err := startFrontendPortForward(appName, frontend, localPort)
+	if err != nil {
+		utils.WithError(err).Errorf("Failed to port-forward the frontend of demo app %s", appName)
+		return
+	}
+	defer fw.Close()
+
+	url := fmt.Sprintf("http://localhost:%d", fw.LocalPort())
+	utils.Infof("Opening the %s frontend at %s. Press Ctrl+C to stop forwarding.", appName, components.URL(url))
+	if err := open.Run(url); err != nil {
+		utils.WithError(err).Errorf("Failed to open a browser, visit %s instead", components.URL(url))
+	}
+	waitForPortForward(fw, appName)
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// The parameters of a demo deploy that are recorded in its checkpoint, which a resumed deploy must match.
+const (
+	demoCheckpointNamespace = "namespace"
+	demoCheckpointCluster   = "cluster"
+	demoCheckpointYAMLs     = "yamls"
+)
+
+// demoCheckpointName is the name of the checkpoint of a deploy of the demo app.
+func demoCheckpointName(appName string) string {
+	return fmt.Sprintf("demo-deploy-%s", appName)
+}
+
+// demoYAMLsDigest returns a digest of the demo YAMLs, which tells whether they changed since a failed deploy.
+func demoYAMLsDigest(yamls map[string][]byte) string {
+	names := make([]string, 0, len(yamls))
+	for name := range yamls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(yamls[name]))
+		h.Write(yamls[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// demoDeployCheckpoint returns the namespace to deploy the demo app to, and the checkpoint that records the steps of
+// the deploy. A resumed deploy loads the checkpoint of the failed deploy, and reuses its namespace. Otherwise, the
+// namespace is resolved, and a new checkpoint is created. The checkpoint is nil if it can't be created, since the
+// deploy can still run without it.
+func demoDeployCheckpoint(appName string, yamls map[string][]byte, resume bool, resolveNamespace func() string) (string, *utils.Checkpoint) {
+	cluster := k8s.GetClientAPIConfig().CurrentContext
+	digest := demoYAMLsDigest(yamls)
+	if !resume {
+		namespace := resolveNamespace()
+		checkpoint, err := utils.NewCheckpoint(demoCheckpointName(appName), map[string]string{
+			demoCheckpointNamespace: namespace,
+			demoCheckpointCluster:   cluster,
+			demoCheckpointYAMLs:     digest,
+		})
+		if err != nil {
+			utils.WithError(err).Error("Failed to create a checkpoint, the deploy can't be resumed if it fails")
+			return namespace, nil
+		}
+		return namespace, checkpoint
+	}
+
+	checkpoint, err := utils.LoadCheckpoint(demoCheckpointName(appName))
+	if errors.Is(err, utils.ErrNoCheckpoint) {
+		utils.Fatalf("There is no failed deploy of demo app %s to resume.", appName)
+	}
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to load the checkpoint of the failed deploy of demo app %s", appName)
+	}
+	params := checkpoint.Params()
+	if params[demoCheckpointCluster] != cluster {
+		utils.Fatalf("The failed deploy of demo app %s was to cluster %s, switch to it to resume the deploy.", appName, params[demoCheckpointCluster])
+	}
+	namespace := params[demoCheckpointNamespace]
+	if params[demoCheckpointYAMLs] != digest {
+		utils.Fatalf("The YAMLs of demo app %s changed since the failed deploy, so it can't be resumed. Run %s to remove it, then deploy it again.",
+			appName, color.GreenString("%s", demoDeleteCommand(appName, namespace)))
+	}
+	utils.Infof("Resuming the failed deploy of demo app %s into namespace %s.", appName, namespace)
+	return namespace, checkpoint
+}
+
+// removeDemoCheckpoint removes the checkpoint of a failed deploy of the demo app, once the demo app is deleted.
+func removeDemoCheckpoint(appName string) {
+	checkpoint, err := utils.LoadCheckpoint(demoCheckpointName(appName))
+	if err == nil {
+		err = checkpoint.Remove()
+	}
+	if err != nil && !errors.Is(err, utils.ErrNoCheckpoint) {
+		utils.WithError(err).Errorf("Failed to remove the checkpoint of the failed deploy of demo app %s", appName)
+	}
+}
+
+// printDemoResumeHint tells the user how to resume a failed deploy, if it completed any steps.
+func printDemoResumeHint(appName, namespace string, checkpoint *utils.Checkpoint) {
+	if checkpoint == nil || !checkpoint.Saved() {
+		return
+	}
+	utils.Infof("The completed steps of the deploy were kept. Run %s to continue the deploy, or %s to remove the demo app.",
+		color.GreenString("px demo deploy %s --resume", appName), color.GreenString("%s", demoDeleteCommand(appName, namespace)))
+}
+
+// demoRollbackConfirmer returns the function that decides whether an interrupted deploy of the demo app deletes what
+// it already created. It's decided by --rollback_on_interrupt if it's set, and by the user otherwise. Deploys that
+// can't ask the user are rolled back.
+func demoRollbackConfirmer(cmd *cobra.Command, appName string) func() bool {
+	return func() bool {
+		if cmd.Flags().Changed("rollback_on_interrupt") {
+			rollback, _ := cmd.Flags().GetBool("rollback_on_interrupt")
+			return rollback
+		}
+		if !viper.GetBool("y") && !components.IsTerminal(os.Stdin) {
+			return true
+		}
+		return demoPrompter.YNPrompt(fmt.Sprintf("Delete what the interrupted deploy of demo app %s already created?", appName), true)
+	}
+}
+
+// demoDeleteCommand returns the command that deletes the demo app deployed to the namespace.
+func demoDeleteCommand(appName, namespace string) string {
+	if namespace == appName {
+		return fmt.Sprintf("px demo delete %s", appName)
+	}
+	return fmt.Sprintf("px demo delete %s --namespace %s", appName, namespace)
+}
@@ -0,0 +1,317 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	DemoCmd.AddCommand(sizeDemoCmd)
+}
+
+var sizeDemoCmd = &cobra.Command{
+	Use:   "size",
+	Short: "Summarize the resources requested by a demo app before deploying it",
+	Args:  cobra.ExactArgs(1),
+	Run:   sizeCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Size App", pxanalytics.NewProperties().
+			Set("app", args[0]))
+	},
+}
+
+// demoFootprint is the aggregate of the resources requested by a demo app's manifests.
+type demoFootprint struct {
+	Pods int64
+	// DaemonSetPods is the number of pods scheduled on every node.
+	DaemonSetPods  int64
+	CPURequests    resource.Quantity
+	CPULimits      resource.Quantity
+	MemoryRequests resource.Quantity
+	MemoryLimits   resource.Quantity
+	Storage        resource.Quantity
+	Services       int
+	Ingresses      int
+
+	// The requests of a single replica of all DaemonSets.
+	daemonSetCPURequests    resource.Quantity
+	daemonSetMemoryRequests resource.Quantity
+}
+
+// podTemplatePaths is the location of the pod template for each workload kind.
+var podTemplatePaths = map[string][]string{
+	"Deployment":  {"spec", "template"},
+	"StatefulSet": {"spec", "template"},
+	"DaemonSet":   {"spec", "template"},
+	"ReplicaSet":  {"spec", "template"},
+	"Job":         {"spec", "template"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template"},
+}
+
+func (f *demoFootprint) addPodSpec(spec *v1.PodSpec, replicas int64) {
+	for _, c := range spec.Containers {
+		for i := int64(0); i < replicas; i++ {
+			f.CPURequests.Add(*c.Resources.Requests.Cpu())
+			f.CPULimits.Add(*c.Resources.Limits.Cpu())
+			f.MemoryRequests.Add(*c.Resources.Requests.Memory())
+			f.MemoryLimits.Add(*c.Resources.Limits.Memory())
+		}
+	}
+}
+
+func (f *demoFootprint) addResource(obj *unstructured.Unstructured) error {
+	kind := obj.GetKind()
+	switch kind {
+	case "Service":
+		f.Services++
+		return nil
+	case "Ingress":
+		f.Ingresses++
+		return nil
+	case "PersistentVolumeClaim":
+		pvc := &v1.PersistentVolumeClaim{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pvc); err != nil {
+			return err
+		}
+		f.Storage.Add(*pvc.Spec.Resources.Requests.Storage())
+		return nil
+	case "Pod":
+		pod := &v1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pod); err != nil {
+			return err
+		}
+		f.Pods++
+		f.addPodSpec(&pod.Spec, 1)
+		return nil
+	}
+
+	templatePath, ok := podTemplatePaths[kind]
+	if !ok {
+		return nil
+	}
+	tmplObj, found, err := unstructured.NestedMap(obj.Object, templatePath...)
+	if err != nil || !found {
+		return err
+	}
+	tmpl := &v1.PodTemplateSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(tmplObj, tmpl); err != nil {
+		return err
+	}
+
+	replicas := int64(1)
+	if r, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); found {
+		replicas = r
+	} else if p, found, _ := unstructured.NestedInt64(obj.Object, "spec", "parallelism"); found {
+		replicas = p
+	}
+
+	if kind == "DaemonSet" {
+		f.DaemonSetPods++
+		for _, c := range tmpl.Spec.Containers {
+			f.daemonSetCPURequests.Add(*c.Resources.Requests.Cpu())
+			f.daemonSetMemoryRequests.Add(*c.Resources.Requests.Memory())
+		}
+	} else {
+		f.Pods += replicas
+	}
+	f.addPodSpec(&tmpl.Spec, replicas)
+
+	if kind == "StatefulSet" {
+		claims, _, _ := unstructured.NestedSlice(obj.Object, "spec", "volumeClaimTemplates")
+		for _, c := range claims {
+			cObj, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			pvc := &v1.PersistentVolumeClaim{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(cObj, pvc); err != nil {
+				return err
+			}
+			for i := int64(0); i < replicas; i++ {
+				f.Storage.Add(*pvc.Spec.Resources.Requests.Storage())
+			}
+		}
+	}
+	return nil
+}
+
+// computeDemoFootprint parses the demo YAMLs and aggregates the resources they request.
+func computeDemoFootprint(yamls map[string][]byte) (*demoFootprint, error) {
+	f := &demoFootprint{}
+	for name, yamlBytes := range yamls {
+		resources, err := k8s.GetResourcesFromNamedYAML(name, bytes.NewReader(yamlBytes))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resources {
+			if err := f.addResource(r.Object); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f, nil
+}
+
+func (f *demoFootprint) rows() [][]interface{} {
+	pods := fmt.Sprintf("%d", f.Pods)
+	if f.DaemonSetPods > 0 {
+		pods = fmt.Sprintf("%d (+%d per node)", f.Pods, f.DaemonSetPods)
+	}
+	return [][]interface{}{
+		{"Pods", pods},
+		{"CPU requests", f.CPURequests.String()},
+		{"CPU limits", f.CPULimits.String()},
+		{"Memory requests", f.MemoryRequests.String()},
+		{"Memory limits", f.MemoryLimits.String()},
+		{"PVC storage", f.Storage.String()},
+		{"Services", f.Services},
+		{"Ingresses", f.Ingresses},
+	}
+}
+
+func printDemoFootprint(appName string, f *demoFootprint) {
+	utils.Infof("Demo app %s requests the following resources:", appName)
+	for _, row := range f.rows() {
+		utils.Infof("  %-16s %v", row[0], row[1])
+	}
+}
+
+// clusterCapacity is the allocatable capacity of the cluster and how much of it is already requested.
+type clusterCapacity struct {
+	Nodes             int64
+	AllocatableCPU    resource.Quantity
+	AllocatableMemory resource.Quantity
+	RequestedCPU      resource.Quantity
+	RequestedMemory   resource.Quantity
+}
+
+// getClusterCapacity sums the allocatable capacity of all schedulable nodes and the requests of all
+// running pods.
+func getClusterCapacity() (*clusterCapacity, error) {
+	clientset := demoKube.Clientset()
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	c := &clusterCapacity{}
+	for _, n := range nodes.Items {
+		if n.Spec.Unschedulable {
+			continue
+		}
+		c.Nodes++
+		c.AllocatableCPU.Add(*n.Status.Allocatable.Cpu())
+		c.AllocatableMemory.Add(*n.Status.Allocatable.Memory())
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pods.Items {
+		for _, ctr := range p.Spec.Containers {
+			c.RequestedCPU.Add(*ctr.Resources.Requests.Cpu())
+			c.RequestedMemory.Add(*ctr.Resources.Requests.Memory())
+		}
+	}
+	return c, nil
+}
+
+// totalRequests returns the CPU and memory requested by the demo app on a cluster with the given number of nodes.
+func (f *demoFootprint) totalRequests(nodes int64) (cpu, memory resource.Quantity) {
+	cpu = f.CPURequests.DeepCopy()
+	memory = f.MemoryRequests.DeepCopy()
+	if f.DaemonSetPods > 0 {
+		// DaemonSet requests are counted once in the footprint, but are scheduled on every node.
+		for i := int64(1); i < nodes; i++ {
+			cpu.Add(f.daemonSetCPURequests)
+			memory.Add(f.daemonSetMemoryRequests)
+		}
+	}
+	return cpu, memory
+}
+
+// compareDemoFootprint prints how the demo app's requests compare to the free capacity of the cluster,
+// and returns false if deploying it would over-commit the cluster.
+func compareDemoFootprint(f *demoFootprint, c *clusterCapacity) bool {
+	cpu, memory := f.totalRequests(c.Nodes)
+
+	freeCPU := c.AllocatableCPU.DeepCopy()
+	freeCPU.Sub(c.RequestedCPU)
+	freeMemory := c.AllocatableMemory.DeepCopy()
+	freeMemory.Sub(c.RequestedMemory)
+
+	utils.Infof("The cluster has %d schedulable node(s) with the following unrequested capacity:", c.Nodes)
+	utils.Infof("  %-16s %s of %s", "CPU", freeCPU.String(), c.AllocatableCPU.String())
+	utils.Infof("  %-16s %s of %s", "Memory", freeMemory.String(), c.AllocatableMemory.String())
+
+	fits := true
+	if cpu.Cmp(freeCPU) > 0 {
+		utils.Errorf("Demo app requests %s CPU, but only %s is available", cpu.String(), freeCPU.String())
+		fits = false
+	}
+	if memory.Cmp(freeMemory) > 0 {
+		utils.Errorf("Demo app requests %s memory, but only %s is available", memory.String(), freeMemory.String())
+		fits = false
+	}
+	return fits
+}
+
+func sizeCmd(cmd *cobra.Command, args []string) {
+	appName := args[0]
+
+	yamls, err := newDemoClient().AppYAMLs(appName)
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatalf("Could not download demo yaml apps for app '%s'", appName)
+	}
+
+	f, err := computeDemoFootprint(yamls)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to parse YAMLs for demo app %s", appName)
+	}
+
+	w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+	defer w.Finish()
+	w.SetHeader("demo_size", []string{"Resource", "Total"})
+	for _, row := range f.rows() {
+		if err := w.Write(row); err != nil {
+			log.WithError(err).Error("Failed to write demo app size")
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func init() {
+	statusDemoCmd.Flags().BoolP("watch", "w", false, "Whether to keep refreshing the status in place until interrupted")
+	statusDemoCmd.Flags().Duration("interval", 2*time.Second, "How often the status is refreshed with --watch")
+	statusDemoCmd.Flags().String("namespace", "", "The namespace the demo app was deployed to. Defaults to the name of the app, or the suffixed namespace px deployed it to.")
+
+	DemoCmd.AddCommand(statusDemoCmd)
+}
+
+var statusDemoCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the pods of a deployed demo app",
+	Args:  cobra.ExactArgs(1),
+	Run:   statusCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Status", pxanalytics.NewProperties().
+			Set("app", args[0]))
+	},
+}
+
+func statusCmd(cmd *cobra.Command, args []string) {
+	appName := args[0]
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+
+	namespace := demoAppNamespace(cmd, appName)
+	if !demoClusterClient().NamespaceExists(namespace) {
+		utils.Fatalf("Demo app %s is not deployed on the current cluster", appName)
+	}
+	clientset := demoKube.Clientset()
+
+	format := demoOutputFormat()
+	if !watch {
+		w := components.CreateStreamWriter(format, os.Stdout)
+		if err := writePodStatuses(context.Background(), clientset, namespace, w); err != nil {
+			utils.WithError(err).Fatalf("Failed to get the status of demo app %s", appName)
+		}
+		w.Finish()
+		return
+	}
+
+	if format != "table" && format != "wide" {
+		utils.WithExitCode(exitcodes.Usage).Fatalf("--watch only supports the table and wide output formats")
+	}
+	if interval <= 0 {
+		utils.WithExitCode(exitcodes.Usage).Fatalf("--interval must be positive")
+	}
+	ctx, cleanup := utils.WithSignalCancellable(context.Background())
+	defer cleanup()
+	// Ages change on every refresh, so only print the table again in non-TTY output when the pods change otherwise.
+	table := components.NewLiveTable(os.Stdout, interval).IgnoreChangesTo("Age")
+	if format == "wide" {
+		table.Wide()
+	}
+	err := table.Run(ctx, func(w components.OutputStreamWriter) (bool, error) {
+		return false, writePodStatuses(ctx, clientset, namespace, w)
+	})
+	if err != nil && ctx.Err() == nil {
+		utils.WithError(err).Fatalf("Failed to get the status of demo app %s", appName)
+	}
+}
+
+// writePodStatuses writes a row for each pod in the namespace, like `kubectl get pods`.
+func writePodStatuses(ctx context.Context, clientset kubernetes.Interface, namespace string, w components.OutputStreamWriter) error {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+
+	w.SetHeader("demo_status", []string{"Name", "Ready", "Status", "Restarts", "Age"})
+	readyPods := 0
+	for _, pod := range pods.Items {
+		ready, restarts := 0, int32(0)
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Ready {
+				ready++
+			}
+			restarts += cs.RestartCount
+		}
+		if ready == len(pod.Spec.Containers) && pod.Status.Phase == v1.PodRunning {
+			readyPods++
+		}
+		age := duration.HumanDuration(time.Since(pod.CreationTimestamp.Time))
+		err := w.Write([]interface{}{
+			pod.Name, fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers)), podStatus(&pod), restarts, age,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return components.SetFooter(w, fmt.Sprintf("%d pods", len(pods.Items)), fmt.Sprintf("%d ready", readyPods), "", "", "")
+}
+
+// podStatus returns the reason that a container of the pod isn't running, if any, or else the phase of the pod.
+func podStatus(pod *v1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+	for _, cs := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+			return cs.State.Waiting.Reason
+		}
+		if cs.State.Terminated != nil && cs.State.Terminated.Reason != "" && cs.State.Terminated.ExitCode != 0 {
+			return cs.State.Terminated.Reason
+		}
+	}
+	if pod.Status.Reason != "" {
+		return pod.Status.Reason
+	}
+	return string(pod.Status.Phase)
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// demoResourceTransform modifies a demo resource before it is applied.
+type demoResourceTransform func(obj *unstructured.Unstructured) error
+
+// transformDemoYAMLs parses the demo YAMLs, runs the transforms on every resource and re-encodes the result.
+func transformDemoYAMLs(yamls map[string][]byte, transforms ...demoResourceTransform) (map[string][]byte, error) {
+	if len(transforms) == 0 {
+		return yamls, nil
+	}
+
+	out := make(map[string][]byte, len(yamls))
+	for name, yamlBytes := range yamls {
+		resources, err := k8s.GetResourcesFromNamedYAML(name, bytes.NewReader(yamlBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		buf := &bytes.Buffer{}
+		for _, r := range resources {
+			for _, t := range transforms {
+				if err := t(r.Object); err != nil {
+					return nil, err
+				}
+			}
+			b, err := yaml.Marshal(r.Object.Object)
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteString("---\n")
+			buf.Write(b)
+		}
+		out[name] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// forEachPodSpec calls fn with the pod spec of the given resource, if it has one.
+func forEachPodSpec(obj *unstructured.Unstructured, fn func(spec map[string]interface{}) error) error {
+	specPath := []string{"spec"}
+	if obj.GetKind() != "Pod" {
+		templatePath, ok := podTemplatePaths[obj.GetKind()]
+		if !ok {
+			return nil
+		}
+		specPath = append(append([]string{}, templatePath...), "spec")
+	}
+
+	spec, found, err := unstructured.NestedMap(obj.Object, specPath...)
+	if err != nil || !found {
+		return err
+	}
+	if err := fn(spec); err != nil {
+		return err
+	}
+	return unstructured.SetNestedMap(obj.Object, spec, specPath...)
+}
+
+// forEachContainer calls fn with every container and init container of the given pod spec.
+func forEachContainer(spec map[string]interface{}, fn func(container map[string]interface{}) error) error {
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, ok := spec[field].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if err := fn(container); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rewriteImage replaces the registry of the given image reference with the given registry prefix.
+// For example, gcr.io/project/app:v1 with registry registry.corp/px-demos becomes registry.corp/px-demos/project/app:v1.
+func rewriteImage(image, registry string) string {
+	repo := image
+	if parts := strings.SplitN(image, "/", 2); len(parts) == 2 {
+		// The first component is only a registry host if it looks like one.
+		if strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost" {
+			repo = parts[1]
+		}
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + repo
+}
+
+// imageRewriteTransform rewrites all container images to the given registry. If images is non-nil, the
+// original and rewritten image references are recorded in it.
+func imageRewriteTransform(registry string, images map[string]string) demoResourceTransform {
+	return func(obj *unstructured.Unstructured) error {
+		return forEachPodSpec(obj, func(spec map[string]interface{}) error {
+			return forEachContainer(spec, func(container map[string]interface{}) error {
+				image, ok := container["image"].(string)
+				if !ok || image == "" {
+					return nil
+				}
+				newImage := rewriteImage(image, registry)
+				if images != nil {
+					images[image] = newImage
+				}
+				container["image"] = newImage
+				return nil
+			})
+		})
+	}
+}
+
+// nodeSelectorTransform merges the given node selector into every pod spec.
+func nodeSelectorTransform(nodeSelector map[string]string) demoResourceTransform {
+	return func(obj *unstructured.Unstructured) error {
+		return forEachPodSpec(obj, func(spec map[string]interface{}) error {
+			selector, _ := spec["nodeSelector"].(map[string]interface{})
+			if selector == nil {
+				selector = make(map[string]interface{})
+			}
+			for k, v := range nodeSelector {
+				selector[k] = v
+			}
+			spec["nodeSelector"] = selector
+			return nil
+		})
+	}
+}
+
+// parseToleration parses a toleration in the same format as a taint passed to `kubectl taint`:
+// key[=value]:Effect. The effect may be omitted to tolerate all effects.
+func parseToleration(s string) (map[string]interface{}, error) {
+	toleration := make(map[string]interface{})
+	keyValue := s
+	if idx := strings.LastIndex(s, ":"); idx != -1 {
+		keyValue = s[:idx]
+		effect := s[idx+1:]
+		switch effect {
+		case "NoSchedule", "PreferNoSchedule", "NoExecute":
+			toleration["effect"] = effect
+		default:
+			return nil, fmt.Errorf("invalid toleration effect %q in %q", effect, s)
+		}
+	}
+
+	parts := strings.SplitN(keyValue, "=", 2)
+	if parts[0] == "" {
+		return nil, fmt.Errorf("toleration %q is missing a key", s)
+	}
+	toleration["key"] = parts[0]
+	if len(parts) == 2 {
+		toleration["operator"] = "Equal"
+		toleration["value"] = parts[1]
+	} else {
+		toleration["operator"] = "Exists"
+	}
+	return toleration, nil
+}
+
+// tolerationsTransform appends the given tolerations to every pod spec.
+func tolerationsTransform(tolerations []map[string]interface{}) demoResourceTransform {
+	return func(obj *unstructured.Unstructured) error {
+		return forEachPodSpec(obj, func(spec map[string]interface{}) error {
+			existing, _ := spec["tolerations"].([]interface{})
+			for _, t := range tolerations {
+				existing = append(existing, runtime.DeepCopyJSONValue(t))
+			}
+			spec["tolerations"] = existing
+			return nil
+		})
+	}
+}
+
+// imageReplaceTransform replaces container images using the given mapping. Images that are
+// not in the mapping are left unchanged.
+func imageReplaceTransform(images map[string]string) demoResourceTransform {
+	return func(obj *unstructured.Unstructured) error {
+		return forEachPodSpec(obj, func(spec map[string]interface{}) error {
+			return forEachContainer(spec, func(container map[string]interface{}) error {
+				image, ok := container["image"].(string)
+				if !ok {
+					return nil
+				}
+				if newImage, ok := images[image]; ok {
+					container["image"] = newImage
+				}
+				return nil
+			})
+		})
+	}
+}
+
+// labelTransform adds the given labels to every resource.
+func labelTransform(labels map[string]string) demoResourceTransform {
+	return func(obj *unstructured.Unstructured) error {
+		objLabels := obj.GetLabels()
+		if objLabels == nil {
+			objLabels = make(map[string]string)
+		}
+		for k, v := range labels {
+			objLabels[k] = v
+		}
+		obj.SetLabels(objLabels)
+		return nil
+	}
+}
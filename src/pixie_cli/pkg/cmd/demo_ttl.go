@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// demoExpiryAnnotation is the namespace annotation that records when a demo app deployed with --ttl expires.
+const demoExpiryAnnotation = "px.dev/demo-expires-at"
+
+// demoNamespaceAnnotations returns the annotations to set on the namespace of a demo app deployed with the given TTL.
+func demoNamespaceAnnotations(ttl time.Duration) map[string]string {
+	if ttl <= 0 {
+		return nil
+	}
+	return map[string]string{
+		demoExpiryAnnotation: time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	}
+}
+
+// expiredDemoApp is a demo app whose TTL has passed.
+type expiredDemoApp struct {
+	AppName   string
+	Namespace string
+}
+
+// expiredDemoApps returns the demo apps on the current cluster whose TTL has passed.
+func expiredDemoApps() ([]*expiredDemoApp, error) {
+	namespaces, err := demoKube.Clientset().CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{LabelSelector: demo.ChannelLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []*expiredDemoApp
+	now := time.Now()
+	for _, ns := range namespaces.Items {
+		expiry, ok := ns.Annotations[demoExpiryAnnotation]
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, expiry)
+		if err != nil {
+			log.WithError(err).Infof("Ignoring invalid expiry on namespace %s", ns.Name)
+			continue
+		}
+		if now.After(t) {
+			appName := ns.Labels[demo.AppLabel]
+			if appName == "" {
+				appName = ns.Name
+			}
+			expired = append(expired, &expiredDemoApp{AppName: appName, Namespace: ns.Name})
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].Namespace < expired[j].Namespace })
+	return expired, nil
+}
+
+func deleteExpiredDemoApps(dryRun bool) {
+	expired, err := expiredDemoApps()
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to list demo apps")
+	}
+	if len(expired) == 0 {
+		utils.Info("No expired demo apps found")
+		return
+	}
+
+	currentCluster := k8s.GetClientAPIConfig().CurrentContext
+	namespaces := make([]string, len(expired))
+	for i, e := range expired {
+		namespaces[i] = e.Namespace
+	}
+	utils.Infof("Deleting expired demo apps in namespaces %s from the following cluster: %s", strings.Join(namespaces, ", "), currentCluster)
+	if !demoPrompter.YNPrompt("Is the cluster correct?", true) {
+		utils.WithExitCode(exitcodes.Aborted).Fatal("Cluster is not correct. Aborting.")
+	}
+
+	client := newDemoClient()
+	failed := false
+	for _, e := range expired {
+		if err := client.Delete(e.AppName, &demo.DeleteOptions{Namespace: e.Namespace, DryRun: dryRun}); err != nil {
+			utils.WithError(err).Errorf("Error deleting demo app %s from cluster %s", e.Namespace, currentCluster)
+			failed = true
+			continue
+		}
+		if !dryRun {
+			utils.Infof("Successfully deleted demo app %s from cluster %s", e.Namespace, currentCluster)
+		}
+	}
+	if failed {
+		utils.Fatal("Failed to delete some expired demo apps")
+	}
+}
@@ -0,0 +1,385 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	// demoUIRefreshInterval is how often the status of the selected demo app is refreshed.
+	demoUIRefreshInterval = 2 * time.Second
+	// demoUIMaxLogLines is the number of recent log lines that are kept in the logs pane.
+	demoUIMaxLogLines = 1000
+	demoUIHelp        = "[::b]d[::-] deploy  [::b]x[::-] delete  [::b]s[::-] status  [::b]l[::-] logs  [::b]r[::-] refresh  [::b]q[::-] quit"
+)
+
+func init() {
+	DemoCmd.AddCommand(uiDemoCmd)
+}
+
+var uiDemoCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Manage demo apps in a full-screen terminal UI",
+	Long: `Manage demo apps in a full-screen terminal UI.
+
+The UI lists the available demo apps and whether they are deployed. The selected app is deployed with d, deleted with
+x, and its pods are shown with s or its logs are tailed with l. Deploys and deletes run px demo deploy and px demo
+delete in the terminal, and return to the UI once they complete.`,
+	Args: cobra.NoArgs,
+	Run:  uiCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo UI", nil)
+	},
+}
+
+func uiCmd(cmd *cobra.Command, args []string) {
+	if !components.Interactive() || !components.IsTerminal(os.Stdin) {
+		utils.WithExitCode(exitcodes.Usage).Fatal("px demo ui needs an interactive terminal, use the other px demo commands instead")
+	}
+	manifest, err := newDemoClient().Manifest()
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatal("Could not download manifest file")
+	}
+
+	ui := newDemoUI(manifest)
+	if err := ui.run(); err != nil {
+		utils.WithError(err).Fatal("Failed to run the demo UI")
+	}
+}
+
+// demoUIPane is what the details pane of the demo UI shows for the selected app.
+type demoUIPane int
+
+const (
+	demoUIPaneStatus demoUIPane = iota
+	demoUIPaneLogs
+)
+
+// demoUI is the full-screen UI of px demo ui. The apps are listed on the left, and the status or the logs of the
+// selected app are shown on the right.
+type demoUI struct {
+	app     *tview.Application
+	apps    *tview.Table
+	details *tview.TextView
+	footer  *tview.TextView
+
+	manifest demo.Manifest
+	names    []string
+	// clientset is nil if the cluster can't be reached, in which case clientErr is why.
+	clientset kubernetes.Interface
+	clientErr error
+
+	mu       sync.Mutex
+	deployed map[string]string
+	pane     demoUIPane
+	// stopLogs stops tailing the logs of the selected app.
+	stopLogs func()
+	logLines []string
+}
+
+func newDemoUI(m demo.Manifest) *demoUI {
+	ui := &demoUI{
+		app:      tview.NewApplication(),
+		apps:     tview.NewTable(),
+		details:  tview.NewTextView(),
+		footer:   tview.NewTextView(),
+		manifest: m,
+		deployed: make(map[string]string),
+	}
+	for name, spec := range m {
+		// Deprecated apps can't be deployed.
+		if spec == nil || spec.Deprecated != nil {
+			continue
+		}
+		ui.names = append(ui.names, name)
+	}
+	sort.Strings(ui.names)
+
+	if config, err := k8s.LoadConfig(); err != nil {
+		ui.clientErr = err
+	} else if ui.clientset, err = kubernetes.NewForConfig(config); err != nil {
+		ui.clientErr = err
+	}
+
+	ui.apps.SetSelectable(true, false).SetFixed(1, 0)
+	ui.apps.SetBorder(true).SetTitle(fmt.Sprintf(" Demo apps (%s) ", demoChannel()))
+	ui.apps.SetSelectionChangedFunc(func(row, column int) {
+		ui.showPane(demoUIPaneStatus)
+	})
+	ui.details.SetDynamicColors(true).SetWrap(false)
+	ui.details.SetBorder(true)
+	ui.footer.SetDynamicColors(true).SetText(demoUIHelp)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(ui.apps, 0, 1, true).
+			AddItem(ui.details, 0, 2, false), 0, 1, true).
+		AddItem(ui.footer, 1, 0, false)
+	ui.app.SetRoot(layout, true).SetInputCapture(ui.keyHandler)
+	return ui
+}
+
+func (ui *demoUI) run() error {
+	ui.refreshApps()
+	ui.apps.Select(1, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(demoUIRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ui.app.QueueUpdateDraw(func() {
+					if ui.pane == demoUIPaneStatus {
+						ui.showStatus()
+					}
+				})
+			}
+		}
+	}()
+	defer ui.stopTailing()
+	return ui.app.Run()
+}
+
+func (ui *demoUI) keyHandler(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyCtrlC, tcell.KeyEscape:
+		if event.Key() == tcell.KeyEscape && ui.pane == demoUIPaneLogs {
+			ui.showPane(demoUIPaneStatus)
+			return nil
+		}
+		ui.app.Stop()
+		return nil
+	case tcell.KeyEnter:
+		ui.showPane(demoUIPaneStatus)
+		return nil
+	case tcell.KeyRune:
+	default:
+		return event
+	}
+
+	switch event.Rune() {
+	case 'q':
+		ui.app.Stop()
+	case 'd':
+		ui.runDemoCommand("deploy")
+	case 'x':
+		ui.runDemoCommand("delete")
+	case 's':
+		ui.showPane(demoUIPaneStatus)
+	case 'l':
+		ui.showPane(demoUIPaneLogs)
+	case 'r':
+		ui.refreshApps()
+		ui.showPane(ui.pane)
+	default:
+		return event
+	}
+	return nil
+}
+
+// selected returns the name of the selected app, or "" if there are no apps.
+func (ui *demoUI) selected() string {
+	row, _ := ui.apps.GetSelection()
+	if row < 1 || row > len(ui.names) {
+		return ""
+	}
+	return ui.names[row-1]
+}
+
+// refreshApps lists the apps and whether they are deployed.
+func (ui *demoUI) refreshApps() {
+	deployed := deployedDemoChannels()
+	ui.mu.Lock()
+	ui.deployed = deployed
+	ui.mu.Unlock()
+
+	ui.apps.Clear()
+	for i, h := range []string{"Name", "Deployed"} {
+		ui.apps.SetCell(0, i, tview.NewTableCell(h).SetSelectable(false).SetAttributes(tcell.AttrBold))
+	}
+	for i, name := range ui.names {
+		ui.apps.SetCell(i+1, 0, tview.NewTableCell(name).SetExpansion(1))
+		ui.apps.SetCell(i+1, 1, tview.NewTableCell(deployed[name]))
+	}
+}
+
+// showPane shows the status or the logs of the selected app in the details pane.
+func (ui *demoUI) showPane(pane demoUIPane) {
+	ui.stopTailing()
+	ui.pane = pane
+	switch pane {
+	case demoUIPaneLogs:
+		ui.tailLogs()
+	default:
+		ui.showStatus()
+	}
+}
+
+func (ui *demoUI) showStatus() {
+	name := ui.selected()
+	ui.details.SetTitle(fmt.Sprintf(" %s ", name))
+	if name == "" {
+		ui.details.SetText("No demo apps are available on this channel.")
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", tview.Escape(ui.manifest[name].Description))
+	ui.mu.Lock()
+	channel := ui.deployed[name]
+	ui.mu.Unlock()
+	switch {
+	case ui.clientset == nil:
+		fmt.Fprintf(&b, "[red]The cluster can't be reached: %s[-]\n", tview.Escape(ui.clientErr.Error()))
+	case channel == "":
+		fmt.Fprintf(&b, "Not deployed. Press [::b]d[::-] to deploy it.\n")
+	default:
+		fmt.Fprintf(&b, "Deployed from the %s channel.\n\n", channel)
+		var buf bytes.Buffer
+		w := components.NewTableStreamWriter(&buf)
+		ctx, cancel := context.WithTimeout(context.Background(), demoUIRefreshInterval)
+		err := writePodStatuses(ctx, ui.clientset, name, w)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(&b, "[red]Failed to get the status: %s[-]\n", tview.Escape(err.Error()))
+		} else {
+			w.Finish()
+			b.WriteString(tview.TranslateANSI(tview.Escape(buf.String())))
+		}
+	}
+	ui.details.SetText(b.String())
+}
+
+// tailLogs streams the logs of the selected app into the details pane, until stopTailing is called.
+func (ui *demoUI) tailLogs() {
+	name := ui.selected()
+	ui.details.SetTitle(fmt.Sprintf(" %s logs (Esc to go back) ", name))
+	ui.logLines = nil
+	ui.details.SetText("")
+	if name == "" || ui.clientset == nil {
+		ui.showStatus()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.stopLogs = cancel
+	tail := int64(100)
+	lines, err := k8s.StreamLogs(ctx, ui.clientset, name, "", &k8s.StreamLogsOptions{Follow: true, TailLines: &tail})
+	if err != nil {
+		ui.details.SetText(fmt.Sprintf("[red]Failed to stream the logs: %s[-]", tview.Escape(err.Error())))
+		return
+	}
+	go func() {
+		for l := range lines {
+			text := fmt.Sprintf("[cyan]%s/%s[-] %s", tview.Escape(l.Pod), tview.Escape(l.Container), tview.Escape(l.Line))
+			if l.Err != nil {
+				text = fmt.Sprintf("[red]%s/%s: %s[-]", tview.Escape(l.Pod), tview.Escape(l.Container), tview.Escape(l.Err.Error()))
+			}
+			ui.app.QueueUpdateDraw(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				ui.logLines = append(ui.logLines, text)
+				if len(ui.logLines) > demoUIMaxLogLines {
+					ui.logLines = ui.logLines[len(ui.logLines)-demoUIMaxLogLines:]
+				}
+				ui.details.SetText(strings.Join(ui.logLines, "\n"))
+				ui.details.ScrollToEnd()
+			})
+		}
+	}()
+}
+
+func (ui *demoUI) stopTailing() {
+	if ui.stopLogs != nil {
+		ui.stopLogs()
+		ui.stopLogs = nil
+	}
+}
+
+// runDemoCommand runs px demo deploy or px demo delete for the selected app in the terminal, and returns to the UI
+// once the user has read its output. The commands run in their own process, since they prompt the user and exit on
+// errors.
+func (ui *demoUI) runDemoCommand(command string) {
+	name := ui.selected()
+	if name == "" {
+		return
+	}
+	ui.stopTailing()
+	ui.app.Suspend(func() {
+		bin, err := os.Executable()
+		if err != nil {
+			utils.WithError(err).Errorf("Failed to run px demo %s", command)
+			return
+		}
+		args := []string{"demo", command, name, "--artifacts", viper.GetString("artifacts"), "--channel", demoChannel()}
+		if context := viper.GetString("context"); context != "" {
+			args = append(args, "--context", context)
+		}
+		if maxRate := viper.GetString("max_download_rate"); maxRate != "" {
+			args = append(args, "--max_download_rate", maxRate)
+		}
+		c := exec.Command(bin, args...)
+		c.Stdin = os.Stdin
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+
+		// Ctrl+C interrupts the command, but not the UI.
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		_ = c.Run()
+		signal.Stop(signals)
+
+		fmt.Print("\nPress Enter to return to the demo UI")
+		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	})
+	ui.refreshApps()
+	ui.showPane(demoUIPaneStatus)
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/restmapper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func init() {
+	DemoCmd.AddCommand(validateDemoCmd)
+}
+
+var validateDemoCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that a demo app's YAMLs are valid for the current cluster before deploying it",
+	Args:  cobra.ExactArgs(1),
+	Run:   validateCmd,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		pxanalytics.Track("Demo Validate App", pxanalytics.NewProperties().
+			Set("app", args[0]))
+	},
+}
+
+// deprecatedAPIVersions maps deprecated group/versions to the group/version that replaces them.
+var deprecatedAPIVersions = map[string]string{
+	"extensions/v1beta1":                   "apps/v1 or networking.k8s.io/v1",
+	"apps/v1beta1":                         "apps/v1",
+	"apps/v1beta2":                         "apps/v1",
+	"networking.k8s.io/v1beta1":            "networking.k8s.io/v1",
+	"rbac.authorization.k8s.io/v1beta1":    "rbac.authorization.k8s.io/v1",
+	"apiextensions.k8s.io/v1beta1":         "apiextensions.k8s.io/v1",
+	"policy/v1beta1":                       "policy/v1",
+	"batch/v1beta1":                        "batch/v1",
+	"autoscaling/v2beta1":                  "autoscaling/v2",
+	"autoscaling/v2beta2":                  "autoscaling/v2",
+	"scheduling.k8s.io/v1beta1":            "scheduling.k8s.io/v1",
+	"storage.k8s.io/v1beta1":               "storage.k8s.io/v1",
+	"admissionregistration.k8s.io/v1beta1": "admissionregistration.k8s.io/v1",
+}
+
+// demoValidationProblem is a single problem found in a demo app's YAMLs.
+type demoValidationProblem struct {
+	File     string
+	Resource string
+	Severity string
+	Message  string
+}
+
+// validateDemoYAMLs checks the given YAMLs against the API resources served by the cluster. If rm is nil, only the
+// checks that don't need a cluster are run.
+func validateDemoYAMLs(yamls map[string][]byte, rm meta.RESTMapper) []*demoValidationProblem {
+	names := make([]string, 0, len(yamls))
+	for name := range yamls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []*demoValidationProblem
+	for _, name := range names {
+		resources, err := k8s.GetResourcesFromNamedYAML(name, bytes.NewReader(yamls[name]))
+		if err != nil {
+			msg := fmt.Sprintf("failed to parse: %s", err)
+			var parseErr *k8s.YAMLParseError
+			if errors.As(err, &parseErr) {
+				msg = fmt.Sprintf("failed to parse document %d: %s", parseErr.Document, parseErr.Err)
+				if parseErr.Line > 0 {
+					msg = fmt.Sprintf("failed to parse document %d, line %d: %s", parseErr.Document, parseErr.Line, parseErr.Err)
+				}
+			}
+			problems = append(problems, &demoValidationProblem{
+				File: name, Severity: "error", Message: msg,
+			})
+			continue
+		}
+
+		for _, r := range resources {
+			obj := r.Object
+			resourceName := fmt.Sprintf("%s/%s", obj.GetKind(), obj.GetName())
+			addProblem := func(severity, format string, args ...interface{}) {
+				problems = append(problems, &demoValidationProblem{
+					File: name, Resource: resourceName, Severity: severity, Message: fmt.Sprintf(format, args...),
+				})
+			}
+
+			if obj.GetName() == "" && obj.GetGenerateName() == "" {
+				addProblem("error", "metadata.name is not set")
+			}
+			if replacement, ok := deprecatedAPIVersions[obj.GetAPIVersion()]; ok {
+				addProblem("warning", "apiVersion %s is deprecated, use %s", obj.GetAPIVersion(), replacement)
+			}
+			if rm == nil {
+				continue
+			}
+
+			if _, err := rm.RESTMapping(r.GVK.GroupKind(), r.GVK.Version); err == nil {
+				continue
+			}
+			// The version is not served, check whether the kind is known at all.
+			if preferred, err := rm.RESTMapping(r.GVK.GroupKind()); err == nil {
+				addProblem("error", "apiVersion %s is not served by the cluster, use %s",
+					obj.GetAPIVersion(), preferred.GroupVersionKind.GroupVersion().String())
+			} else {
+				addProblem("error", "kind %s is not known to the cluster", r.GVK.GroupKind().String())
+			}
+		}
+	}
+	return problems
+}
+
+func validateCmd(cmd *cobra.Command, args []string) {
+	appName := args[0]
+
+	yamls, err := newDemoClient().AppYAMLs(appName)
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatalf("Could not download demo yaml apps for app '%s'", appName)
+	}
+
+	discoveryClient := demoKube.Discovery()
+	apiGroupResources, err := restmapper.GetAPIGroupResources(discoveryClient)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to fetch the cluster's API resources")
+	}
+
+	problems := validateDemoYAMLs(yamls, restmapper.NewDiscoveryRESTMapper(apiGroupResources))
+	if len(problems) == 0 {
+		utils.Infof("Demo app %s is valid for the current cluster", appName)
+		return
+	}
+
+	w := components.CreateStreamWriter(demoOutputFormat(), os.Stdout)
+	w.SetHeader("demo_validate", []string{"File", "Resource", "Severity", "Problem"})
+	errCount := 0
+	for _, p := range problems {
+		if p.Severity == "error" {
+			errCount++
+		}
+		if err := w.Write([]interface{}{p.File, p.Resource, p.Severity, p.Message}); err != nil {
+			log.WithError(err).Error("Failed to write validation problem")
+		}
+	}
+	w.Finish()
+
+	if errCount > 0 {
+		utils.Fatalf("Demo app %s has %d error(s) and would fail to deploy", appName, errCount)
+	}
+	utils.Infof("Demo app %s has %d warning(s)", appName, len(problems))
+}
@@ -29,7 +29,6 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/gofrs/uuid"
-	"github.com/segmentio/analytics-go/v3"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -45,8 +44,8 @@ import (
 	"px.dev/pixie/src/operator/client/versioned"
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
 	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
-	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/pixie_cli/pkg/vizier"
 	utils2 "px.dev/pixie/src/utils"
@@ -79,7 +78,7 @@ func init() {
 	DeployCmd.Flags().BoolP("check_only", "", false, "Only run check and exit.")
 	DeployCmd.Flags().StringP("namespace", "n", "pl", "The namespace to deploy Vizier to")
 	DeployCmd.Flags().StringP("deploy_key", "k", "", "The deploy key to use to deploy Pixie")
-	DeployCmd.Flags().BoolP("use_etcd_operator", "o", false, "Whether to use the operator for etcd instead of the statefulset")
+	DeployCmd.Flags().Bool("use_etcd_operator", false, "Whether to use the operator for etcd instead of the statefulset")
 	DeployCmd.Flags().StringP("labels", "l", "", "Custom labels to apply to Pixie resources")
 	DeployCmd.Flags().StringP("annotations", "t", "", "Custom annotations to apply to Pixie resources")
 	DeployCmd.Flags().StringP("cluster_name", "u", "", "The name for your cluster. Otherwise, the name will be taken from the current kubeconfig.")
@@ -107,6 +106,7 @@ var DeployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploys Pixie on the current K8s cluster",
 	PreRun: func(cmd *cobra.Command, args []string) {
+		trackKubernetesVersion()
 		viper.BindPFlag("extract_yaml", cmd.Flags().Lookup("extract_yaml"))
 		viper.BindPFlag("vizier_version", cmd.Flags().Lookup("vizier_version"))
 		viper.BindPFlag("check", cmd.Flags().Lookup("check"))
@@ -162,10 +162,21 @@ var DeployCmd = &cobra.Command{
 
 type taskWrapper struct {
 	name string
-	run  func() error
+	run  func(ctx context.Context) error
 }
 
+// newTaskWrapper creates a task that doesn't observe the context of the task runner. Interrupting the run doesn't stop
+// the task once it started, it only keeps the task runner from starting the tasks after it. Tasks that can be
+// canceled should use newContextTaskWrapper instead.
 func newTaskWrapper(name string, run func() error) *taskWrapper {
+	return &taskWrapper{
+		name,
+		func(context.Context) error { return run() },
+	}
+}
+
+// newContextTaskWrapper creates a task whose work is canceled when the task runner is interrupted.
+func newContextTaskWrapper(name string, run func(ctx context.Context) error) *taskWrapper {
 	return &taskWrapper{
 		name,
 		run,
@@ -176,8 +187,8 @@ func (t *taskWrapper) Name() string {
 	return t.name
 }
 
-func (t *taskWrapper) Run() error {
-	return t.run()
+func (t *taskWrapper) Run(ctx context.Context) error {
+	return t.run(ctx)
 }
 
 func newArtifactTrackerClient(conn *grpc.ClientConn) cloudpb.ArtifactTrackerClient {
@@ -239,7 +250,7 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	deployKey, _ := cmd.Flags().GetString("deploy_key")
 	useEtcdOperator, _ := cmd.Flags().GetBool("use_etcd_operator")
 	disableAutoUpdate, _ := cmd.Flags().GetBool("disable_auto_update")
-	customLabels, _ := cmd.Flags().GetString("labels")
+	customLabels := viper.GetString("labels")
 	customAnnotations, _ := cmd.Flags().GetString("annotations")
 	pemMemoryLimit, _ := cmd.Flags().GetString("pem_memory_limit")
 	pemMemoryRequest, _ := cmd.Flags().GetString("pem_memory_request")
@@ -310,19 +321,12 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	}
 
 	if (check || checkOnly) && extractPath == "" {
-		_ = pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Cluster Check Run",
-		})
+		pxanalytics.Track("Cluster Check Run", nil)
 
 		err := utils.RunDefaultClusterChecks()
 		if err != nil {
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Cluster Check Failed",
-				Properties: analytics.NewProperties().
-					Set("error", err.Error()),
-			})
+			pxanalytics.Track("Cluster Check Failed", pxanalytics.NewProperties().
+				Set("error", err.Error()))
 			utils.WithError(err).Fatal("Check pre-check has failed. To bypass pass in --check=false.")
 		}
 
@@ -335,8 +339,7 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		if err != nil {
 			clusterOk := components.YNPrompt("Some cluster checks failed. Pixie may not work properly on your cluster. Continue with deploy?", true)
 			if !clusterOk {
-				utils.Error("Deploy cancelled. Aborting...")
-				return
+				utils.WithExitCode(exitcodes.Aborted).Fatal("Deploy cancelled. Aborting...")
 			}
 		}
 	}
@@ -467,26 +470,17 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		yamlMap[y.Name] = y.YAML
 	}
 
-	_ = pxanalytics.Client().Enqueue(&analytics.Track{
-		UserId: pxconfig.Cfg().UniqueClientID,
-		Event:  "Deploy Initiated",
-		Properties: analytics.NewProperties().
-			Set("cloud_addr", cloudAddr),
-	})
+	pxanalytics.Track("Deploy Initiated", pxanalytics.NewProperties().
+		Set("cloud_addr", cloudAddr))
 
-	_ = pxanalytics.Client().Enqueue(&analytics.Track{
-		UserId: pxconfig.Cfg().UniqueClientID,
-		Event:  "Deploy Started",
-		Properties: analytics.NewProperties().
-			Set("cloud_addr", cloudAddr),
-	})
+	pxanalytics.Track("Deploy Started", pxanalytics.NewProperties().
+		Set("cloud_addr", cloudAddr))
 
 	currentCluster := kubeAPIConfig.CurrentContext
 	utils.Infof("Deploying Pixie to the following cluster: %s", currentCluster)
 	clusterOk := components.YNPrompt("Is the cluster correct?", true)
 	if !clusterOk {
-		utils.Error("Cluster is not correct. Aborting.")
-		return
+		utils.WithExitCode(exitcodes.Aborted).Fatal("Cluster is not correct. Aborting.")
 	}
 
 	// Get the number of nodes.
@@ -510,50 +504,50 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 }
 
 func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClient *versioned.Clientset, kubeConfig *rest.Config, yamlMap map[string]string, deployOLM bool, olmNs, olmOpNs, namespace string) uuid.UUID {
-	olmCRDJob := newTaskWrapper("Installing OLM CRDs", func() error {
-		return retryDeploy(clientset, kubeConfig, yamlMap["olm_crd"])
+	olmCRDJob := newContextTaskWrapper("Installing OLM CRDs", func(ctx context.Context) error {
+		return retryDeploy(ctx, clientset, kubeConfig, yamlMap["olm_crd"])
 	})
-	olmJob := newTaskWrapper("Deploying OLM", func() error {
-		return retryDeploy(clientset, kubeConfig, yamlMap["olm"])
+	olmJob := newContextTaskWrapper("Deploying OLM", func(ctx context.Context) error {
+		return retryDeploy(ctx, clientset, kubeConfig, yamlMap["olm"])
 	})
 
-	olmPxJob := newTaskWrapper("Deploying Pixie OLM Namespace", func() error {
-		return retryDeploy(clientset, kubeConfig, yamlMap["px_olm"])
+	olmPxJob := newContextTaskWrapper("Deploying Pixie OLM Namespace", func(ctx context.Context) error {
+		return retryDeploy(ctx, clientset, kubeConfig, yamlMap["px_olm"])
 	})
 
-	olmCatalogJob := newTaskWrapper("Deploying OLM Catalog", func() error {
-		return retryDeploy(clientset, kubeConfig, yamlMap["catalog"])
+	olmCatalogJob := newContextTaskWrapper("Deploying OLM Catalog", func(ctx context.Context) error {
+		return retryDeploy(ctx, clientset, kubeConfig, yamlMap["catalog"])
 	})
-	olmSubscriptionJob := newTaskWrapper("Deploying OLM Subscription", func() error {
-		return retryDeploy(clientset, kubeConfig, yamlMap["subscription"])
+	olmSubscriptionJob := newContextTaskWrapper("Deploying OLM Subscription", func(ctx context.Context) error {
+		return retryDeploy(ctx, clientset, kubeConfig, yamlMap["subscription"])
 	})
 
-	namespaceJob := newTaskWrapper("Creating namespace", func() error {
+	namespaceJob := newContextTaskWrapper("Creating namespace", func(ctx context.Context) error {
 		// Create namespace, if needed.
 		ns := &v1.Namespace{}
 		ns.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("Namespace"))
 		ns.Name = namespace
 
-		_, err := clientset.CoreV1().Namespaces().Create(context.Background(), ns, metav1.CreateOptions{})
+		_, err := clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
 		if err != nil && k8serrors.IsAlreadyExists(err) {
 			return nil
 		}
 		return err
 	})
 
-	vzCRDJob := newTaskWrapper("Installing Vizier CRD", func() error {
+	vzCRDJob := newContextTaskWrapper("Installing Vizier CRD", func(ctx context.Context) error {
 		// Delete existing CRD, if any.
-		_ = vzClient.PxV1alpha1().Viziers(namespace).Delete(context.Background(), "pixie", metav1.DeleteOptions{})
+		_ = vzClient.PxV1alpha1().Viziers(namespace).Delete(ctx, "pixie", metav1.DeleteOptions{})
 
-		return retryDeploy(clientset, kubeConfig, yamlMap["vizier_crd"])
+		return retryDeploy(ctx, clientset, kubeConfig, yamlMap["vizier_crd"])
 	})
-	vzJob := newTaskWrapper("Deploying Vizier", func() error {
-		return retryDeploy(clientset, kubeConfig, yamlMap["vizier"])
+	vzJob := newContextTaskWrapper("Deploying Vizier", func(ctx context.Context) error {
+		return retryDeploy(ctx, clientset, kubeConfig, yamlMap["vizier"])
 	})
 
 	var clusterID uuid.UUID
-	waitJob := newTaskWrapper("Waiting for Cloud Connector to come online", func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	waitJob := newContextTaskWrapper("Waiting for Cloud Connector to come online", func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 		defer cancel()
 
 		t := time.NewTicker(2 * time.Second)
@@ -562,6 +556,9 @@ func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClien
 		for !clusterIDExists { // Wait for secret to be updated with clusterID.
 			select {
 			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.Canceled) {
+					return ctx.Err()
+				}
 				// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 				log.Fatal("Timed out waiting for cluster ID assignment")
 			case <-t.C:
@@ -592,12 +589,8 @@ func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClien
 	jr := utils.NewSerialTaskRunner(deployJobs)
 	err := jr.RunAndMonitor()
 	if err != nil {
-		_ = pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Deploy Failure",
-			Properties: analytics.NewProperties().
-				Set("err", err.Error()),
-		})
+		pxanalytics.Track("Deploy Failure", pxanalytics.NewProperties().
+			Set("err", err.Error()))
 		// Using log.Fatal rather than CLI log in order to track this error in Sentry.
 		log.WithError(err).Fatal("Failed to deploy Vizier")
 	}
@@ -692,18 +685,11 @@ func waitForHealthCheck(cloudAddr string, clusterID uuid.UUID, clientset *kubern
 	hc := utils.NewSerialTaskRunner(healthCheckJobs)
 	err := hc.RunAndMonitor()
 	if err != nil {
-		_ = pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Deploy Healthcheck Failed",
-			Properties: analytics.NewProperties().
-				Set("err", err.Error()),
-		})
+		pxanalytics.Track("Deploy Healthcheck Failed", pxanalytics.NewProperties().
+			Set("err", err.Error()))
 		utils.WithError(err).Fatal("Failed Pixie healthcheck")
 	}
-	_ = pxanalytics.Client().Enqueue(&analytics.Track{
-		UserId: pxconfig.Cfg().UniqueClientID,
-		Event:  "Deploy Healthcheck Passed",
-	})
+	pxanalytics.Track("Deploy Healthcheck Passed", nil)
 }
 
 func waitForCluster(ctx context.Context, conn *grpc.ClientConn, clusterID uuid.UUID) error {
@@ -752,11 +738,11 @@ func initiateUpdate(ctx context.Context, conn *grpc.ClientConn, clusterID uuid.U
 	return nil
 }
 
-func retryDeploy(clientset *kubernetes.Clientset, config *rest.Config, yamlContents string) error {
+func retryDeploy(ctx context.Context, clientset *kubernetes.Clientset, config *rest.Config, yamlContents string) error {
 	tries := 12
 	var err error
 	for tries > 0 {
-		err = k8s.ApplyYAML(clientset, config, "", strings.NewReader(yamlContents), false)
+		_, err = k8s.ApplyYAML(clientset, config, "", strings.NewReader(yamlContents), false)
 		if err == nil {
 			return nil
 		}
@@ -764,7 +750,12 @@ func retryDeploy(clientset *kubernetes.Clientset, config *rest.Config, yamlConte
 		if err != nil && k8serrors.IsAlreadyExists(err) {
 			return nil
 		}
-		time.Sleep(5 * time.Second)
+		// Once the deploy is interrupted, it stops retrying.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
 		tries--
 	}
 	if tries == 0 {
@@ -847,5 +838,6 @@ func waitForPems(clientset *kubernetes.Clientset, namespace string, expectedPods
 			return fmt.Errorf("Failed to schedule pems:\n%s", strings.Join(failedPems, "\n"))
 		}
 	}
-	return nil
+	// The watch ends if the connection to the API server is lost, which doesn't tell whether the PEMs are ready.
+	return fmt.Errorf("watch of the PEMs ended before %d of them were running", expectedPods)
 }
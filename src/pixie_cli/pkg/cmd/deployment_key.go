@@ -22,14 +22,11 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
-	"syscall"
 
 	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"golang.org/x/term"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
@@ -50,8 +47,6 @@ func init() {
 
 	DeleteDeployKeyCmd.Flags().StringP("id", "i", "", "The deploy key to delete")
 
-	ListDeployKeyCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
-
 	LookupDeployKeyCmd.Flags().StringP("key", "k", "", "Value of the key. Leave blank to be prompted.")
 }
 
@@ -124,13 +119,9 @@ var DeleteDeployKeyCmd = &cobra.Command{
 var ListDeployKeyCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all deployment key metadata",
-	PreRun: func(cmd *cobra.Command, args []string) {
-		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
-	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := outputFormat()
 
 		keys, err := listDeployKeys(cloudAddr)
 		if err != nil {
@@ -154,17 +145,15 @@ var LookupDeployKeyCmd = &cobra.Command{
 	Short: "Lookup deployment key based on the value of the key",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := outputFormat()
 
 		deployKey, err := cmd.Flags().GetString("key")
 		if err != nil || len(deployKey) == 0 {
-			fmt.Print("\nEnter Deploy Key (won't echo): ")
-			k, err := term.ReadPassword(syscall.Stdin)
+			fmt.Print("\n")
+			deployKey, err = components.SecretPrompt("Enter Deploy Key")
 			if err != nil {
 				log.WithError(err).Fatal("Failed to read Deploy Key")
 			}
-			deployKey = string(k)
 		}
 
 		k, err := lookupDeployKeys(cloudAddr, deployKey)
@@ -187,8 +176,7 @@ var GetDeployKeyCmd = &cobra.Command{
 	Short: "Get deployment key details for a single key",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := outputFormat()
 
 		if len(args) != 1 {
 			utils.Fatal("Expected a single argument 'key id'.")
@@ -0,0 +1,287 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// doctorTimeout is how long each check of px doctor may take to reach the cluster or the artifacts.
+const doctorTimeout = 10 * time.Second
+
+const (
+	doctorPass = "pass"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorResult is the result of a check of px doctor.
+type doctorResult struct {
+	check   string
+	status  string
+	message string
+	// fix is how to fix a warning or failure.
+	fix string
+}
+
+// doctorPermissions are the permissions that px demo needs on the cluster.
+var doctorPermissions = []authorizationv1.ResourceAttributes{
+	{Verb: "create", Resource: "namespaces"},
+	{Verb: "delete", Resource: "namespaces"},
+	{Verb: "create", Group: "apps", Resource: "deployments"},
+	{Verb: "create", Resource: "services"},
+	{Verb: "list", Resource: "pods"},
+	{Verb: "get", Resource: "pods", Subresource: "log"},
+	{Verb: "list", Resource: "events"},
+}
+
+// DoctorCmd checks the kubeconfig, the cluster, the demo artifacts, the terminal and the config for common problems.
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the kubeconfig, cluster, demo artifacts, terminal and config for common problems",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		results := runDoctorChecks()
+
+		w := components.CreateStreamWriter(outputFormat(), os.Stdout)
+		w.SetHeader("doctor", []string{"Check", "Status", "Details", "Fix"})
+		fails := 0
+		for _, r := range results {
+			if r.status == doctorFail {
+				fails++
+			}
+			_ = w.Write([]interface{}{r.check, r.status, r.message, r.fix})
+		}
+		w.Finish()
+
+		switch {
+		case fails == 1:
+			utils.Fatal("1 check failed")
+		case fails > 1:
+			utils.Fatalf("%d checks failed", fails)
+		}
+	},
+}
+
+// runDoctorChecks runs the checks of px doctor. The cluster checks are skipped if the kubeconfig can't be loaded.
+func runDoctorChecks() []doctorResult {
+	var results []doctorResult
+	config, r := checkDoctorKubeconfig()
+	results = append(results, r)
+	if config == nil {
+		results = append(results,
+			doctorResult{check: "API server", status: doctorWarn, message: "skipped, the kubeconfig can't be loaded"},
+			doctorResult{check: "Permissions", status: doctorWarn, message: "skipped, the kubeconfig can't be loaded"})
+	} else {
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			results = append(results, doctorResult{check: "API server", status: doctorFail, message: err.Error(),
+				fix: "Check the server and credentials of the context in the kubeconfig"})
+		} else {
+			r := checkDoctorAPIServer(clientset)
+			results = append(results, r)
+			if r.status == doctorFail {
+				results = append(results,
+					doctorResult{check: "Permissions", status: doctorWarn, message: "skipped, the API server can't be reached"})
+			} else {
+				results = append(results, checkDoctorPermissions(clientset))
+			}
+		}
+	}
+	results = append(results, checkDoctorArtifacts(), checkDoctorTerminal(), checkDoctorConfig())
+	return results
+}
+
+func checkDoctorKubeconfig() (*rest.Config, doctorResult) {
+	r := doctorResult{check: "Kubeconfig"}
+	config, err := k8s.LoadConfig()
+	if err != nil {
+		r.status = doctorFail
+		r.message = err.Error()
+		r.fix = "Check $KUBECONFIG or ~/.kube/config, or select a context with --context"
+		return nil, r
+	}
+	context, err := k8s.CurrentContext()
+	if err != nil || context == "" {
+		context = "in-cluster"
+	}
+	r.status = doctorPass
+	r.message = fmt.Sprintf("context %s, server %s", context, config.Host)
+	return config, r
+}
+
+func checkDoctorAPIServer(clientset kubernetes.Interface) doctorResult {
+	r := doctorResult{check: "API server"}
+	version, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		r.status = doctorFail
+		r.message = err.Error()
+		r.fix = "Check that the cluster is running and reachable from this machine, for example through a VPN"
+		return r
+	}
+	r.status = doctorPass
+	r.message = fmt.Sprintf("Kubernetes %s", version.GitVersion)
+	return r
+}
+
+func checkDoctorPermissions(clientset kubernetes.Interface) doctorResult {
+	r := doctorResult{check: "Permissions"}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	var missing []string
+	for _, attrs := range doctorPermissions {
+		attrs := attrs
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attrs},
+		}
+		resp, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			r.status = doctorWarn
+			r.message = fmt.Sprintf("the permissions can't be checked: %v", err)
+			return r
+		}
+		if !resp.Status.Allowed {
+			resource := attrs.Resource
+			if attrs.Subresource != "" {
+				resource += "/" + attrs.Subresource
+			}
+			missing = append(missing, fmt.Sprintf("%s %s", attrs.Verb, resource))
+		}
+	}
+	if len(missing) > 0 {
+		r.status = doctorFail
+		r.message = "missing: " + strings.Join(missing, ", ")
+		r.fix = "Ask a cluster admin to grant these permissions, which px demo needs"
+		return r
+	}
+	r.status = doctorPass
+	r.message = "px demo has the permissions it needs"
+	return r
+}
+
+func checkDoctorArtifacts() doctorResult {
+	r := doctorResult{check: "Demo artifacts"}
+	// The demo flags are only bound for px demo, so fall back to their defaults when they aren't set by the config.
+	if !viper.IsSet("artifacts") {
+		viper.SetDefault("artifacts", DemoCmd.PersistentFlags().Lookup("artifacts").DefValue)
+	}
+	artifacts := demoArtifactsURL()
+	src, err := newArtifactSource(artifacts)
+	if err != nil {
+		r.status = doctorFail
+		r.message = err.Error()
+		r.fix = "Fix the demo.artifacts setting with px config set"
+		return r
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := src.Fetch(demo.ManifestFile)
+		errCh <- err
+	}()
+	select {
+	case err = <-errCh:
+	case <-time.After(doctorTimeout):
+		err = fmt.Errorf("timed out after %s", doctorTimeout)
+	}
+	if err != nil {
+		r.status = doctorFail
+		r.message = fmt.Sprintf("%s can't be fetched: %v", demo.ManifestFile, err)
+		r.fix = "Check the network and proxy settings, or point demo.artifacts at a reachable mirror"
+		return r
+	}
+	r.status = doctorPass
+	r.message = fmt.Sprintf("fetched %s", demo.ManifestFile)
+	return r
+}
+
+func checkDoctorTerminal() doctorResult {
+	r := doctorResult{check: "Terminal"}
+	if !components.Interactive() {
+		r.status = doctorWarn
+		r.message = "stdout is not a terminal, or TERM is dumb, so output is plain and prompts take their defaults"
+		r.fix = "Run px in an interactive terminal to use prompts, spinners and colors"
+		return r
+	}
+	var features []string
+	if !color.NoColor {
+		features = append(features, "colors")
+	}
+	if components.HyperlinksSupported() {
+		features = append(features, "hyperlinks")
+	}
+	r.status = doctorPass
+	r.message = "interactive"
+	if len(features) > 0 {
+		r.message += " with " + strings.Join(features, " and ")
+	}
+	return r
+}
+
+func checkDoctorConfig() doctorResult {
+	r := doctorResult{check: "Config"}
+	problems, err := pxconfig.Validate()
+	if err != nil {
+		r.status = doctorFail
+		r.message = err.Error()
+		r.fix = "Check the permissions of ~/.config/pixie"
+		return r
+	}
+	errs, warnings := 0, 0
+	for _, p := range problems {
+		if p.Warning {
+			warnings++
+		} else {
+			errs++
+		}
+	}
+	switch {
+	case errs > 0:
+		r.status = doctorFail
+		r.message = fmt.Sprintf("%d errors and %d warnings", errs, warnings)
+		r.fix = "Run px config validate for details"
+	case warnings > 0:
+		r.status = doctorWarn
+		r.message = fmt.Sprintf("%d warnings", warnings)
+		r.fix = "Run px config validate for details"
+	default:
+		r.status = doctorPass
+		r.message = "valid"
+	}
+	return r
+}
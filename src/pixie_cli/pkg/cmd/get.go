@@ -42,8 +42,6 @@ import (
 )
 
 func init() {
-	GetCmd.PersistentFlags().StringP("output", "o", "", "Output format: one of: json|proto")
-
 	GetPEMsCmd.Flags().BoolP("all-clusters", "d", false, "Run script across all clusters")
 	GetPEMsCmd.Flags().StringP("cluster", "c", "", "Run only on selected cluster")
 	GetPEMsCmd.Flags().MarkHidden("all-clusters")
@@ -63,8 +61,7 @@ var GetPEMsCmd = &cobra.Command{
 	Short:   "Get information about running pems",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := outputFormat()
 		br := mustCreateBundleReader()
 		execScript := br.MustGetScript(script.AgentStatusScript)
 
@@ -96,8 +93,7 @@ var GetViziersCmd = &cobra.Command{
 	Short:   "Get information about registered viziers",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := outputFormat()
 
 		l, err := vizier.NewLister(cloudAddr)
 		if err != nil {
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	version "px.dev/pixie/src/shared/goversion"
+)
+
+// pluginPrefix is the prefix of the executables that extend the CLI. px foo runs px-foo, unless foo is a built-in
+// command.
+const pluginPrefix = "px-"
+
+func init() {
+	PluginCmd.AddCommand(listPluginsCmd)
+}
+
+// PluginCmd is the "plugin" command.
+var PluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Manage the plugins that extend the CLI",
+	Long: `Manage the plugins that extend the CLI.
+
+A plugin is an executable on the PATH whose name starts with px-. px foo runs the px-foo plugin, and px foo bar runs
+px-foo-bar if it exists, or else px-foo with the bar argument. Dashes in the names of commands are matched by
+underscores in the names of plugins, so px foo-bar runs px-foo_bar. Built-in commands can't be overridden by plugins.
+
+Plugins receive the arguments that follow their name, and the environment of px with the following env vars:
+  PX_BINARY       The path of the px binary, to run px commands.
+  PX_CONFIG_FILE  The path of the config file of px.
+  PX_CONTEXT      The kubeconfig context that px uses, if the kube.context setting is set.
+  PX_VERSION      The version of px.`,
+}
+
+var listPluginsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins on the PATH",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		plugins := findPlugins()
+		if len(plugins) == 0 {
+			utils.Info("No plugins found on the PATH")
+			return
+		}
+
+		w := components.CreateStreamWriter(outputFormat(), os.Stdout)
+		defer w.Finish()
+		w.SetHeader("plugins", []string{"Name", "Path", "Warning"})
+		for _, p := range plugins {
+			_ = w.Write([]interface{}{p.name, p.path, p.warning})
+		}
+	},
+}
+
+// plugin is an executable on the PATH that extends the CLI.
+type plugin struct {
+	name string
+	path string
+	// warning is why the plugin can't be run, for example because it's shadowed by another plugin.
+	warning string
+}
+
+// findPlugins returns the plugins on the PATH, sorted by name. Plugins that are shadowed by a plugin earlier on the
+// PATH or by a built-in command are returned with a warning.
+func findPlugins() []*plugin {
+	var plugins []*plugin
+	seen := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasPrefix(e.Name(), pluginPrefix) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			name := strings.TrimPrefix(e.Name(), pluginPrefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			p := &plugin{name: name, path: path}
+			if first, ok := seen[name]; ok {
+				p.warning = "shadowed by " + first
+			} else if isBuiltinCommand(name) {
+				p.warning = "overridden by the built-in command"
+			}
+			seen[name] = path
+			plugins = append(plugins, p)
+		}
+	}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		return plugins[i].name < plugins[j].name
+	})
+	return plugins
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(path))
+		return ext == ".exe" || ext == ".bat" || ext == ".cmd"
+	}
+	return info.Mode()&0111 != 0
+}
+
+// isBuiltinCommand returns whether the plugin name, with its dashes separating subcommands, starts with a built-in
+// command.
+func isBuiltinCommand(name string) bool {
+	first := strings.SplitN(name, "-", 2)[0]
+	for _, c := range RootCmd.Commands() {
+		if c.Name() == first || c.HasAlias(first) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupPlugin returns the plugin for the command line, and the arguments to run it with. The longest run of leading
+// arguments that names a plugin is used, so px foo bar prefers px-foo-bar to px-foo.
+func lookupPlugin(args []string) (string, []string, bool) {
+	var names []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		names = append(names, strings.ReplaceAll(arg, "-", "_"))
+	}
+	for i := len(names); i > 0; i-- {
+		path, err := exec.LookPath(pluginPrefix + strings.Join(names[:i], "-"))
+		if err == nil {
+			return path, args[i:], true
+		}
+	}
+	return "", nil, false
+}
+
+// pluginEnv returns the environment that plugins run with: the environment of px, with the context of px added.
+func pluginEnv() []string {
+	env := os.Environ()
+	if bin, err := os.Executable(); err == nil {
+		env = append(env, "PX_BINARY="+bin)
+	}
+	if path, err := utils.EnsureDefaultConfigFilePath(); err == nil {
+		env = append(env, "PX_CONFIG_FILE="+path)
+	}
+	if _, ok := os.LookupEnv("PX_CONTEXT"); !ok && pxconfig.Cfg().Kube.Context != "" {
+		env = append(env, "PX_CONTEXT="+pxconfig.Cfg().Kube.Context)
+	}
+	return append(env, "PX_VERSION="+version.GetVersion().ToString())
+}
+
+// runPluginForArgs runs the plugin for the command line, if the command line doesn't name a built-in command. It only
+// returns if there is no such plugin.
+func runPluginForArgs(args []string) {
+	if len(args) == 0 {
+		return
+	}
+	if _, _, err := RootCmd.Find(args); err == nil {
+		return
+	}
+	path, pluginArgs, ok := lookupPlugin(args)
+	if !ok {
+		return
+	}
+
+	pxanalytics.Track("Exec Plugin", nil)
+	// The plugin replaces px, so the events are sent before it runs.
+	_ = pxanalytics.Close()
+	if err := execPlugin(path, pluginArgs, pluginEnv()); err != nil {
+		utils.WithError(err).Fatalf("Failed to run plugin %s", path)
+	}
+}
@@ -19,21 +19,27 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
 
 	"github.com/fatih/color"
-	"github.com/segmentio/analytics-go/v3"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"k8s.io/client-go/discovery"
 
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
 	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
-	"px.dev/pixie/src/pixie_cli/pkg/update"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
 )
 
 func init() {
@@ -47,12 +53,72 @@ func init() {
 	RootCmd.PersistentFlags().BoolP("y", "y", false, "Whether to accept all user input")
 	viper.BindPFlag("y", RootCmd.PersistentFlags().Lookup("y"))
 
-	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "quiet mode")
+	RootCmd.PersistentFlags().Duration("prompt_timeout", 0, "How long prompts wait for an answer before --prompt_timeout_action is taken, for example: 10m. Defaults to the prompts.timeout setting, or else prompts wait forever")
+	viper.BindPFlag("prompt_timeout", RootCmd.PersistentFlags().Lookup("prompt_timeout"))
+
+	RootCmd.PersistentFlags().String("prompt_timeout_action", "", "What happens once a prompt times out: one of: abort|default. abort exits the command, default takes the prompt's default answer. Defaults to the prompts.timeout_action setting, or else abort")
+	viper.BindPFlag("prompt_timeout_action", RootCmd.PersistentFlags().Lookup("prompt_timeout_action"))
+
+	RootCmd.PersistentFlags().StringP("output", "o", "", "Output format of commands that write results: one of: table|wide|json|json-array|csv|csv-rfc4180|yaml. csv-rfc4180 quotes and escapes all fields as described in RFC 4180, while csv only quotes fields that contain commas or newlines. Some commands support additional formats, such as proto for px get and live for px run. With json and json-array, failures are written to stderr as JSON objects with a stable error code. Overrides the output.format setting.")
+	viper.BindPFlag("output", RootCmd.PersistentFlags().Lookup("output"))
+
+	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "Don't show spinners and progress bars, or log messages below the error level")
 	viper.BindPFlag("quiet", RootCmd.PersistentFlags().Lookup("quiet"))
 
+	RootCmd.PersistentFlags().Bool("verbose", false, "Log debug messages, including the HTTP and kubernetes API requests that the CLI makes. Same as --log_level=debug")
+	viper.BindPFlag("verbose", RootCmd.PersistentFlags().Lookup("verbose"))
+
+	RootCmd.PersistentFlags().String("log_level", "", "The level of the log messages to show: one of: panic|fatal|error|warn|info|debug|trace. trace also logs the headers of HTTP requests. Overrides --verbose and --quiet")
+	viper.BindPFlag("log_level", RootCmd.PersistentFlags().Lookup("log_level"))
+
+	RootCmd.PersistentFlags().String("proxy", "", "The SOCKS5 proxy that connections to Pixie Cloud, artifacts and analytics go through, such as socks5://bastion:1080. Defaults to the network.proxy setting, or else the HTTP_PROXY and HTTPS_PROXY env vars")
+	viper.BindPFlag("proxy", RootCmd.PersistentFlags().Lookup("proxy"))
+
+	RootCmd.PersistentFlags().String("cacert", "", "A PEM file of CA certificates to trust the servers of Pixie Cloud, artifacts and analytics with, in addition to the system's, such as the CA of a TLS-intercepting proxy. Defaults to the network.cacert setting")
+	viper.BindPFlag("cacert", RootCmd.PersistentFlags().Lookup("cacert"))
+
+	RootCmd.PersistentFlags().String("client_cert", "", "A PEM file of the certificate to authenticate to the servers of Pixie Cloud, artifacts and analytics with. Defaults to the network.client_cert setting")
+	viper.BindPFlag("client_cert", RootCmd.PersistentFlags().Lookup("client_cert"))
+
+	RootCmd.PersistentFlags().String("client_key", "", "A PEM file of the key of --client_cert. Defaults to the network.client_key setting")
+	viper.BindPFlag("client_key", RootCmd.PersistentFlags().Lookup("client_key"))
+
+	RootCmd.PersistentFlags().Bool("insecure_skip_tls_verify", false, "Don't verify the certificates of the servers of Pixie Cloud, artifacts and analytics. A last resort, since anyone on the network can then read and change the connections. Prefer --cacert")
+	viper.BindPFlag("insecure_skip_tls_verify", RootCmd.PersistentFlags().Lookup("insecure_skip_tls_verify"))
+
+	RootCmd.PersistentFlags().String("color", "auto", "Whether to color output: one of: auto|always|never. Colors are disabled by the NO_COLOR env var in auto mode.")
+	viper.BindPFlag("color", RootCmd.PersistentFlags().Lookup("color"))
+
+	RootCmd.PersistentFlags().Bool("no_truncate", false, "Don't truncate table cells to fit the terminal width")
+	viper.BindPFlag("no_truncate", RootCmd.PersistentFlags().Lookup("no_truncate"))
+
+	RootCmd.PersistentFlags().StringSlice("columns", []string{}, "The columns to output tables with, in order, for example: name,status. All columns are output by default.")
+	viper.BindPFlag("columns", RootCmd.PersistentFlags().Lookup("columns"))
+
+	RootCmd.PersistentFlags().String("sort_by", "", "The column to sort tables by. Prefix the column with - to sort in descending order, for example: -name")
+	viper.BindPFlag("sort_by", RootCmd.PersistentFlags().Lookup("sort_by"))
+
+	RootCmd.PersistentFlags().Bool("no_pager", false, "Don't show output that doesn't fit the terminal height in $PAGER")
+	viper.BindPFlag("no_pager", RootCmd.PersistentFlags().Lookup("no_pager"))
+
+	RootCmd.PersistentFlags().Bool("show_task_logs", false, "Show the output of all tasks once they complete, rather than only the output of tasks that fail")
+	viper.BindPFlag("show_task_logs", RootCmd.PersistentFlags().Lookup("show_task_logs"))
+
+	RootCmd.PersistentFlags().Bool("no_analytics", false, "Don't send usage analytics for this command, also set by the PX_NO_ANALYTICS or DO_NOT_TRACK env vars. Run px config set analytics.enabled=false to turn them off for all commands.")
+	viper.BindPFlag("no_analytics", RootCmd.PersistentFlags().Lookup("no_analytics"))
+
+	RootCmd.PersistentFlags().String("credentials_store", "", "Where to store credentials: keychain, for the OS keychain, or file, for plaintext files under ~/.config/pixie. Overrides the credentials.store setting.")
+	viper.BindPFlag("credentials_store", RootCmd.PersistentFlags().Lookup("credentials_store"))
+
+	RootCmd.PersistentFlags().Bool("analytics_debug", false, "Print each usage analytics event to stderr, along with whether it is sent. Combine with --no_analytics to see the events without sending them.")
+	viper.BindPFlag("analytics_debug", RootCmd.PersistentFlags().Lookup("analytics_debug"))
+
 	RootCmd.PersistentFlags().Bool("do_not_track", false, "do_not_track")
 	viper.BindPFlag("do_not_track", RootCmd.PersistentFlags().Lookup("do_not_track"))
 
+	RootCmd.PersistentFlags().String("context", "", "The kubeconfig context to use, instead of the kube.context setting or the current context of the kubeconfig")
+	viper.BindPFlag("context", RootCmd.PersistentFlags().Lookup("context"))
+
 	RootCmd.PersistentFlags().String("direct_vizier_addr", "", "If set, connect directly to the Vizier service at the given address.")
 	viper.BindPFlag("direct_vizier_addr", RootCmd.PersistentFlags().Lookup("direct_vizier_addr"))
 
@@ -62,11 +128,14 @@ func init() {
 	RootCmd.AddCommand(VersionCmd)
 	RootCmd.AddCommand(AuthCmd)
 	RootCmd.AddCommand(CollectLogsCmd)
+	RootCmd.AddCommand(CollectSupportBundleCmd)
 	RootCmd.AddCommand(CreateCloudCertsCmd)
 	RootCmd.AddCommand(DemoCmd)
 	RootCmd.AddCommand(DeployCmd)
 	RootCmd.AddCommand(DeleteCmd)
 	RootCmd.AddCommand(UpdateCmd)
+	// SelfUpdateCmd is added once the public key of the release signing key is checked in as
+	// update.ReleasePublicKey, since it can't verify any release until then.
 	RootCmd.AddCommand(RunCmd)
 	RootCmd.AddCommand(LiveCmd)
 	RootCmd.AddCommand(GetCmd)
@@ -75,6 +144,12 @@ func init() {
 	RootCmd.AddCommand(DeployKeyCmd)
 	RootCmd.AddCommand(APIKeyCmd)
 	RootCmd.AddCommand(DebugCmd)
+	RootCmd.AddCommand(ConfigCmd)
+	RootCmd.AddCommand(DoctorCmd)
+	RootCmd.AddCommand(PluginCmd)
+	RootCmd.AddCommand(StatsCmd)
+	RootCmd.AddCommand(AliasCmd)
+	RootCmd.AddCommand(AuditCmd)
 
 	RootCmd.PersistentFlags().MarkHidden("cloud_addr")
 	RootCmd.PersistentFlags().MarkHidden("dev_cloud_namespace")
@@ -100,11 +175,311 @@ func init() {
 	_ = RootCmd.ParseFlags(os.Args[1:])
 }
 
+// applyGlobalFlags configures the output and kubernetes client from the global flags. It must also be called by
+// commands that override the root PersistentPreRun, since cobra only runs the closest one.
+func applyGlobalFlags(cmd *cobra.Command, args []string) {
+	startCommandMetrics(cmd)
+	utils.SetAuditCommand(cmd.CommandPath())
+	applyEnvFlags(cmd)
+	redactAnalyticsIdentifiers(cmd, args)
+	applyLogLevel()
+	components.SetJSONErrors(isJSONOutput(outputFormat()))
+
+	if err := components.ConfigureColor(viper.GetString("color")); err != nil {
+		utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Invalid --color")
+	}
+
+	utils.SetShowTaskLogs(viper.GetBool("show_task_logs"))
+
+	applyConfigSettings()
+	applyNetworkSettings()
+	if err := components.SetPromptTimeout(viper.GetDuration("prompt_timeout"), viper.GetString("prompt_timeout_action")); err != nil {
+		utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Invalid --prompt_timeout or --prompt_timeout_action")
+	}
+
+	// The kube client flags are bound to viper, so they can also be set with PX_KUBE_* env vars.
+	err := k8s.SetClientOptions(&k8s.ClientOptions{
+		QPS:            float32(viper.GetFloat64("kube_qps")),
+		Burst:          viper.GetInt("kube_burst"),
+		RequestTimeout: viper.GetDuration("kube_request_timeout"),
+		WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
+			return utils.NewAuditTransport(utils.NewLoggingTransport(rt))
+		},
+	})
+	if err != nil {
+		utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Invalid kubernetes client options")
+	}
+
+	startUpdateNotice(cmd)
+}
+
+// promptForAnalyticsConsent asks the user whether px may send usage analytics, unless the command is one that people
+// run to check px, or to turn analytics off, before they would answer.
+func promptForAnalyticsConsent(cmd *cobra.Command) {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == VersionCmd || c == ConfigCmd {
+			return
+		}
+	}
+	pxanalytics.PromptForConsent()
+}
+
+// applyNetworkSettings configures the connections to Pixie Cloud, artifacts and analytics from the network flags, or
+// else the network settings.
+func applyNetworkSettings() {
+	cfg := pxconfig.Cfg().Network
+	flagOrSetting := func(flag, setting string) string {
+		if v := viper.GetString(flag); v != "" {
+			return v
+		}
+		return setting
+	}
+	opts := utils.HTTPOptions{
+		Proxy:                 flagOrSetting("proxy", cfg.Proxy),
+		CACert:                flagOrSetting("cacert", cfg.CACert),
+		ClientCert:            flagOrSetting("client_cert", cfg.ClientCert),
+		ClientKey:             flagOrSetting("client_key", cfg.ClientKey),
+		InsecureSkipTLSVerify: viper.GetBool("insecure_skip_tls_verify"),
+	}
+	if opts.InsecureSkipTLSVerify {
+		utils.Error("Warning: the certificates of Pixie Cloud, artifact and analytics servers aren't verified")
+	}
+	if err := utils.SetHTTPOptions(opts); err != nil {
+		utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Invalid network settings")
+	}
+	// Shared packages, such as the one that downloads the Vizier YAMLs, make their requests with the default client.
+	http.DefaultClient = utils.HTTPClient()
+}
+
+// applyLogLevel sets the level of the log messages of the console from --log_level, or else --verbose or --quiet.
+// Once the level of the console or the log file is debug or lower, the requests of the HTTP and kubernetes clients
+// are logged too.
+func applyLogLevel() {
+	level := log.InfoLevel
+	switch {
+	case viper.GetString("log_level") != "":
+		var err error
+		level, err = log.ParseLevel(viper.GetString("log_level"))
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Invalid --log_level")
+		}
+	case viper.GetBool("verbose"):
+		level = log.DebugLevel
+	case viper.GetBool("quiet"):
+		level = log.ErrorLevel
+	}
+	utils.SetConsoleLogLevel(level)
+	// The log file gets debug messages whatever the level of the console.
+	if log.IsLevelEnabled(log.DebugLevel) {
+		http.DefaultTransport = utils.NewLoggingTransport(http.DefaultTransport)
+	}
+}
+
+// applyEnvFlags sets the flags of the command that aren't given on the command line from their PX_ env vars, for
+// example --namespace from PX_NAMESPACE, so that the CLI can be configured entirely through the environment. Flags
+// that are bound to viper are also read from the env by viper, but other flags are only read from the flag set.
+func applyEnvFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed || f.Name == "help" {
+			return
+		}
+		env := flagEnvVar(f.Name)
+		value := os.Getenv(env)
+		if value == "" {
+			return
+		}
+		if err := flags.Set(f.Name, value); err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Usage).Fatalf("Invalid %s", env)
+		}
+	})
+}
+
+// flagEnvVar returns the env var that sets the flag with the given name.
+func flagEnvVar(name string) string {
+	return "PX_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyConfigSettings applies the settings of px config that provide the defaults of flags, including the overrides
+// for the cluster of the current kubeconfig context. Flags and their env vars take precedence over the settings.
+func applyConfigSettings() {
+	cfg := pxconfig.Cfg()
+	if context := viper.GetString("context"); context != "" {
+		k8s.SetContext(context)
+	} else {
+		k8s.SetContext(cfg.Kube.Context)
+	}
+	k8s.SetContextResolver(pickKubeContext)
+	if len(cfg.Clusters) > 0 {
+		if context, err := k8s.CurrentContext(); err == nil {
+			cfg = pxconfig.ClusterCfg(context)
+		}
+	}
+	if cfg.Demo.Artifacts != "" {
+		viper.SetDefault("artifacts", cfg.Demo.Artifacts)
+	}
+	if cfg.Demo.Channel != "" {
+		viper.SetDefault("channel", cfg.Demo.Channel)
+	}
+	if cfg.Deploy.Labels != "" {
+		viper.SetDefault("labels", cfg.Deploy.Labels)
+	}
+	if cfg.Prompts.Timeout != "" {
+		viper.SetDefault("prompt_timeout", cfg.Prompts.Timeout)
+	}
+	if cfg.Prompts.TimeoutAction != "" {
+		viper.SetDefault("prompt_timeout_action", cfg.Prompts.TimeoutAction)
+	}
+}
+
+// pickKubeContext lets the user pick the context when the kubeconfig files of KUBECONFIG set different current
+// contexts, and no context was selected with --context or the kube.context setting. With -y, the context of the first
+// file is used, like kubectl does.
+func pickKubeContext(candidates []k8s.ContextCandidate) (string, error) {
+	options := make([]string, len(candidates))
+	names := make(map[string]string, len(candidates))
+	for i, c := range candidates {
+		options[i] = fmt.Sprintf("%s (%s)", c.Name, c.File)
+		names[options[i]] = c.Name
+	}
+	picked, err := components.Select("The kubeconfig files of KUBECONFIG set different current contexts. Which one do you want to use?",
+		options, options[0])
+	if errors.Is(err, components.ErrNonInteractive) {
+		return "", exitcodes.Wrap(exitcodes.Usage, fmt.Errorf(
+			"the kubeconfig files of KUBECONFIG set different current contexts: %s. Pick one with --context or the kube.context setting",
+			strings.Join(options, ", ")))
+	}
+	if err != nil {
+		return "", err
+	}
+	name := names[picked]
+	utils.Infof("Using the %s context. Pass --context %s to skip this prompt.", name, name)
+	return name, nil
+}
+
+// outputFormat returns the format that commands write their results in: the global --output flag or its PX_OUTPUT env
+// var if either is given, or else the output.format setting. It is empty if neither is set, in which case each
+// command uses its own default, which is a table for most commands.
+func outputFormat() string {
+	if format := viper.GetString("output"); format != "" {
+		return strings.ToLower(format)
+	}
+	return pxconfig.Cfg().Output.Format
+}
+
+// isJSONOutput returns whether the output format is JSON, in which case failures are written as JSON objects too.
+func isJSONOutput(format string) bool {
+	return format == "json" || format == "json-array"
+}
+
+// redactAnalyticsIdentifiers redacts the arguments and the values of the string flags of the command from analytics
+// events, since they can be the names of clusters, namespaces or files.
+func redactAnalyticsIdentifiers(cmd *cobra.Command, args []string) {
+	pxanalytics.RedactIdentifiers(args...)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		switch v := f.Value.(type) {
+		case pflag.SliceValue:
+			pxanalytics.RedactIdentifiers(v.GetSlice()...)
+		default:
+			if f.Value.Type() == "string" {
+				pxanalytics.RedactIdentifiers(f.Value.String())
+			}
+		}
+	})
+}
+
+// trackKubernetesVersion looks up the version of the current cluster in the background, to include it in the
+// analytics events of commands that work with the cluster.
+func trackKubernetesVersion() {
+	if !pxanalytics.Enabled() && !viper.GetBool("analytics_debug") {
+		return
+	}
+	go func() {
+		config, err := k8s.LoadConfig()
+		if err != nil {
+			return
+		}
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+		if err != nil {
+			return
+		}
+		version, err := discoveryClient.ServerVersion()
+		if err != nil {
+			return
+		}
+		pxanalytics.SetKubernetesVersion(version.GitVersion)
+	}()
+}
+
+// ScrubbedArgs returns the command line for analytics, with the arguments and the values of flags replaced, since
+// they can be the names of clusters, namespaces or files:
+//
+//	px,demo,deploy,<arg>,--namespace,<value>
+func ScrubbedArgs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	c, _, err := RootCmd.Find(args[1:])
+	if err != nil {
+		c = RootCmd
+	}
+	scrubbed := strings.Fields(c.CommandPath())
+	flags := pflag.NewFlagSet(c.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(c.Flags())
+	flags.AddFlagSet(c.InheritedFlags())
+	inCmdPath := strings.Fields(c.CommandPath())[1:]
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			// The names of subcommands are already in the command path.
+			if len(inCmdPath) > 0 && arg == inCmdPath[0] {
+				inCmdPath = inCmdPath[1:]
+				continue
+			}
+			scrubbed = append(scrubbed, "<arg>")
+			continue
+		}
+		if arg == "--" {
+			// The arguments after -- are passed on as is, for example to scripts.
+			scrubbed = append(scrubbed, arg)
+			for range args[i+1:] {
+				scrubbed = append(scrubbed, "<arg>")
+			}
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var f *pflag.Flag
+		if strings.HasPrefix(arg, "--") {
+			f = flags.Lookup(name)
+		} else if len(name) == 1 {
+			f = flags.ShorthandLookup(name)
+		}
+		if hasValue {
+			scrubbed = append(scrubbed, strings.SplitN(arg, "=", 2)[0]+"=<value>")
+			continue
+		}
+		scrubbed = append(scrubbed, arg)
+		// Flags that aren't booleans take the next argument as their value.
+		if f != nil && f.NoOptDefVal == "" && i+1 < len(args) {
+			scrubbed = append(scrubbed, "<value>")
+			i++
+		}
+	}
+	return scrubbed
+}
+
+// secretEnvVar matches the names of env vars whose values are secrets, and aren't printed.
+var secretEnvVar = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD)`)
+
 func printEnvVars() {
 	envs := os.Environ()
 	var pxEnvs []string
 	for _, env := range envs {
 		if strings.HasPrefix(env, "PL_") || strings.HasPrefix(env, "PX_") {
+			// Env vars are printed in CI logs, since the CLI can be configured entirely through them.
+			if name, _, _ := strings.Cut(env, "="); secretEnvVar.MatchString(name) {
+				env = name + "=<hidden>"
+			}
 			pxEnvs = append(pxEnvs, env)
 		}
 	}
@@ -125,8 +500,26 @@ var RootCmd = &cobra.Command{
 	Use:   "px",
 	Short: "Pixie CLI",
 	// TODO(zasgar): Add description and update this.
-	Long: `The Pixie command line interface.`,
+	Long: `The Pixie command line interface.
+
+Every flag can also be set with a PX_ env var, for example --namespace with PX_NAMESPACE. Flags given on the command
+line take precedence over env vars, which take precedence over the settings of px config.
+
+px exits with one of the following codes, so that scripts can tell failures apart:
+  0    Success.
+  1    Any other error.
+  2    Invalid command, arguments or flags.
+  3    Invalid or unreadable config.
+  4    Network error, such as Pixie Cloud or the demo artifacts being unreachable.
+  5    The Kubernetes cluster rejected or failed a request.
+  6    Not logged in to Pixie Cloud.
+  130  Aborted with Ctrl+C or by declining a prompt.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Shell completions must not print anything else, and must be fast.
+		if isCompletionCmd(cmd) {
+			return
+		}
+
 		printEnvVars()
 
 		cloudAddr := viper.GetString("cloud_addr")
@@ -139,40 +532,23 @@ var RootCmd = &cobra.Command{
 			viper.Set("dev_cloud_namespace", "plc-dev")
 		}
 
+		applyGlobalFlags(cmd, args)
+		promptForAnalyticsConsent(cmd)
+
 		p := cmd
 
 		if p != nil {
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Exec CMD",
-				Properties: analytics.NewProperties().
-					Set("cmd", p.Name()),
-			})
+			pxanalytics.Track("Exec CMD", pxanalytics.NewProperties().
+				Set("cmd", p.Name()))
 		}
 
-		for p != nil && p != UpdateCmd {
+		for p != nil && p != UpdateCmd && p != SelfUpdateCmd {
 			p = p.Parent()
 		}
 
-		if p == UpdateCmd {
+		if p == UpdateCmd || p == SelfUpdateCmd {
 			return
 		}
-		versionStr := update.UpdatesAvailable(viper.GetString("cloud_addr"))
-		if versionStr != "" {
-			cmdName := "<NONE>"
-			if p != nil {
-				cmdName = p.Name()
-			}
-
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Update Available",
-				Properties: analytics.NewProperties().
-					Set("cmd", cmdName),
-			})
-			c := color.New(color.Bold, color.FgGreen)
-			_, _ = c.Fprintf(os.Stderr, "Update to version \"%s\" available. Run \"px update cli\" to update.\n", versionStr)
-		}
 
 		// If the command requires auth, check that the user is logged in before running the command. Most of these commands,
 		// such as `px deploy` run through most of the command before suddenly complaining partway through when we
@@ -189,12 +565,12 @@ func checkAuthForCmd(c *cobra.Command) {
 	if viper.GetString("direct_vizier_addr") != "" {
 		if viper.GetString("direct_vizier_key") == "" {
 			utils.Errorf("Failed to authenticate. `direct_vizier_key` must be provided using `PX_DIRECT_VIZIER_KEY`")
-			os.Exit(1)
+			exitcodes.ExitWith(exitcodes.Auth)
 		}
 		switch c {
 		case DeployCmd, UpdateCmd, GetCmd, DeployKeyCmd, APIKeyCmd:
 			utils.Errorf("These commands are unsupported in Direct Vizier mode.")
-			os.Exit(1)
+			exitcodes.ExitWith(exitcodes.Usage)
 		default:
 		}
 		return
@@ -205,19 +581,26 @@ func checkAuthForCmd(c *cobra.Command) {
 		authenticated := auth.IsAuthenticated(viper.GetString("cloud_addr"))
 		if !authenticated {
 			utils.Errorf("Failed to authenticate. Please retry `px auth login`.")
-			os.Exit(1)
+			exitcodes.ExitWith(exitcodes.Auth)
 		}
 	default:
 	}
 }
 
-// Execute is the main function for the Cobra CLI.
+// Execute is the main function for the Cobra CLI. Panics of the command are written to a crash report.
 func Execute() {
+	defer RecoverCrash()
+	runPluginForArgs(os.Args[1:])
+	if isJSONOutput(outputFormat()) {
+		// Invalid commands are reported by the JSON error below rather than by cobra.
+		components.SetJSONErrors(true)
+		RootCmd.SilenceErrors = true
+		RootCmd.SilenceUsage = true
+	}
 	if err := RootCmd.Execute(); err != nil {
-		_ = pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Exec Error",
-		})
-		utils.WithError(err).Fatal("Error executing command")
+		pxanalytics.Track("Exec Error", nil)
+		// The commands handle their own errors, so the errors returned here are invalid commands, arguments or flags.
+		utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Error executing command")
 	}
+	exitcodes.RunExitHooks(exitcodes.Success)
 }
@@ -24,7 +24,6 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/fatih/color"
 	"github.com/gofrs/uuid"
@@ -33,13 +32,13 @@ import (
 	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/cloud/api/ptproxy"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/pixie_cli/pkg/vizier"
 	"px.dev/pixie/src/utils/script"
 )
 
 func init() {
-	RunCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table|csv")
 	RunCmd.Flags().StringP("file", "f", "", "Script file, specify - for STDIN")
 	RunCmd.Flags().BoolP("list", "l", false, "List available scripts")
 	RunCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
@@ -115,11 +114,10 @@ func createNewCobraCommand() *cobra.Command {
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			cloudAddr := viper.GetString("cloud_addr")
-			format, _ := cmd.Flags().GetString("output")
+			format := outputFormat()
 			directVzAddr := viper.GetString("direct_vizier_addr")
 			directVzKey := viper.GetString("direct_vizier_key")
 
-			format = strings.ToLower(format)
 			if format == "live" {
 				LiveCmd.Run(cmd, args)
 				return
@@ -163,14 +161,14 @@ func createNewCobraCommand() *cobra.Command {
 					if err == flag.ErrHelp {
 						os.Exit(0)
 					}
-					utils.WithError(err).Fatal("Failed to parse script flags")
+					utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Failed to parse script flags")
 				}
 				err := execScript.UpdateFlags(fs)
 				if err != nil {
 					if errors.Is(err, script.ErrMissingRequiredArgument) {
 						utils.Errorf("Missing required argument, please look at help below on how to pass in required arguments\n")
 						cmd.Help()
-						os.Exit(1)
+						exitcodes.ExitWith(exitcodes.Usage)
 					}
 					utils.WithError(err).Fatal("Error parsing script flags")
 				}
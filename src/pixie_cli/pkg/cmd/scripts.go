@@ -34,7 +34,6 @@ func init() {
 	ScriptCmd.AddCommand(RunSubCmd)
 
 	ScriptCmd.PersistentFlags().StringP("bundle", "b", "", "Path/URL to bundle file")
-	ScriptListCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table")
 }
 
 // ScriptCmd is the "script" command.
@@ -52,12 +51,9 @@ var ScriptListCmd = &cobra.Command{
 	Use:     "list",
 	Short:   "List pre-registered pxl scripts",
 	Aliases: []string{"scripts"},
-	PreRun: func(cmd *cobra.Command, args []string) {
-		viper.BindPFlag("output_format", cmd.Flags().Lookup("output"))
-	},
 	Run: func(cmd *cobra.Command, args []string) {
 		br := mustCreateBundleReader()
-		listBundleScripts(br, viper.GetString("output_format"))
+		listBundleScripts(br, outputFormat())
 	},
 }
 
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/update"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	version "px.dev/pixie/src/shared/goversion"
+)
+
+// cliArtifactsURL is the default location of the CLI releases.
+const cliArtifactsURL = "https://storage.googleapis.com/pixie-prod-artifacts/cli"
+
+func init() {
+	SelfUpdateCmd.Flags().String("artifacts", cliArtifactsURL, "The location of the CLI releases, in the same formats as the --artifacts flag of px demo")
+	SelfUpdateCmd.Flags().String("channel", "stable", "The release channel of the CLI to update to (stable, beta, dev)")
+	SelfUpdateCmd.Flags().String("public_key", "", "Path to a PEM encoded ECDSA or RSA public key that the release must be signed with, instead of the Pixie release key")
+	SelfUpdateCmd.Flags().Bool("insecure_skip_signature", false, "Install the release without verifying its signature, only its checksum")
+	SelfUpdateCmd.Flags().Bool("check", false, "Only check whether a newer release is available, without installing it")
+	SelfUpdateCmd.Flags().Bool("force", false, "Install the release of the channel even if it isn't newer than the running CLI")
+}
+
+// SelfUpdateCmd replaces the running CLI with the latest release of a channel from the artifacts endpoint.
+var SelfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update the CLI to the latest release of a channel",
+	Long: `Update the CLI to the latest release of a channel.
+
+The release is described by the release.json file of the channel's artifacts, which lists the binary and its SHA256
+checksum and signature for each platform. The downloaded binary must match its checksum, and the checksum must be
+signed with the Pixie release key (or the key given with --public_key), before it replaces the running binary.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		artifacts, _ := cmd.Flags().GetString("artifacts")
+		channel, _ := cmd.Flags().GetString("channel")
+		channel = releaseChannel(channel)
+		check, _ := cmd.Flags().GetBool("check")
+		force, _ := cmd.Flags().GetBool("force")
+
+		verifyOpts := &update.VerifyOptions{}
+		verifyOpts.InsecureSkipSignature, _ = cmd.Flags().GetBool("insecure_skip_signature")
+		if keyPath, _ := cmd.Flags().GetString("public_key"); keyPath != "" {
+			var err error
+			verifyOpts.PublicKeyPEM, err = os.ReadFile(keyPath)
+			if err != nil {
+				utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Failed to read --public_key")
+			}
+		}
+
+		src, err := newArtifactSource(demo.ChannelArtifactsURL(artifacts, channel))
+		if err != nil {
+			utils.WithError(err).WithExitCode(exitcodes.Usage).Fatal("Invalid --artifacts")
+		}
+		release, err := fetchRelease(src)
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to fetch the %s release", channel)
+		}
+		releaseVersion, _ := release.Semver()
+
+		currVersion := version.GetVersion()
+		if !force && !currVersion.IsDev() && !currVersion.Semver().LT(releaseVersion) {
+			utils.Infof("px %s is the latest %s release", currVersion.Semver(), channel)
+			return
+		}
+		if check {
+			fmt.Printf("px %s is available on the %s channel. Run \"px self-update --channel %s\" to update.\n",
+				releaseVersion, channel, channel)
+			return
+		}
+
+		binary, err := release.Binary()
+		if err != nil {
+			utils.WithError(err).Fatal("Cannot update the CLI")
+		}
+		if verifyOpts.InsecureSkipSignature {
+			utils.WithColor(color.New(color.FgYellow)).Info("Warning: skipping the signature check of the release, only its checksum is verified")
+		} else if !binary.Signed() {
+			utils.Fatalf("%s is not signed. Use --insecure_skip_signature to install it anyway.", binary.File)
+		}
+		if ok, err := update.NewCLIUpdater("").IsUpdatable(); !ok || err != nil {
+			utils.Fatal("Cannot perform update, it's likely the file is not in a writable path.")
+		}
+
+		if !components.YNPrompt(fmt.Sprintf("Update px from %s to %s?", currVersion.Semver(), releaseVersion), true) {
+			utils.WithExitCode(exitcodes.Aborted).Fatal("Update cancelled.")
+		}
+
+		pxanalytics.Track("CLI Self Update Initiated", pxanalytics.NewProperties().
+			Set("channel", channel).
+			Set("version", releaseVersion.String()))
+
+		data, err := src.Fetch(binary.File)
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to download %s", binary.File)
+		}
+		if err := update.ApplyBinary(data, binary, verifyOpts); err != nil {
+			pxanalytics.Track("CLI Self Update Failed", pxanalytics.NewProperties().
+				Set("channel", channel).
+				Set("version", releaseVersion.String()))
+			utils.WithError(err).Fatal("Failed to apply update.")
+		}
+
+		pxanalytics.Track("CLI Self Update Complete", pxanalytics.NewProperties().
+			Set("channel", channel).
+			Set("version", releaseVersion.String()))
+		utils.Infof("Updated px to %s", releaseVersion)
+	},
+}
+
+// fetchRelease fetches the release of the channel that the artifact source is for.
+func fetchRelease(src demo.Source) (*update.Release, error) {
+	b, err := src.Fetch(update.ReleaseFile)
+	if err != nil {
+		return nil, err
+	}
+	return update.ParseRelease(b)
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func init() {
+	StatsCmd.Flags().Bool("tasks", false, "Show the durations of the tasks of the commands, rather than of the commands")
+	StatsCmd.Flags().String("command", "", "Only show the durations of the given command, for example: \"px demo deploy\"")
+	StatsCmd.Flags().Duration("since", 0, "Only show the durations of the commands run within the given duration, for example: 24h")
+	StatsCmd.Flags().Bool("clear", false, "Remove the recorded durations")
+}
+
+// StatsCmd is the "stats" command.
+var StatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show how long commands and their tasks took",
+	Long: `Show how long commands and their tasks took, from the durations recorded once metrics are enabled with:
+
+  px config set metrics.enabled true
+
+The durations of the last 1000 commands are kept locally. They are also sent with the usage analytics if
+metrics.analytics is set to true.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if clear, _ := cmd.Flags().GetBool("clear"); clear {
+			if err := utils.ClearMetrics(); err != nil {
+				utils.WithError(err).Fatal("Failed to remove the recorded durations")
+			}
+			utils.Info("Removed the recorded durations")
+			return
+		}
+
+		entries, err := utils.ReadMetrics()
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to read the recorded durations")
+		}
+		command, _ := cmd.Flags().GetString("command")
+		since, _ := cmd.Flags().GetDuration("since")
+		var filtered []*utils.CommandMetrics
+		for _, e := range entries {
+			if command != "" && e.Command != command {
+				continue
+			}
+			if since > 0 && time.Since(e.Time) > since {
+				continue
+			}
+			filtered = append(filtered, e)
+		}
+		if len(filtered) == 0 {
+			if !pxconfig.Cfg().Metrics.Enabled {
+				utils.Info("No durations recorded. Run \"px config set metrics.enabled true\" to record them.")
+			} else {
+				utils.Info("No durations recorded")
+			}
+			return
+		}
+
+		w := components.CreateStreamWriter(outputFormat(), os.Stdout)
+		defer w.Finish()
+		if tasks, _ := cmd.Flags().GetBool("tasks"); tasks {
+			w.SetHeader("task_stats", []string{"Command", "Task", "Runs", "Failed", "Median", "P90", "Max", "Total"})
+			for _, s := range taskStats(filtered) {
+				_ = w.Write(append([]interface{}{s.command, s.name}, s.columns()...))
+			}
+			return
+		}
+		w.SetHeader("stats", []string{"Command", "Runs", "Failed", "Median", "P90", "Max", "Total"})
+		for _, s := range commandStats(filtered) {
+			_ = w.Write(append([]interface{}{s.command}, s.columns()...))
+		}
+	},
+}
+
+// durationStats summarizes the durations of the runs of a command or a task.
+type durationStats struct {
+	command string
+	// name is the name of the task, which is empty for the stats of a command.
+	name      string
+	failed    int
+	durations []time.Duration
+}
+
+func (s *durationStats) add(d time.Duration, failed bool) {
+	s.durations = append(s.durations, d)
+	if failed {
+		s.failed++
+	}
+}
+
+func (s *durationStats) total() time.Duration {
+	var total time.Duration
+	for _, d := range s.durations {
+		total += d
+	}
+	return total
+}
+
+// percentile returns the duration that p percent of the runs took at most.
+func (s *durationStats) percentile(p int) time.Duration {
+	sorted := append([]time.Duration(nil), s.durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func (s *durationStats) columns() []interface{} {
+	return []interface{}{
+		len(s.durations),
+		s.failed,
+		formatStatsDuration(s.percentile(50)),
+		formatStatsDuration(s.percentile(90)),
+		formatStatsDuration(s.percentile(100)),
+		formatStatsDuration(s.total()),
+	}
+}
+
+// formatStatsDuration rounds the duration to a precision that is readable, but still tells fast commands apart.
+func formatStatsDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// sortStats sorts the stats by the total time spent in them, so that the commands or tasks that are worth optimizing
+// come first.
+func sortStats(stats map[string]*durationStats) []*durationStats {
+	sorted := make([]*durationStats, 0, len(stats))
+	for _, s := range stats {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].total() > sorted[j].total()
+	})
+	return sorted
+}
+
+func commandStats(entries []*utils.CommandMetrics) []*durationStats {
+	stats := make(map[string]*durationStats)
+	for _, e := range entries {
+		s, ok := stats[e.Command]
+		if !ok {
+			s = &durationStats{command: e.Command}
+			stats[e.Command] = s
+		}
+		s.add(e.Duration, e.ExitCode != exitcodes.Success)
+	}
+	return sortStats(stats)
+}
+
+// taskStats summarizes the durations of the tasks of the commands. Subtasks are named after their parent task.
+func taskStats(entries []*utils.CommandMetrics) []*durationStats {
+	stats := make(map[string]*durationStats)
+	for _, e := range entries {
+		for _, t := range e.Tasks {
+			name := t.Name
+			if t.Parent != "" {
+				name = t.Parent + " > " + t.Name
+			}
+			key := e.Command + "\x00" + name
+			s, ok := stats[key]
+			if !ok {
+				s = &durationStats{command: e.Command, name: name}
+				stats[key] = s
+			}
+			s.add(t.Duration, t.Failed)
+		}
+	}
+	return sortStats(stats)
+}
+
+var startMetricsOnce sync.Once
+
+// startCommandMetrics records how long the command and its tasks take, if the metrics.enabled setting is on. The
+// durations are recorded when the CLI exits.
+func startCommandMetrics(cmd *cobra.Command) {
+	if !pxconfig.Cfg().Metrics.Enabled {
+		return
+	}
+	startMetricsOnce.Do(func() {
+		utils.StartCommandMetrics(cmd.CommandPath())
+		exitcodes.OnExit(finishCommandMetrics)
+	})
+}
+
+func finishCommandMetrics(code int) {
+	m, err := utils.FinishCommandMetrics(code)
+	if err != nil {
+		log.WithError(err).Debug("Failed to record the durations of the command")
+	}
+	if m == nil || !pxconfig.Cfg().Metrics.Analytics {
+		return
+	}
+
+	// Subtasks are left out, to keep the event small.
+	var tasks []string
+	for _, t := range m.Tasks {
+		if t.Parent == "" {
+			tasks = append(tasks, fmt.Sprintf("%s: %dms", t.Name, t.Duration.Milliseconds()))
+		}
+	}
+	pxanalytics.Track("Exec Metrics", pxanalytics.NewProperties().
+		Set("cmd", m.Command).
+		Set("duration_ms", m.Duration.Milliseconds()).
+		Set("exit_code", code).
+		Set("tasks", strings.Join(tasks, "; ")))
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	version "px.dev/pixie/src/shared/goversion"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// supportBundleTimeout is how long collecting the state of a demo namespace may take.
+const supportBundleTimeout = 2 * time.Minute
+
+func init() {
+	CollectSupportBundleCmd.Flags().StringP("namespace", "n", "", "The namespace of a demo app to include the pods, events and logs of")
+	CollectSupportBundleCmd.Flags().StringP("file", "f", "", "The file to write the bundle to. Defaults to px_support_bundle_<time>.zip")
+	CollectSupportBundleCmd.Flags().Int64("tail", 1000, "The number of recent log lines to include for each container")
+}
+
+// CollectSupportBundleCmd collects the state of the CLI, and of a demo namespace, into an archive to attach to bug
+// reports.
+var CollectSupportBundleCmd = &cobra.Command{
+	Use:   "collect-support-bundle",
+	Short: "Collect the CLI's config, history and logs, and the state of a demo namespace, to attach to bug reports",
+	Long: `Collect the CLI's config, history and logs, and the state of a demo namespace, to attach to bug reports.
+
+The bundle contains the version of px, the settings that px config export would share, the recent commands with their
+arguments and flag values replaced, the debug log files and the checkpoints of failed demo deploys. With --namespace,
+it also contains the status of the pods, the events and the recent logs of the namespace.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		namespace, _ := cmd.Flags().GetString("namespace")
+		fName, _ := cmd.Flags().GetString("file")
+		tail, _ := cmd.Flags().GetInt64("tail")
+		if fName == "" {
+			fName = fmt.Sprintf("px_support_bundle_%s.zip", time.Now().Format("20060102150405"))
+		}
+
+		var buf bytes.Buffer
+		b := &supportBundle{w: zip.NewWriter(&buf)}
+		b.addCLIState()
+		if namespace != "" {
+			// The bundle is still useful without the namespace, for example when the kubeconfig is broken.
+			config, err := k8s.LoadConfig()
+			if err == nil {
+				var clientset kubernetes.Interface
+				clientset, err = kubernetes.NewForConfig(config)
+				if err == nil {
+					ctx, cancel := context.WithTimeout(context.Background(), supportBundleTimeout)
+					defer cancel()
+					b.addNamespace(ctx, clientset, namespace, tail)
+				}
+			}
+			if err != nil {
+				b.errorf("Failed to connect to the cluster: %v", err)
+			}
+		}
+		if len(b.errs) > 0 {
+			b.add("errors.txt", []byte(strings.Join(b.errs, "\n")+"\n"))
+		}
+		if err := b.w.Close(); err != nil {
+			utils.WithError(err).Fatal("Failed to create the support bundle")
+		}
+		if err := os.WriteFile(fName, buf.Bytes(), 0600); err != nil {
+			utils.WithError(err).Fatal("Failed to write the support bundle")
+		}
+
+		for _, e := range b.errs {
+			utils.Error(e)
+		}
+		utils.Infof("Support bundle written to %s", fName)
+	},
+}
+
+// supportBundle writes the files of a support bundle. Files that can't be collected are recorded in errs, so that
+// the rest of the bundle is still written.
+type supportBundle struct {
+	w    *zip.Writer
+	errs []string
+}
+
+func (b *supportBundle) add(name string, data []byte) {
+	f, err := b.w.Create(name)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err != nil {
+		b.errorf("Failed to add %s: %v", name, err)
+	}
+}
+
+func (b *supportBundle) addYAML(name string, v interface{}) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		b.errorf("Failed to add %s: %v", name, err)
+		return
+	}
+	b.add(name, data)
+}
+
+func (b *supportBundle) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
+
+// addCLIState adds the version, the shareable settings, the history, the log files and the checkpoints of the CLI.
+func (b *supportBundle) addCLIState() {
+	b.add("version.txt", []byte(fmt.Sprintf("%s\n%s/%s %s\n", version.GetVersion().ToString(), runtime.GOOS,
+		runtime.GOARCH, runtime.Version())))
+
+	// Only the settings that can be shared with a team are included, which leaves out secrets.
+	settings, err := json.MarshalIndent(pxconfig.Export(), "", "  ")
+	if err != nil {
+		b.errorf("Failed to add the settings: %v", err)
+	} else {
+		b.add("config.json", settings)
+	}
+
+	history, err := utils.ReadHistory()
+	if err != nil {
+		b.errorf("Failed to read the history: %v", err)
+	} else {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, e := range history {
+			_ = enc.Encode(e)
+		}
+		b.add("history.jsonl", buf.Bytes())
+	}
+
+	if logsDir, err := utils.EnsureDefaultLogsDirPath(); err != nil {
+		b.errorf("Failed to read the log files: %v", err)
+	} else {
+		logs, _ := filepath.Glob(filepath.Join(logsDir, "px.log*"))
+		for _, l := range logs {
+			data, err := os.ReadFile(l)
+			if err != nil {
+				b.errorf("Failed to read the log file %s: %v", l, err)
+				continue
+			}
+			b.add(path.Join("logs", filepath.Base(l)), data)
+		}
+	}
+
+	crashReports, err := utils.CrashReportPaths()
+	if err != nil {
+		b.errorf("Failed to read the crash reports: %v", err)
+	}
+	for _, c := range crashReports {
+		data, err := os.ReadFile(c)
+		if err != nil {
+			b.errorf("Failed to read the crash report %s: %v", c, err)
+			continue
+		}
+		b.add(path.Join("crashes", filepath.Base(c)), data)
+	}
+
+	dir, err := utils.EnsureDefaultCheckpointsDirPath()
+	if err != nil {
+		b.errorf("Failed to read the checkpoints: %v", err)
+		return
+	}
+	checkpoints, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, c := range checkpoints {
+		data, err := os.ReadFile(c)
+		if err != nil {
+			b.errorf("Failed to read the checkpoint %s: %v", c, err)
+			continue
+		}
+		b.add(path.Join("checkpoints", filepath.Base(c)), data)
+	}
+}
+
+// supportBundlePod is the status of a pod in the support bundle. The spec is left out, since the env vars of the
+// containers may hold secrets.
+type supportBundlePod struct {
+	Name   string       `json:"name"`
+	Node   string       `json:"node,omitempty"`
+	Status v1.PodStatus `json:"status"`
+}
+
+// addNamespace adds the status of the pods, the events and the recent logs of the namespace.
+func (b *supportBundle) addNamespace(ctx context.Context, clientset kubernetes.Interface, namespace string, tail int64) {
+	dir := path.Join("namespaces", namespace)
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		b.errorf("Failed to list the pods of %s: %v", namespace, err)
+	} else {
+		statuses := make([]supportBundlePod, len(pods.Items))
+		for i, p := range pods.Items {
+			statuses[i] = supportBundlePod{Name: p.Name, Node: p.Spec.NodeName, Status: p.Status}
+		}
+		b.addYAML(path.Join(dir, "pods.yaml"), statuses)
+	}
+
+	events, err := k8s.GetNamespaceEvents(ctx, clientset, namespace, &k8s.NamespaceEventsOptions{})
+	if err != nil {
+		b.errorf("Failed to get the events of %s: %v", namespace, err)
+	} else {
+		b.addYAML(path.Join(dir, "events.yaml"), events)
+	}
+
+	lines, err := k8s.StreamLogs(ctx, clientset, namespace, "", &k8s.StreamLogsOptions{TailLines: &tail})
+	if err != nil {
+		b.errorf("Failed to get the logs of %s: %v", namespace, err)
+		return
+	}
+	logs := make(map[string]*bytes.Buffer)
+	for l := range lines {
+		if l.Err != nil {
+			b.errorf("Failed to get the logs of %s/%s: %v", l.Pod, l.Container, l.Err)
+			continue
+		}
+		name := fmt.Sprintf("%s_%s.log", l.Pod, l.Container)
+		if _, ok := logs[name]; !ok {
+			logs[name] = &bytes.Buffer{}
+		}
+		logs[name].WriteString(l.Line + "\n")
+	}
+	names := make([]string, 0, len(logs))
+	for name := range logs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.add(path.Join(dir, "logs", name), logs[name].Bytes())
+	}
+}
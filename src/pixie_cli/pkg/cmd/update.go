@@ -26,15 +26,14 @@ import (
 
 	"github.com/blang/semver"
 	"github.com/gofrs/uuid"
-	"github.com/segmentio/analytics-go/v3"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
-	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/update"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/pixie_cli/pkg/vizier"
@@ -111,8 +110,7 @@ var VizierUpdateCmd = &cobra.Command{
 		utils.Infof("Updating Pixie on the following cluster: %s", clusterInfo.ClusterName)
 		clusterOk := components.YNPrompt("Is the cluster correct?", true)
 		if !clusterOk {
-			utils.Error("Cluster is not correct. Aborting.")
-			return
+			utils.WithExitCode(exitcodes.Aborted).Fatal("Cluster is not correct. Aborting.")
 		}
 
 		if len(versionString) == 0 {
@@ -133,14 +131,10 @@ var VizierUpdateCmd = &cobra.Command{
 			}
 		}
 
-		_ = pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Vizier Update Initiated",
-			Properties: analytics.NewProperties().
-				Set("cloud_addr", cloudAddr).
-				Set("cluster_id", utils2.UUIDFromProtoOrNil(clusterInfo.ID)).
-				Set("cluster_status", clusterInfo.Status.String()),
-		})
+		pxanalytics.Track("Vizier Update Initiated", pxanalytics.NewProperties().
+			Set("cloud_addr", cloudAddr).
+			Set("cluster_id", utils2.UUIDFromProtoOrNil(clusterInfo.ID)).
+			Set("cluster_status", clusterInfo.Status.String()))
 
 		utils.Infof("Updating to version: %s", versionString)
 
@@ -187,26 +181,18 @@ var VizierUpdateCmd = &cobra.Command{
 		err = uj.RunAndMonitor()
 
 		if err != nil {
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Vizier Update Failed",
-				Properties: analytics.NewProperties().
-					Set("cloud_addr", cloudAddr).
-					Set("cluster_id", clusterID),
-			})
+			pxanalytics.Track("Vizier Update Failed", pxanalytics.NewProperties().
+				Set("cloud_addr", cloudAddr).
+				Set("cluster_id", clusterID))
 
 			// Keep as log.Fatal which produces a Sentry error for this unexpected behavior
 			// (as opposed to user error which shouldn't be tracked in Sentry)
 			log.WithError(err).Fatal("Update failed")
 		}
 
-		_ = pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Vizier Update Complete",
-			Properties: analytics.NewProperties().
-				Set("cloud_addr", cloudAddr).
-				Set("cluster_id", clusterID),
-		})
+		pxanalytics.Track("Vizier Update Complete", pxanalytics.NewProperties().
+			Set("cloud_addr", cloudAddr).
+			Set("cluster_id", clusterID))
 	},
 }
 
@@ -248,8 +234,7 @@ var CLIUpdateCmd = &cobra.Command{
 			continueUpdate := components.YNPrompt(`Homebrew installation detected. Please use homebrew to update the cli.
 Update anyway?`, false)
 			if !continueUpdate {
-				utils.Error("Update cancelled.")
-				return
+				utils.WithExitCode(exitcodes.Aborted).Fatal("Update cancelled.")
 			}
 		}
 
@@ -258,8 +243,7 @@ Update anyway?`, false)
 We recommend rebuilding/updating the CLI using the same method as the initial install.
 Update anyway?`, false)
 			if !continueUpdate {
-				utils.Error("Update cancelled.")
-				return
+				utils.WithExitCode(exitcodes.Aborted).Fatal("Update cancelled.")
 			}
 		}
 
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	version "px.dev/pixie/src/shared/goversion"
+)
+
+const (
+	// updateCheckInterval is how often the latest release is checked for the update notice.
+	updateCheckInterval = 24 * time.Hour
+	// updateCheckWait is how long the CLI waits on exit for a check that is still running. Quick commands would
+	// otherwise exit before any check completes.
+	updateCheckWait = time.Second
+	// latestReleaseCacheFile is the file in the cache folder that the result of the last check is kept in.
+	latestReleaseCacheFile = "latest-release.json"
+)
+
+// latestReleaseCache is the result of the last check for the update notice.
+type latestReleaseCache struct {
+	Channel string `json:"channel"`
+	// Version is the latest release of the channel, which is empty if the check failed.
+	Version   string    `json:"version"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// startUpdateNotice shows a notice after the command if a newer release of the CLI is available. The latest release
+// is checked at most once per updateCheckInterval, in the background, and the result is cached in between. The
+// notice is only shown to people, when stderr is a terminal, and can be turned off with:
+//
+//	px config set update_notice.enabled false
+func startUpdateNotice(cmd *cobra.Command) {
+	cfg := pxconfig.Cfg().UpdateNotice
+	if cfg.Disabled || version.GetVersion().IsDev() || !components.IsTerminal(os.Stderr) {
+		return
+	}
+	// These commands check for newer releases themselves.
+	for p := cmd; p != nil; p = p.Parent() {
+		if p == VersionCmd || p == UpdateCmd || p == SelfUpdateCmd {
+			return
+		}
+	}
+	channel := releaseChannel(cfg.Channel)
+	cached := readLatestReleaseCache()
+	if cached != nil && cached.Channel == channel && time.Since(cached.CheckedAt) < updateCheckInterval {
+		exitcodes.OnExit(func(int) { printUpdateNotice(cached) })
+		return
+	}
+
+	// The options of the demo artifacts, such as their headers, are meant for the demo artifacts only.
+	src, err := demo.NewSource(demo.ChannelArtifactsURL(cliArtifactsURL, channel), &demo.SourceOptions{})
+	if err != nil {
+		log.WithError(err).Debug("Failed to check for a newer release")
+		return
+	}
+	checked := make(chan *latestReleaseCache, 1)
+	go func() {
+		latest := fetchLatestRelease(src, channel)
+		if latest.err != nil {
+			log.WithError(latest.err).Debug("Failed to check for a newer release")
+		}
+		// Failed checks are cached too, so that they aren't retried, and waited for, on every command.
+		entry := &latestReleaseCache{Channel: channel, Version: latest.version, CheckedAt: time.Now()}
+		writeLatestReleaseCache(entry)
+		checked <- entry
+	}()
+	exitcodes.OnExit(func(int) {
+		select {
+		case entry := <-checked:
+			printUpdateNotice(entry)
+		case <-time.After(updateCheckWait):
+			// The result of the previous check is still better than no notice.
+			if cached != nil && cached.Channel == channel {
+				printUpdateNotice(cached)
+			}
+		}
+	})
+}
+
+// printUpdateNotice prints the notice if the cached release is newer than the running CLI.
+func printUpdateNotice(entry *latestReleaseCache) {
+	latest, err := semver.Parse(entry.Version)
+	curr := version.GetVersion().Semver()
+	if err != nil || !curr.LT(latest) {
+		return
+	}
+	// The build metadata only tells builds of the same version apart.
+	curr.Build = nil
+	pxanalytics.Track("Update Available", pxanalytics.NewProperties().
+		Set("channel", entry.Channel).
+		Set("version", entry.Version))
+	c := color.New(color.Bold, color.FgGreen)
+	_, _ = c.Fprintf(os.Stderr, "A newer px is available: %s (you have %s). Run \"px update cli\" to update.\n",
+		entry.Version, curr)
+}
+
+func readLatestReleaseCache() *latestReleaseCache {
+	path, err := utils.EnsureDefaultCacheFilePath(latestReleaseCacheFile)
+	if err != nil {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	entry := &latestReleaseCache{}
+	if err := json.Unmarshal(b, entry); err != nil {
+		return nil
+	}
+	return entry
+}
+
+// writeLatestReleaseCache caches the result of the check. Failing to cache it isn't an error.
+func writeLatestReleaseCache(entry *latestReleaseCache) {
+	path, err := utils.EnsureDefaultCacheFilePath(latestReleaseCacheFile)
+	if err != nil {
+		return
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		log.WithError(err).Debug("Failed to cache the latest release")
+	}
+}
@@ -20,17 +20,155 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"runtime"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	version "px.dev/pixie/src/shared/goversion"
 )
 
+func init() {
+	VersionCmd.Flags().Bool("short", false, "Only print the version number")
+	VersionCmd.Flags().Bool("check_latest", false, "Also check whether a newer release of the CLI is published")
+	VersionCmd.Flags().String("channel", "stable", "The release channel to check for newer releases (stable, beta, dev)")
+	VersionCmd.Flags().String("artifacts", cliArtifactsURL, "The location of the CLI releases to check for newer releases")
+}
+
+// latestRelease is the result of checking the latest published release of a channel.
+type latestRelease struct {
+	channel string
+	version string
+	// newer is whether the release is newer than the running CLI.
+	newer bool
+	err   error
+}
+
 // VersionCmd is the "version" command.
 var VersionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of the cli",
+	Long: `Print the version number of the cli, and the metadata of its build.
+
+With --output, the version, the git revision, the build date, the Go version, the platform and the telemetry status are
+written in the given format, such as json. The telemetry status is "not compiled in" for builds with the notel tag,
+which leave out usage analytics and error reporting. With --check_latest, the latest published release of the channel
+is checked too.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("%s\n", version.GetVersion().ToString())
+		v := version.GetVersion()
+		if short, _ := cmd.Flags().GetBool("short"); short {
+			fmt.Printf("%s\n", v.Semver())
+			return
+		}
+
+		var latest *latestRelease
+		if checkLatest, _ := cmd.Flags().GetBool("check_latest"); checkLatest {
+			artifacts, _ := cmd.Flags().GetString("artifacts")
+			channel, _ := cmd.Flags().GetString("channel")
+			latest = checkLatestRelease(artifacts, releaseChannel(channel))
+		}
+
+		format := outputFormat()
+		if format == "" {
+			printVersion(v, latest)
+		} else {
+			writeVersion(format, v, latest)
+		}
+		if latest != nil && latest.err != nil {
+			exitcodes.Exit(latest.err)
+		}
 	},
 }
+
+func checkLatestRelease(artifacts, channel string) *latestRelease {
+	latest := &latestRelease{channel: channel}
+	src, err := newArtifactSource(demo.ChannelArtifactsURL(artifacts, channel))
+	if err != nil {
+		latest.err = exitcodes.Wrap(exitcodes.Usage, err)
+		return latest
+	}
+	return fetchLatestRelease(src, channel)
+}
+
+// fetchLatestRelease fetches the release of the channel that the artifact source is for, and compares it to the
+// running CLI.
+func fetchLatestRelease(src demo.Source, channel string) *latestRelease {
+	latest := &latestRelease{channel: channel}
+	release, err := fetchRelease(src)
+	if err != nil {
+		latest.err = err
+		return latest
+	}
+	releaseVersion, _ := release.Semver()
+	latest.version = releaseVersion.String()
+	curr := version.GetVersion()
+	latest.newer = !curr.IsDev() && curr.Semver().LT(releaseVersion)
+	return latest
+}
+
+func buildPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// printVersion prints the version and the build metadata for people. The first line is only the version, like the
+// output of earlier versions of the CLI.
+// telemetryStatus returns whether usage analytics and error reporting are compiled into the CLI, and if so, whether
+// usage analytics are sent.
+func telemetryStatus() string {
+	switch {
+	case !pxanalytics.Compiled:
+		return "not compiled in"
+	case pxanalytics.Enabled():
+		return "enabled"
+	default:
+		return "disabled"
+	}
+}
+
+func printVersion(v *version.Version, latest *latestRelease) {
+	fmt.Printf("%s\n", v.ToString())
+	fmt.Printf("  Revision:    %s (%s)\n", v.Revision(), v.RevisionStatus())
+	fmt.Printf("  Build date:  %s\n", v.BuildTime().UTC().Format(time.RFC3339))
+	fmt.Printf("  Built by:    %s\n", v.Builder())
+	fmt.Printf("  Go version:  %s\n", runtime.Version())
+	fmt.Printf("  Platform:    %s\n", buildPlatform())
+	fmt.Printf("  Telemetry:   %s\n", telemetryStatus())
+	if latest == nil {
+		return
+	}
+	switch {
+	case latest.err != nil:
+		utils.WithError(latest.err).Errorf("Failed to check the latest %s release", latest.channel)
+	case latest.newer:
+		fmt.Printf("px %s is available on the %s channel. Run \"px update cli\" to update.\n",
+			latest.version, latest.channel)
+	default:
+		fmt.Printf("px %s is the latest %s release.\n", latest.version, latest.channel)
+	}
+}
+
+// writeVersion writes the version and the build metadata in the given format, for tools and bug reports.
+func writeVersion(format string, v *version.Version, latest *latestRelease) {
+	header := []string{"Version", "Revision", "RevisionStatus", "BuildDate", "BuiltBy", "GoVersion", "Platform", "Telemetry"}
+	row := []interface{}{v.Semver().String(), v.Revision(), v.RevisionStatus(),
+		v.BuildTime().UTC().Format(time.RFC3339), v.Builder(), runtime.Version(), buildPlatform(), telemetryStatus()}
+	if latest != nil {
+		header = append(header, "Channel", "Latest", "UpdateAvailable")
+		row = append(row, latest.channel, latest.version, latest.newer)
+	}
+
+	w := components.CreateStreamWriter(format, os.Stdout)
+	w.SetHeader("version", header)
+	_ = w.Write(row)
+	w.Finish()
+	if latest != nil && latest.err != nil {
+		utils.WithError(latest.err).Errorf("Failed to check the latest %s release", latest.channel)
+	}
+}
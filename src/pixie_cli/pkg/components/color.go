@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+)
+
+// autoNoColor is whether color is disabled by default, because the output is plain or NO_COLOR is set.
+var autoNoColor = color.NoColor || !Interactive()
+
+// ConfigureColor sets whether output is colored. The mode is one of "auto", which colors output if stdout is a
+// terminal and the NO_COLOR environment variable is unset, "always" or "never".
+func ConfigureColor(mode string) error {
+	switch mode {
+	case "", "auto":
+		color.NoColor = autoNoColor
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	default:
+		return fmt.Errorf("invalid color mode %q, must be one of: auto|always|never", mode)
+	}
+	return nil
+}
+
+// Theme is the set of colors used for styled output.
+type Theme struct {
+	// OK colors values that indicate success, such as "Ready" or "created".
+	OK *color.Color
+	// Warn colors values that need attention, such as "Pending".
+	Warn *color.Color
+	// Error colors values that indicate failure, such as "failed".
+	Error *color.Color
+	// Header is the color of table headers.
+	Header tablewriter.Colors
+	// Footer is the color of table footers, such as totals.
+	Footer tablewriter.Colors
+}
+
+// DefaultTheme is the theme used unless another one is set with SetTheme.
+var DefaultTheme = &Theme{
+	OK:     color.New(color.FgGreen),
+	Warn:   color.New(color.FgYellow),
+	Error:  color.New(color.FgRed),
+	Header: tablewriter.Colors{tablewriter.Bold},
+	Footer: tablewriter.Colors{tablewriter.Bold},
+}
+
+var theme = DefaultTheme
+
+// SetTheme sets the theme used for styled output.
+func SetTheme(t *Theme) {
+	theme = t
+}
+
+// statusWords maps values that describe a status to whether they are OK, a warning or an error.
+var statusWords = map[string]statusKind{
+	"ok": statusKindOK, "ready": statusKindOK, "healthy": statusKindOK, "running": statusKindOK, "succeeded": statusKindOK,
+	"created": statusKindOK, "configured": statusKindOK, "unchanged": statusKindOK, "pass": statusKindOK,
+	"warn": statusKindWarn, "warning": statusKindWarn, "pending": statusKindWarn, "updating": statusKindWarn, "unknown": statusKindWarn,
+	"pruned": statusKindWarn, "deprecated": statusKindWarn,
+	"error": statusKindError, "failed": statusKindError, "unhealthy": statusKindError, "disconnected": statusKindError,
+	"crashloopbackoff": statusKindError, "fail": statusKindError,
+}
+
+// statusKind is whether a status value is OK, a warning or an error.
+type statusKind int
+
+const (
+	statusKindNone statusKind = iota
+	statusKindOK
+	statusKindWarn
+	statusKindError
+)
+
+// ColorizeStatus colors the value according to the status it describes, if it is one of the known status
+// values such as "OK", "Pending" or "failed". Other values are returned unchanged.
+func ColorizeStatus(s string) string {
+	if color.NoColor {
+		return s
+	}
+	var c *color.Color
+	switch statusWords[strings.ToLower(strings.TrimSpace(s))] {
+	case statusKindOK:
+		c = theme.OK
+	case statusKindWarn:
+		c = theme.Warn
+	case statusKindError:
+		c = theme.Error
+	default:
+		return s
+	}
+	return c.Sprint(s)
+}
+
+// headerColors returns the colors of the table header, or nil if output isn't colored.
+func headerColors(columns int) []tablewriter.Colors {
+	if color.NoColor || theme.Header == nil {
+		return nil
+	}
+	colors := make([]tablewriter.Colors, columns)
+	for i := range colors {
+		colors[i] = theme.Header
+	}
+	return colors
+}
+
+// footerColors returns the colors of the table footer, or nil if output isn't colored.
+func footerColors(columns int) []tablewriter.Colors {
+	if color.NoColor || theme.Footer == nil {
+		return nil
+	}
+	colors := make([]tablewriter.Colors, columns)
+	for i := range colors {
+		colors[i] = theme.Footer
+	}
+	return colors
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ColumnOptions selects, orders and sorts the columns of tabular output.
+type ColumnOptions struct {
+	// Columns are the names of the columns to output, in order. All columns are output if it's empty.
+	Columns []string
+	// SortBy is the name of the column to sort the rows by. The rows are sorted in descending order if the name is
+	// prefixed with "-". The rows are output in the order they were written if it's empty.
+	SortBy string
+}
+
+// columnOptionsFromConfig returns the column options set by the --columns and --sort_by flags.
+func columnOptionsFromConfig() ColumnOptions {
+	var columns []string
+	for _, c := range viper.GetStringSlice("columns") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return ColumnOptions{
+		Columns: columns,
+		SortBy:  strings.TrimSpace(viper.GetString("sort_by")),
+	}
+}
+
+func (o ColumnOptions) empty() bool {
+	return len(o.Columns) == 0 && o.SortBy == ""
+}
+
+// normalizeColumnName makes column names match regardless of case, and whether words are separated by spaces,
+// underscores or dashes.
+func normalizeColumnName(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// ColumnStreamWriter wraps an output writer to output a subset of the columns, and to sort the rows. Because
+// the rows can only be sorted once they have all been written, they are buffered until Finish is called.
+type ColumnStreamWriter struct {
+	w    OutputStreamWriter
+	opts ColumnOptions
+
+	headerValues []string
+	// indices are the indices of the output columns in the written rows.
+	indices []int
+	// sortIndex is the index of the column to sort by, or -1 if the rows aren't sorted.
+	sortIndex int
+	sortDesc  bool
+	data      [][]interface{}
+	footer    []interface{}
+}
+
+// NewColumnStreamWriter creates a writer that selects and sorts the columns of the rows written to it, before
+// writing them to w.
+func NewColumnStreamWriter(w OutputStreamWriter, opts ColumnOptions) *ColumnStreamWriter {
+	return &ColumnStreamWriter{
+		w:         w,
+		opts:      opts,
+		sortIndex: -1,
+		data:      make([][]interface{}, 0),
+	}
+}
+
+// SetHeader is called to set the key values for each of the data values. Must be called before Write is.
+// Column names that don't match any of the header values are ignored with a warning.
+func (c *ColumnStreamWriter) SetHeader(id string, headerValues []string) {
+	c.headerValues = headerValues
+	byName := make(map[string]int, len(headerValues))
+	for i, h := range headerValues {
+		byName[normalizeColumnName(h)] = i
+	}
+
+	var unknown []string
+	c.indices = nil
+	for _, col := range c.opts.Columns {
+		i, ok := byName[normalizeColumnName(col)]
+		if !ok {
+			unknown = append(unknown, col)
+			continue
+		}
+		c.indices = append(c.indices, i)
+	}
+	if len(c.indices) == 0 {
+		c.indices = make([]int, len(headerValues))
+		for i := range headerValues {
+			c.indices[i] = i
+		}
+	}
+
+	c.sortIndex = -1
+	if c.opts.SortBy != "" {
+		sortBy := c.opts.SortBy
+		c.sortDesc = strings.HasPrefix(sortBy, "-")
+		sortBy = strings.TrimPrefix(sortBy, "-")
+		if i, ok := byName[normalizeColumnName(sortBy)]; ok {
+			c.sortIndex = i
+		} else {
+			unknown = append(unknown, sortBy)
+		}
+	}
+	if len(unknown) > 0 {
+		fmt.Fprintf(os.Stderr, "Ignoring unknown columns %s, the columns of %s are: %s\n",
+			strings.Join(unknown, ", "), id, strings.Join(headerValues, ", "))
+	}
+
+	header := make([]string, len(c.indices))
+	for i, idx := range c.indices {
+		header[i] = headerValues[idx]
+	}
+	c.w.SetHeader(id, header)
+}
+
+// SetWideColumns marks the columns with the given names as only shown in wide output. The columns are always shown
+// if they are explicitly selected by the column options.
+func (c *ColumnStreamWriter) SetWideColumns(columns ...string) {
+	if len(c.opts.Columns) == 0 {
+		SetWideColumns(c.w, columns...)
+	}
+}
+
+// SetFooter sets the footer, which is written to the wrapped writer with the selected columns.
+func (c *ColumnStreamWriter) SetFooter(values []interface{}) error {
+	if len(values) != len(c.headerValues) {
+		return errors.New("header/footer length mismatch")
+	}
+	c.footer = values
+	return nil
+}
+
+// Write is called for each record of data.
+func (c *ColumnStreamWriter) Write(data []interface{}) error {
+	if len(data) != len(c.headerValues) {
+		return errors.New("header/data length mismatch")
+	}
+	c.data = append(c.data, data)
+	return nil
+}
+
+// Finish sorts the rows and writes the selected columns to the wrapped writer.
+func (c *ColumnStreamWriter) Finish() {
+	if c.sortIndex >= 0 {
+		sort.SliceStable(c.data, func(i, j int) bool {
+			a, b := c.data[i][c.sortIndex], c.data[j][c.sortIndex]
+			if c.sortDesc {
+				return lessValue(b, a)
+			}
+			return lessValue(a, b)
+		})
+	}
+	for _, row := range c.data {
+		_ = c.w.Write(c.project(row))
+	}
+	if c.footer != nil {
+		_ = SetFooter(c.w, c.project(c.footer)...)
+	}
+	c.w.Finish()
+}
+
+// project returns the selected columns of the row.
+func (c *ColumnStreamWriter) project(row []interface{}) []interface{} {
+	out := make([]interface{}, len(c.indices))
+	for i, idx := range c.indices {
+		out[i] = row[idx]
+	}
+	return out
+}
+
+// lessValue compares two values of a column. Numbers and times are compared by value, and other values by their
+// string representation.
+func lessValue(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return fa < fb
+		}
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Before(tb)
+		}
+	}
+	if da, ok := a.(time.Duration); ok {
+		if db, ok := b.(time.Duration); ok {
+			return da < db
+		}
+	}
+	return stringifyValue(a) < stringifyValue(b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
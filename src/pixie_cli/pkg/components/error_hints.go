@@ -0,0 +1,224 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+)
+
+// ErrorHint explains the probable cause of an error, and how the user can fix it.
+type ErrorHint struct {
+	// Code is the stable code that identifies the error in JSON output, such as namespace_exists. If it's empty,
+	// the code of the error's class is used, see exitcodes.ErrorCode.
+	Code string
+	// Cause is the probable cause of the error.
+	Cause string
+	// NextSteps are the suggested steps to fix the error, for example "Run px demo delete px-sock-shop".
+	NextSteps []string
+}
+
+// ErrorHinter returns the hint for the error, or nil if it doesn't know the error.
+type ErrorHinter func(err error) *ErrorHint
+
+var (
+	errorHintersMu sync.RWMutex
+	errorHinters   []ErrorHinter
+	// logFilePath is the log file of the run, which rendered errors refer to.
+	logFilePath string
+	// jsonErrors is whether errors are rendered as JSON objects, for automation.
+	jsonErrors bool
+)
+
+// SetJSONErrors sets whether errors are rendered as JSON objects rather than text, which is the case when the
+// output format is JSON.
+func SetJSONErrors(enabled bool) {
+	errorHintersMu.Lock()
+	defer errorHintersMu.Unlock()
+	jsonErrors = enabled
+}
+
+// JSONErrors returns whether errors are rendered as JSON objects.
+func JSONErrors() bool {
+	errorHintersMu.RLock()
+	defer errorHintersMu.RUnlock()
+	return jsonErrors
+}
+
+// SetLogFilePath sets the log file of the run, which rendered errors refer to.
+func SetLogFilePath(path string) {
+	errorHintersMu.Lock()
+	defer errorHintersMu.Unlock()
+	logFilePath = path
+}
+
+// RegisterErrorHinter registers a function that returns hints for errors. Hinters are tried in the order that
+// they were registered.
+func RegisterErrorHinter(h ErrorHinter) {
+	errorHintersMu.Lock()
+	defer errorHintersMu.Unlock()
+	errorHinters = append(errorHinters, h)
+}
+
+// RegisterErrorHint registers the hint for errors that match the target error with errors.Is.
+func RegisterErrorHint(target error, hint *ErrorHint) {
+	RegisterErrorHinter(func(err error) *ErrorHint {
+		if errors.Is(err, target) {
+			return hint
+		}
+		return nil
+	})
+}
+
+// HintForError returns the first registered hint for the error, or nil if there is none.
+func HintForError(err error) *ErrorHint {
+	if err == nil {
+		return nil
+	}
+	errorHintersMu.RLock()
+	defer errorHintersMu.RUnlock()
+	for _, h := range errorHinters {
+		if hint := h(err); hint != nil {
+			return hint
+		}
+	}
+	return nil
+}
+
+// RenderError writes a concise error message, followed by the probable cause and the suggested next steps from
+// the given hint, or the registered hint for the error if hint is nil:
+//
+//	Error: Failed to deploy demo app: namespace px-sock-shop already exists
+//	Cause: The demo app is already deployed, or another app uses its namespace.
+//	Next steps:
+//	  - Run px demo delete px-sock-shop to remove it.
+//
+// If errors are rendered as JSON, it writes a JSON object instead, see RenderErrorJSON.
+func RenderError(w io.Writer, msg string, err error, hint *ErrorHint) {
+	if hint == nil {
+		hint = HintForError(err)
+	}
+	if JSONErrors() {
+		RenderErrorJSON(w, msg, err, hint, exitCodeForError(err))
+		return
+	}
+	label := color.New(color.FgRed, color.Bold)
+	text := msg
+	if err != nil {
+		text = fmt.Sprintf("%s: %s", msg, err.Error())
+	}
+	fmt.Fprintf(w, "%s %s\n", label.Sprint("Error:"), text)
+	defer func() {
+		errorHintersMu.RLock()
+		defer errorHintersMu.RUnlock()
+		if logFilePath != "" {
+			fmt.Fprintf(w, "%s %s\n", color.New(color.Bold).Sprint("Log:"), logFilePath)
+		}
+	}()
+	if hint == nil {
+		return
+	}
+	if hint.Cause != "" {
+		fmt.Fprintf(w, "%s %s\n", color.New(color.Bold).Sprint("Cause:"), hint.Cause)
+	}
+	if len(hint.NextSteps) > 0 {
+		fmt.Fprintln(w, color.New(color.Bold).Sprint("Next steps:"))
+		for _, step := range hint.NextSteps {
+			fmt.Fprintf(w, "  - %s\n", strings.TrimSpace(step))
+		}
+	}
+}
+
+// jsonError is the JSON object that errors are rendered as.
+type jsonError struct {
+	Code        string   `json:"code"`
+	Message     string   `json:"message"`
+	Cause       string   `json:"cause,omitempty"`
+	Remediation []string `json:"remediation,omitempty"`
+	ExitCode    int      `json:"exit_code"`
+	LogFile     string   `json:"log_file,omitempty"`
+}
+
+// RenderErrorJSON writes the error as a single-line JSON object, for a failure that exits with the given code:
+//
+//	{"error":{"code":"namespace_exists","message":"Failed to deploy demo app: ...","cause":"...","remediation":["..."],"exit_code":1}}
+//
+// The code is the hint's code, or else the code of the error's class. Like RenderError, it uses the registered hint
+// for the error if hint is nil.
+func RenderErrorJSON(w io.Writer, msg string, err error, hint *ErrorHint, exitCode int) {
+	if hint == nil {
+		hint = HintForError(err)
+	}
+	e := &jsonError{
+		Code:     exitcodes.ErrorCode(err, exitCode),
+		Message:  msg,
+		ExitCode: exitCode,
+	}
+	if err != nil {
+		e.Message = fmt.Sprintf("%s: %s", msg, err.Error())
+	}
+	if hint != nil {
+		if hint.Code != "" {
+			e.Code = hint.Code
+		}
+		e.Cause = hint.Cause
+		for _, step := range hint.NextSteps {
+			e.Remediation = append(e.Remediation, strings.TrimSpace(step))
+		}
+	}
+	errorHintersMu.RLock()
+	e.LogFile = logFilePath
+	errorHintersMu.RUnlock()
+
+	b, jsonErr := json.Marshal(map[string]*jsonError{"error": e})
+	if jsonErr != nil {
+		fmt.Fprintln(w, e.Message)
+		return
+	}
+	fmt.Fprintln(w, string(b))
+}
+
+// PrintError renders the error with its hint to stderr.
+func PrintError(msg string, err error) {
+	RenderError(os.Stderr, msg, err, nil)
+}
+
+// exitCodeForError returns the code that a failure with the error exits with, which is exitcodes.Error without one.
+func exitCodeForError(err error) int {
+	if err == nil {
+		return exitcodes.Error
+	}
+	return exitcodes.ForError(err)
+}
+
+// FatalError renders the error with its hint to stderr, and exits with the exit code for the error. Errors that are
+// unexpected should be logged with log.Fatal instead, so that they are tracked in Sentry.
+func FatalError(msg string, err error) {
+	PrintError(msg, err)
+	exitcodes.ExitWith(exitCodeForError(err))
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// hyperlinkTermPrograms are the values of $TERM_PROGRAM of terminals that support OSC 8 hyperlinks.
+var hyperlinkTermPrograms = map[string]bool{
+	"iTerm.app": true,
+	"WezTerm":   true,
+	"vscode":    true,
+	"Hyper":     true,
+	"ghostty":   true,
+}
+
+// HyperlinksSupported returns whether the terminal supports clickable OSC 8 hyperlinks. Support can be forced on or
+// off by setting the FORCE_HYPERLINK environment variable to 1 or 0.
+func HyperlinksSupported() bool {
+	if force, ok := os.LookupEnv("FORCE_HYPERLINK"); ok {
+		enabled, err := strconv.ParseBool(force)
+		return err == nil && enabled
+	}
+	if !Interactive() {
+		return false
+	}
+	if hyperlinkTermPrograms[os.Getenv("TERM_PROGRAM")] {
+		return true
+	}
+	// VTE based terminals, such as GNOME Terminal, support hyperlinks since VTE 0.50.
+	if vte, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true
+	}
+	for _, env := range []string{"WT_SESSION", "KITTY_WINDOW_ID", "KONSOLE_VERSION", "DOMTERM"} {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+	return strings.Contains(os.Getenv("TERM"), "kitty") || strings.Contains(os.Getenv("TERM"), "alacritty")
+}
+
+// Hyperlink returns text that links to the URL. If the terminal supports OSC 8 hyperlinks, the text is clickable.
+// Otherwise, the URL is shown after the text, unless the text is the URL.
+func Hyperlink(url, text string) string {
+	if HyperlinksSupported() {
+		return fmt.Sprintf("\x1b]8;;%s\x1b\\%s\x1b]8;;\x1b\\", url, text)
+	}
+	if text == url || text == "" {
+		return url
+	}
+	return fmt.Sprintf("%s (%s)", text, url)
+}
+
+// URL returns the URL as a clickable hyperlink, if the terminal supports it.
+func URL(url string) string {
+	return Hyperlink(url, url)
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// LiveTable repaints a table in place on an interval, like `watch`. When the output isn't a terminal, the table
+// is printed again whenever its contents change instead.
+type LiveTable struct {
+	w        io.Writer
+	interval time.Duration
+	wide     bool
+	// ignored are the columns whose changes alone don't print the table again when the output isn't a terminal.
+	ignored map[string]bool
+
+	// lines is the number of lines of the last repaint, which are cleared by the next one.
+	lines int
+	// last is the content of the last printed table, without the ignored columns.
+	last    string
+	printed bool
+}
+
+// NewLiveTable creates a live table that is written to w and refreshed on the given interval.
+func NewLiveTable(w io.Writer, interval time.Duration) *LiveTable {
+	return &LiveTable{w: w, interval: interval}
+}
+
+// Wide makes the table show the columns that are only shown in wide output.
+func (l *LiveTable) Wide() *LiveTable {
+	l.wide = true
+	return l
+}
+
+// IgnoreChangesTo makes the table not be printed again when only the given columns changed, such as ages that change
+// on every refresh, when the output isn't a terminal. Repaints of a terminal always show the current values.
+func (l *LiveTable) IgnoreChangesTo(columns ...string) *LiveTable {
+	if l.ignored == nil {
+		l.ignored = make(map[string]bool)
+	}
+	for _, c := range columns {
+		l.ignored[c] = true
+	}
+	return l
+}
+
+// Run refreshes the table until the context is canceled, refresh returns an error, or refresh returns true to
+// indicate that the table won't change anymore. refresh sets the header of the table and writes its rows.
+func (l *LiveTable) Run(ctx context.Context, refresh func(w OutputStreamWriter) (bool, error)) error {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		done, err := l.refresh(refresh)
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *LiveTable) refresh(refresh func(w OutputStreamWriter) (bool, error)) (bool, error) {
+	var buf bytes.Buffer
+	t := NewTableStreamWriter(&buf)
+	t.hideID = true
+	t.wide = l.wide
+	f, isFile := l.w.(*os.File)
+	live := isFile && Interactive() && IsTerminal(f)
+	if live {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil {
+			// Wrapped lines would be miscounted when clearing the previous repaint.
+			t.width = width
+		}
+	}
+	var w OutputStreamWriter = t
+	if opts := columnOptionsFromConfig(); !opts.empty() {
+		w = NewColumnStreamWriter(t, opts)
+	}
+	done, err := refresh(w)
+	if err != nil {
+		return false, err
+	}
+	w.Finish()
+
+	if !live {
+		content := l.content(t)
+		if !l.printed || content != l.last {
+			if l.printed {
+				fmt.Fprintln(l.w)
+			}
+			_, _ = l.w.Write(buf.Bytes())
+			l.last = content
+			l.printed = true
+		}
+		return done, nil
+	}
+
+	if l.lines > 0 {
+		// Move the cursor to the start of the previous repaint, and clear it.
+		fmt.Fprintf(l.w, "\x1b[%dA\r\x1b[J", l.lines)
+	}
+	status := fmt.Sprintf("Every %s, last refreshed at %s. Press Ctrl+C to stop.", l.interval, time.Now().Format("15:04:05"))
+	if done {
+		status = fmt.Sprintf("Refreshed at %s.", time.Now().Format("15:04:05"))
+	}
+	fmt.Fprintln(l.w, color.New(color.Faint).Sprint(status))
+	_, _ = l.w.Write(buf.Bytes())
+	l.lines = bytes.Count(buf.Bytes(), []byte("\n")) + 1
+	return done, nil
+}
+
+// content returns the visible header, rows and footer of the table without the ignored columns, to tell whether the
+// table changed.
+func (l *LiveTable) content(t *TableStreamWriter) string {
+	header, data, footer := t.visibleColumns()
+	var sb strings.Builder
+	writeRow := func(row []interface{}) {
+		for i, v := range row {
+			if i < len(header) && l.ignored[header[i]] {
+				continue
+			}
+			fmt.Fprintf(&sb, "%s\t", stringifyValue(v))
+		}
+		sb.WriteString("\n")
+	}
+	for _, h := range header {
+		if !l.ignored[h] {
+			fmt.Fprintf(&sb, "%s\t", h)
+		}
+	}
+	sb.WriteString("\n")
+	for _, row := range data {
+		writeRow(row)
+	}
+	if footer != nil {
+		writeRow(footer)
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// mdRuleWidth is the width of horizontal rules.
+	mdRuleWidth = 40
+	// mdCodeIndent is the indentation of code blocks.
+	mdCodeIndent = "    "
+)
+
+var (
+	mdHeadingRe     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdBulletRe      = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdOrderedRe     = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	mdRuleRe        = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdLinkRe        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdAutoLinkRe    = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	mdBoldRe        = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalicRe      = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	mdCodeFenceRe   = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeadingColor  = color.New(color.Bold, color.FgCyan)
+	mdCodeColor     = color.New(color.FgYellow)
+	mdLinkColor     = color.New(color.Underline, color.FgBlue)
+	mdBoldColor     = color.New(color.Bold)
+	mdItalicColor   = color.New(color.Italic)
+	mdQuoteColor    = color.New(color.Faint)
+	mdBulletSymbols = []string{"•", "◦", "▪"}
+)
+
+// RenderMarkdown formats markdown text for the terminal: headings, emphasis, lists, block quotes, code blocks and
+// links. Styles are only applied if output is colored, but the structure, such as the indentation of code blocks
+// and the URLs of links, is always kept. Links are clickable if the terminal supports hyperlinks.
+func RenderMarkdown(md string) string {
+	var out []string
+	inCode := false
+	for _, line := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		if mdCodeFenceRe.MatchString(line) {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out = append(out, mdCodeIndent+mdCodeColor.Sprint(line))
+			continue
+		}
+		out = append(out, renderMarkdownLine(line))
+	}
+	return strings.Join(out, "\n")
+}
+
+func renderMarkdownLine(line string) string {
+	if m := mdHeadingRe.FindStringSubmatch(line); m != nil {
+		return mdHeadingColor.Sprint(renderMarkdownInline(m[2]))
+	}
+	if mdRuleRe.MatchString(line) {
+		return strings.Repeat("─", mdRuleWidth)
+	}
+	if m := mdBulletRe.FindStringSubmatch(line); m != nil {
+		depth := len(m[1]) / 2
+		symbol := mdBulletSymbols[depth%len(mdBulletSymbols)]
+		return "  " + m[1] + symbol + " " + renderMarkdownInline(m[2])
+	}
+	if m := mdOrderedRe.FindStringSubmatch(line); m != nil {
+		return "  " + m[1] + m[2] + ". " + renderMarkdownInline(m[3])
+	}
+	if trimmed := strings.TrimLeft(line, " "); strings.HasPrefix(trimmed, ">") {
+		quote := strings.TrimPrefix(strings.TrimPrefix(trimmed, ">"), " ")
+		return mdQuoteColor.Sprint("│ ") + renderMarkdownInline(quote)
+	}
+	if strings.HasPrefix(line, mdCodeIndent) || strings.HasPrefix(line, "\t") {
+		return mdCodeIndent + mdCodeColor.Sprint(strings.TrimLeft(line, " \t"))
+	}
+	return renderMarkdownInline(line)
+}
+
+// renderMarkdownInline formats code spans, links and emphasis. Code spans are left as is, apart from their color.
+func renderMarkdownInline(s string) string {
+	parts := strings.Split(s, "`")
+	var sb strings.Builder
+	for i, part := range parts {
+		// Text between an odd number of backticks is code, unless the last backtick isn't closed.
+		if i%2 == 1 && i < len(parts)-1 {
+			sb.WriteString(mdCodeColor.Sprint(part))
+			continue
+		}
+		if i%2 == 1 {
+			sb.WriteString("`")
+		}
+		sb.WriteString(renderMarkdownText(part))
+	}
+	return sb.String()
+}
+
+func renderMarkdownText(s string) string {
+	s = mdLinkRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdLinkRe.FindStringSubmatch(m)
+		text, url := sub[1], sub[2]
+		if HyperlinksSupported() {
+			return mdLinkColor.Sprint(Hyperlink(url, text))
+		}
+		if text == url {
+			return mdLinkColor.Sprint(url)
+		}
+		return text + " (" + mdLinkColor.Sprint(url) + ")"
+	})
+	s = mdAutoLinkRe.ReplaceAllStringFunc(s, func(m string) string {
+		return mdLinkColor.Sprint(URL(mdAutoLinkRe.FindStringSubmatch(m)[1]))
+	})
+	s = mdBoldRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdBoldRe.FindStringSubmatch(m)
+		return mdBoldColor.Sprint(sub[1] + sub[2])
+	})
+	s = mdItalicRe.ReplaceAllStringFunc(s, func(m string) string {
+		return mdItalicColor.Sprint(mdItalicRe.FindStringSubmatch(m)[1])
+	})
+	return s
+}
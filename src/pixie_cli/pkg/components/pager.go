@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// defaultPager is the pager used when $PAGER is unset.
+const defaultPager = "less"
+
+// defaultLessFlags makes less pass colors through, and leave the output on the screen when it exits.
+const defaultLessFlags = "FRX"
+
+// pagerCommand returns the command line of the pager, or nil if paging is disabled through an empty $PAGER.
+func pagerCommand() []string {
+	pager, ok := os.LookupEnv("PAGER")
+	if !ok {
+		pager = defaultPager
+	}
+	return strings.Fields(pager)
+}
+
+// shouldPage returns whether the output is taller than the terminal that it's written to.
+func shouldPage(w io.Writer, b []byte) bool {
+	if viper.GetBool("no_pager") || !Interactive() || !IsTerminal(w) {
+		return false
+	}
+	_, height, err := term.GetSize(int(w.(*os.File).Fd()))
+	if err != nil || height <= 0 {
+		return false
+	}
+	return bytes.Count(b, []byte("\n")) >= height
+}
+
+// WritePaged writes the output to w. If w is a terminal and the output doesn't fit on the screen, the output is
+// piped through $PAGER (less by default) instead, unless --no_pager is set.
+func WritePaged(w io.Writer, b []byte) error {
+	args := pagerCommand()
+	if len(args) == 0 || !shouldPage(w, b) {
+		_, err := w.Write(b)
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS="+defaultLessFlags)
+	}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The pager ran, but the user may have quit it early, so don't write the output again.
+			return nil
+		}
+		_, err := w.Write(b)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/vbauerster/mpb/v4"
+	"github.com/vbauerster/mpb/v4/decor"
+)
+
+// progressBarWidth is the width of the bar drawn by a ProgressBar.
+const progressBarWidth = 40
+
+// plainProgressStep is the percentage between the log lines a ProgressBar prints when it isn't attached to a TTY.
+const plainProgressStep = 25
+
+// plainOutput returns the writer for the plain log lines printed instead of animations, or nil if the output is
+// silenced by --quiet.
+func plainOutput() io.Writer {
+	if viper.GetBool("quiet") {
+		return nil
+	}
+	return os.Stderr
+}
+
+func printPlain(format string, args ...interface{}) {
+	if w := plainOutput(); w != nil {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+}
+
+func newProgressContainer() *mpb.Progress {
+	var opt mpb.ContainerOption
+	if viper.GetBool("quiet") {
+		opt = mpb.WithOutput(nil)
+	}
+	return mpb.New(opt)
+}
+
+func completionMessage(name string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("%s: failed: %s", name, err)
+	}
+	return fmt.Sprintf("%s: done", name)
+}
+
+// Spinner shows a single spinner while a task of unknown length runs.
+type Spinner struct {
+	table *SpinnerTable
+	ti    *TaskInfo
+}
+
+// NewSpinner starts a spinner with the given name.
+func NewSpinner(name string) *Spinner {
+	table := NewSpinnerTable()
+	return &Spinner{table, table.AddTask(name)}
+}
+
+// Complete stops the spinner and shows whether the task succeeded.
+func (s *Spinner) Complete(err error) {
+	s.ti.Complete(err)
+	s.table.Wait()
+}
+
+// ProgressBar shows the progress of a task of known size, such as a download.
+type ProgressBar struct {
+	name  string
+	total int64
+
+	m   *mpb.Progress
+	bar *mpb.Bar
+
+	mu          sync.Mutex
+	current     int64
+	lastPercent int64
+}
+
+// NewProgressBar starts a progress bar with the given name, measured in bytes. If the total isn't known, pass 0.
+func NewProgressBar(name string, total int64) *ProgressBar {
+	p := &ProgressBar{name: name, total: total}
+	if !Interactive() {
+		printPlain("%s...", name)
+		return p
+	}
+	p.m = newProgressContainer()
+	p.bar = p.m.AddBar(total,
+		mpb.BarWidth(progressBarWidth),
+		mpb.PrependDecorators(
+			decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
+			decor.CountersKibiByte("% .1f / % .1f", decor.WCSyncSpace)),
+		mpb.AppendDecorators(
+			decor.OnComplete(decor.Percentage(decor.WCSyncSpace), "done")),
+	)
+	return p
+}
+
+// Add records that n more bytes have been processed.
+func (p *ProgressBar) Add(n int) {
+	if p.bar != nil {
+		p.bar.IncrBy(n)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current += int64(n)
+	if p.total <= 0 {
+		return
+	}
+	percent := p.current * 100 / p.total
+	if percent >= p.lastPercent+plainProgressStep && percent < 100 {
+		p.lastPercent = percent - percent%plainProgressStep
+		printPlain("%s: %d%%", p.name, p.lastPercent)
+	}
+}
+
+// ProxyReader wraps the given reader so that reads from it advance the progress bar.
+func (p *ProgressBar) ProxyReader(r io.Reader) io.Reader {
+	return &progressReader{r: r, p: p}
+}
+
+// Complete finishes the progress bar. A failed bar is abandoned where it stopped.
+func (p *ProgressBar) Complete(err error) {
+	if p.bar == nil {
+		printPlain("%s", completionMessage(p.name, err))
+		return
+	}
+	if err != nil {
+		p.bar.Abort(false)
+	} else {
+		p.bar.SetTotal(p.bar.Current(), true)
+	}
+	p.m.Wait()
+	if err != nil {
+		printPlain("%s", completionMessage(p.name, err))
+	}
+}
+
+type progressReader struct {
+	r io.Reader
+	p *ProgressBar
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 {
+		r.p.Add(n)
+	}
+	return n, err
+}
+
+// StepCounter numbers the steps of a multi-step operation, e.g. "[2/5] Deploying vizier".
+type StepCounter struct {
+	total   int
+	current int
+}
+
+// NewStepCounter creates a step counter for an operation with the given number of steps.
+func NewStepCounter(total int) *StepCounter {
+	return &StepCounter{total: total}
+}
+
+// Next starts the next step and prints its name.
+func (s *StepCounter) Next(name string) {
+	s.current++
+	if s.current > s.total {
+		s.total = s.current
+	}
+	printPlain("[%d/%d] %s", s.current, s.total, name)
+}
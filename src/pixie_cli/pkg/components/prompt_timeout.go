@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package components
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+)
+
+// The actions taken when a prompt isn't answered within the prompt timeout.
+const (
+	// PromptTimeoutAbort exits the command.
+	PromptTimeoutAbort = "abort"
+	// PromptTimeoutDefault takes the default answer of the prompt, or exits if the prompt has none.
+	PromptTimeoutDefault = "default"
+)
+
+// ErrPromptTimeout is returned when a prompt isn't answered within the prompt timeout.
+var ErrPromptTimeout = exitcodes.New(exitcodes.Aborted, "no answer within the prompt timeout, aborting")
+
+var (
+	promptTimeout       time.Duration
+	promptTimeoutAction = PromptTimeoutAbort
+)
+
+// SetPromptTimeout sets how long prompts wait for an answer, and whether the default answer is taken or the command
+// aborts once they time out. Prompts wait forever if the timeout is 0.
+func SetPromptTimeout(timeout time.Duration, action string) error {
+	switch action {
+	case "":
+		action = PromptTimeoutAbort
+	case PromptTimeoutAbort, PromptTimeoutDefault:
+	default:
+		return fmt.Errorf("invalid prompt timeout action %q, must be %s or %s", action, PromptTimeoutAbort, PromptTimeoutDefault)
+	}
+	if timeout < 0 {
+		return fmt.Errorf("invalid prompt timeout %s, must not be negative", timeout)
+	}
+	promptTimeout = timeout
+	promptTimeoutAction = action
+	return nil
+}
+
+// promptDeadline returns when a prompt that is shown now times out, or the zero time if prompts don't time out.
+func promptDeadline() time.Time {
+	if promptTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(promptTimeout)
+}
+
+// takeDefaultOnTimeout returns whether a prompt that timed out should take its default answer, and tells the user
+// so.
+func takeDefaultOnTimeout(hasDefault bool, answer string) bool {
+	if promptTimeoutAction != PromptTimeoutDefault || !hasDefault {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "No answer within %s, answering %s\n", promptTimeout, answer)
+	return true
+}
+
+type stdinRead struct {
+	input string
+	err   error
+}
+
+// pendingStdinRead is a read of stdin that was still running when a prompt timed out. The next prompt gets its
+// result, rather than reading stdin concurrently with it.
+var pendingStdinRead chan stdinRead
+
+// readStdin returns the result of read, which reads from stdin, or ErrPromptTimeout if it doesn't return before the
+// deadline. It waits forever if the deadline is zero.
+func readStdin(deadline time.Time, read func() (string, error)) (string, error) {
+	ch := pendingStdinRead
+	if ch == nil {
+		if deadline.IsZero() {
+			return read()
+		}
+		ch = make(chan stdinRead, 1)
+		go func() {
+			input, err := read()
+			ch <- stdinRead{input, err}
+		}()
+	}
+	pendingStdinRead = ch
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case r := <-ch:
+		pendingStdinRead = nil
+		return r.input, r.err
+	case <-timeout:
+		return "", ErrPromptTimeout
+	}
+}
+
+// scanStdinLine reads the next line from stdin. It returns io.EOF once stdin is closed.
+func scanStdinLine() (string, error) {
+	if !stdinScanner.Scan() {
+		if err := stdinScanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return stdinScanner.Text(), nil
+}
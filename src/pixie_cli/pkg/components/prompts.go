@@ -20,15 +20,25 @@ package components
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 )
 
 // This file has components that interact with the user via prompts.
 
+// stdinScanner reads the answers to prompts. It's shared by all prompts, so that input buffered while reading one
+// answer isn't lost to the next prompt.
+var stdinScanner = bufio.NewScanner(os.Stdin)
+
 // Prompter proves a user input dialog.
 type Prompter struct {
 	message string
@@ -45,26 +55,62 @@ func NewPrompter(message string, choices []string, defaultValue string) *Prompte
 	}
 }
 
+// ErrNonInteractive is returned by prompts when stdin isn't a terminal and has no answer to read, so the user can't
+// answer them.
+var ErrNonInteractive = exitcodes.New(exitcodes.Usage, "non-interactive session, pass -y to accept the default answers")
+
+// stdinIsTerminal returns whether prompts can be answered by the user.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
 // Prompt prompts the user and return the value. If the config parameter "y" is set we will return the default.
+// If stdin isn't a terminal, the answer is read from it, so that answers can be piped in. If the prompt can't be
+// answered, because stdin is closed, Prompt exits with an error.
 func (p *Prompter) Prompt() string {
-	if p.skip() {
-		return p.dv
-	}
-	fmt.Print(p.msg())
-	input := ""
-	s := bufio.NewScanner(os.Stdin)
-	ok := s.Scan()
-	if ok {
-		input = strings.TrimRight(s.Text(), "\r\n")
+	v, err := p.PromptE()
+	if err != nil {
+		// Don't use log.Fatal, because it will send an error to Sentry when invoked from the CLI.
+		fmt.Fprintf(os.Stderr, "%s: %s\n", p.message, err.Error())
+		exitcodes.Exit(err)
 	}
-	if input == "" {
-		return p.dv
+	return v
+}
+
+// PromptE prompts the user and return the value. If the config parameter "y" is set we will return the default.
+// It returns ErrNonInteractive if stdin isn't a terminal and is at EOF, ErrPromptCanceled if the terminal is closed,
+// and ErrPromptTimeout if the prompt timeout passes without an answer and the default answer isn't taken instead.
+func (p *Prompter) PromptE() (string, error) {
+	if p.skip() {
+		return p.dv, nil
 	}
-	if !p.validInput(input) {
+	deadline := promptDeadline()
+	for {
+		fmt.Print(p.msg())
+		input, err := readStdin(deadline, scanStdinLine)
+		if errors.Is(err, ErrPromptTimeout) {
+			fmt.Print("\n")
+			if takeDefaultOnTimeout(p.dv != "", p.dv) {
+				return p.dv, nil
+			}
+			return "", err
+		}
+		if err != nil {
+			fmt.Print("\n")
+			if errors.Is(err, io.EOF) && !stdinIsTerminal() {
+				return "", ErrNonInteractive
+			}
+			return "", ErrPromptCanceled
+		}
+		input = strings.TrimRight(input, "\r\n")
+		if input == "" {
+			return p.dv, nil
+		}
+		if p.validInput(input) {
+			return input, nil
+		}
 		fmt.Println(p.errorMsg())
-		return p.Prompt()
 	}
-	return input
 }
 
 func (p *Prompter) validInput(s string) bool {
@@ -94,11 +140,73 @@ func (p *Prompter) skip() bool {
 	return viper.GetBool("y")
 }
 
-// YNPrompt is a helper function that prompts the user for a Y/N response.
+// YNPrompt is a helper function that prompts the user for a Y/N response. It exits with an error if the prompt
+// can't be answered and the config parameter "y" isn't set.
 func YNPrompt(message string, defaultValue bool) bool {
+	return strings.ToLower(ynPrompter(message, defaultValue).Prompt()) == "y"
+}
+
+// YNPromptE prompts the user for a Y/N response, like YNPrompt, but returns ErrNonInteractive or ErrPromptCanceled
+// instead of exiting if the prompt can't be answered.
+func YNPromptE(message string, defaultValue bool) (bool, error) {
+	v, err := ynPrompter(message, defaultValue).PromptE()
+	if err != nil {
+		return false, err
+	}
+	return strings.ToLower(v) == "y", nil
+}
+
+func ynPrompter(message string, defaultValue bool) *Prompter {
 	defaultChoice := "n"
 	if defaultValue {
 		defaultChoice = "y"
 	}
-	return strings.ToLower(NewPrompter(message, []string{"y", "n"}, defaultChoice).Prompt()) == "y"
+	return NewPrompter(message, []string{"y", "n"}, defaultChoice)
+}
+
+// SecretPrompt prompts the user for a secret, such as a token or a password. In a terminal, the input isn't
+// echoed. Otherwise, the secret is read as a line from stdin, so that it can be piped in.
+func SecretPrompt(message string) (string, error) {
+	fmt.Printf("%s (won't echo): ", message)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		secret, err := term.ReadPassword(fd)
+		fmt.Print("\n")
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+	// Secrets have no default answer to take, so they don't time out.
+	secret, err := readStdin(time.Time{}, scanStdinLine)
+	fmt.Print("\n")
+	if errors.Is(err, io.EOF) {
+		return "", errors.New("no input on stdin")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(secret), nil
+}
+
+// UserPrompter asks the user questions. Commands that take one, rather than calling the prompt functions directly,
+// can be run with canned answers.
+type UserPrompter interface {
+	// YNPrompt prompts for a Y/N response, like YNPrompt.
+	YNPrompt(message string, defaultValue bool) bool
+	// Select prompts for a single option, like Select.
+	Select(message string, options []string, defaultValue string) (string, error)
+}
+
+// TerminalPrompter asks the questions with the prompts of this package.
+var TerminalPrompter UserPrompter = terminalPrompter{}
+
+type terminalPrompter struct{}
+
+func (terminalPrompter) YNPrompt(message string, defaultValue bool) bool {
+	return YNPrompt(message, defaultValue)
+}
+
+func (terminalPrompter) Select(message string, options []string, defaultValue string) (string, error) {
+	return Select(message, options, defaultValue)
 }
@@ -0,0 +1,396 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+)
+
+// ErrPromptCanceled is returned when the user cancels a prompt with Esc or Ctrl-C.
+var ErrPromptCanceled = exitcodes.New(exitcodes.Aborted, "prompt canceled")
+
+// selectMaxVisible is the number of options shown at once. The list scrolls to show the rest.
+const selectMaxVisible = 10
+
+// SelectPrompt lets the user pick one or more values from a list of options. In a terminal, the options are
+// navigated with the arrow keys and filtered by typing. If stdout isn't a terminal, the options are numbered and
+// the user types the numbers of their choices. If stdin isn't a terminal, the prompt fails with ErrNonInteractive
+// unless the config parameter "y" is set and there are defaults.
+// The following keys can be used:
+//
+//   - Up arrow, Ctrl-P: Move to the previous option.
+//   - Down arrow, Ctrl-N: Move to the next option.
+//   - Space, Tab: Toggle the current option, for multi-select prompts.
+//   - Enter: Accept the current option, or the toggled options for multi-select prompts.
+//   - Backspace: Delete the last character of the filter.
+//   - Esc, Ctrl-C: Cancel the prompt.
+type SelectPrompt struct {
+	message  string
+	options  []string
+	multi    bool
+	defaults map[int]bool
+
+	filter   string
+	cursor   int
+	selected map[int]bool
+	drawn    int
+}
+
+// NewSelectPrompt creates a prompt that picks a single option.
+func NewSelectPrompt(message string, options []string) *SelectPrompt {
+	return &SelectPrompt{
+		message:  message,
+		options:  options,
+		defaults: make(map[int]bool),
+		selected: make(map[int]bool),
+	}
+}
+
+// NewMultiSelectPrompt creates a prompt that picks any number of options.
+func NewMultiSelectPrompt(message string, options []string) *SelectPrompt {
+	p := NewSelectPrompt(message, options)
+	p.multi = true
+	return p
+}
+
+// WithDefaults sets the options that are picked if the user doesn't pick any, or if the config parameter "y" is
+// set. Single select prompts use the first default.
+func (p *SelectPrompt) WithDefaults(defaults ...string) *SelectPrompt {
+	for _, d := range defaults {
+		for i, o := range p.options {
+			if o == d {
+				p.defaults[i] = true
+			}
+		}
+	}
+	return p
+}
+
+// Select prompts the user for a single option.
+func Select(message string, options []string, defaultValue string) (string, error) {
+	p := NewSelectPrompt(message, options)
+	if defaultValue != "" {
+		p.WithDefaults(defaultValue)
+	}
+	picked, err := p.Prompt()
+	if err != nil {
+		return "", err
+	}
+	return picked[0], nil
+}
+
+// MultiSelect prompts the user for any number of options.
+func MultiSelect(message string, options []string, defaults []string) ([]string, error) {
+	return NewMultiSelectPrompt(message, options).WithDefaults(defaults...).Prompt()
+}
+
+// Prompt shows the prompt and returns the picked options, in the order that they were given in. A single select
+// prompt returns exactly one option.
+func (p *SelectPrompt) Prompt() ([]string, error) {
+	if len(p.options) == 0 {
+		return nil, errors.New("no options to select from")
+	}
+	if viper.GetBool("y") && (p.multi || len(p.defaults) > 0) {
+		return p.picked(p.defaults), nil
+	}
+	if !stdinIsTerminal() {
+		return nil, ErrNonInteractive
+	}
+	if !Interactive() {
+		return p.promptNumbered()
+	}
+	return p.promptInteractive()
+}
+
+// picked returns the options whose indices are set, in order. Single select prompts return the first.
+func (p *SelectPrompt) picked(indices map[int]bool) []string {
+	var picked []string
+	for i, o := range p.options {
+		if indices[i] {
+			picked = append(picked, o)
+			if !p.multi {
+				break
+			}
+		}
+	}
+	return picked
+}
+
+func (p *SelectPrompt) defaultNumbers() string {
+	var numbers []string
+	for i := range p.options {
+		if p.defaults[i] {
+			numbers = append(numbers, strconv.Itoa(i+1))
+			if !p.multi {
+				break
+			}
+		}
+	}
+	return strings.Join(numbers, ",")
+}
+
+// promptNumbered lists the options with their numbers and reads the numbers of the picked options from stdin.
+func (p *SelectPrompt) promptNumbered() ([]string, error) {
+	fmt.Println(p.message)
+	for i, o := range p.options {
+		fmt.Printf("  %d) %s\n", i+1, o)
+	}
+	msg := "Enter a number"
+	if p.multi {
+		msg = "Enter numbers separated by commas"
+	}
+	if dv := p.defaultNumbers(); dv != "" {
+		msg += fmt.Sprintf(" [%s]", dv)
+	}
+
+	deadline := promptDeadline()
+	for {
+		fmt.Printf("%s: ", msg)
+		input, err := readStdin(deadline, scanStdinLine)
+		if errors.Is(err, ErrPromptTimeout) {
+			fmt.Print("\n")
+			return p.timedOut()
+		}
+		if err != nil {
+			return nil, ErrPromptCanceled
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			if len(p.defaults) > 0 || p.multi {
+				return p.picked(p.defaults), nil
+			}
+			continue
+		}
+		indices, err := p.parseNumbers(input)
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		return p.picked(indices), nil
+	}
+}
+
+// timedOut returns the default options of a prompt that wasn't answered within the prompt timeout, if they are
+// taken, and otherwise ErrPromptTimeout.
+func (p *SelectPrompt) timedOut() ([]string, error) {
+	picked := p.picked(p.defaults)
+	answer := strings.Join(picked, ", ")
+	if answer == "" {
+		answer = "none"
+	}
+	if takeDefaultOnTimeout(len(p.defaults) > 0 || p.multi, answer) {
+		return picked, nil
+	}
+	return nil, ErrPromptTimeout
+}
+
+func (p *SelectPrompt) parseNumbers(input string) (map[int]bool, error) {
+	fields := strings.Split(input, ",")
+	if !p.multi && len(fields) > 1 {
+		return nil, errors.New("invalid input, must be a single number")
+	}
+	indices := make(map[int]bool)
+	for _, f := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 1 || n > len(p.options) {
+			return nil, fmt.Errorf("invalid input, must be numbers between 1 and %d", len(p.options))
+		}
+		indices[n-1] = true
+	}
+	return indices, nil
+}
+
+// filtered returns the indices of the options that contain the filter, ignoring case.
+func (p *SelectPrompt) filtered() []int {
+	filter := strings.ToLower(p.filter)
+	var indices []int
+	for i, o := range p.options {
+		if strings.Contains(strings.ToLower(o), filter) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// promptInteractive puts the terminal in raw mode and lets the user navigate the options with the keyboard.
+func (p *SelectPrompt) promptInteractive() ([]string, error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return p.promptNumbered()
+	}
+	defer term.Restore(fd, state)
+
+	for i := range p.defaults {
+		p.selected[i] = true
+	}
+	if !p.multi {
+		for i, o := range p.filtered() {
+			if p.defaults[o] {
+				p.cursor = i
+				break
+			}
+		}
+	}
+
+	deadline := promptDeadline()
+	buf := make([]byte, 64)
+	for {
+		p.draw()
+		keys, err := readStdin(deadline, func() (string, error) {
+			n, err := os.Stdin.Read(buf)
+			return string(buf[:n]), err
+		})
+		if errors.Is(err, ErrPromptTimeout) {
+			p.finish("")
+			return p.timedOut()
+		}
+		if err != nil {
+			p.finish("")
+			return nil, ErrPromptCanceled
+		}
+		picked, done, err := p.handleKeys([]byte(keys))
+		if err != nil {
+			p.finish("")
+			return nil, err
+		}
+		if done {
+			p.finish(strings.Join(picked, ", "))
+			return picked, nil
+		}
+	}
+}
+
+// handleKeys updates the prompt state with the keys that were read, and returns the picked options once the user
+// accepts them.
+func (p *SelectPrompt) handleKeys(keys []byte) ([]string, bool, error) {
+	matches := p.filtered()
+	switch string(keys) {
+	case "\x1b[A", "\x1bOA", "\x10":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		return nil, false, nil
+	case "\x1b[B", "\x1bOB", "\x0e":
+		if p.cursor < len(matches)-1 {
+			p.cursor++
+		}
+		return nil, false, nil
+	case "\x1b", "\x03":
+		return nil, false, ErrPromptCanceled
+	case "\r", "\n":
+		if !p.multi {
+			if len(matches) == 0 {
+				return nil, false, nil
+			}
+			return []string{p.options[matches[p.cursor]]}, true, nil
+		}
+		return p.picked(p.selected), true, nil
+	case "\x7f", "\b":
+		if p.filter != "" {
+			_, size := utf8.DecodeLastRuneInString(p.filter)
+			p.filter = p.filter[:len(p.filter)-size]
+			p.cursor = 0
+		}
+		return nil, false, nil
+	}
+	if p.multi && (string(keys) == " " || string(keys) == "\t") {
+		if len(matches) > 0 {
+			i := matches[p.cursor]
+			p.selected[i] = !p.selected[i]
+		}
+		return nil, false, nil
+	}
+	if keys[0] == '\x1b' {
+		// Ignore any other escape sequences.
+		return nil, false, nil
+	}
+	for _, r := range string(keys) {
+		if unicode.IsPrint(r) {
+			p.filter += string(r)
+		}
+	}
+	p.cursor = 0
+	return nil, false, nil
+}
+
+// draw redraws the prompt in place of the previous one. The terminal is in raw mode, so lines end in "\r\n".
+func (p *SelectPrompt) draw() {
+	var b strings.Builder
+	if p.drawn > 0 {
+		fmt.Fprintf(&b, "\r\x1b[%dA", p.drawn)
+	}
+	b.WriteString("\r\x1b[J")
+
+	hint := "type to filter, arrows to move, enter to select"
+	if p.multi {
+		hint = "type to filter, arrows to move, space to toggle, enter to accept"
+	}
+	fmt.Fprintf(&b, "%s %s %s", color.CyanString("?"), p.message, p.filter)
+	if p.filter == "" {
+		b.WriteString(color.New(color.Faint).Sprintf("(%s)", hint))
+	}
+	b.WriteString("\r\n")
+	lines := 1
+
+	matches := p.filtered()
+	start := 0
+	if p.cursor >= selectMaxVisible {
+		start = p.cursor - selectMaxVisible + 1
+	}
+	for i := start; i < len(matches) && i < start+selectMaxVisible; i++ {
+		o := matches[i]
+		pointer := "  "
+		if i == p.cursor {
+			pointer = color.CyanString("> ")
+		}
+		box := ""
+		if p.multi {
+			box = "[ ] "
+			if p.selected[o] {
+				box = color.GreenString("[x] ")
+			}
+		}
+		fmt.Fprintf(&b, "%s%s%s\r\n", pointer, box, p.options[o])
+		lines++
+	}
+	if len(matches) == 0 {
+		b.WriteString("  no matches\r\n")
+		lines++
+	}
+	fmt.Print(b.String())
+	p.drawn = lines
+}
+
+// finish replaces the prompt with a single line showing the answer.
+func (p *SelectPrompt) finish(answer string) {
+	fmt.Printf("\r\x1b[%dA\r\x1b[J%s %s %s\r\n", p.drawn, color.CyanString("?"), p.message, color.CyanString(answer))
+}
@@ -20,73 +20,218 @@ package components
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
-	"github.com/spf13/viper"
 	"github.com/vbauerster/mpb/v4"
 	"github.com/vbauerster/mpb/v4/decor"
 )
 
 const barWidth = 4
 
+// taskProgressBarWidth is the width of the bar drawn for tasks that report their progress.
+const taskProgressBarWidth = 20
+
+// taskProgressScale is the total of the bars of tasks that report their progress, which is the precision that the
+// progress is shown with.
+const taskProgressScale = 1000
+
 // TaskInfo is the information associated with a task.
 type TaskInfo struct {
-	bar *mpb.Bar
-	sd  *statusDecorator
-	evd *errorViewDecorator
+	name  string
+	table *SpinnerTable
+	bar   *mpb.Bar
+	sd    *statusDecorator
+	dd    *detailDecorator
+	pd    *progressDecorator
+	evd   *errorViewDecorator
+	// showsProgress is whether the task is drawn with a progress bar rather than a spinner.
+	showsProgress bool
+
+	parent *TaskInfo
+	depth  int
+	// mu guards children, which is read by the tree decorators of the children while they render.
+	mu       sync.Mutex
+	children []*TaskInfo
+}
+
+// AddSubtask puts a child task on the display, indented below this task and its earlier children.
+func (t *TaskInfo) AddSubtask(name string) *TaskInfo {
+	return t.table.addTask(name, t, false)
+}
+
+// isLastChild returns whether the task is the last child of its parent, so far.
+func (t *TaskInfo) isLastChild() bool {
+	t.parent.mu.Lock()
+	defer t.parent.mu.Unlock()
+	return t.parent.children[len(t.parent.children)-1] == t
+}
+
+// plainIndent is the indentation of the plain log lines of the task.
+func (t *TaskInfo) plainIndent() string {
+	return strings.Repeat("  ", t.depth)
+}
+
+// SetDetail shows details about the progress of the task after its name, such as "attempt 2/3". The details are
+// cleared by passing an empty string.
+func (t *TaskInfo) SetDetail(detail string) {
+	if t.bar == nil {
+		if detail != "" {
+			printPlain("%s%s: %s", t.plainIndent(), t.name, detail)
+		}
+		return
+	}
+	t.dd.setDetail(detail)
+}
+
+// SetProgress shows the fraction of the work of the task that is done, from 0 to 1, along with the estimated time
+// until the task completes.
+func (t *TaskInfo) SetProgress(fraction float64) {
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	if t.bar == nil {
+		if percent := t.pd.setProgress(fraction); percent >= 0 {
+			printPlain("%s%s: %d%%", t.plainIndent(), t.name, percent)
+		}
+		return
+	}
+	t.pd.setProgress(fraction)
+	if t.showsProgress {
+		// Reaching the total would complete the bar, so it's left one step short until the task completes.
+		current := int64(fraction * taskProgressScale)
+		if current >= taskProgressScale {
+			current = taskProgressScale - 1
+		}
+		t.bar.SetCurrent(current)
+	}
 }
 
 // Complete finishes the task.
 func (t *TaskInfo) Complete(err error) {
+	if t.bar == nil {
+		printPlain("%s%s", t.plainIndent(), completionMessage(t.name, err))
+		return
+	}
 	t.bar.SetTotal(1, true)
 	t.evd.setError(err)
 	t.sd.setError(err)
 }
 
-// SpinnerTable is view for a job run table with spinners.
+// SpinnerTable is view for a job run table with spinners. When stdout isn't a terminal, the table prints a plain
+// log line as each task starts and finishes instead.
 type SpinnerTable struct {
-	m     *mpb.Progress
+	m *mpb.Progress
+	// mu guards tasks, which are the tasks in the order they are displayed in.
+	mu    sync.Mutex
 	tasks []*TaskInfo
 }
 
 // NewSpinnerTable creates a new table with Spinners.
 func NewSpinnerTable() *SpinnerTable {
-	var opt mpb.ContainerOption
-	if viper.GetBool("quiet") {
-		opt = mpb.WithOutput(nil)
+	var m *mpb.Progress
+	if Interactive() {
+		m = newProgressContainer()
 	}
 
 	return &SpinnerTable{
-		mpb.New(opt),
-		make([]*TaskInfo, 0),
+		m:     m,
+		tasks: make([]*TaskInfo, 0),
 	}
 }
 
 // AddTask puts a task on the display.
 func (s *SpinnerTable) AddTask(name string) *TaskInfo {
-	ti := &TaskInfo{}
+	return s.addTask(name, nil, false)
+}
+
+// AddProgressTask puts a task on the display that is drawn with a progress bar rather than a spinner. The task
+// reports its progress with SetProgress.
+func (s *SpinnerTable) AddProgressTask(name string) *TaskInfo {
+	return s.addTask(name, nil, true)
+}
+
+// addTask puts a task on the display, below the last descendant of its parent if it has one.
+func (s *SpinnerTable) addTask(name string, parent *TaskInfo, showsProgress bool) *TaskInfo {
+	ti := &TaskInfo{name: name, table: s, parent: parent, pd: newProgressDecorator(), showsProgress: showsProgress}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos := len(s.tasks)
+	if parent != nil {
+		ti.depth = parent.depth + 1
+		pos = s.descendantsEnd(parent)
+		parent.mu.Lock()
+		parent.children = append(parent.children, ti)
+		parent.mu.Unlock()
+	}
+	s.tasks = append(s.tasks, nil)
+	copy(s.tasks[pos+1:], s.tasks[pos:])
+	s.tasks[pos] = ti
+
+	if s.m == nil {
+		printPlain("%s%s...", ti.plainIndent(), name)
+		return ti
+	}
 	sd := newStatusDecorator(barWidth)
+	dd := newDetailDecorator()
 	evd := newErrorViewDecorator()
-	// We treat the spinner is either done/not-done, so we only need progress of 1 and 0, respectively.
-	maxProgress := int64(1)
-	bar := s.m.AddSpinner(maxProgress, mpb.SpinnerOnLeft,
-		mpb.PrependDecorators(sd),
-		mpb.BarWidth(barWidth),
+	opts := []mpb.BarOption{
+		mpb.PrependDecorators(newTreeDecorator(ti), sd),
 		mpb.AppendDecorators(
 			decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
+			ti.pd,
+			dd,
 			evd),
-		mpb.BarClearOnComplete())
+		mpb.BarClearOnComplete(),
+		mpb.BarPriority(pos),
+	}
+	var bar *mpb.Bar
+	if showsProgress {
+		bar = s.m.AddBar(taskProgressScale, append(opts, mpb.BarWidth(taskProgressBarWidth))...)
+	} else {
+		// We treat the spinner is either done/not-done, so we only need progress of 1 and 0, respectively.
+		maxProgress := int64(1)
+		bar = s.m.AddSpinner(maxProgress, mpb.SpinnerOnLeft, append(opts, mpb.BarWidth(barWidth))...)
+	}
 
 	ti.sd = sd
+	ti.dd = dd
 	ti.evd = evd
 	ti.bar = bar
-	s.tasks = append(s.tasks, ti)
+	// Move the tasks below the new task down.
+	for i := pos + 1; i < len(s.tasks); i++ {
+		if s.tasks[i].bar != nil {
+			s.tasks[i].bar.SetPriority(i)
+		}
+	}
 
 	return ti
 }
 
+// descendantsEnd returns the position after the last descendant of the task.
+func (s *SpinnerTable) descendantsEnd(t *TaskInfo) int {
+	pos := len(s.tasks)
+	for i, ti := range s.tasks {
+		if ti == t {
+			pos = i + 1
+			break
+		}
+	}
+	for pos < len(s.tasks) && s.tasks[pos].depth > t.depth {
+		pos++
+	}
+	return pos
+}
+
 // Wait for all the spinners to complete.
 func (s *SpinnerTable) Wait() {
+	if s.m == nil {
+		return
+	}
 	s.m.Wait()
 }
 
@@ -117,6 +262,108 @@ func (d *statusDecorator) setError(err error) {
 	d.err = err
 }
 
+// treeDecorator draws the branches of the tree of subtasks.
+type treeDecorator struct {
+	decor.WC
+	ti *TaskInfo
+}
+
+func newTreeDecorator(ti *TaskInfo) *treeDecorator {
+	wc := decor.WC{}
+	wc.Init()
+	return &treeDecorator{wc, ti}
+}
+
+// Decor is the output function for this decorator.
+func (d *treeDecorator) Decor(stat *decor.Statistics) string {
+	if d.ti.parent == nil {
+		return ""
+	}
+	branch := "├─ "
+	if d.ti.isLastChild() {
+		branch = "└─ "
+	}
+	return strings.Repeat("   ", d.ti.depth-1) + color.New(color.Faint).Sprint(branch)
+}
+
+type detailDecorator struct {
+	decor.WC
+	mu     sync.Mutex
+	detail string
+}
+
+func newDetailDecorator() *detailDecorator {
+	wc := decor.WC{}
+	wc.Init()
+	return &detailDecorator{WC: wc}
+}
+
+// Decor is the output function for this decorator.
+func (d *detailDecorator) Decor(stat *decor.Statistics) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.detail == "" {
+		return ""
+	}
+	return color.New(color.Faint).Sprintf("(%s) ", d.detail)
+}
+
+func (d *detailDecorator) setDetail(detail string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.detail = detail
+}
+
+// progressDecorator shows the progress reported by a task as a percentage, with the estimated time until the task
+// completes, which assumes that the rest of the work progresses at the average rate so far.
+type progressDecorator struct {
+	decor.WC
+	mu       sync.Mutex
+	start    time.Time
+	fraction float64
+	reported bool
+	// lastPercent is the percentage that was last printed in a plain log line.
+	lastPercent int
+}
+
+func newProgressDecorator() *progressDecorator {
+	wc := decor.WC{}
+	wc.Init()
+	return &progressDecorator{WC: wc, start: time.Now()}
+}
+
+// Decor is the output function for this decorator.
+func (d *progressDecorator) Decor(stat *decor.Statistics) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if stat.Completed || !d.reported {
+		return ""
+	}
+	text := fmt.Sprintf("%d%%", int(d.fraction*100))
+	if d.fraction > 0 {
+		elapsed := time.Since(d.start)
+		remaining := time.Duration(float64(elapsed) * (1 - d.fraction) / d.fraction)
+		text += fmt.Sprintf(", ETA %s", remaining.Round(time.Second))
+	}
+	return color.New(color.Faint).Sprintf("(%s) ", text)
+}
+
+// setProgress records the progress, and returns its percentage rounded down to a step of the plain log lines if it
+// has reached the next step, or -1 otherwise.
+func (d *progressDecorator) setProgress(fraction float64) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fraction = fraction
+	d.reported = true
+	percent := int(fraction * 100)
+	step := percent - percent%plainProgressStep
+	if step > d.lastPercent && percent < 100 {
+		d.lastPercent = step
+		return step
+	}
+	return -1
+}
+
 type errorViewDecorator struct {
 	decor.WC
 	err error
@@ -20,14 +20,20 @@ package components
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 	"time"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v2"
 )
 
 // OutputStreamWriter is the default interface for all output writers.
@@ -44,15 +50,36 @@ type TableView interface {
 	Data() [][]interface{}
 }
 
-// CreateStreamWriter creates a formatted writer with the default options.
+// CreateStreamWriter creates a formatted writer with the default options. The columns are selected and sorted
+// according to the --columns and --sort_by flags.
 func CreateStreamWriter(format string, w io.Writer) OutputStreamWriter {
+	writer := createFormatWriter(format, w)
+	switch format {
+	case "null", "inmemory":
+		return writer
+	}
+	if opts := columnOptionsFromConfig(); !opts.empty() {
+		return NewColumnStreamWriter(writer, opts)
+	}
+	return writer
+}
+
+func createFormatWriter(format string, w io.Writer) OutputStreamWriter {
 	switch format {
 	case "json":
 		return NewJSONStreamWriter(w)
+	case "json-array":
+		return NewJSONArrayStreamWriter(w)
 	case "table":
 		return NewTableStreamWriter(w)
+	case "wide":
+		return NewWideTableStreamWriter(w)
 	case "csv":
 		return NewCSVStreamWriter(w)
+	case "csv-rfc4180":
+		return NewRFC4180CSVStreamWriter(w)
+	case "yaml":
+		return NewYAMLStreamWriter(w)
 	case "null":
 		return &NullStreamWriter{}
 	case "inmemory":
@@ -62,12 +89,50 @@ func CreateStreamWriter(format string, w io.Writer) OutputStreamWriter {
 	}
 }
 
+// WideColumnWriter is implemented by writers that hide some columns unless the output is wide, such as the table
+// writer. Other writers output all columns.
+type WideColumnWriter interface {
+	// SetWideColumns marks the columns with the given names as only shown in wide output.
+	SetWideColumns(columns ...string)
+}
+
+// SetWideColumns marks the columns with the given names as only shown in wide output (-o wide), if the writer
+// supports it.
+func SetWideColumns(w OutputStreamWriter, columns ...string) {
+	if ww, ok := w.(WideColumnWriter); ok {
+		ww.SetWideColumns(columns...)
+	}
+}
+
+// FooterWriter is implemented by writers that can show a footer, such as totals or counts, below the data rows.
+// Other writers don't output footers, because the aggregates can be computed from the data.
+type FooterWriter interface {
+	// SetFooter sets the footer, which has a value for each column. May be called at any time before Finish.
+	SetFooter(values []interface{}) error
+}
+
+// SetFooter sets the footer of the table, if the writer supports footers.
+func SetFooter(w OutputStreamWriter, values ...interface{}) error {
+	if fw, ok := w.(FooterWriter); ok {
+		return fw.SetFooter(values)
+	}
+	return nil
+}
+
 // TableStreamWriter writer output in tabular format. It's blocking so data is only written after the table is complete.
 type TableStreamWriter struct {
 	w            io.Writer
 	id           string
 	headerValues []string
 	data         [][]interface{}
+	// wide is whether the columns set by SetWideColumns are shown.
+	wide        bool
+	wideColumns map[string]bool
+	footer      []interface{}
+	// hideID is whether the "Table ID" line is omitted.
+	hideID bool
+	// width is the width to fit the table to, if it isn't the width of the terminal that the table is written to.
+	width int
 }
 
 type stringer interface {
@@ -95,6 +160,63 @@ func NewTableStreamWriter(w io.Writer) *TableStreamWriter {
 	}
 }
 
+// NewWideTableStreamWriter creates a table writer that also shows the columns set by SetWideColumns.
+func NewWideTableStreamWriter(w io.Writer) *TableStreamWriter {
+	t := NewTableStreamWriter(w)
+	t.wide = true
+	return t
+}
+
+// SetWideColumns marks the columns with the given names as only shown in wide output.
+func (t *TableStreamWriter) SetWideColumns(columns ...string) {
+	if t.wideColumns == nil {
+		t.wideColumns = make(map[string]bool)
+	}
+	for _, c := range columns {
+		t.wideColumns[c] = true
+	}
+}
+
+// SetFooter sets the footer of the table, which is rendered in bold below the data rows.
+func (t *TableStreamWriter) SetFooter(values []interface{}) error {
+	if len(values) != len(t.headerValues) {
+		return errors.New("header/footer length mismatch")
+	}
+	t.footer = values
+	return nil
+}
+
+// visibleColumns returns the header values, data and footer of the columns that are shown.
+func (t *TableStreamWriter) visibleColumns() ([]string, [][]interface{}, []interface{}) {
+	if t.wide || len(t.wideColumns) == 0 {
+		return t.headerValues, t.data, t.footer
+	}
+	var indices []int
+	var header []string
+	for i, h := range t.headerValues {
+		if !t.wideColumns[h] {
+			indices = append(indices, i)
+			header = append(header, h)
+		}
+	}
+	project := func(row []interface{}) []interface{} {
+		out := make([]interface{}, len(indices))
+		for j, idx := range indices {
+			out[j] = row[idx]
+		}
+		return out
+	}
+	data := make([][]interface{}, len(t.data))
+	for i, row := range t.data {
+		data[i] = project(row)
+	}
+	var footer []interface{}
+	if t.footer != nil {
+		footer = project(t.footer)
+	}
+	return header, data, footer
+}
+
 // SetHeader is called to set the key values for each of the data values. Must be called before Write is.
 func (t *TableStreamWriter) SetHeader(id string, headerValues []string) {
 	t.id = id
@@ -118,16 +240,127 @@ func (t *TableStreamWriter) stringifyRow(row []interface{}) []string {
 	return s
 }
 
+// minTableColumnWidth is the narrowest a column is truncated to when fitting a table to the terminal.
+const minTableColumnWidth = 12
+
+// tableColumnOverhead is the number of characters of padding the table adds around each column.
+const tableColumnOverhead = 3
+
+// terminalWidth returns the width of the terminal that the table is written to, or 0 if the table should not be
+// truncated, because it isn't written to a terminal or --no_truncate is set.
+func (t *TableStreamWriter) terminalWidth() int {
+	if viper.GetBool("no_truncate") {
+		return 0
+	}
+	if t.width > 0 {
+		return t.width
+	}
+	if !IsTerminal(t.w) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(t.w.(*os.File).Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// fitColumnWidths shrinks the widest columns until the columns fit in the given width, without shrinking any
+// column below minTableColumnWidth.
+func fitColumnWidths(widths []int, width int) []int {
+	fitted := make([]int, len(widths))
+	copy(fitted, widths)
+	total := 0
+	for _, w := range fitted {
+		total += w
+	}
+	for total > width {
+		widest := 0
+		for i, w := range fitted {
+			if w > fitted[widest] {
+				widest = i
+			}
+		}
+		if fitted[widest] <= minTableColumnWidth {
+			break
+		}
+		fitted[widest]--
+		total--
+	}
+	return fitted
+}
+
+// isNumericColumn returns whether all values of the column are numbers, so that it can be right aligned.
+func isNumericColumn(data [][]interface{}, col int) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, row := range data {
+		switch row[col].(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // Finish is called when all the data has been sent. In the case of the table we can now render all the values.
+// Tables written to a terminal are truncated to fit its width, unless --no_truncate is set, and shown in a pager
+// if they don't fit its height, unless --no_pager is set. Columns set by SetWideColumns are only shown in wide tables.
 func (t *TableStreamWriter) Finish() {
-	fmt.Printf("Table ID: %s\n", t.id)
-	table := tablewriter.NewWriter(t.w)
-	table.SetHeader(t.headerValues)
+	if !t.hideID {
+		fmt.Printf("Table ID: %s\n", t.id)
+	}
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	headerValues, tableData, footer := t.visibleColumns()
+	// The header is formatted here rather than by the table, which would also format the footer.
+	titles := make([]string, len(headerValues))
+	for i, h := range headerValues {
+		titles[i] = tablewriter.Title(h)
+	}
+	table.SetHeader(titles)
 
-	table.SetAutoFormatHeaders(true)
+	rows := make([][]string, len(tableData))
+	widths := make([]int, len(headerValues))
+	for i, h := range headerValues {
+		widths[i] = runewidth.StringWidth(h)
+	}
+	for i, row := range tableData {
+		rows[i] = t.stringifyRow(row)
+		for j, cell := range rows[i] {
+			if w := runewidth.StringWidth(cell); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+	if width := t.terminalWidth(); width > 0 {
+		fitted := fitColumnWidths(widths, width-tableColumnOverhead*len(widths))
+		for _, row := range rows {
+			for j, cell := range row {
+				row[j] = runewidth.Truncate(cell, fitted[j], "…")
+			}
+		}
+	}
+	for _, row := range rows {
+		for j, cell := range row {
+			row[j] = ColorizeStatus(cell)
+		}
+	}
+
+	alignments := make([]int, len(headerValues))
+	for i := range alignments {
+		alignments[i] = tablewriter.ALIGN_LEFT
+		if isNumericColumn(tableData, i) {
+			alignments[i] = tablewriter.ALIGN_RIGHT
+		}
+	}
+
+	table.SetAutoFormatHeaders(false)
 	table.SetAutoWrapText(false)
 	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetColumnAlignment(alignments)
 	table.SetColWidth(30)
 	table.SetReflowDuringAutoWrap(true)
 	table.SetCenterSeparator("")
@@ -138,11 +371,19 @@ func (t *TableStreamWriter) Finish() {
 	table.SetTablePadding("\t")
 	table.SetNoWhiteSpace(false)
 
-	for _, row := range t.data {
-		table.Append(t.stringifyRow(row))
+	if colors := headerColors(len(headerValues)); colors != nil {
+		table.SetHeaderColor(colors...)
 	}
-
+	if footer != nil {
+		table.SetFooter(t.stringifyRow(footer))
+		table.SetFooterAlignment(tablewriter.ALIGN_LEFT)
+		if colors := footerColors(len(headerValues)); colors != nil {
+			table.SetFooterColor(colors...)
+		}
+	}
+	table.AppendBulk(rows)
 	table.Render()
+	_ = WritePaged(t.w, buf.Bytes())
 }
 
 const tableNameKey = "_tableName_"
@@ -216,6 +457,116 @@ func (j *JSONStreamWriter) Finish() {
 	// Since JSON writer outputs records right away there is nothing to do here.
 }
 
+// JSONArrayStreamWriter writes all records as a single JSON array, for tools that expect one JSON document
+// rather than one record per line. Records are written as they arrive.
+type JSONArrayStreamWriter struct {
+	w            io.Writer
+	id           string
+	headerValues []string
+	started      bool
+}
+
+// NewJSONArrayStreamWriter creates a JSONArrayStreamWriter.
+func NewJSONArrayStreamWriter(w io.Writer) *JSONArrayStreamWriter {
+	return &JSONArrayStreamWriter{w: w}
+}
+
+// SetHeader is called to set the key values for each of the data values. Must be called before Write is.
+func (j *JSONArrayStreamWriter) SetHeader(id string, headerValues []string) {
+	j.id = id
+	j.headerValues = headerValues
+}
+
+// Write is called for each record of data.
+func (j *JSONArrayStreamWriter) Write(data []interface{}) error {
+	if len(data) != len(j.headerValues) {
+		return errors.New("header/data length mismatch")
+	}
+
+	val := make([]MapItem, len(data)+1) // +1 for the table name
+	val[0].Key = tableNameKey
+	val[0].Value = j.id
+	for i, d := range data {
+		val[i+1].Key = j.headerValues[i]
+		val[i+1].Value = d
+	}
+	b, err := json.Marshal(MapSlice(val))
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if !j.started {
+		sep = "[\n"
+		j.started = true
+	}
+	_, err = fmt.Fprintf(j.w, "%s%s", sep, b)
+	return err
+}
+
+// Finish is called to close the array once all the data has been sent.
+func (j *JSONArrayStreamWriter) Finish() {
+	if !j.started {
+		fmt.Fprintln(j.w, "[]")
+		return
+	}
+	fmt.Fprintln(j.w, "\n]")
+}
+
+// YAMLStreamWriter writes one YAML document per record, in the same shape as the JSONStreamWriter.
+type YAMLStreamWriter struct {
+	w            io.Writer
+	id           string
+	headerValues []string
+}
+
+// NewYAMLStreamWriter creates a YAMLStreamWriter.
+func NewYAMLStreamWriter(w io.Writer) *YAMLStreamWriter {
+	return &YAMLStreamWriter{w: w}
+}
+
+// SetHeader is called to set the key values for each of the data values. Must be called before Write is.
+func (y *YAMLStreamWriter) SetHeader(id string, headerValues []string) {
+	y.id = id
+	y.headerValues = headerValues
+}
+
+// Write is called for each record of data.
+func (y *YAMLStreamWriter) Write(data []interface{}) error {
+	if len(data) != len(y.headerValues) {
+		return errors.New("header/data length mismatch")
+	}
+
+	val := make(yaml.MapSlice, len(data)+1) // +1 for the table name
+	val[0].Key = tableNameKey
+	val[0].Value = y.id
+	for i, d := range data {
+		// Values are converted through JSON, so that they are rendered the same way as by the JSONStreamWriter.
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		val[i+1].Key = y.headerValues[i]
+		val[i+1].Value = v
+	}
+
+	b, err := yaml.Marshal(val)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(y.w, "---\n%s", b)
+	return err
+}
+
+// Finish is called to flush all the data.
+func (y *YAMLStreamWriter) Finish() {
+	// Since YAML writer outputs records right away there is nothing to do here.
+}
+
 // NullStreamWriter reads the data but does not output it.
 type NullStreamWriter struct{}
 
@@ -276,7 +627,8 @@ func (t *TableAccumulator) Data() [][]interface{} {
 	return t.data
 }
 
-// CSVStreamWriter writes data in CSV format.
+// CSVStreamWriter writes data in CSV format. Only fields that contain commas or newlines are quoted. Its output is
+// kept as is for the scripts that parse it; RFC4180CSVStreamWriter escapes all fields.
 type CSVStreamWriter struct {
 	w             io.Writer
 	delimiter     []byte
@@ -342,3 +694,60 @@ func (c *CSVStreamWriter) Write(data []interface{}) error {
 func (c *CSVStreamWriter) Finish() {
 	// Since CSV writer outputs records right away there is nothing to do here.
 }
+
+// RFC4180CSVStreamWriter writes data in CSV format, like CSVStreamWriter, but fields are quoted and escaped as
+// described in RFC 4180, and the header is written even if there are no records. It's the csv-rfc4180 output format.
+type RFC4180CSVStreamWriter struct {
+	w             *csv.Writer
+	id            string
+	headerValues  []string
+	headerWritten bool
+}
+
+// NewRFC4180CSVStreamWriter creates an RFC4180CSVStreamWriter.
+func NewRFC4180CSVStreamWriter(w io.Writer) *RFC4180CSVStreamWriter {
+	return &RFC4180CSVStreamWriter{w: csv.NewWriter(w), headerWritten: false}
+}
+
+// SetHeader is called to set the key values for each of the data values. Must be called before Write is.
+func (c *RFC4180CSVStreamWriter) SetHeader(id string, headerValues []string) {
+	c.id = id
+	c.headerValues = headerValues
+}
+
+func (c *RFC4180CSVStreamWriter) writeHeader() error {
+	c.headerWritten = true
+	return c.w.Write(append([]string{"table_id"}, c.headerValues...))
+}
+
+// Write is called for each record of data.
+func (c *RFC4180CSVStreamWriter) Write(data []interface{}) error {
+	if !c.headerWritten {
+		if err := c.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	if len(data) != len(c.headerValues) {
+		return errors.New("header/data length mismatch")
+	}
+	record := make([]string, len(data)+1)
+	record[0] = c.id
+	for i, d := range data {
+		record[i+1] = stringifyValue(d)
+	}
+	if err := c.w.Write(record); err != nil {
+		return err
+	}
+	// Flush every record, so that streamed results show up right away.
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Finish is called to flush all the data. The header is written even if there were no records.
+func (c *RFC4180CSVStreamWriter) Finish() {
+	if !c.headerWritten {
+		_ = c.writeHeader()
+	}
+	c.w.Flush()
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// virtualTerminal is whether the terminal interprets ANSI escape sequences. Only old Windows consoles don't.
+var virtualTerminal = true
+
+// IsTerminal returns whether the given writer is attached to a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// Interactive returns whether output is going to a terminal that supports colors and cursor movement. When it
+// isn't, e.g. in CI logs, when stdout is piped to a file or in old Windows consoles, components switch to plain,
+// uncolored, line-oriented output: spinners and progress bars print a log line per event instead of redrawing
+// themselves.
+func Interactive() bool {
+	return IsTerminal(os.Stdout) && os.Getenv("TERM") != "dumb" && virtualTerminal
+}
@@ -0,0 +1,468 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/option"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// DefaultArtifactsURL is the location of the demo apps that Pixie publishes.
+const DefaultArtifactsURL = "https://storage.googleapis.com/pixie-prod-artifacts/prod-demo-apps"
+
+// Source fetches files from a location that stores the demo artifacts.
+type Source interface {
+	Fetch(filename string) ([]byte, error)
+}
+
+// SourceOptions configure how artifacts are fetched.
+type SourceOptions struct {
+	// Headers are added to all HTTP(S) requests, in the format "Name: value".
+	Headers []string
+	// GCSCredentialsFile is the path of a GCP service account key that gs:// artifacts are read with. If it's empty,
+	// application default credentials are used.
+	GCSCredentialsFile string
+	// MaxDownloadRate is the maximum rate, in bytes per second, that artifacts are downloaded at. The rate is
+	// unlimited if it's 0.
+	MaxDownloadRate int64
+	// Progress receives the progress of downloads. If it's nil, the progress is shown in the terminal.
+	Progress ProgressFunc
+}
+
+// rateLimitedReader reads at most at the rate of its limiter, so that downloads don't starve the rest of the network.
+type rateLimitedReader struct {
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > l.limiter.Burst() {
+		p = p[:l.limiter.Burst()]
+	}
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if waitErr := l.limiter.WaitN(context.Background(), n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// limitDownloadRate limits the rate of the download to the maximum download rate, if it's set.
+func (o *SourceOptions) limitDownloadRate(r io.Reader) io.Reader {
+	bytesPerSec := o.MaxDownloadRate
+	if bytesPerSec <= 0 {
+		return r
+	}
+	// The burst is at most a tenth of a second of data, which keeps the rate smooth.
+	burst := bytesPerSec / 10
+	if burst < 1 {
+		burst = 1
+	}
+	if burst > 32*1024 {
+		burst = 32 * 1024
+	}
+	return &rateLimitedReader{r: r, limiter: rate.NewLimiter(rate.Limit(bytesPerSec), int(burst))}
+}
+
+// ErrNotModified is returned by FetchIfModified when the file still matches the validators of the cached copy.
+var ErrNotModified = errors.New("artifact not modified")
+
+// Validators identify a version of a file, so that a request for it only transfers it if it changed.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ConditionalSource is a Source that supports conditional requests.
+type ConditionalSource interface {
+	Source
+	// FetchIfModified fetches the file with its validators, or returns ErrNotModified if it still matches the given
+	// validators.
+	FetchIfModified(filename string, v Validators) ([]byte, Validators, error)
+}
+
+// FetchIfModified fetches the file from the source with a conditional request if the source supports it.
+func FetchIfModified(src Source, filename string, v Validators) ([]byte, Validators, error) {
+	if c, ok := src.(ConditionalSource); ok {
+		return c.FetchIfModified(filename, v)
+	}
+	b, err := src.Fetch(filename)
+	return b, Validators{}, err
+}
+
+// channelPrefixes maps each release channel to the prefix of its artifacts, relative to the artifacts URL.
+var channelPrefixes = map[string]string{
+	"stable": "",
+	"beta":   "beta",
+	"dev":    "dev",
+}
+
+// IsChannel returns whether the given release channel exists.
+func IsChannel(channel string) bool {
+	_, ok := channelPrefixes[channel]
+	return ok
+}
+
+// ChannelArtifactsURL returns the artifacts URL(s) of the given release channel.
+func ChannelArtifactsURL(artifacts, channel string) string {
+	prefix := channelPrefixes[channel]
+	if prefix == "" {
+		return artifacts
+	}
+
+	mirrors := strings.Split(artifacts, ",")
+	for i, m := range mirrors {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		mirrors[i] = strings.TrimSuffix(m, "/") + "/" + prefix
+	}
+	return strings.Join(mirrors, ",")
+}
+
+// NewSource returns the Source for the given artifacts URL, which supports http(s)://, gs://<bucket>/<path>,
+// s3://<bucket>/<path>, az://<account>/<container>/<path> and file://<path>. The URL may be a comma-separated list of
+// mirrors, which are tried in order.
+func NewSource(artifacts string, opts *SourceOptions) (Source, error) {
+	if opts == nil {
+		opts = &SourceOptions{}
+	}
+	var mirrors []*mirror
+	for _, m := range strings.Split(artifacts, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		src, err := newSingleSource(m, opts)
+		if err != nil {
+			return nil, err
+		}
+		mirrors = append(mirrors, &mirror{url: m, src: src})
+	}
+
+	switch len(mirrors) {
+	case 0:
+		return nil, errors.New("no artifacts URL specified")
+	case 1:
+		return mirrors[0].src, nil
+	default:
+		return &mirroredSource{mirrors: mirrors, r: reporter{opts.Progress}}, nil
+	}
+}
+
+// newSingleSource returns the Source for a single artifacts URL, selected by the URL scheme. Absolute paths, including
+// Windows paths whose drive letter would parse as a scheme, are directories.
+func newSingleSource(artifacts string, opts *SourceOptions) (Source, error) {
+	if filepath.VolumeName(artifacts) != "" || filepath.IsAbs(artifacts) {
+		return &fileSource{dir: artifacts}, nil
+	}
+	u, err := url.Parse(artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &httpSource{dirURL: strings.TrimSuffix(artifacts, "/"), opts: opts}, nil
+	case "gs":
+		return &gcsSource{bucket: u.Host, prefix: strings.Trim(u.Path, "/"), opts: opts}, nil
+	case "s3":
+		return &s3Source{bucket: u.Host, prefix: strings.Trim(u.Path, "/"), opts: opts}, nil
+	case "az":
+		// az://<account>/<container>/<path>
+		parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("az artifacts URL must be of the form az://<account>/<container>/<path>: %s", artifacts)
+		}
+		src := &azureSource{account: u.Host, container: parts[0], opts: opts}
+		if len(parts) > 1 {
+			src.prefix = parts[1]
+		}
+		return src, nil
+	case "file", "":
+		// The host is the start of relative paths, such as file://./artifacts, and the drive of Windows paths, such as
+		// file://C:/artifacts.
+		return &fileSource{dir: u.Host + u.Path}, nil
+	default:
+		return nil, fmt.Errorf("unsupported artifacts URL scheme: %s", u.Scheme)
+	}
+}
+
+type mirror struct {
+	url string
+	src Source
+}
+
+// mirroredSource fetches artifacts from the first mirror that has them.
+type mirroredSource struct {
+	mirrors []*mirror
+	r       reporter
+}
+
+func (m *mirroredSource) Fetch(filename string) ([]byte, error) {
+	b, _, err := m.FetchIfModified(filename, Validators{})
+	return b, err
+}
+
+func (m *mirroredSource) FetchIfModified(filename string, v Validators) ([]byte, Validators, error) {
+	var errs []error
+	for _, mirror := range m.mirrors {
+		b, newV, err := FetchIfModified(mirror.src, filename, v)
+		if errors.Is(err, ErrNotModified) {
+			return nil, v, err
+		}
+		if err != nil {
+			m.r.errorf(err, "Failed to fetch %s from %s, trying next mirror", filename, mirror.url)
+			errs = append(errs, err)
+			continue
+		}
+		m.r.infof("Fetched %s from %s", filename, mirror.url)
+		return b, newV, nil
+	}
+	return nil, Validators{}, fmt.Errorf("failed to fetch %s from all mirrors: %w", filename, errors.Join(errs...))
+}
+
+// addHeaders adds the user-specified headers, in the format "Name: value", to the given request.
+func (o *SourceOptions) addHeaders(req *http.Request) error {
+	for _, h := range o.Headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return fmt.Errorf("artifacts header must be in the format \"Name: value\": %s", h)
+		}
+		req.Header.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return nil
+}
+
+func (o *SourceOptions) do(req *http.Request) ([]byte, error) {
+	b, _, err := o.doConditional(req, Validators{})
+	return b, err
+}
+
+// doConditional does the request, only transferring the file if it doesn't match the given validators. It returns
+// ErrNotModified if it does.
+func (o *SourceOptions) doConditional(req *http.Request, v Validators) ([]byte, Validators, error) {
+	if err := o.addHeaders(req); err != nil {
+		return nil, Validators{}, err
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+	resp, err := utils.HTTPClient().Do(req)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && (v.ETag != "" || v.LastModified != "") {
+		return nil, v, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, Validators{}, fmt.Errorf("failed to fetch %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	newV := Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	b, err := reporter{o.Progress}.download(path.Base(req.URL.Path), resp.ContentLength, o.limitDownloadRate(resp.Body))
+	return b, newV, err
+}
+
+// httpSource reads artifacts from a public HTTP(S) endpoint.
+type httpSource struct {
+	dirURL string
+	opts   *SourceOptions
+}
+
+func (h *httpSource) Fetch(filename string) ([]byte, error) {
+	b, _, err := h.FetchIfModified(filename, Validators{})
+	return b, err
+}
+
+func (h *httpSource) FetchIfModified(filename string, v Validators) ([]byte, Validators, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s", h.dirURL, filename), http.NoBody)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	return h.opts.doConditional(req, v)
+}
+
+// gcsSource reads artifacts directly from a (possibly private) GCS bucket using either the configured service account
+// key or application default credentials.
+type gcsSource struct {
+	bucket string
+	prefix string
+	opts   *SourceOptions
+}
+
+func (g *gcsSource) Fetch(filename string) ([]byte, error) {
+	opts := []option.ClientOption{option.WithUserAgent(utils.UserAgent())}
+	if g.opts.GCSCredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(g.opts.GCSCredentialsFile))
+	}
+
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	r, err := client.Bucket(g.bucket).Object(path.Join(g.prefix, filename)).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(g.opts.limitDownloadRate(r))
+}
+
+// s3Source reads artifacts from an S3 bucket. Requests are signed with SigV4 when AWS credentials are present in the
+// environment, otherwise the bucket must be public.
+type s3Source struct {
+	bucket string
+	prefix string
+	opts   *SourceOptions
+}
+
+func s3Region() string {
+	if r := os.Getenv("AWS_REGION"); r != "" {
+		return r
+	}
+	if r := os.Getenv("AWS_DEFAULT_REGION"); r != "" {
+		return r
+	}
+	return "us-east-1"
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// signS3Request adds an AWS SigV4 authorization header to the given (body-less) request.
+func signS3Request(req *http.Request, region, accessKey, secretKey, sessionToken string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	emptyHash := hex.EncodeToString(sha256.New().Sum(nil))
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", emptyHash)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", req.URL.Host, emptyHash, amzDate)
+	if sessionToken != "" {
+		req.Header.Set("x-amz-security-token", sessionToken)
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += fmt.Sprintf("x-amz-security-token:%s\n", sessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		emptyHash,
+	}, "\n")
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(crHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func (s *s3Source) Fetch(filename string) ([]byte, error) {
+	region := s3Region()
+	u := &url.URL{
+		Scheme: "https",
+		Host:   fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, region),
+		Path:   "/" + path.Join(s.prefix, filename),
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if accessKey := os.Getenv("AWS_ACCESS_KEY_ID"); accessKey != "" {
+		signS3Request(req, region, accessKey, os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"), time.Now())
+	}
+	return s.opts.do(req)
+}
+
+// azureSource reads artifacts from Azure Blob Storage. Private containers can be accessed by providing a SAS token
+// through AZURE_STORAGE_SAS_TOKEN.
+type azureSource struct {
+	account   string
+	container string
+	prefix    string
+	opts      *SourceOptions
+}
+
+func (a *azureSource) Fetch(filename string) ([]byte, error) {
+	u := &url.URL{
+		Scheme:   "https",
+		Host:     fmt.Sprintf("%s.blob.core.windows.net", a.account),
+		Path:     "/" + path.Join(a.container, a.prefix, filename),
+		RawQuery: strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?"),
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", "2020-04-08")
+	return a.opts.do(req)
+}
+
+// fileSource reads artifacts from a directory on the local filesystem.
+type fileSource struct {
+	dir string
+}
+
+func (f *fileSource) Fetch(filename string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(f.dir, filename))
+}
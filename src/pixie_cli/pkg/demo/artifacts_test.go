@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSingleSource_Directories(t *testing.T) {
+	tests := []struct {
+		name      string
+		artifacts string
+		dir       string
+		// windows is set for paths that are only absolute on Windows.
+		windows bool
+	}{
+		{name: "absolute path", artifacts: "/tmp/artifacts", dir: "/tmp/artifacts"},
+		{name: "absolute path that isn't a valid URL", artifacts: "/tmp/100%/artifacts", dir: "/tmp/100%/artifacts"},
+		{name: "relative path", artifacts: "artifacts", dir: "artifacts"},
+		{name: "file URL", artifacts: "file:///tmp/artifacts", dir: "/tmp/artifacts"},
+		{name: "relative file URL", artifacts: "file://./artifacts", dir: "./artifacts"},
+		{name: "file URL with a drive", artifacts: "file://C:/artifacts", dir: "C:/artifacts"},
+		{name: "windows path", artifacts: `C:\artifacts`, dir: `C:\artifacts`, windows: true},
+		{name: "windows path with forward slashes", artifacts: "C:/artifacts", dir: "C:/artifacts", windows: true},
+		{name: "UNC path", artifacts: `\\server\share\artifacts`, dir: `\\server\share\artifacts`, windows: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.windows && runtime.GOOS != "windows" {
+				t.Skip("only an absolute path on windows")
+			}
+			src, err := newSingleSource(tc.artifacts, &SourceOptions{})
+			require.NoError(t, err)
+			require.IsType(t, &fileSource{}, src)
+			assert.Equal(t, tc.dir, src.(*fileSource).dir)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBundleName returns the name of the YAML bundle of the app for amd64 clusters.
+func DefaultBundleName(appName string) string {
+	return appName + ".tar.gz"
+}
+
+// ReadBundle returns the YAMLs in a gzipped tarball, keyed by their path in it. Other files are skipped.
+func ReadBundle(targzBytes []byte) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(targzBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	outputYAMLs := map[string][]byte{}
+
+	for {
+		hdr, err := tarReader.Next()
+		if err == io.EOF {
+			break // End of archive
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasSuffix(hdr.Name, ".yaml") {
+			continue
+		}
+
+		contents, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		outputYAMLs[hdr.Name] = contents
+	}
+	return outputYAMLs, nil
+}
+
+// WriteBundle writes the given YAMLs to a gzipped tarball in the layout that ReadBundle reads. Entries are sorted and
+// have fixed timestamps, so that the same YAMLs always produce the same bundle.
+func WriteBundle(w io.Writer, yamls map[string][]byte) error {
+	names := make([]string, 0, len(yamls))
+	for name := range yamls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, name := range names {
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(yamls[name])),
+			ModTime:  time.Unix(0, 0),
+		}
+		if err := tarWriter.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tarWriter.Write(yamls[name]); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// FetchBundle downloads the YAML bundle with the given name, and returns the YAMLs in it.
+func (c *Client) FetchBundle(bundle string) (map[string][]byte, error) {
+	src, err := c.source()
+	if err != nil {
+		return nil, err
+	}
+	targzBytes, err := src.Fetch(bundle)
+	if err != nil {
+		return nil, err
+	}
+	return ReadBundle(targzBytes)
+}
+
+// AppYAMLs downloads the YAMLs of the app for amd64 clusters.
+func (c *Client) AppYAMLs(appName string) (map[string][]byte, error) {
+	return c.FetchBundle(DefaultBundleName(appName))
+}
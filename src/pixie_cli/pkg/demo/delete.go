@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// DeleteTimeout is how long deleting a demo app may take by default, including waiting for its namespace to
+// terminate.
+const DeleteTimeout = 5 * time.Minute
+
+var namespaceGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "namespaces",
+}
+
+// DeleteOptions configure how a demo app is deleted.
+type DeleteOptions struct {
+	// Namespace is the namespace the demo app was deployed to. It defaults to the name of the app.
+	Namespace string
+	// DryRun only prints the steps that would delete the demo app.
+	DryRun bool
+	// Timeout is how long the delete may take. It defaults to DeleteTimeout.
+	Timeout time.Duration
+	// Progress receives the progress of the steps of the delete. If it's nil, the progress is shown in the terminal.
+	Progress ProgressFunc
+}
+
+// Delete deletes the demo app from the cluster. If px created the app's namespace, the namespace is deleted
+// along with the app. Otherwise, only the resources that px deployed into it are.
+func (c *Client) Delete(appName string, opts *DeleteOptions) error {
+	if opts == nil {
+		opts = &DeleteOptions{}
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = appName
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DeleteTimeout
+	}
+
+	shared, err := c.IsSharedNamespace(namespace)
+	if err != nil {
+		return err
+	}
+	var deleteDemo []utils.Task
+	if shared {
+		// The namespace wasn't created by px, so only remove the resources that px deployed into it.
+		deleteDemo = []utils.Task{
+			utils.WithTarget(&task{fmt.Sprintf("Deleting demo app %s from namespace %s", appName, namespace), func(context.Context) error {
+				kube := c.kube()
+				_, err := k8s.DeleteInstance(kube.Clientset(), kube.Config(), Instance(namespace), 2*time.Minute)
+				return err
+			}}, fmt.Sprintf("resources labeled with instance %s", Instance(namespace))),
+		}
+	} else {
+		deleteDemo = []utils.Task{
+			utils.WithTarget(&task{fmt.Sprintf("Deleting demo app %s", appName), func(ctx context.Context) error {
+				return c.deleteNamespace(ctx, appName, namespace)
+			}}, fmt.Sprintf("namespace %s and resources labeled with instance %s", namespace, Instance(namespace))),
+		}
+	}
+	tr := utils.NewSerialTaskRunner(deleteDemo)
+	tr.SetTimeout(timeout)
+	tr.SetDryRun(opts.DryRun)
+	if opts.Progress != nil {
+		tr.SetEventHandler(opts.Progress)
+	}
+	return tr.RunAndMonitor()
+}
+
+// deleteNamespace deletes the resources of the demo app, and the namespace that px created for it. Only the resources
+// labeled with the namespace's instance are deleted outside of the namespace, so that other instances of the app, in
+// suffixed or shared namespaces, are kept. Demo apps deployed by older versions of px, which only label their
+// resources with the app, can only be in the namespace named after the app, so their resources are deleted along
+// with it.
+func (c *Client) deleteNamespace(ctx context.Context, appName, namespace string) error {
+	od := k8s.ObjectDeleter{
+		Clientset:  c.kube().Clientset(),
+		RestConfig: c.kube().Config(),
+		Timeout:    2 * time.Minute,
+	}
+
+	// Resources labeled as "pixie-demo-initial-cleanup" should be cleaned up first.
+	for _, selector := range deleteSelectors(appName, namespace) {
+		selector.MatchLabels["pixie-demo-initial-cleanup"] = "true"
+		if _, err := od.DeleteByLabel(metav1.FormatLabelSelector(selector)); err != nil {
+			return err
+		}
+	}
+
+	// Delete the remaining resources before namespace deletion, including the cluster-scoped ones that deleting the
+	// namespace doesn't remove.
+	for _, selector := range deleteSelectors(appName, namespace) {
+		if _, err := od.DeleteByLabel(metav1.FormatLabelSelector(selector)); err != nil {
+			return err
+		}
+	}
+	return c.removeNamespace(ctx, namespace)
+}
+
+// deleteSelectors returns the selectors of the resources of the demo app in the namespace: the ones labeled with the
+// namespace's instance and, in the namespace named after the app, the ones deployed by older versions of px without
+// an instance label.
+func deleteSelectors(appName, namespace string) []*metav1.LabelSelector {
+	instance := k8s.InstanceLabelSelector(Instance(namespace))
+	selectors := []*metav1.LabelSelector{&instance}
+	if namespace == appName {
+		selectors = append(selectors, &metav1.LabelSelector{
+			MatchLabels: map[string]string{ResourceLabel: appName},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: k8s.InstanceLabel, Operator: metav1.LabelSelectorOpDoesNotExist},
+			},
+		})
+	}
+	return selectors
+}
+
+// rollbackNamespace deletes the namespace that a deploy created, along with what was deployed into it. Unlike
+// deleteNamespace, it leaves the resources of the instance outside of the namespace alone, since a rolled back deploy
+// deletes the ones that it created itself.
+func (c *Client) rollbackNamespace(ctx context.Context, namespace string) error {
+	od := k8s.ObjectDeleter{
+		Namespace:  namespace,
+		Clientset:  c.kube().Clientset(),
+		RestConfig: c.kube().Config(),
+		Timeout:    2 * time.Minute,
+	}
+	initialCleanup := k8s.InstanceLabelSelector(Instance(namespace))
+	initialCleanup.MatchLabels["pixie-demo-initial-cleanup"] = "true"
+	if _, err := od.DeleteByLabel(metav1.FormatLabelSelector(&initialCleanup)); err != nil {
+		return err
+	}
+	return c.removeNamespace(ctx, namespace)
+}
+
+// removeNamespace deletes the namespace, and waits for it to terminate.
+func (c *Client) removeNamespace(ctx context.Context, namespace string) error {
+	err := c.kube().Clientset().CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+	if err != nil {
+		return err
+	}
+	return k8s.WaitForCondition(c.kube().Config(), namespaceGVR, "", namespace, k8s.Deleted, 180*time.Second)
+}
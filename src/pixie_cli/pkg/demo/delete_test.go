@@ -0,0 +1,234 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/demo/fake"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// apiObject is an object served by the API server of deleteAPIServer.
+type apiObject struct {
+	resource  string
+	namespace string
+	name      string
+	labels    map[string]string
+}
+
+func (o *apiObject) String() string {
+	if o.namespace == "" {
+		return o.resource + "/" + o.name
+	}
+	return o.resource + "/" + o.namespace + "/" + o.name
+}
+
+func (o *apiObject) unstructured() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       apiResources[o.resource].kind,
+		"metadata":   map[string]interface{}{"name": o.name, "namespace": o.namespace, "labels": o.labels},
+	}
+}
+
+var apiResources = map[string]struct {
+	kind       string
+	namespaced bool
+}{
+	"configmaps":        {kind: "ConfigMap", namespaced: true},
+	"persistentvolumes": {kind: "PersistentVolume"},
+}
+
+// deleteAPIServer is an API server that lists and deletes the given objects, for the dynamic clients that delete
+// the resources of demo apps. It records the objects in the order they are deleted.
+type deleteAPIServer struct {
+	mu      sync.Mutex
+	objects []*apiObject
+	deleted []string
+}
+
+func newDeleteAPIServer(t *testing.T, objects ...*apiObject) (*deleteAPIServer, *rest.Config) {
+	s := &deleteAPIServer{objects: objects}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, &rest.Config{Host: srv.URL}
+}
+
+func (s *deleteAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api":
+		writeJSON(w, map[string]interface{}{"kind": "APIVersions", "versions": []string{"v1"}})
+		return
+	case "/apis":
+		writeJSON(w, map[string]interface{}{"kind": "APIGroupList", "apiVersion": "v1", "groups": []interface{}{}})
+		return
+	case "/api/v1":
+		var list []map[string]interface{}
+		for name, r := range apiResources {
+			list = append(list, map[string]interface{}{
+				"name": name, "kind": r.kind, "namespaced": r.namespaced, "verbs": []string{"delete", "get", "list"},
+			})
+		}
+		writeJSON(w, map[string]interface{}{"kind": "APIResourceList", "groupVersion": "v1", "resources": list})
+		return
+	}
+
+	// The remaining paths are /api/v1/[namespaces/<namespace>/]<resource>[/<name>].
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	namespace := ""
+	if len(parts) > 2 && parts[0] == "namespaces" {
+		namespace, parts = parts[1], parts[2:]
+	}
+	resource, name := parts[0], ""
+	if len(parts) > 1 {
+		name = parts[1]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name != "" {
+		for i, o := range s.objects {
+			if o.resource != resource || o.namespace != namespace || o.name != name {
+				continue
+			}
+			if r.Method == http.MethodDelete {
+				s.objects = append(s.objects[:i], s.objects[i+1:]...)
+				s.deleted = append(s.deleted, o.String())
+				writeJSON(w, map[string]interface{}{"kind": "Status", "apiVersion": "v1", "status": "Success"})
+				return
+			}
+			writeJSON(w, o.unstructured())
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]interface{}{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404})
+		return
+	}
+
+	labelSelector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	fieldSelector, err := fields.ParseSelector(r.URL.Query().Get("fieldSelector"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	items := []interface{}{}
+	for _, o := range s.objects {
+		if o.resource != resource || (namespace != "" && o.namespace != namespace) ||
+			!labelSelector.Matches(labels.Set(o.labels)) ||
+			!fieldSelector.Matches(fields.Set{"metadata.name": o.name, "metadata.namespace": o.namespace}) {
+			continue
+		}
+		items = append(items, o.unstructured())
+	}
+	if r.URL.Query().Get("watch") == "true" {
+		// Nothing changes once the objects are listed, so watches only end with the request.
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		s.mu.Unlock()
+		<-r.Context().Done()
+		s.mu.Lock()
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       apiResources[resource].kind + "List",
+		"metadata":   map[string]interface{}{"resourceVersion": "1"},
+		"items":      items,
+	})
+}
+
+func (s *deleteAPIServer) remaining() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, o := range s.objects {
+		names = append(names, o.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// apiServerKube is a fake.Kube whose dynamic clients are served by an API server.
+type apiServerKube struct {
+	*fake.Kube
+	config *rest.Config
+}
+
+func (k *apiServerKube) Config() *rest.Config {
+	return k.config
+}
+
+func TestDelete_LegacyDemo(t *testing.T) {
+	appLabels := func(extra map[string]string) map[string]string {
+		l := map[string]string{demo.ResourceLabel: "px-sock-shop"}
+		for k, v := range extra {
+			l[k] = v
+		}
+		return l
+	}
+	// The demo app in px-sock-shop was deployed by an older version of px, which didn't label its resources with the
+	// instance. Another instance of the app is deployed to px-sock-shop-2.
+	server, config := newDeleteAPIServer(t,
+		&apiObject{resource: "configmaps", namespace: "px-sock-shop", name: "carts", labels: appLabels(nil)},
+		&apiObject{resource: "configmaps", namespace: "px-sock-shop", name: "cluster", labels: appLabels(map[string]string{"pixie-demo-initial-cleanup": "true"})},
+		&apiObject{resource: "persistentvolumes", name: "carts-db", labels: appLabels(nil)},
+		&apiObject{resource: "persistentvolumes", name: "carts-db-2", labels: appLabels(map[string]string{k8s.InstanceLabel: "px-sock-shop-2"})},
+		&apiObject{resource: "configmaps", namespace: "px-sock-shop-2", name: "carts", labels: appLabels(map[string]string{k8s.InstanceLabel: "px-sock-shop-2"})},
+	)
+	kube := &apiServerKube{Kube: fake.NewKube(namespace("px-sock-shop", nil, nil)), config: config}
+	client := &demo.Client{Kube: kube}
+
+	err := client.Delete("px-sock-shop", &demo.DeleteOptions{Progress: func(*utils.TaskEvent) {}})
+	require.NoError(t, err)
+
+	require.Len(t, server.deleted, 3)
+	assert.Equal(t, "configmaps/px-sock-shop/cluster", server.deleted[0])
+	assert.ElementsMatch(t, []string{"configmaps/px-sock-shop/carts", "persistentvolumes/carts-db"}, server.deleted[1:])
+	assert.Equal(t, []string{"configmaps/px-sock-shop-2/carts", "persistentvolumes/carts-db-2"}, server.remaining())
+
+	_, err = kube.Clients.CoreV1().Namespaces().Get(context.Background(), "px-sock-shop", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
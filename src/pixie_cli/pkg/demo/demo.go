@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package demo downloads the Pixie demo apps from their artifacts, and deploys them to and deletes them from
+// Kubernetes clusters. The px demo commands are a thin wrapper around it.
+package demo
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// progressThreshold is the smallest download, in bytes, that shows a progress bar.
+const progressThreshold = 1 << 20
+
+// ProgressFunc receives the progress of an operation as task events: the steps that start and complete, and details
+// such as the mirror that an artifact was fetched from. It's called one event at a time.
+type ProgressFunc func(e *utils.TaskEvent)
+
+// Client downloads the demo apps from an artifacts location, and deploys them to a cluster.
+type Client struct {
+	// Artifacts is the location of the demo apps, see NewSource.
+	Artifacts string
+	// SourceOptions configure how the artifacts are fetched.
+	SourceOptions SourceOptions
+	// Source fetches the artifacts. It's created from Artifacts and SourceOptions if it's nil.
+	Source Source
+	// Channel is the release channel of the artifacts, which deployed demo apps are labeled with.
+	Channel string
+	// ManifestOverridesFile is the path of a JSON file that is deep-merged into the downloaded manifest, if it's set.
+	ManifestOverridesFile string
+	// CacheDir is the folder that the manifest is cached in, so that it's only transferred again once it changes, and
+	// can be used when the artifacts can't be reached. The manifest isn't cached if it's empty.
+	CacheDir string
+	// Progress receives the progress of downloads. If it's nil, the progress is shown in the terminal.
+	Progress ProgressFunc
+	// Kube is the cluster that demo apps are deployed to. It's the current cluster of the kubeconfig if it's nil.
+	Kube Kube
+
+	sourceOnce sync.Once
+	sourceErr  error
+}
+
+// source returns the source of the artifacts, creating it on first use.
+func (c *Client) source() (Source, error) {
+	c.sourceOnce.Do(func() {
+		if c.Source != nil {
+			return
+		}
+		opts := c.SourceOptions
+		if opts.Progress == nil {
+			opts.Progress = c.Progress
+		}
+		c.Source, c.sourceErr = NewSource(c.Artifacts, &opts)
+	})
+	return c.Source, c.sourceErr
+}
+
+// kube returns the cluster that demo apps are deployed to.
+func (c *Client) kube() Kube {
+	if c.Kube == nil {
+		return SharedKube()
+	}
+	return c.Kube
+}
+
+// reporter shows messages and download progress, either in the terminal or as task events.
+type reporter struct {
+	progress ProgressFunc
+}
+
+// infof shows an informational message.
+func (r reporter) infof(format string, args ...interface{}) {
+	if r.progress == nil {
+		utils.Infof(format, args...)
+		return
+	}
+	r.progress(&utils.TaskEvent{Type: utils.TaskProgress, Detail: fmt.Sprintf(format, args...)})
+}
+
+// errorf shows an error that doesn't fail the operation.
+func (r reporter) errorf(err error, format string, args ...interface{}) {
+	if r.progress == nil {
+		utils.WithError(err).Errorf(format, args...)
+		return
+	}
+	r.progress(&utils.TaskEvent{Type: utils.TaskProgress, Detail: fmt.Sprintf(format, args...), Error: err.Error()})
+}
+
+// download reads the body of a download of the given size, showing its progress if it's large.
+func (r reporter) download(name string, size int64, body io.Reader) ([]byte, error) {
+	if size < progressThreshold {
+		return io.ReadAll(body)
+	}
+	task := fmt.Sprintf("Downloading %s", name)
+	if r.progress == nil {
+		bar := components.NewProgressBar(task, size)
+		b, err := io.ReadAll(bar.ProxyReader(body))
+		bar.Complete(err)
+		return b, err
+	}
+
+	r.progress(&utils.TaskEvent{Type: utils.TaskStarted, Task: task})
+	b, err := io.ReadAll(&progressReader{r: body, size: size, report: func(fraction float64) {
+		r.progress(&utils.TaskEvent{Type: utils.TaskProgress, Task: task, Progress: fraction})
+	}})
+	e := &utils.TaskEvent{Type: utils.TaskSucceeded, Task: task}
+	if err != nil {
+		e.Type = utils.TaskFailed
+		e.Error = err.Error()
+	}
+	r.progress(e)
+	return b, err
+}
+
+// progressReader reports the fraction of its size that was read, once per percent.
+type progressReader struct {
+	r           io.Reader
+	size        int64
+	read        int64
+	lastPercent int64
+	report      func(fraction float64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if percent := p.read * 100 / p.size; percent != p.lastPercent {
+		p.lastPercent = percent
+		p.report(float64(p.read) / float64(p.size))
+	}
+	return n, err
+}
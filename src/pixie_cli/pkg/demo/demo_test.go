@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/demo/fake"
+)
+
+func namespace(name string, labels, annotations map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations}}
+}
+
+func TestNamespaceState(t *testing.T) {
+	client := &demo.Client{Kube: fake.NewKube(
+		namespace("px-sock-shop", map[string]string{demo.ChannelLabel: "stable"}, nil),
+		namespace("px-kafka", nil, map[string]string{demo.SharedNamespaceAnnotation: "true"}),
+		namespace("default", nil, nil),
+	)}
+
+	tests := []struct {
+		namespace string
+		exists    bool
+		managed   bool
+		shared    bool
+	}{
+		{namespace: "px-sock-shop", exists: true, managed: true},
+		{namespace: "px-kafka", exists: true, shared: true},
+		{namespace: "default", exists: true},
+		{namespace: "px-online-boutique"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.namespace, func(t *testing.T) {
+			exists, managed, err := client.NamespaceState(tc.namespace)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exists, exists)
+			assert.Equal(t, tc.managed, managed)
+			assert.Equal(t, tc.exists, client.NamespaceExists(tc.namespace))
+
+			shared, err := client.IsSharedNamespace(tc.namespace)
+			require.NoError(t, err)
+			assert.Equal(t, tc.shared, shared)
+		})
+	}
+}
+
+func TestNextFreeNamespace(t *testing.T) {
+	client := &demo.Client{Kube: fake.NewKube(
+		namespace("px-sock-shop", nil, nil),
+		namespace("px-sock-shop-2", nil, nil),
+		namespace("px-sock-shop-3", nil, nil),
+	)}
+
+	ns, err := client.NextFreeNamespace("px-sock-shop")
+	require.NoError(t, err)
+	assert.Equal(t, "px-sock-shop-4", ns)
+}
+
+func TestAppNamespaces(t *testing.T) {
+	client := &demo.Client{Kube: fake.NewKube(
+		namespace("px-sock-shop-3", map[string]string{demo.AppLabel: "px-sock-shop"}, nil),
+		namespace("px-sock-shop-2", map[string]string{demo.AppLabel: "px-sock-shop"}, nil),
+		namespace("px-sock-shop-4", nil, nil),
+		namespace("px-kafka", map[string]string{demo.AppLabel: "px-kafka"}, nil),
+	)}
+
+	namespaces, err := client.AppNamespaces("px-sock-shop")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"px-sock-shop-2", "px-sock-shop-3"}, namespaces)
+
+	namespaces, err = client.AppNamespaces("px-online-boutique")
+	require.NoError(t, err)
+	assert.Empty(t, namespaces)
+}
+
+func TestManifestFromSource(t *testing.T) {
+	src := &fake.Source{Files: map[string][]byte{
+		demo.ManifestFile: []byte(`{"px-sock-shop": {"description": "Sock shop demo", "instructions": []}}`),
+	}}
+	client := &demo.Client{Artifacts: "fake://", Source: src}
+
+	m, err := client.Manifest()
+	require.NoError(t, err)
+	require.Contains(t, m, "px-sock-shop")
+	assert.Equal(t, "Sock shop demo", m["px-sock-shop"].Description)
+	assert.Equal(t, []string{demo.ManifestFile}, src.Fetched())
+
+	_, err = client.AppYAMLs("px-sock-shop")
+	assert.Error(t, err)
+}
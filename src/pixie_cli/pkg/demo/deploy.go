@@ -0,0 +1,487 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// kindPhases is the order in which kinds are applied. Kinds that are not listed, such as workloads and custom
+// resources, are applied in the last phase.
+var kindPhases = map[string]int{
+	"Namespace":                0,
+	"CustomResourceDefinition": 1,
+	"ServiceAccount":           2,
+	"ClusterRole":              2,
+	"ClusterRoleBinding":       2,
+	"Role":                     2,
+	"RoleBinding":              2,
+	"ConfigMap":                3,
+	"Secret":                   3,
+	"PersistentVolumeClaim":    3,
+	"StorageClass":             3,
+	"Service":                  4,
+}
+
+const defaultPhase = 5
+
+// previousFieldManager is the field manager that demo apps were applied with before they were applied with
+// k8s.DefaultFieldManager. Redeploys take over its fields.
+const previousFieldManager = "px-demo"
+
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// DeployOptions configure how a demo app is deployed.
+type DeployOptions struct {
+	// Namespace is the namespace to deploy the demo app to. It defaults to the name of the app.
+	Namespace string
+	// NamespaceAnnotations are set on the demo namespace when it is created.
+	NamespaceAnnotations map[string]string
+	// Dependencies are the dependencies of the app in the manifest, which must be installed on the cluster.
+	Dependencies map[string]bool
+	// Force re-applies the YAMLs into an existing namespace instead of failing.
+	Force bool
+	// Prune deletes demo resources in an existing namespace that are not part of the re-applied YAMLs.
+	Prune bool
+	// ForceConflicts takes ownership of fields that are owned by other field managers.
+	ForceConflicts bool
+	// SCC is the SecurityContextConstraints granted to the demo app's service accounts on OpenShift clusters.
+	SCC string
+	// MultiNamespace deploys objects that declare a namespace to that namespace instead of the demo namespace.
+	MultiNamespace bool
+	// Checkpoint records the completed steps of the deploy, so that it can be resumed if it fails. The steps that
+	// a loaded checkpoint records as completed are skipped.
+	Checkpoint *utils.Checkpoint
+	// ConfirmRollback is called when the deploy is interrupted, to decide whether to delete what it already created.
+	// If it's nil, an interrupted deploy is rolled back unless it can be resumed from its checkpoint.
+	ConfirmRollback func() bool
+	// Progress receives the progress of the steps of the deploy. If it's nil, the progress is shown in the terminal.
+	Progress ProgressFunc
+}
+
+// task is a step of a deploy or delete.
+type task struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (t *task) Name() string {
+	return t.name
+}
+
+func (t *task) Run(ctx context.Context) error {
+	return t.run(ctx)
+}
+
+// Deploy deploys the given YAMLs of the demo app to the cluster, and returns the outcome of applying each
+// resource. Unless it can be resumed from its checkpoint, a deploy that fails or is interrupted deletes the namespace
+// that it created, along with the resources that it created outside of the namespace. Once interrupted, the deploy
+// stops applying resources.
+func (c *Client) Deploy(appName string, yamls map[string][]byte, opts *DeployOptions) ([]*k8s.AppliedResource, error) {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+	kubeConfig := c.kube().Config()
+	clientset := c.kube().Clientset()
+
+	// Check deps.
+	if opts.Dependencies["cert-manager"] {
+		certMgrExists, err := c.certManagerExists()
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return nil, err
+		}
+
+		if !certMgrExists || k8s_errors.IsNotFound(err) {
+			return nil, ErrCertManagerMissing
+		}
+	}
+
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = appName
+	}
+	channel := c.Channel
+	if channel == "" {
+		channel = "stable"
+	}
+	nsExists := c.NamespaceExists(namespace)
+	resumed := opts.Checkpoint != nil && opts.Checkpoint.Saved()
+	if nsExists && !opts.Force && !resumed {
+		return nil, &NamespaceError{App: appName, Namespace: namespace}
+	}
+	// A resumed deploy that created the namespace runs the same steps, so that the completed ones are skipped.
+	createNamespaceName := fmt.Sprintf("Creating namespace %s", namespace)
+	createsNamespace := !nsExists || (resumed && opts.Checkpoint.Completed(createNamespaceName))
+
+	// The namespace is set up first, then the YAMLs are deployed once the namespace's permissions are granted,
+	// and finally stale resources are pruned.
+	var applied []*k8s.AppliedResource
+	tr := utils.NewDAGTaskRunner(0)
+	var namespaceTask utils.Task
+	if createsNamespace {
+		namespaceTask = utils.WithRetry(&task{createNamespaceName, func(ctx context.Context) error {
+			labels := map[string]string{
+				AppLabel:     appName,
+				ChannelLabel: channel,
+			}
+			if err := c.createNamespace(ctx, namespace, labels, opts.NamespaceAnnotations); err != nil {
+				return err
+			}
+			// Unless it can be resumed, a failed or interrupted deploy deletes the namespace it created, so that it
+			// doesn't leave a half-deployed demo app behind. The resources it created outside of the namespace are
+			// deleted by their own cleanup, so that the rollback only removes what this deploy created.
+			utils.RegisterCleanup(ctx, fmt.Sprintf("Deleting namespace %s", namespace), func(ctx context.Context) error {
+				return c.rollbackNamespace(ctx, namespace)
+			})
+			return nil
+		}}, utils.DefaultRetryPolicy)
+	} else {
+		namespaceTask = utils.WithRetry(&task{fmt.Sprintf("Marking namespace %s as shared", namespace), func(context.Context) error {
+			return c.markSharedNamespace(namespace)
+		}}, utils.DefaultRetryPolicy)
+	}
+	tr.AddTask(namespaceTask)
+	deployDeps := []utils.Task{namespaceTask}
+	if opts.SCC != "" {
+		isOpenShift, err := k8s.IsOpenShift(c.kube().Discovery())
+		if err != nil {
+			reporter{opts.Progress}.errorf(err, "Failed to check whether the cluster runs OpenShift")
+		}
+		if isOpenShift {
+			sccTask := utils.WithRetry(&task{fmt.Sprintf("Granting %s SecurityContextConstraints to namespace %s", opts.SCC, namespace), func(context.Context) error {
+				if err := k8s.GrantSCC(clientset, namespace, opts.SCC, map[string]string{
+					ResourceLabel:     appName,
+					k8s.InstanceLabel: Instance(namespace),
+				}); err != nil {
+					return &SCCGrantError{Namespace: namespace, SCC: opts.SCC, Err: err}
+				}
+				return nil
+			}}, utils.DefaultRetryPolicy)
+			tr.AddTask(sccTask, namespaceTask)
+			deployDeps = append(deployDeps, sccTask)
+		}
+	}
+	deployTask := utils.WithProgress(&task{fmt.Sprintf("Deploying %s YAMLs", appName), func(ctx context.Context) error {
+		phases, files, err := orderResources(yamls)
+		if err != nil {
+			return err
+		}
+		if createsNamespace && resumed && opts.Checkpoint.Completed(createNamespaceName) {
+			// The namespace was created by the deploy that is being resumed, so it's deleted if this one is rolled back,
+			// along with the resources of the instance outside of it.
+			utils.RegisterCleanup(ctx, fmt.Sprintf("Deleting demo app %s", appName), func(ctx context.Context) error {
+				return c.deleteNamespace(ctx, appName, namespace)
+			})
+		}
+		// The resources that deleting the namespace doesn't remove are tracked, so that they are deleted too if the
+		// deploy is rolled back.
+		ownedNamespace := ""
+		if createsNamespace {
+			ownedNamespace = namespace
+		}
+		var created []*k8s.Resource
+		trackCreated := func(resources []*k8s.Resource, results []*k8s.AppliedResource) {
+			newlyCreated := createdResources(resources, results, ownedNamespace)
+			if len(newlyCreated) == 0 {
+				return
+			}
+			if len(created) == 0 {
+				utils.RegisterCleanup(ctx, fmt.Sprintf("Deleting the resources created for demo app %s", appName), func(ctx context.Context) error {
+					_, err := k8s.DeleteResources(clientset, kubeConfig, created, 2*time.Minute)
+					return err
+				})
+			}
+			created = append(created, newlyCreated...)
+		}
+
+		progress := newFileProgress(ctx, files)
+		for _, resources := range phases {
+			resources := resources
+			// Once the deploy is interrupted, the remaining phases aren't applied.
+			if err := ctx.Err(); err != nil {
+				progress.failed(applied)
+				return err
+			}
+			bo := backoff.NewExponentialBackOff()
+			bo.MaxElapsedTime = 5 * time.Minute
+
+			op := func() error {
+				results, err := k8s.ServerSideApplyResourcesContext(ctx, clientset, kubeConfig, resources, namespace, &k8s.ServerSideApplyOptions{
+					Force:                   opts.ForceConflicts,
+					Instance:                Instance(namespace),
+					PreviousFieldManagers:   []string{previousFieldManager},
+					NamespaceLabels:         map[string]string{ResourceLabel: appName},
+					RespectObjectNamespaces: opts.MultiNamespace,
+				})
+				trackCreated(resources, results)
+				applied = mergeAppliedResources(applied, results)
+				var conflictErr *k8s.ApplyConflictError
+				if errors.As(err, &conflictErr) {
+					return backoff.Permanent(err)
+				}
+				return err
+			}
+
+			err := backoff.Retry(op, backoff.WithContext(bo, ctx))
+			if err == nil {
+				err = waitForCRDsEstablished(kubeConfig, resources)
+			}
+			if err != nil {
+				progress.failed(applied)
+				return err
+			}
+			progress.resourcesApplied(resources)
+		}
+		return nil
+	}})
+	tr.AddTask(deployTask, deployDeps...)
+	if nsExists && opts.Force && opts.Prune {
+		tr.AddTask(&task{fmt.Sprintf("Pruning stale %s resources", appName), func(context.Context) error {
+			if len(applied) == 0 {
+				// The YAMLs were applied by the deploy that is being resumed, so the resources to keep aren't known.
+				return nil
+			}
+			selector := k8s.InstanceLabelSelector(Instance(namespace))
+			pruned, err := k8s.Prune(clientset, kubeConfig, namespace, applied, &k8s.PruneOptions{
+				Selector: metav1.FormatLabelSelector(&selector),
+				Timeout:  2 * time.Minute,
+			})
+			applied = append(applied, pruned...)
+			return err
+		}}, deployTask)
+	}
+
+	// Deploys can take minutes, so show which tasks the time was spent on.
+	tr.SetShowSummary(true)
+	tr.SetCheckpoint(opts.Checkpoint)
+	tr.SetConfirmRollback(opts.ConfirmRollback)
+	if opts.Progress != nil {
+		tr.SetEventHandler(opts.Progress)
+	}
+	return applied, tr.RunAndMonitor()
+}
+
+func (c *Client) certManagerExists() (bool, error) {
+	deps, err := c.kube().Clientset().AppsV1().Deployments("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, d := range deps.Items {
+		if d.Name == "cert-manager" {
+			return true, nil
+		}
+	}
+
+	return false, err
+}
+
+// mergeAppliedResources adds the results of an apply attempt to the existing results. Resources that
+// were created by an earlier, failed attempt are still reported as created, and resources that failed
+// in an earlier attempt are reported with their latest outcome.
+func mergeAppliedResources(applied, results []*k8s.AppliedResource) []*k8s.AppliedResource {
+	for _, r := range results {
+		found := false
+		for i, a := range applied {
+			if a.Kind == r.Kind && a.Name == r.Name && a.Namespace == r.Namespace {
+				found = true
+				if a.Status == k8s.StatusFailed {
+					applied[i] = r
+				}
+				break
+			}
+		}
+		if !found {
+			applied = append(applied, r)
+		}
+	}
+	return applied
+}
+
+// createdResources returns the resources that the results of an apply report as created, except for those in the
+// given namespace, which are deleted along with it. The namespaces that the apply created for the resources are
+// returned too.
+func createdResources(resources []*k8s.Resource, results []*k8s.AppliedResource, skipNamespace string) []*k8s.Resource {
+	var created []*k8s.Resource
+	for _, a := range results {
+		if a.Status != k8s.StatusCreated || (skipNamespace != "" && a.Namespace == skipNamespace) {
+			continue
+		}
+		found := false
+		for _, r := range resources {
+			if r.GVK.Kind == a.Kind && r.Object.GetName() == a.Name && (a.Namespace == "" || r.Object.GetNamespace() == a.Namespace) {
+				created = append(created, r)
+				found = true
+				break
+			}
+		}
+		if !found && a.Kind == "Namespace" {
+			ns := &unstructured.Unstructured{}
+			ns.SetAPIVersion("v1")
+			ns.SetKind("Namespace")
+			ns.SetName(a.Name)
+			created = append(created, &k8s.Resource{Object: ns, GVK: &schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}})
+		}
+	}
+	return created
+}
+
+// orderResources parses the demo YAMLs and groups the resources into phases that must be applied in order. Within a
+// phase, resources keep the order of the (sorted) YAML files. It also returns the file that each resource was parsed
+// from.
+func orderResources(yamls map[string][]byte) ([][]*k8s.Resource, map[*k8s.Resource]string, error) {
+	names := make([]string, 0, len(yamls))
+	for name := range yamls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	phases := make([][]*k8s.Resource, defaultPhase+1)
+	files := make(map[*k8s.Resource]string)
+	for _, name := range names {
+		resources, err := k8s.GetResourcesFromNamedYAML(name, bytes.NewReader(yamls[name]))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range resources {
+			files[r] = name
+			phase, ok := kindPhases[r.GVK.Kind]
+			if !ok {
+				phase = defaultPhase
+			}
+			phases[phase] = append(phases[phase], r)
+		}
+	}
+
+	ordered := make([][]*k8s.Resource, 0, len(phases))
+	for _, p := range phases {
+		if len(p) != 0 {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered, files, nil
+}
+
+// errNotApplied is the error of the files that weren't applied because another file failed.
+var errNotApplied = errors.New("not applied")
+
+// fileProgress shows the YAML files of a demo app as subtasks of the task that applies them. A file is complete once
+// all of its resources are applied, which may take several phases. The task's progress is the fraction of the
+// resources that have been applied.
+type fileProgress struct {
+	ctx       context.Context
+	files     map[*k8s.Resource]string
+	subtasks  map[string]*utils.Subtask
+	remaining map[string]int
+	applied   int
+}
+
+func newFileProgress(ctx context.Context, files map[*k8s.Resource]string) *fileProgress {
+	p := &fileProgress{
+		ctx:       ctx,
+		files:     files,
+		subtasks:  make(map[string]*utils.Subtask),
+		remaining: make(map[string]int),
+	}
+	for _, f := range files {
+		p.remaining[f]++
+	}
+	names := make([]string, 0, len(p.remaining))
+	for f := range p.remaining {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	for _, f := range names {
+		p.subtasks[f] = utils.StartSubtask(ctx, f)
+	}
+	return p
+}
+
+// resourcesApplied completes the files whose resources have all been applied.
+func (p *fileProgress) resourcesApplied(resources []*k8s.Resource) {
+	p.applied += len(resources)
+	utils.ReportProgress(p.ctx, float64(p.applied)/float64(len(p.files)))
+	for _, r := range resources {
+		f := p.files[r]
+		p.remaining[f]--
+		if p.remaining[f] == 0 {
+			p.subtasks[f].Complete(nil)
+			delete(p.subtasks, f)
+		}
+	}
+}
+
+// failed completes the remaining files. Files with resources that failed to apply show the first failure, and the
+// other files are marked as not applied.
+func (p *fileProgress) failed(results []*k8s.AppliedResource) {
+	failures := make(map[string]error)
+	for r, f := range p.files {
+		if _, ok := failures[f]; ok {
+			continue
+		}
+		for _, a := range results {
+			if a.Status == k8s.StatusFailed && a.Kind == r.GVK.Kind && a.Name == r.Object.GetName() {
+				failures[f] = fmt.Errorf("%s %s: %w", strings.ToLower(a.Kind), a.Name, a.Err)
+				break
+			}
+		}
+	}
+	for f, s := range p.subtasks {
+		if err, ok := failures[f]; ok {
+			s.Complete(err)
+		} else {
+			s.Complete(errNotApplied)
+		}
+	}
+	p.subtasks = nil
+}
+
+// waitForCRDsEstablished waits until all CRDs in the given resources are established, so that custom resources that
+// depend on them can be applied.
+func waitForCRDsEstablished(config *rest.Config, resources []*k8s.Resource) error {
+	for _, r := range resources {
+		if r.GVK.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		if err := k8s.WaitForCondition(config, crdGVR, "", r.Object.GetName(), k8s.HasCondition("Established"), 2*time.Minute); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"errors"
+	"fmt"
+
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+)
+
+var (
+	// ErrNamespaceAlreadyExists is returned when the namespace that a demo app is deployed to already exists.
+	ErrNamespaceAlreadyExists = exitcodes.New(exitcodes.Usage, "namespace already exists")
+	// ErrCertManagerMissing is returned when a demo app needs cert-manager, and the cluster doesn't run it.
+	ErrCertManagerMissing = exitcodes.New(exitcodes.Cluster, "cert-manager does not exist")
+	// ErrSCCGrantFailed is returned when the SecurityContextConstraints that a demo app needs can't be granted.
+	ErrSCCGrantFailed = errors.New("failed to grant SecurityContextConstraints")
+	// ErrManifestUnavailable is returned when the manifest of the demo apps can't be downloaded or parsed.
+	ErrManifestUnavailable = errors.New("demo manifest unavailable")
+)
+
+// NamespaceError is returned when the namespace that a demo app is deployed to already exists. It matches
+// ErrNamespaceAlreadyExists.
+type NamespaceError struct {
+	App       string
+	Namespace string
+}
+
+func (e *NamespaceError) Error() string {
+	return fmt.Sprintf("namespace %s already exists", e.Namespace)
+}
+
+// Is returns whether the target is ErrNamespaceAlreadyExists.
+func (e *NamespaceError) Is(target error) bool {
+	return target == ErrNamespaceAlreadyExists
+}
+
+// ExitCode returns the exit code of ErrNamespaceAlreadyExists, since the namespace is chosen by the user.
+func (e *NamespaceError) ExitCode() int {
+	return exitcodes.Usage
+}
+
+// SCCGrantError is returned when the SecurityContextConstraints that a demo app needs on OpenShift can't be granted to
+// its namespace. It matches ErrSCCGrantFailed.
+type SCCGrantError struct {
+	Namespace string
+	SCC       string
+	Err       error
+}
+
+func (e *SCCGrantError) Error() string {
+	return fmt.Sprintf("%s %s: %v", ErrSCCGrantFailed, e.SCC, e.Err)
+}
+
+// Is returns whether the target is ErrSCCGrantFailed.
+func (e *SCCGrantError) Is(target error) bool {
+	return target == ErrSCCGrantFailed
+}
+
+func (e *SCCGrantError) Unwrap() error {
+	return e.Err
+}
+
+// ManifestError is returned when the manifest of the demo apps at the artifacts location can't be downloaded or
+// parsed. It matches ErrManifestUnavailable.
+type ManifestError struct {
+	Artifacts string
+	Err       error
+}
+
+func (e *ManifestError) Error() string {
+	return e.Err.Error()
+}
+
+// Is returns whether the target is ErrManifestUnavailable.
+func (e *ManifestError) Is(target error) bool {
+	return target == ErrManifestUnavailable
+}
+
+func (e *ManifestError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+)
+
+func TestErrors_ExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "namespace exists", err: demo.ErrNamespaceAlreadyExists, want: exitcodes.Usage},
+		{name: "namespace error", err: &demo.NamespaceError{App: "px-sock-shop", Namespace: "px-sock-shop"}, want: exitcodes.Usage},
+		{name: "wrapped namespace error", err: fmt.Errorf("deploy: %w", &demo.NamespaceError{Namespace: "px-sock-shop"}), want: exitcodes.Usage},
+		{name: "cert-manager missing", err: demo.ErrCertManagerMissing, want: exitcodes.Cluster},
+		{name: "wrapped cert-manager missing", err: fmt.Errorf("deploy: %w", demo.ErrCertManagerMissing), want: exitcodes.Cluster},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, exitcodes.ForError(test.err))
+		})
+	}
+}
+
+func TestNamespaceError_Is(t *testing.T) {
+	err := fmt.Errorf("deploy: %w", &demo.NamespaceError{Namespace: "px-sock-shop"})
+	assert.True(t, errors.Is(err, demo.ErrNamespaceAlreadyExists))
+	assert.Equal(t, "deploy: namespace px-sock-shop already exists", err.Error())
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package fake has fakes of the cluster, the artifacts and the user that demo apps and the px demo commands depend
+// on, for tests.
+package fake
+
+import (
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+// Kube is a demo.Kube whose clientset is a fake clientset, which keeps the objects of the cluster in memory. The
+// resources of demo apps are applied and deleted with dynamic clients, which can't be created from its config, so
+// only what uses the clientset works with it.
+type Kube struct {
+	Clients *k8sfake.Clientset
+}
+
+// NewKube returns a Kube whose cluster has the given objects.
+func NewKube(objects ...runtime.Object) *Kube {
+	return &Kube{Clients: k8sfake.NewSimpleClientset(objects...)}
+}
+
+// Config returns a config for a host that doesn't exist.
+func (k *Kube) Config() *rest.Config {
+	return &rest.Config{Host: "https://fake.invalid"}
+}
+
+// Clientset returns the fake clientset.
+func (k *Kube) Clientset() kubernetes.Interface {
+	return k.Clients
+}
+
+// Discovery returns the discovery client of the fake clientset.
+func (k *Kube) Discovery() discovery.DiscoveryInterface {
+	return k.Clients.Discovery()
+}
+
+// Source is a demo.Source that serves the artifacts from memory.
+type Source struct {
+	// Files are the contents of the artifacts, by filename.
+	Files map[string][]byte
+
+	mu      sync.Mutex
+	fetched []string
+}
+
+// Fetch returns the contents of the file, or an error wrapping fs.ErrNotExist if there is no such file.
+func (s *Source) Fetch(filename string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetched = append(s.fetched, filename)
+	b, ok := s.Files[filename]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", filename, fs.ErrNotExist)
+	}
+	return b, nil
+}
+
+// Fetched returns the filenames that were fetched, in order.
+func (s *Source) Fetched() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.fetched...)
+}
+
+// Prompter is a components.UserPrompter that gives canned answers.
+type Prompter struct {
+	// YN are the answers to Y/N prompts, by message. Prompts without an answer take their default.
+	YN map[string]bool
+	// Selections are the answers to select prompts, by message. Prompts without an answer take their default, or
+	// the first option if there is no default.
+	Selections map[string]string
+
+	mu    sync.Mutex
+	asked []string
+}
+
+// YNPrompt returns the answer to the prompt.
+func (p *Prompter) YNPrompt(message string, defaultValue bool) bool {
+	p.ask(message)
+	if answer, ok := p.YN[message]; ok {
+		return answer
+	}
+	return defaultValue
+}
+
+// Select returns the answer to the prompt, which must be one of the options.
+func (p *Prompter) Select(message string, options []string, defaultValue string) (string, error) {
+	p.ask(message)
+	answer, ok := p.Selections[message]
+	if !ok {
+		answer = defaultValue
+	}
+	if answer == "" && len(options) > 0 {
+		answer = options[0]
+	}
+	for _, o := range options {
+		if o == answer {
+			return answer, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not an option of prompt %q", answer, message)
+}
+
+// Asked returns the messages of the prompts that were asked, in order.
+func (p *Prompter) Asked() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.asked...)
+}
+
+func (p *Prompter) ask(message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.asked = append(p.asked, message)
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+const (
+	// dockerHubRegistry is the registry of image references that don't name one.
+	dockerHubRegistry = "docker.io"
+	// dockerHubAPIHost is the host that serves the registry API of Docker Hub.
+	dockerHubAPIHost = "registry-1.docker.io"
+)
+
+// manifestMediaTypes are the media types of the image manifests that ResolveDigest accepts. Multi-arch indexes are
+// preferred, so that the digest covers the images of all architectures, like the digests shown by docker pull.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ImageRef is a parsed container image reference.
+type ImageRef struct {
+	// Registry is the host of the registry, which is docker.io for Docker Hub.
+	Registry string
+	// Repository is the path of the image in the registry, for example library/redis.
+	Repository string
+	// Tag is empty if the reference doesn't have one.
+	Tag string
+	// Digest is empty if the reference isn't pinned to a digest.
+	Digest string
+}
+
+// ParseImageRef parses a container image reference of the form [registry[:port]/]repository[:tag][@digest]. Images
+// without a registry are on Docker Hub.
+func ParseImageRef(image string) (*ImageRef, error) {
+	ref := &ImageRef{}
+	name := image
+	if idx := strings.Index(name, "@"); idx != -1 {
+		name, ref.Digest = name[:idx], name[idx+1:]
+	}
+	if idx := strings.LastIndex(name, ":"); idx > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:idx], name[idx+1:]
+	}
+
+	ref.Registry = dockerHubRegistry
+	ref.Repository = name
+	if parts := strings.SplitN(name, "/", 2); len(parts) == 2 {
+		// The first component is only a registry host if it looks like one.
+		if strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost" {
+			ref.Registry, ref.Repository = parts[0], parts[1]
+		}
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	if ref.Repository == "" || strings.HasSuffix(ref.Repository, "/") {
+		return nil, fmt.Errorf("invalid image reference %q", image)
+	}
+	return ref, nil
+}
+
+// Name returns the fully qualified name of the image, without its tag or digest.
+func (r *ImageRef) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the fully qualified reference.
+func (r *ImageRef) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// ResolveDigest looks up the digest that the tag of the image currently points to in its registry. Images without a
+// tag resolve the latest tag. The registry is accessed anonymously, so only public images can be resolved.
+func ResolveDigest(ctx context.Context, ref *ImageRef) (string, error) {
+	host := ref.Registry
+	if host == dockerHubRegistry {
+		host = dockerHubAPIHost
+	}
+	tag := ref.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, ref.Repository, tag)
+
+	resp, err := headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Registries hand out anonymous pull tokens for public images.
+		token, err := registryToken(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", err
+		}
+		if resp, err = headManifest(ctx, manifestURL, token); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry %s returned %s for %s:%s", ref.Registry, resp.Status, ref.Name(), tag)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry %s didn't return the digest of %s:%s", ref.Registry, ref.Name(), tag)
+	}
+	return digest, nil
+}
+
+func headManifest(ctx context.Context, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := utils.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// challengeParamRegexp matches the parameters of a WWW-Authenticate challenge, such as realm="https://auth".
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// registryToken fetches an anonymous token for the Bearer challenge of a registry.
+func registryToken(ctx context.Context, challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", errors.New("registry requires credentials to access the image")
+	}
+	params := make(map[string]string)
+	for _, m := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return "", fmt.Errorf("invalid realm %q in the registry's challenge", params["realm"])
+	}
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			q.Set(k, params[k])
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	resp, err := utils.HTTPClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a token from %s: %s", realm.Host, resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token in the response of %s", realm.Host)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/demo"
+)
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected demo.ImageRef
+		name     string
+	}{
+		{
+			image:    "redis",
+			expected: demo.ImageRef{Registry: "docker.io", Repository: "library/redis"},
+			name:     "docker.io/library/redis",
+		},
+		{
+			image:    "weaveworksdemos/front-end:0.3.12",
+			expected: demo.ImageRef{Registry: "docker.io", Repository: "weaveworksdemos/front-end", Tag: "0.3.12"},
+			name:     "docker.io/weaveworksdemos/front-end",
+		},
+		{
+			image:    "gcr.io/pixie-prod/demos/carts:v1@sha256:abc",
+			expected: demo.ImageRef{Registry: "gcr.io", Repository: "pixie-prod/demos/carts", Tag: "v1", Digest: "sha256:abc"},
+			name:     "gcr.io/pixie-prod/demos/carts",
+		},
+		{
+			image:    "localhost:5000/mongo",
+			expected: demo.ImageRef{Registry: "localhost:5000", Repository: "mongo"},
+			name:     "localhost:5000/mongo",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.image, func(t *testing.T) {
+			ref, err := demo.ParseImageRef(tc.image)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, *ref)
+			assert.Equal(t, tc.name, ref.Name())
+		})
+	}
+
+	_, err := demo.ParseImageRef("gcr.io/")
+	assert.Error(t, err)
+}
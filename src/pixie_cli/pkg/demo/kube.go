@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// Kube gives access to the Kubernetes cluster that demo apps are deployed to.
+type Kube interface {
+	// Config is the config that the dynamic clients, which apply and delete the resources of demo apps, are
+	// created from.
+	Config() *rest.Config
+	Clientset() kubernetes.Interface
+	Discovery() discovery.DiscoveryInterface
+}
+
+// SharedKube returns the Kube of the current cluster of the kubeconfig. Its clients are created on first use, and
+// shared with the rest of the CLI.
+func SharedKube() Kube {
+	return sharedKube{}
+}
+
+type sharedKube struct{}
+
+func (sharedKube) Config() *rest.Config {
+	return k8s.GetSharedConfig()
+}
+
+func (sharedKube) Clientset() kubernetes.Interface {
+	return k8s.GetSharedClientset()
+}
+
+func (sharedKube) Discovery() discovery.DiscoveryInterface {
+	return k8s.GetSharedDiscoveryClient()
+}
+
+// NewKube returns the Kube of the cluster that the config connects to, for example the config returned by
+// rest.InClusterConfig when demo apps are deployed from inside the cluster.
+func NewKube(config *rest.Config) (Kube, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &configKube{config: config, clientset: clientset}, nil
+}
+
+type configKube struct {
+	config    *rest.Config
+	clientset *kubernetes.Clientset
+}
+
+func (k *configKube) Config() *rest.Config {
+	return k.config
+}
+
+func (k *configKube) Clientset() kubernetes.Interface {
+	return k.clientset
+}
+
+func (k *configKube) Discovery() discovery.DiscoveryInterface {
+	return k.clientset.Discovery()
+}
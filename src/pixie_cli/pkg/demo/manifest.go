@@ -0,0 +1,282 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ManifestFile is the name of the manifest of the demo apps, in the artifacts.
+const ManifestFile = "manifest.json"
+
+// Manifest describes the demo apps in the artifacts, keyed by app name. Apps that were deprecated without metadata
+// have a nil spec.
+type Manifest = map[string]*AppSpec
+
+// AppSpec describes a demo app in the manifest.
+type AppSpec struct {
+	Description  string          `json:"description"`
+	Instructions []string        `json:"instructions"`
+	Dependencies map[string]bool `json:"dependencies"`
+	Frontend     *Frontend       `json:"frontend,omitempty"`
+	// Architectures contains overrides for clusters that don't run on amd64, keyed by architecture (e.g. arm64).
+	Architectures map[string]*ArchSpec `json:"architectures,omitempty"`
+	// Deprecated is set when the app should no longer be deployed.
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	// LiveView is a PxL script that shows the app's data, suggested after deploy.
+	LiveView *LiveView `json:"live_view,omitempty"`
+	// MultiNamespace is set for apps whose YAMLs place objects in several namespaces. Objects that declare a
+	// namespace are deployed to it, instead of the demo namespace.
+	MultiNamespace bool `json:"multi_namespace,omitempty"`
+}
+
+// BundleName returns the name of the YAML bundle of the app for the given node architecture.
+func (s *AppSpec) BundleName(appName, arch string) string {
+	if archSpec := s.Architectures[arch]; archSpec != nil && archSpec.Bundle != "" {
+		return archSpec.Bundle
+	}
+	return DefaultBundleName(appName)
+}
+
+// LiveView is a PxL script and its arguments. Occurrences of {namespace} in the arguments are replaced with the
+// namespace the app was deployed to.
+type LiveView struct {
+	Script string            `json:"script"`
+	Args   map[string]string `json:"args,omitempty"`
+}
+
+// ScriptArgs returns the flags to pass to the live view's script.
+func (l *LiveView) ScriptArgs(namespace string) []string {
+	keys := make([]string, 0, len(l.Args))
+	for k := range l.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, k := range keys {
+		args = append(args, "--"+k, strings.ReplaceAll(l.Args[k], "{namespace}", namespace))
+	}
+	return args
+}
+
+// ArchSpec describes the artifacts of an app for a specific architecture.
+type ArchSpec struct {
+	// Bundle is the name of a YAML bundle to use instead of <app>.tar.gz.
+	Bundle string `json:"bundle,omitempty"`
+	// Images maps an image in the default bundle to the image that should be used for this architecture.
+	Images map[string]string `json:"images,omitempty"`
+}
+
+// Deprecation describes why an app was deprecated and what to use instead.
+type Deprecation struct {
+	Message     string `json:"message"`
+	Replacement string `json:"replacement"`
+}
+
+// Guidance returns the message of the deprecation, followed by the replacement to use.
+func (d *Deprecation) Guidance() string {
+	guidance := d.Message
+	if d.Replacement != "" {
+		if guidance != "" {
+			guidance += " "
+		}
+		guidance += fmt.Sprintf("Use %s instead.", d.Replacement)
+	}
+	return guidance
+}
+
+// Frontend is the Service that serves the web frontend of a demo app.
+type Frontend struct {
+	Service string `json:"service"`
+	Port    int    `json:"port"`
+}
+
+// MergeJSONObjects deep-merges src into dst. Nested objects are merged key by key, all other values in src
+// (including null) replace the value in dst.
+func MergeJSONObjects(dst, src map[string]interface{}) {
+	for k, srcVal := range src {
+		srcMap, srcIsMap := srcVal.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			MergeJSONObjects(dstMap, srcMap)
+			continue
+		}
+		dst[k] = srcVal
+	}
+}
+
+// ParseManifest parses the manifest, with the given overrides deep-merged into it if they are set.
+func ParseManifest(jsonBytes, overrides []byte) (Manifest, error) {
+	if len(overrides) != 0 {
+		base := make(map[string]interface{})
+		if err := json.Unmarshal(jsonBytes, &base); err != nil {
+			return nil, err
+		}
+		o := make(map[string]interface{})
+		if err := json.Unmarshal(overrides, &o); err != nil {
+			return nil, err
+		}
+		MergeJSONObjects(base, o)
+		var err error
+		if jsonBytes, err = json.Marshal(base); err != nil {
+			return nil, err
+		}
+	}
+	m := make(Manifest)
+	if err := json.Unmarshal(jsonBytes, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// manifestCacheEntry is the last manifest downloaded from an artifacts URL, as served, with its validators.
+type manifestCacheEntry struct {
+	Validators
+	FetchedAt time.Time       `json:"fetched_at"`
+	Manifest  json.RawMessage `json:"manifest"`
+}
+
+// Manifest downloads the manifest, with the overrides applied. If a cache folder is set, the manifest is only
+// transferred again once it changes, and the cached copy is used if the artifacts can't be reached. It returns a
+// ManifestError if it fails.
+func (c *Client) Manifest() (Manifest, error) {
+	m, err := c.manifest()
+	if err != nil {
+		return nil, &ManifestError{Artifacts: c.Artifacts, Err: err}
+	}
+	return m, nil
+}
+
+func (c *Client) manifest() (Manifest, error) {
+	src, err := c.source()
+	if err != nil {
+		return nil, err
+	}
+	cached, _ := c.readManifestCache()
+	var v Validators
+	if cached != nil {
+		v = cached.Validators
+	}
+
+	jsonBytes, newV, err := FetchIfModified(src, ManifestFile, v)
+	switch {
+	case errors.Is(err, ErrNotModified):
+		log.Debugf("The cached manifest of %s is up to date", c.Artifacts)
+		jsonBytes = cached.Manifest
+		newV = v
+	case err != nil && cached != nil:
+		reporter{c.Progress}.errorf(err, "Failed to fetch the manifest, using the copy cached on %s",
+			cached.FetchedAt.Local().Format(time.RFC1123))
+		return c.parseManifest(cached.Manifest)
+	case err != nil:
+		return nil, err
+	}
+
+	m, err := c.parseManifest(jsonBytes)
+	if err != nil {
+		return nil, err
+	}
+	c.writeManifestCache(&manifestCacheEntry{
+		Validators: newV,
+		FetchedAt:  time.Now(),
+		Manifest:   jsonBytes,
+	})
+	return m, nil
+}
+
+// CachedManifest returns the manifest that was last downloaded, or downloads it if it hasn't been yet. It's meant for
+// callers that need to be fast, such as shell completions.
+func (c *Client) CachedManifest() (Manifest, error) {
+	cached, err := c.readManifestCache()
+	if err != nil {
+		return c.Manifest()
+	}
+	m, err := c.parseManifest(cached.Manifest)
+	if err != nil {
+		return c.Manifest()
+	}
+	return m, nil
+}
+
+// parseManifest parses the manifest, as served, with the overrides file applied.
+func (c *Client) parseManifest(jsonBytes []byte) (Manifest, error) {
+	var overrides []byte
+	if c.ManifestOverridesFile != "" {
+		var err error
+		if overrides, err = os.ReadFile(c.ManifestOverridesFile); err != nil {
+			return nil, err
+		}
+	}
+	return ParseManifest(jsonBytes, overrides)
+}
+
+// readManifestCache reads the cached manifest of the artifacts.
+func (c *Client) readManifestCache() (*manifestCacheEntry, error) {
+	path, err := c.manifestCachePath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &manifestCacheEntry{}
+	if err := json.Unmarshal(b, entry); err != nil {
+		return nil, err
+	}
+	if len(entry.Manifest) == 0 {
+		return nil, errors.New("cached manifest is empty")
+	}
+	return entry, nil
+}
+
+// writeManifestCache caches the manifest of the artifacts. Failing to cache it isn't an error.
+func (c *Client) writeManifestCache(entry *manifestCacheEntry) {
+	path, err := c.manifestCachePath()
+	if err != nil {
+		return
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		log.WithError(err).Debug("Failed to cache the manifest")
+	}
+}
+
+// manifestCachePath returns the path that the manifest of the artifacts is cached at.
+func (c *Client) manifestCachePath() (string, error) {
+	if c.CacheDir == "" {
+		return "", errors.New("no cache folder is set")
+	}
+	sum := sha256.Sum256([]byte(c.Artifacts))
+	return filepath.Join(c.CacheDir, fmt.Sprintf("demo-manifest-%x.json", sum[:8])), nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package demo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// AppLabel is the namespace label that records which demo app is deployed in the namespace.
+	AppLabel = "pixie-demo-app"
+	// ResourceLabel is set on every resource of a demo app, and on the namespaces created for them, to the name of
+	// the app.
+	ResourceLabel = "pixie-demo"
+	// ChannelLabel is the namespace label that records the release channel a demo app was deployed from. Only
+	// namespaces that px created have it.
+	ChannelLabel = "pixie-demo-channel"
+	// SharedNamespaceAnnotation is set on namespaces that were not created by px, but that a demo app was deployed
+	// into. Deleting the demo app from such a namespace only removes its own resources.
+	SharedNamespaceAnnotation = "px.dev/demo-shared-namespace"
+)
+
+// maxNamespaceSuffix is the largest suffix tried when looking for a free demo namespace.
+const maxNamespaceSuffix = 100
+
+// Instance returns the instance that the resources of the demo app deployed to the namespace are labeled with. Only
+// one demo app is deployed per namespace, so the namespace identifies the deploy.
+func Instance(namespace string) string {
+	return namespace
+}
+
+// NamespaceExists returns whether the namespace exists on the cluster.
+func (c *Client) NamespaceExists(namespace string) bool {
+	_, err := c.kube().Clientset().CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+	return err == nil
+}
+
+func (c *Client) getNamespace(namespace string) (*v1.Namespace, error) {
+	ns, err := c.kube().Clientset().CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+	if k8s_errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return ns, err
+}
+
+func (c *Client) createNamespace(ctx context.Context, namespace string, labels, annotations map[string]string) error {
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: labels, Annotations: annotations}}
+	_, err := c.kube().Clientset().CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	return err
+}
+
+// AppNamespaces returns the namespaces that px created for the demo app, in order of their names.
+func (c *Client) AppNamespaces(appName string) ([]string, error) {
+	selector := fmt.Sprintf("%s=%s", AppLabel, appName)
+	list, err := c.kube().Clientset().CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// NamespaceState returns whether the namespace exists, and whether it was created by deploying a demo app.
+func (c *Client) NamespaceState(namespace string) (exists bool, managed bool, err error) {
+	ns, err := c.getNamespace(namespace)
+	if err != nil || ns == nil {
+		return false, false, err
+	}
+	_, managed = ns.Labels[ChannelLabel]
+	return true, managed, nil
+}
+
+// IsSharedNamespace returns whether a demo app was deployed into the namespace without px creating it.
+func (c *Client) IsSharedNamespace(namespace string) (bool, error) {
+	ns, err := c.getNamespace(namespace)
+	if err != nil || ns == nil {
+		return false, err
+	}
+	_, managed := ns.Labels[ChannelLabel]
+	return !managed && ns.Annotations[SharedNamespaceAnnotation] == "true", nil
+}
+
+// markSharedNamespace annotates an existing namespace that px didn't create as shared.
+func (c *Client) markSharedNamespace(namespace string) error {
+	ns, err := c.getNamespace(namespace)
+	if err != nil || ns == nil {
+		return err
+	}
+	if _, managed := ns.Labels[ChannelLabel]; managed {
+		return nil
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, SharedNamespaceAnnotation)
+	_, err = c.kube().Clientset().CoreV1().Namespaces().Patch(context.Background(), namespace, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// NextFreeNamespace returns the first namespace of the form <app>-<n> that doesn't exist yet.
+func (c *Client) NextFreeNamespace(appName string) (string, error) {
+	for i := 2; i <= maxNamespaceSuffix; i++ {
+		candidate := fmt.Sprintf("%s-%d", appName, i)
+		exists, _, err := c.NamespaceState(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a free namespace for demo app %s", appName)
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package exitcodes
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// The error codes that identify the classes of failures in JSON output, so that automation can branch on them.
+// Like the exit codes, they are stable. Specific errors, such as a demo namespace that already exists, get their own
+// codes from their error hints.
+const (
+	// CodeError is the error code of failures that don't belong to any of the other classes.
+	CodeError = "error"
+	// CodeUsage is the error code of invalid commands, arguments or flags.
+	CodeUsage = "invalid_usage"
+	// CodeConfig is the error code of an invalid or unreadable config file or setting.
+	CodeConfig = "invalid_config"
+	// CodeNetworkUnreachable is the error code of failures to reach a server other than the cluster, such as Pixie
+	// Cloud or the demo artifacts.
+	CodeNetworkUnreachable = "network_unreachable"
+	// CodeClusterUnreachable is the error code of failures to reach the API server of the cluster.
+	CodeClusterUnreachable = "cluster_unreachable"
+	// CodeClusterError is the error code of requests that the cluster fails for other reasons.
+	CodeClusterError = "cluster_error"
+	// CodeRBACDenied is the error code of requests that the cluster rejects, because the kubeconfig user lacks an RBAC
+	// permission or can't be authenticated.
+	CodeRBACDenied = "rbac_denied"
+	// CodeNotFound is the error code of resources that don't exist on the cluster.
+	CodeNotFound = "not_found"
+	// CodeConflict is the error code of resources that already exist, or were changed concurrently.
+	CodeConflict = "conflict"
+	// CodeTimeout is the error code of operations that didn't finish in time.
+	CodeTimeout = "timeout"
+	// CodeUnauthenticated is the error code of commands that need the user to log in to Pixie Cloud first.
+	CodeUnauthenticated = "unauthenticated"
+	// CodeAborted is the error code of commands that the user aborts.
+	CodeAborted = "aborted"
+	// CodeCrash is the error code of unexpected panics of the CLI.
+	CodeCrash = "crash"
+)
+
+// codesForExitCodes are the error codes of the exit codes, for errors that aren't classified more precisely.
+var codesForExitCodes = map[int]string{
+	Error:   CodeError,
+	Usage:   CodeUsage,
+	Config:  CodeConfig,
+	Network: CodeNetworkUnreachable,
+	Cluster: CodeClusterError,
+	Auth:    CodeUnauthenticated,
+	Aborted: CodeAborted,
+	Crash:   CodeCrash,
+}
+
+// ErrorCode returns the error code for a failure that exits with the given code, classifying the error if there is
+// one.
+func ErrorCode(err error, exitCode int) string {
+	if code := classifyError(err); code != "" {
+		return code
+	}
+	if code, ok := codesForExitCodes[exitCode]; ok {
+		return code
+	}
+	return CodeError
+}
+
+// classifyError returns the error code of the class of the error, or "" if it doesn't belong to one.
+func classifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	switch {
+	case errors.Is(err, k8s.ErrForbidden) || k8serrors.IsForbidden(err) || k8serrors.IsUnauthorized(err):
+		return CodeRBACDenied
+	case errors.Is(err, k8s.ErrNotFound) || k8serrors.IsNotFound(err):
+		return CodeNotFound
+	case errors.Is(err, k8s.ErrConflict) || k8serrors.IsConflict(err) || k8serrors.IsAlreadyExists(err):
+		return CodeConflict
+	case errors.Is(err, k8s.ErrTimeout):
+		return CodeTimeout
+	}
+
+	var apiErr *k8s.APIError
+	if errors.As(err, &apiErr) && isNetworkError(apiErr.Err) {
+		return CodeClusterUnreachable
+	}
+	// Requests of the kubernetes clients that fail to connect aren't always wrapped in an APIError, so they're told
+	// apart by their paths.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && isKubernetesAPIURL(urlErr.URL) {
+		return CodeClusterUnreachable
+	}
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unauthenticated {
+		return CodeUnauthenticated
+	}
+	return ""
+}
+
+// isKubernetesAPIURL returns whether the URL is a request to the API server of a Kubernetes cluster.
+func isKubernetesAPIURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(u.Path, "/api/") || strings.HasPrefix(u.Path, "/apis/") || u.Path == "/api" ||
+		u.Path == "/apis" || u.Path == "/version"
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package exitcodes defines the exit codes of the CLI, so that automation can tell failures apart, and maps errors
+// to them.
+package exitcodes
+
+import (
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// The exit codes of the CLI. They are stable, so new classes of errors get new codes.
+const (
+	// Success is the exit code of commands that succeed.
+	Success = 0
+	// Error is the exit code of failures that don't belong to any of the other classes.
+	Error = 1
+	// Usage is the exit code of invalid commands, arguments or flags.
+	Usage = 2
+	// Config is the exit code of an invalid or unreadable config file or setting.
+	Config = 3
+	// Network is the exit code of failures to reach a server, such as Pixie Cloud, the demo artifacts or the API
+	// server of the cluster.
+	Network = 4
+	// Cluster is the exit code of requests that the Kubernetes cluster rejects or fails.
+	Cluster = 5
+	// Auth is the exit code of commands that need the user to log in to Pixie Cloud first.
+	Auth = 6
+	// Crash is the exit code of unexpected panics of the CLI, which are bugs.
+	Crash = 7
+	// Aborted is the exit code of commands that the user aborts, with Ctrl+C or by declining a prompt.
+	Aborted = 130
+)
+
+// codedError is an error with the exit code that it should exit with.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+func (e *codedError) ExitCode() int {
+	return e.code
+}
+
+// exitCoder is implemented by errors that know the exit code they should exit with, such as the errors of New and
+// Wrap.
+type exitCoder interface {
+	ExitCode() int
+}
+
+// New returns an error with the given message that exits with the given code.
+func New(code int, msg string) error {
+	return &codedError{code: code, err: errors.New(msg)}
+}
+
+// Wrap returns the error, marked to exit with the given code. It returns nil if err is nil.
+func Wrap(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// ForError returns the exit code for the error: the code it was marked with by New or Wrap, or returns from an
+// ExitCode method, or else the code of its class.
+func ForError(err error) int {
+	if err == nil {
+		return Success
+	}
+	var coded exitCoder
+	if errors.As(err, &coded) {
+		return coded.ExitCode()
+	}
+
+	var apiErr *k8s.APIError
+	if errors.As(err, &apiErr) {
+		return Cluster
+	}
+	var statusErr k8serrors.APIStatus
+	if errors.As(err, &statusErr) {
+		return Cluster
+	}
+
+	if isNetworkError(err) {
+		return Network
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return Network
+		case codes.Unauthenticated:
+			return Auth
+		}
+	}
+	return Error
+}
+
+// isNetworkError returns whether the error is a failure to reach a server.
+func isNetworkError(err error) bool {
+	// net.Error isn't matched, since it's also implemented by errors that aren't from the network, such as
+	// *fs.PathError.
+	var urlErr *url.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var certErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	return errors.As(err, &urlErr) || errors.As(err, &opErr) || errors.As(err, &dnsErr) || errors.As(err, &certErr) ||
+		errors.As(err, &hostErr)
+}
+
+// Exit exits with the code for the error.
+func Exit(err error) {
+	ExitWith(ForError(err))
+}
+
+// ExitWith runs the exit hooks and exits with the code.
+func ExitWith(code int) {
+	RunExitHooks(code)
+	os.Exit(code)
+}
+
+var (
+	exitHooksMu   sync.Mutex
+	exitHooks     []func(code int)
+	lastExitHooks []func(code int)
+	exitHooksRan  bool
+)
+
+// OnExit registers a function that is called with the exit code when the CLI exits, through Exit, ExitWith, log.Fatal
+// or RunExitHooks.
+func OnExit(f func(code int)) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	exitHooks = append(exitHooks, f)
+}
+
+// OnExitLast registers a function that is called when the CLI exits, after the functions registered with OnExit, even
+// those registered after it. It is meant for flushing what the other functions record.
+func OnExitLast(f func(code int)) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	lastExitHooks = append(lastExitHooks, f)
+}
+
+// RunExitHooks calls the functions registered with OnExit, in the order they were registered, and then those
+// registered with OnExitLast. They are only called once, so it is called on the way out of commands that return
+// rather than exit.
+func RunExitHooks(code int) {
+	exitHooksMu.Lock()
+	if exitHooksRan {
+		exitHooksMu.Unlock()
+		return
+	}
+	exitHooksRan = true
+	hooks := append(append([]func(code int){}, exitHooks...), lastExitHooks...)
+	exitHooksMu.Unlock()
+	for _, f := range hooks {
+		f(code)
+	}
+}
+
+var (
+	fatalErrMu sync.Mutex
+	fatalErr   error
+)
+
+// fatalHook records the error of the last fatal log entry, which the logger exits with.
+type fatalHook struct{}
+
+func (fatalHook) Levels() []log.Level {
+	return []log.Level{log.FatalLevel}
+}
+
+func (fatalHook) Fire(entry *log.Entry) error {
+	if err, ok := entry.Data[log.ErrorKey].(error); ok {
+		fatalErrMu.Lock()
+		fatalErr = err
+		fatalErrMu.Unlock()
+	}
+	return nil
+}
+
+// HandleLogFatal makes log.Fatal exit with the code for the error of the log entry, such as
+// log.WithError(err).Fatal("..."), rather than always exiting with 1.
+func HandleLogFatal(logger *log.Logger) {
+	logger.AddHook(fatalHook{})
+	logger.ExitFunc = func(code int) {
+		fatalErrMu.Lock()
+		err := fatalErr
+		fatalErrMu.Unlock()
+		if code == 1 && err != nil {
+			code = ForError(err)
+		}
+		ExitWith(code)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package exitcodes_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+type codedError struct{}
+
+func (codedError) Error() string {
+	return "coded"
+}
+
+func (codedError) ExitCode() int {
+	return exitcodes.Auth
+}
+
+func TestForError(t *testing.T) {
+	notFound := k8serrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "px")
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: exitcodes.Success},
+		{name: "unclassified", err: errors.New("failed"), want: exitcodes.Error},
+		{name: "new", err: exitcodes.New(exitcodes.Usage, "invalid flag"), want: exitcodes.Usage},
+		{name: "wrapped", err: fmt.Errorf("loading: %w", exitcodes.Wrap(exitcodes.Config, errors.New("bad"))), want: exitcodes.Config},
+		{name: "wrap overrides class", err: exitcodes.Wrap(exitcodes.Usage, notFound), want: exitcodes.Usage},
+		{name: "exit code method", err: fmt.Errorf("login: %w", codedError{}), want: exitcodes.Auth},
+		{name: "kubernetes status", err: notFound, want: exitcodes.Cluster},
+		{name: "api error", err: &k8s.APIError{Err: errors.New("failed")}, want: exitcodes.Cluster},
+		{name: "url error", err: &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("refused")}, want: exitcodes.Network},
+		{name: "dns error", err: &net.DNSError{Err: "no such host", Name: "example.com"}, want: exitcodes.Network},
+		{name: "path error", err: &fs.PathError{Op: "open", Path: "config", Err: fs.ErrNotExist}, want: exitcodes.Error},
+		{name: "grpc unavailable", err: status.Error(codes.Unavailable, "down"), want: exitcodes.Network},
+		{name: "grpc unauthenticated", err: status.Error(codes.Unauthenticated, "login"), want: exitcodes.Auth},
+		{name: "grpc internal", err: status.Error(codes.Internal, "failed"), want: exitcodes.Error},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, exitcodes.ForError(test.err))
+		})
+	}
+}
+
+func TestRunExitHooks(t *testing.T) {
+	var calls []string
+	exitcodes.OnExitLast(func(code int) { calls = append(calls, fmt.Sprintf("last %d", code)) })
+	exitcodes.OnExit(func(code int) { calls = append(calls, fmt.Sprintf("first %d", code)) })
+	exitcodes.OnExit(func(code int) { calls = append(calls, fmt.Sprintf("second %d", code)) })
+
+	exitcodes.RunExitHooks(exitcodes.Usage)
+	// The hooks only run once.
+	exitcodes.RunExitHooks(exitcodes.Success)
+	assert.Equal(t, []string{
+		fmt.Sprintf("first %d", exitcodes.Usage),
+		fmt.Sprintf("second %d", exitcodes.Usage),
+		fmt.Sprintf("last %d", exitcodes.Usage),
+	}, calls)
+}
@@ -1,3 +1,5 @@
+//go:build !notel
+
 /*
  * Copyright 2018- The Pixie Authors.
  *
@@ -19,19 +21,40 @@
 package pxanalytics
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"os"
 	"runtime"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/segmentio/analytics-go/v3"
 	"github.com/spf13/viper"
 
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	version "px.dev/pixie/src/shared/goversion"
 )
 
+// Variables loaded from x_defs. Air-gapped builds set defaultEndpoint to "none", so that the CLI makes no analytics
+// calls unless an endpoint is configured with px config set analytics.endpoint.
+var (
+	// defaultEndpoint is the endpoint that analytics are sent to if none is configured. Analytics are sent to the
+	// Segment proxy of the cloud that the CLI uses if it's empty.
+	defaultEndpoint = ""
+)
+
+// Compiled is whether usage analytics are compiled into the CLI. They are left out of builds with the notel tag.
+const Compiled = true
+
+// analyticsTimeout is how long to wait for the analytics endpoint before queueing events.
+const analyticsTimeout = 5 * time.Second
+
 var (
 	client analytics.Client
 	once   sync.Once
@@ -53,48 +76,199 @@ type nullLogger struct{}
 func (l nullLogger) Logf(format string, args ...interface{})   {}
 func (l nullLogger) Errorf(format string, args ...interface{}) {}
 
-// Client returns the default analytics client.
+// optedOut returns whether analytics are turned off for this run of the CLI, by the --no_analytics flag, its
+// PX_NO_ANALYTICS env var, or the DO_NOT_TRACK env var that is honored by many CLIs.
+func optedOut() bool {
+	if viper.GetBool("do_not_track") || viper.GetBool("no_analytics") {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("DO_NOT_TRACK"))) {
+	case "", "0", "false", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+// Enabled returns whether usage analytics are sent. They can be turned off for a single command with the
+// --no_analytics flag or the PX_NO_ANALYTICS or DO_NOT_TRACK env vars, or persistently with px config set
+// analytics.enabled=false. They are also off until the user consents to them, and if there is no endpoint to send
+// them to.
+func Enabled() bool {
+	if optedOut() {
+		return false
+	}
+	if cfg := pxconfig.Cfg().Analytics; !cfg.Decided || cfg.Disabled {
+		return false
+	}
+	_, ok := endpoint()
+	return ok
+}
+
+// undecided returns whether the user may still be asked to consent to analytics.
+func undecided() bool {
+	if optedOut() || pxconfig.Cfg().Analytics.Decided {
+		return false
+	}
+	_, ok := endpoint()
+	return ok
+}
+
+// endpoint returns the URL that analytics are sent to, or false if analytics aren't sent anywhere.
+func endpoint() (string, bool) {
+	e := pxconfig.Cfg().Analytics.Endpoint
+	if e == "" {
+		e = defaultEndpoint
+	}
+	switch e {
+	case pxconfig.AnalyticsEndpointNone:
+		return "", false
+	case "":
+		return fmt.Sprintf("https://segment.%s", viper.GetString("cloud_addr")), true
+	default:
+		return strings.TrimSuffix(e, "/"), true
+	}
+}
+
+// Client returns the default analytics client. The client doesn't send anything if analytics are disabled. If the
+// analytics endpoint can't be reached, events are queued in a file and sent by a later run of the CLI. Personal
+// information is scrubbed from the properties of events before they are sent or queued. Events are batched, and sent
+// together when the client is closed, and high-frequency events registered with SampleEvent are sampled. The standard
+// properties are attached to every event. With --analytics_debug, each event is also printed to stderr.
 func Client() analytics.Client {
 	once.Do(func() {
-		client = disabledAnalyticsClient{}
-
-		if viper.GetBool("do_not_track") {
-			return
+		var c analytics.Client
+		// Whether the user consents is only known once the command runs, so the events tracked until then are
+		// batched, and only sent if they do.
+		if Enabled() || undecided() {
+			c = &batchingClient{newClient: func() analytics.Client {
+				return withDebug(newClient())
+			}}
+		} else {
+			c = withDebug(disabledAnalyticsClient{}, deliveryDisabled)
 		}
+		client = scrubbingClient{contextClient{samplingClient{c}}}
+	})
+	return client
+}
 
-		cloudAddr := viper.GetString("cloud_addr")
-		resp, err := http.Get(fmt.Sprintf("https://segment.%s/cli-write-key", cloudAddr))
-		if err != nil || resp == nil || resp.StatusCode != 200 {
-			return
-		}
+// scrubbingClient redacts personal information from the properties of events before they are enqueued.
+type scrubbingClient struct {
+	analytics.Client
+}
 
-		analyticsKey, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return
+func (c scrubbingClient) Enqueue(msg analytics.Message) error {
+	switch m := msg.(type) {
+	case analytics.Track:
+		m.Properties = analytics.Properties(scrubProperties(Properties(m.Properties)))
+		msg = m
+	case *analytics.Track:
+		if m == nil {
+			return errors.New("nil track event")
 		}
+		track := *m
+		track.Properties = analytics.Properties(scrubProperties(Properties(track.Properties)))
+		msg = track
+	}
+	return c.Client.Enqueue(msg)
+}
 
-		if len(analyticsKey) == 0 {
-			return
-		}
+// Close sends the events that are still batched. Events tracked afterwards are sent as they happen.
+func Close() error {
+	return Client().Close()
+}
+
+// newClient creates the client that sends or queues events, and returns what it does with them.
+func newClient() (analytics.Client, string) {
+	if !Enabled() {
+		return disabledAnalyticsClient{}, deliveryDisabled
+	}
 
-		client, _ = analytics.NewWithConfig(string(analyticsKey), analytics.Config{
-			Endpoint: fmt.Sprintf("https://segment.%s", cloudAddr),
-			DefaultContext: &analytics.Context{
-				App: analytics.AppInfo{
-					Name:    "PX CLI",
-					Version: version.GetVersion().ToString(),
-					Build:   version.GetVersion().RevisionStatus(),
-				},
-				OS: analytics.OSInfo{
-					Name: runtime.GOOS,
-				},
-				Extra: map[string]interface{}{
-					"sessionID": uuid.Must(uuid.NewV4()).String(),
-				},
+	// Events that can't be sent are queued, and sent by a later run of the CLI.
+	queue := newEventQueue()
+
+	analyticsEndpoint, _ := endpoint()
+	analyticsKey := pxconfig.Cfg().Analytics.WriteKey
+	var err error
+	if analyticsKey == "" {
+		analyticsKey, err = fetchWriteKey(analyticsEndpoint)
+	}
+	if errors.Is(err, errUnreachable) && queue != nil {
+		return offlineAnalyticsClient{queue: queue}, deliveryQueued
+	}
+	if err != nil {
+		return disabledAnalyticsClient{}, deliveryDisabled
+	}
+
+	config := analytics.Config{
+		Endpoint:  analyticsEndpoint,
+		Transport: utils.WrapHTTPTransport(newTransport(), nil),
+		DefaultContext: &analytics.Context{
+			App: analytics.AppInfo{
+				Name:    "PX CLI",
+				Version: version.GetVersion().ToString(),
+				Build:   version.GetVersion().RevisionStatus(),
 			},
-			Logger: nullLogger{},
-		})
-	})
-	return client
+			OS: analytics.OSInfo{
+				Name: runtime.GOOS,
+			},
+			Extra: map[string]interface{}{
+				"sessionID": uuid.Must(uuid.NewV4()).String(),
+			},
+		},
+		Logger: nullLogger{},
+	}
+	if queue != nil {
+		config.Callback = queueCallback{queue: queue}
+	}
+	c, err := analytics.NewWithConfig(analyticsKey, config)
+	if err != nil {
+		return disabledAnalyticsClient{}, deliveryDisabled
+	}
+
+	if queue == nil {
+		return c, deliverySent
+	}
+	// The queued events were scrubbed before they were queued.
+	if queued, err := queue.drain(); err == nil {
+		for _, msg := range queued {
+			_ = c.Enqueue(msg)
+		}
+	}
+	return c, deliverySent
+}
+
+// newTransport returns a transport that gives up quickly if the analytics endpoint can't be reached, so that
+// commands don't hang when the CLI is used offline.
+func newTransport() *http.Transport {
+	t := utils.NewHTTPTransport()
+	t.DialContext = (&net.Dialer{Timeout: analyticsTimeout}).DialContext
+	t.TLSHandshakeTimeout = analyticsTimeout
+	t.ResponseHeaderTimeout = analyticsTimeout
+	return t
+}
+
+// errUnreachable is returned when the analytics endpoint can't be reached, for example because the CLI is offline.
+var errUnreachable = errors.New("analytics endpoint unreachable")
+
+// fetchWriteKey fetches the key that analytics events are sent with.
+func fetchWriteKey(analyticsEndpoint string) (string, error) {
+	httpClient := utils.NewHTTPClient(newTransport(), analyticsTimeout, nil)
+	resp, err := httpClient.Get(analyticsEndpoint + "/cli-write-key")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errUnreachable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status fetching the analytics key: %s", resp.Status)
+	}
+
+	analyticsKey, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if len(analyticsKey) == 0 {
+		return "", errors.New("empty analytics key")
+	}
+	return string(analyticsKey), nil
 }
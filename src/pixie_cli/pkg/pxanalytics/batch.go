@@ -0,0 +1,75 @@
+//go:build !notel
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxanalytics
+
+import (
+	"sync"
+
+	"github.com/segmentio/analytics-go/v3"
+)
+
+// maxPendingEvents is the number of events that are batched before they are sent, for commands that run long enough
+// to produce many events. Once the batch is sent, later events are sent as they happen.
+const maxPendingEvents = 100
+
+// batchingClient holds on to events until it is closed, and then sends them together, so that short commands don't
+// wait on the analytics endpoint. The client that sends the events is only created once there is something to send,
+// since it fetches the analytics key.
+type batchingClient struct {
+	mu        sync.Mutex
+	pending   []analytics.Message
+	client    analytics.Client
+	newClient func() analytics.Client
+}
+
+func (c *batchingClient) Enqueue(msg analytics.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client.Enqueue(msg)
+	}
+	c.pending = append(c.pending, withTimestamp(msg))
+	if len(c.pending) >= maxPendingEvents {
+		c.flush()
+	}
+	return nil
+}
+
+// flush creates the client, and passes it the pending events. It must be called with the lock held.
+func (c *batchingClient) flush() {
+	c.client = c.newClient()
+	for _, msg := range c.pending {
+		_ = c.client.Enqueue(msg)
+	}
+	c.pending = nil
+}
+
+func (c *batchingClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		if len(c.pending) == 0 {
+			return nil
+		}
+		c.flush()
+	}
+	return c.client.Close()
+}
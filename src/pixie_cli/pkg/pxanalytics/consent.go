@@ -0,0 +1,64 @@
+//go:build !notel
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxanalytics
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+)
+
+const consentMessage = `Pixie collects anonymous usage analytics to improve the CLI: the commands that you run, whether they
+succeed, and the version and OS of the CLI. Names of clusters and namespaces, file paths, hostnames and IP
+addresses are removed before events are sent. No analytics are sent unless you agree.
+You can change your choice at any time with px config set analytics.enabled=<true|false>.`
+
+// PromptForConsent asks the user whether the CLI may send usage analytics, if they haven't been asked yet, and
+// stores their choice in the config. The user isn't asked if they can't answer, for example because stdin isn't a
+// terminal or -y is set; analytics stay off, and the user is asked again by the next interactive command.
+func PromptForConsent() {
+	cfg := pxconfig.Cfg()
+	if cfg.Analytics.Decided || viper.GetBool("y") || optedOut() {
+		return
+	}
+	// There is nothing to consent to if analytics aren't sent anywhere.
+	if _, ok := endpoint(); !ok {
+		return
+	}
+	if !components.IsTerminal(os.Stdin) || !components.IsTerminal(os.Stdout) {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, consentMessage)
+	consented, err := components.YNPromptE("Send usage analytics to Pixie?", false)
+	if err != nil {
+		return
+	}
+	cfg.Analytics.Decided = true
+	cfg.Analytics.Disabled = !consented
+	if err := pxconfig.Save(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save your choice: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,120 @@
+//go:build !notel
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxanalytics
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/segmentio/analytics-go/v3"
+
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	version "px.dev/pixie/src/shared/goversion"
+)
+
+var (
+	// processStart is when the CLI started, to report how long the invocation took.
+	processStart = time.Now()
+
+	kubernetesVersionMu sync.Mutex
+	kubernetesVersion   string
+
+	userIDOnce sync.Once
+	userID     string
+)
+
+// trackedUserID returns the client ID that the events of this run are tracked with. It doesn't change if the ID is
+// reset by the command, so that the events of the run aren't linked to the new ID.
+func trackedUserID() string {
+	userIDOnce.Do(func() {
+		userID = pxconfig.Cfg().UniqueClientID
+	})
+	return userID
+}
+
+// SetKubernetesVersion records the version of the Kubernetes cluster that the command works with, so that it is
+// included in the events that are tracked afterwards.
+func SetKubernetesVersion(v string) {
+	kubernetesVersionMu.Lock()
+	defer kubernetesVersionMu.Unlock()
+	kubernetesVersion = v
+}
+
+// standardProperties returns the properties that are attached to every event.
+func standardProperties() analytics.Properties {
+	props := analytics.NewProperties().
+		Set("cli_version", version.GetVersion().ToString()).
+		Set("os", runtime.GOOS).
+		Set("arch", runtime.GOARCH).
+		Set("duration_ms", time.Since(processStart).Milliseconds())
+	kubernetesVersionMu.Lock()
+	defer kubernetesVersionMu.Unlock()
+	if kubernetesVersion != "" {
+		props.Set("k8s_version", kubernetesVersion)
+	}
+	return props
+}
+
+// contextClient attaches the standard properties to every event. Properties that are set by the event take
+// precedence.
+type contextClient struct {
+	analytics.Client
+}
+
+func (c contextClient) Enqueue(msg analytics.Message) error {
+	var track analytics.Track
+	switch m := msg.(type) {
+	case analytics.Track:
+		track = m
+	case *analytics.Track:
+		if m == nil {
+			return c.Client.Enqueue(msg)
+		}
+		track = *m
+	default:
+		return c.Client.Enqueue(msg)
+	}
+	props := standardProperties()
+	for k, v := range track.Properties {
+		props[k] = v
+	}
+	track.Properties = props
+	return c.Client.Enqueue(track)
+}
+
+// Track sends an event for the CLI user, with the given properties, which may be nil. The standard properties, such
+// as the version of the CLI and how long the invocation has taken so far, are attached to it.
+func Track(event string, props Properties) {
+	_ = Client().Enqueue(analytics.Track{
+		UserId:     trackedUserID(),
+		Event:      event,
+		Properties: analytics.Properties(props),
+	})
+}
+
+// Alias associates the ID of the user that logged in with the client ID that events were tracked with before.
+func Alias(userID string) {
+	_ = Client().Enqueue(analytics.Alias{
+		UserId:     pxconfig.Cfg().UniqueClientID,
+		PreviousId: userID,
+	})
+}
@@ -0,0 +1,71 @@
+//go:build !notel
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxanalytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/segmentio/analytics-go/v3"
+	"github.com/spf13/viper"
+)
+
+// What the client does with events, as printed by --analytics_debug.
+const (
+	deliverySent     = "sent"
+	deliveryQueued   = "queued, the analytics endpoint is unreachable"
+	deliveryDisabled = "not sent, analytics are disabled"
+)
+
+// withDebug makes the client print each event with what it does with it, if --analytics_debug is set.
+func withDebug(c analytics.Client, delivery string) analytics.Client {
+	if !viper.GetBool("analytics_debug") {
+		return c
+	}
+	return debugClient{Client: c, delivery: delivery}
+}
+
+// debugClient prints each event to stderr before passing it on, so that users can see exactly what the CLI sends.
+type debugClient struct {
+	analytics.Client
+	delivery string
+}
+
+var debugMu sync.Mutex
+
+func (c debugClient) Enqueue(msg analytics.Message) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Keep redacted values, such as <ip>, readable.
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(msg); err != nil {
+		fmt.Fprintf(&buf, "%+v\n", msg)
+	}
+	debugMu.Lock()
+	fmt.Fprintf(os.Stderr, "%s (%s):\n%s", color.New(color.Bold).Sprint("Analytics event"), c.delivery, buf.String())
+	debugMu.Unlock()
+	return c.Client.Enqueue(msg)
+}
@@ -0,0 +1,51 @@
+//go:build notel
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package pxanalytics
+
+// This file replaces the analytics client in builds with the notel tag, so that the CLI can be proven to send no usage
+// analytics: the Segment client isn't linked into the binary, and all functions are no-ops.
+
+// Compiled is whether usage analytics are compiled into the CLI. They are left out of builds with the notel tag.
+const Compiled = false
+
+// Enabled returns whether usage analytics are sent, which they never are without the client.
+func Enabled() bool {
+	return false
+}
+
+// PromptForConsent does nothing, since there are no usage analytics to consent to.
+func PromptForConsent() {}
+
+// Track does nothing.
+func Track(string, Properties) {}
+
+// Alias does nothing.
+func Alias(string) {}
+
+// Close does nothing.
+func Close() error {
+	return nil
+}
+
+// SampleEvent does nothing.
+func SampleEvent(string, float64) {}
+
+// SetKubernetesVersion does nothing.
+func SetKubernetesVersion(string) {}
@@ -0,0 +1,33 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package pxanalytics
+
+// Properties are the properties of an analytics event. They are a type of their own, so that the CLI tracks events
+// the same way whether usage analytics are compiled in or not.
+type Properties map[string]interface{}
+
+// NewProperties returns empty properties.
+func NewProperties() Properties {
+	return make(Properties, 10)
+}
+
+// Set sets the property, and returns the properties so that calls can be chained.
+func (p Properties) Set(name string, value interface{}) Properties {
+	p[name] = value
+	return p
+}
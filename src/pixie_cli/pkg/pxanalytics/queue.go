@@ -0,0 +1,210 @@
+//go:build !notel
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxanalytics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/segmentio/analytics-go/v3"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// maxQueuedEvents is the number of events kept in the queue. The oldest events are dropped once it is full, so that
+// the queue doesn't grow without bound while the CLI is used offline.
+const maxQueuedEvents = 1000
+
+// queuedEvent is an event in the queue file. The type of the event is stored, so that it can be decoded.
+type queuedEvent struct {
+	Type  string           `json:"type"`
+	Track *analytics.Track `json:"track,omitempty"`
+	Alias *analytics.Alias `json:"alias,omitempty"`
+}
+
+func newQueuedEvent(msg analytics.Message) (*queuedEvent, error) {
+	switch m := msg.(type) {
+	case analytics.Track:
+		return &queuedEvent{Type: "track", Track: &m}, nil
+	case *analytics.Track:
+		return &queuedEvent{Type: "track", Track: m}, nil
+	case analytics.Alias:
+		return &queuedEvent{Type: "alias", Alias: &m}, nil
+	case *analytics.Alias:
+		return &queuedEvent{Type: "alias", Alias: m}, nil
+	default:
+		return nil, fmt.Errorf("events of type %T can't be queued", msg)
+	}
+}
+
+func (e *queuedEvent) message() analytics.Message {
+	switch {
+	case e.Type == "track" && e.Track != nil:
+		return *e.Track
+	case e.Type == "alias" && e.Alias != nil:
+		return *e.Alias
+	default:
+		return nil
+	}
+}
+
+// eventQueue stores the events that couldn't be sent in a file, so that they can be sent by a later run of the CLI.
+type eventQueue struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newEventQueue() *eventQueue {
+	path, err := utils.EnsureDefaultAnalyticsQueueFilePath()
+	if err != nil {
+		return nil
+	}
+	return &eventQueue{path: path}
+}
+
+func (q *eventQueue) read() ([]*queuedEvent, error) {
+	b, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var events []*queuedEvent
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, len(b)+1)
+	for scanner.Scan() {
+		e := &queuedEvent{}
+		// Skip lines that are corrupt, for example because a write was interrupted.
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil || e.message() == nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+func (q *eventQueue) write(events []*queuedEvent) error {
+	if len(events) > maxQueuedEvents {
+		events = events[len(events)-maxQueuedEvents:]
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path)
+}
+
+// add appends the messages to the queue.
+func (q *eventQueue) add(msgs ...analytics.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	events, err := q.read()
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		e, err := newQueuedEvent(msg)
+		if err != nil {
+			return err
+		}
+		events = append(events, e)
+	}
+	return q.write(events)
+}
+
+// drain removes all the messages from the queue, and returns them.
+func (q *eventQueue) drain() ([]analytics.Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	events, err := q.read()
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	if err := os.Remove(q.path); err != nil {
+		return nil, err
+	}
+	msgs := make([]analytics.Message, len(events))
+	for i, e := range events {
+		msgs[i] = e.message()
+	}
+	return msgs, nil
+}
+
+// queueCallback queues the messages that the client fails to send.
+type queueCallback struct {
+	queue *eventQueue
+}
+
+func (c queueCallback) Success(analytics.Message) {}
+
+func (c queueCallback) Failure(msg analytics.Message, _ error) {
+	_ = c.queue.add(msg)
+}
+
+// offlineAnalyticsClient queues all messages, for when the analytics endpoint can't be reached.
+type offlineAnalyticsClient struct {
+	queue *eventQueue
+}
+
+func (c offlineAnalyticsClient) Enqueue(msg analytics.Message) error {
+	return c.queue.add(withTimestamp(msg))
+}
+
+func (c offlineAnalyticsClient) Close() error {
+	return nil
+}
+
+// withTimestamp sets the timestamp of the message to now, if it isn't set, so that events that are sent later keep
+// the time that they happened at.
+func withTimestamp(msg analytics.Message) analytics.Message {
+	switch m := msg.(type) {
+	case *analytics.Track:
+		msg = *m
+	case *analytics.Alias:
+		msg = *m
+	}
+	switch m := msg.(type) {
+	case analytics.Track:
+		if m.Timestamp.IsZero() {
+			m.Timestamp = time.Now()
+		}
+		msg = m
+	case analytics.Alias:
+		if m.Timestamp.IsZero() {
+			m.Timestamp = time.Now()
+		}
+		msg = m
+	}
+	return msg
+}
@@ -0,0 +1,90 @@
+//go:build !notel
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxanalytics
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/segmentio/analytics-go/v3"
+)
+
+var (
+	samplingMu  sync.Mutex
+	sampleRates = map[string]float64{}
+	eventCounts = map[string]int{}
+)
+
+// SampleEvent makes the client send only a fraction of the occurrences of a high-frequency event, such as the
+// execution of a script that is re-run by px live. The first occurrence in each run of the CLI is always sent. Sent
+// events have a sampleRate property, so that their counts can be scaled back up.
+func SampleEvent(event string, rate float64) {
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	sampleRates[event] = rate
+}
+
+// sample returns whether an occurrence of the event should be sent, and the rate that it was sampled at.
+func sample(event string) (bool, float64) {
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	rate, ok := sampleRates[event]
+	if !ok || rate >= 1 {
+		return true, 1
+	}
+	eventCounts[event]++
+	if eventCounts[event] == 1 {
+		return true, rate
+	}
+	return rand.Float64() < rate, rate
+}
+
+// samplingClient drops the occurrences of sampled events that aren't sent.
+type samplingClient struct {
+	analytics.Client
+}
+
+func (c samplingClient) Enqueue(msg analytics.Message) error {
+	var track analytics.Track
+	switch m := msg.(type) {
+	case analytics.Track:
+		track = m
+	case *analytics.Track:
+		if m == nil {
+			return c.Client.Enqueue(msg)
+		}
+		track = *m
+	default:
+		return c.Client.Enqueue(msg)
+	}
+	send, rate := sample(track.Event)
+	if !send {
+		return nil
+	}
+	if rate < 1 {
+		props := analytics.NewProperties()
+		for k, v := range track.Properties {
+			props[k] = v
+		}
+		track.Properties = props.Set("sampleRate", rate)
+	}
+	return c.Client.Enqueue(track)
+}
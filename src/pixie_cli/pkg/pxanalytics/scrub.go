@@ -0,0 +1,142 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxanalytics
+
+import (
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// unscrubbedProperties are the event properties that never contain personal information, and are sent as is.
+var unscrubbedProperties = map[string]bool{
+	"app":            true,
+	"cloud_addr":     true,
+	"cluster_id":     true,
+	"cluster_status": true,
+	"cmd":            true,
+	"scriptName":     true,
+}
+
+// minIdentifierLen is the length of the shortest identifier that is redacted. Shorter identifiers would redact too
+// much of the text that they appear in.
+const minIdentifierLen = 3
+
+var (
+	scrubURLRe      = regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]*[^\s"'<>.,:;)\]]`)
+	scrubEmailRe    = regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)
+	scrubIPv4Re     = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`)
+	scrubIPv6Re     = regexp.MustCompile(`[\w:.]*:[\w:.]*(?:%[\w.-]+)?`)
+	scrubPathRe     = regexp.MustCompile(`(^|[\s"'=(\[,:])(~?/[^\s"',:)\]]+|[a-zA-Z]:[\\/][^\s"',:)\]]+|\\\\[^\s"',:)\]]+)`)
+	scrubHostRe     = regexp.MustCompile(`\b(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,63}(?::\d+)?\b`)
+	scrubQuotedRe   = regexp.MustCompile(`"[^"\n]+"`)
+	fileExtensions  = map[string]bool{".go": true, ".json": true, ".yaml": true, ".yml": true, ".txt": true, ".log": true, ".pxl": true, ".sh": true, ".md": true}
+	identifiersMu   sync.Mutex
+	userIdentifiers []string
+)
+
+// RedactIdentifiers registers identifiers that the user provided, such as the names of clusters and namespaces, so
+// that they are redacted from the properties of analytics events.
+func RedactIdentifiers(ids ...string) {
+	identifiersMu.Lock()
+	defer identifiersMu.Unlock()
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); len(id) >= minIdentifierLen {
+			userIdentifiers = append(userIdentifiers, id)
+		}
+	}
+	// Redact the longest identifiers first, in case an identifier contains another.
+	sort.SliceStable(userIdentifiers, func(i, j int) bool {
+		return len(userIdentifiers[i]) > len(userIdentifiers[j])
+	})
+}
+
+func redactIdentifiers(s string) string {
+	identifiersMu.Lock()
+	defer identifiersMu.Unlock()
+	for _, id := range userIdentifiers {
+		re := regexp.MustCompile(`(^|[^\w-])` + regexp.QuoteMeta(id) + `($|[^\w-])`)
+		s = re.ReplaceAllString(s, "${1}<redacted>${2}")
+	}
+	return s
+}
+
+// Scrub redacts personal information from text that is sent with analytics events, such as error messages:
+// identifiers registered with RedactIdentifiers, URLs, email addresses, IP addresses, file paths, hostnames and
+// quoted values.
+func Scrub(s string) string {
+	s = redactIdentifiers(s)
+	s = scrubURLRe.ReplaceAllString(s, "<url>")
+	s = scrubEmailRe.ReplaceAllString(s, "<email>")
+	s = scrubIPv4Re.ReplaceAllString(s, "<ip>")
+	s = scrubIPv6Re.ReplaceAllStringFunc(s, func(m string) string {
+		// The expression also matches times and words separated by colons, so only redact valid addresses. The
+		// match may end with the colon that follows the address in error messages.
+		if isIP(m) {
+			return "<ip>"
+		}
+		if trimmed := strings.TrimSuffix(m, ":"); trimmed != m && isIP(trimmed) {
+			return "<ip>:"
+		}
+		return m
+	})
+	s = scrubPathRe.ReplaceAllString(s, "${1}<path>")
+	s = scrubHostRe.ReplaceAllStringFunc(s, func(m string) string {
+		// File names, such as demo.yaml, look like hostnames.
+		if fileExtensions[strings.ToLower(filepath.Ext(m))] {
+			return m
+		}
+		return "<host>"
+	})
+	s = scrubQuotedRe.ReplaceAllString(s, `"<redacted>"`)
+	return s
+}
+
+// isIP returns whether s is an IP address, which may have a zone such as %eth0.
+func isIP(s string) bool {
+	if i := strings.LastIndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	return net.ParseIP(s) != nil
+}
+
+func scrubProperties(props Properties) Properties {
+	if props == nil {
+		return nil
+	}
+	scrubbed := make(Properties, len(props))
+	for k, v := range props {
+		if unscrubbedProperties[k] {
+			scrubbed[k] = v
+			continue
+		}
+		switch val := v.(type) {
+		case string:
+			scrubbed[k] = Scrub(val)
+		case error:
+			scrubbed[k] = Scrub(val.Error())
+		default:
+			scrubbed[k] = v
+		}
+	}
+	return scrubbed
+}
@@ -20,6 +20,7 @@ package pxconfig
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"sync"
 
@@ -30,15 +31,183 @@ import (
 
 // ConfigInfo store the config about the CLI.
 type ConfigInfo struct {
+	// Version is the version of the layout of the config file, which is migrated to configVersion when it is read.
+	Version int `json:"version"`
 	// UniqueClientID is the ID assigned to this user on first startup when auth information is not know. This can be later associated with the UserID.
 	UniqueClientID string `json:"uniqueClientID"`
+	// Analytics configures the usage analytics that the CLI sends.
+	Analytics AnalyticsConfig `json:"analytics"`
+	// CrashReports configures what happens to the reports of crashes of the CLI.
+	CrashReports CrashReportsConfig `json:"crashReports"`
+	// Credentials configures how credentials are stored.
+	Credentials CredentialsConfig `json:"credentials"`
+	// Kube configures how the CLI connects to Kubernetes clusters.
+	Kube KubeConfig `json:"kube"`
+	// Demo configures the demo apps of px demo.
+	Demo DemoConfig `json:"demo"`
+	// Deploy configures px deploy.
+	Deploy DeployConfig `json:"deploy"`
+	// Output configures how commands write their results.
+	Output OutputConfig `json:"output"`
+	// Prompts configures the prompts that ask the user for input.
+	Prompts PromptsConfig `json:"prompts"`
+	// Metrics configures the durations of commands that the CLI records.
+	Metrics MetricsConfig `json:"metrics"`
+	// UpdateNotice configures the notice that a newer release of the CLI is available.
+	UpdateNotice UpdateNoticeConfig `json:"updateNotice"`
+	// Network configures the connections of the CLI to Pixie Cloud, the artifact endpoints and the analytics
+	// endpoint.
+	Network NetworkConfig `json:"network"`
+	// Clusters overrides settings for the clusters of kubeconfig contexts, keyed by the name of the context.
+	Clusters map[string]*ClusterConfig `json:"clusters,omitempty"`
+	// Aliases are the user's short names for commands, such as "dd" for "demo deploy", keyed by the alias.
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// ClusterConfig overrides the settings of the config for the cluster of a kubeconfig context. Empty fields don't
+// override anything.
+type ClusterConfig struct {
+	Demo   DemoConfig   `json:"demo"`
+	Deploy DeployConfig `json:"deploy"`
+}
+
+// settings returns the overrides as a config, so that they can be read and changed by the settings.
+func (c *ClusterConfig) settings() *ConfigInfo {
+	return &ConfigInfo{Demo: c.Demo, Deploy: c.Deploy}
+}
+
+// setSettings stores the overrides of the given config.
+func (c *ClusterConfig) setSettings(cfg *ConfigInfo) {
+	c.Demo = cfg.Demo
+	c.Deploy = cfg.Deploy
+}
+
+// KubeConfig configures how the CLI connects to Kubernetes clusters.
+type KubeConfig struct {
+	// Context is the kubeconfig context that commands use. The current context of the kubeconfig is used if it's
+	// empty.
+	Context string `json:"context,omitempty"`
+}
+
+// DemoConfig configures the demo apps of px demo.
+type DemoConfig struct {
+	// Artifacts is the location of the demo apps, in the format of the --artifacts flag, which overrides it. The
+	// default location is used if it's empty.
+	Artifacts string `json:"artifacts,omitempty"`
+	// Channel is the release channel of the demo apps, which the --channel flag overrides. The stable channel is
+	// used if it's empty.
+	Channel string `json:"channel,omitempty"`
+}
+
+// DeployConfig configures px deploy.
+type DeployConfig struct {
+	// Labels are the custom labels applied to Pixie resources, in the format of the --labels flag, which overrides
+	// them.
+	Labels string `json:"labels,omitempty"`
+}
+
+// OutputConfig configures how commands write their results.
+type OutputConfig struct {
+	// Format is the output format of commands that aren't given --output. Each command's own default is used if it's
+	// empty.
+	Format string `json:"format,omitempty"`
+}
+
+// PromptsConfig configures the prompts that ask the user for input.
+type PromptsConfig struct {
+	// Timeout is how long prompts wait for an answer, as a duration such as "10m", which the --prompt_timeout flag
+	// overrides. Prompts wait forever if it's empty.
+	Timeout string `json:"timeout,omitempty"`
+	// TimeoutAction is what happens once a prompt times out, which the --prompt_timeout_action flag overrides: "abort"
+	// to exit the command, or "default" to take the default answer. Commands abort if it's empty.
+	TimeoutAction string `json:"timeoutAction,omitempty"`
+}
+
+// NetworkConfig configures the connections of the CLI to Pixie Cloud, the artifact endpoints and the analytics
+// endpoint. Connections to Kubernetes clusters are configured by the kubeconfig instead.
+type NetworkConfig struct {
+	// Proxy is the URL of the SOCKS5 proxy that connections go through, which the --proxy flag overrides.
+	// Connections go through the proxy of the HTTP_PROXY and HTTPS_PROXY env vars if it's empty.
+	Proxy string `json:"proxy,omitempty"`
+	// CACert is the path of a PEM file of CA certificates that servers are trusted with, in addition to the system's,
+	// which the --cacert flag overrides.
+	CACert string `json:"cacert,omitempty"`
+	// ClientCert and ClientKey are the paths of the certificate and key that the CLI authenticates to servers with,
+	// which the --client_cert and --client_key flags override.
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+}
+
+// MetricsConfig configures the durations of commands and their tasks that the CLI records, which px stats shows.
+type MetricsConfig struct {
+	// Enabled turns on recording the durations. They aren't recorded by default.
+	Enabled bool `json:"enabled,omitempty"`
+	// Analytics is whether the durations are also sent with the usage analytics, if they are enabled.
+	Analytics bool `json:"analytics,omitempty"`
+}
+
+// CrashReportsConfig configures what happens to the reports of crashes of the CLI. The reports are always written
+// locally, under ~/.local/state/pixie/crashes.
+type CrashReportsConfig struct {
+	// Submit is whether crash reports are submitted to the Pixie team: CrashReportsAsk to ask the user after each
+	// crash, CrashReportsAlways or CrashReportsNever. The user is asked if it's empty.
+	Submit string `json:"submit,omitempty"`
+}
+
+// UpdateNoticeConfig configures the notice that a newer release of the CLI is available, which is shown after
+// commands.
+type UpdateNoticeConfig struct {
+	// Disabled turns off the notice, and checking for newer releases.
+	Disabled bool `json:"disabled,omitempty"`
+	// Channel is the release channel that is checked for newer releases. The stable channel is checked if it's empty.
+	Channel string `json:"channel,omitempty"`
+}
+
+// The values of the crash_reports.submit setting.
+const (
+	CrashReportsAsk    = "ask"
+	CrashReportsAlways = "always"
+	CrashReportsNever  = "never"
+)
+
+// CredentialsConfig configures how credentials, such as the refresh token of px auth login, are stored.
+type CredentialsConfig struct {
+	// Store is where credentials are stored: CredentialsStoreKeychain or CredentialsStoreFile. They are stored in the
+	// OS keychain if it's empty.
+	Store string `json:"store,omitempty"`
+}
+
+// AnalyticsConfig configures the usage analytics that the CLI sends.
+type AnalyticsConfig struct {
+	// Disabled turns off all usage analytics.
+	Disabled bool `json:"disabled,omitempty"`
+	// Decided is whether the user chose whether to send usage analytics, at the consent prompt or with px config set
+	// analytics.enabled. No analytics are sent until they do.
+	Decided bool `json:"decided,omitempty"`
+	// Endpoint is the URL of the Segment-compatible endpoint that analytics are sent to, or "none" to not send
+	// analytics anywhere. The default endpoint of the build is used if it's empty.
+	Endpoint string `json:"endpoint,omitempty"`
+	// WriteKey is the key that analytics are sent with. It is fetched from the endpoint if it's empty.
+	WriteKey string `json:"writeKey,omitempty"`
 }
 
 var (
-	config *ConfigInfo
-	once   sync.Once
+	config     *ConfigInfo
+	configPath string
+	// configErr is why the config can't be saved: the config file couldn't be read, so the default config is used
+	// instead, or it was written by a newer version of the CLI, whose changes would be lost.
+	configErr error
+	once      sync.Once
 )
 
+func newClientID() (string, error) {
+	clientID, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return clientID.String(), nil
+}
+
 func writeDefaultConfig(path string) (*ConfigInfo, error) {
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
 	if err != nil {
@@ -46,12 +215,12 @@ func writeDefaultConfig(path string) (*ConfigInfo, error) {
 	}
 	defer f.Close()
 
-	clientID, err := uuid.NewV4()
+	clientID, err := newClientID()
 	if err != nil {
 		return nil, err
 	}
 
-	cfg := &ConfigInfo{UniqueClientID: clientID.String()}
+	cfg := &ConfigInfo{Version: configVersion, UniqueClientID: clientID}
 	if err := json.NewEncoder(f).Encode(cfg); err != nil {
 		return nil, err
 	}
@@ -59,23 +228,57 @@ func writeDefaultConfig(path string) (*ConfigInfo, error) {
 }
 
 func readDefaultConfig(path string) (*ConfigInfo, error) {
-	f, err := os.Open(path)
+	orig, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	plain, err := decryptConfig(orig)
+	if err != nil {
+		return nil, err
+	}
+	b, version, err := migrateConfig(plain)
+	if err != nil {
+		return nil, err
+	}
+	if version < configVersion {
+		// Keep the file as it was before the migration, in case an older version of the CLI is used again.
+		if err := os.WriteFile(fmt.Sprintf("%s.v%d.bak", path, version), orig, 0600); err != nil {
+			return nil, err
+		}
+		if err := writeConfigFile(path, b); err != nil {
+			return nil, err
+		}
+	}
 
 	cfg := &ConfigInfo{}
-	if err := json.NewDecoder(f).Decode(cfg); err != nil {
+	if err := json.Unmarshal(b, cfg); err != nil {
 		return nil, err
 	}
 	return cfg, nil
 }
 
+func writeConfig(path string, cfg *ConfigInfo) error {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return writeConfigFile(path, b)
+}
+
+// writeConfigFile writes the given config file, encrypting it if the config file is encrypted.
+func writeConfigFile(path string, b []byte) error {
+	b, err := encryptConfig(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0600)
+}
+
 // Cfg returns the default config.
 func Cfg() *ConfigInfo {
 	once.Do(func() {
-		configPath, err := utils.EnsureDefaultConfigFilePath()
+		var err error
+		configPath, err = utils.EnsureDefaultConfigFilePath()
 		if err != nil {
 			utils.WithError(err).Fatal("Failed to load/create config file path")
 		}
@@ -89,8 +292,41 @@ func Cfg() *ConfigInfo {
 		}
 
 		if config, err = readDefaultConfig(configPath); err != nil {
-			utils.WithError(err).Fatal("Failed to read config file")
+			utils.WithError(err).Errorf("Failed to read config file %s, using the default settings. "+
+				"Run px config validate to find the problem.", configPath)
+			config = &ConfigInfo{Version: configVersion}
+			configErr = fmt.Errorf("the config file %s can't be read, run px config validate to find the problem: %w",
+				configPath, err)
+			return
+		}
+		if config.Version > configVersion {
+			// The settings that this version knows about are still used.
+			utils.Errorf("The config file %s was written by a newer version of px. Some settings may be ignored.",
+				configPath)
+			configErr = fmt.Errorf("the config file %s was written by a newer version of px, upgrade px to change it",
+				configPath)
 		}
 	})
 	return config
 }
+
+// Save writes the config to the config file.
+func Save(cfg *ConfigInfo) error {
+	Cfg()
+	if configErr != nil {
+		return configErr
+	}
+	return writeConfig(configPath, cfg)
+}
+
+// ResetClientID assigns a new UniqueClientID, so that later analytics events can't be linked to earlier ones, and
+// writes it to the config file.
+func ResetClientID() error {
+	cfg := Cfg()
+	clientID, err := newClientID()
+	if err != nil {
+		return err
+	}
+	cfg.UniqueClientID = clientID
+	return Save(cfg)
+}
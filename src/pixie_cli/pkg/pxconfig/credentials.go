@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// Where credentials, such as the refresh token of px auth login, are stored.
+const (
+	// CredentialsStoreKeychain stores credentials in the keychain of the OS: the macOS Keychain, or the Secret
+	// Service on Linux. Credentials are stored in files if the keychain isn't available.
+	CredentialsStoreKeychain = "keychain"
+	// CredentialsStoreFile stores credentials in plaintext files under ~/.config/pixie.
+	CredentialsStoreFile = "file"
+)
+
+// keychainService is the service that credentials are stored under in the keychain.
+const keychainService = "px.dev/pixie"
+
+// errKeychainUnavailable is returned by the keychain functions if the OS has no keychain that the CLI can use.
+var errKeychainUnavailable = errors.New("no OS keychain is available")
+
+// CredentialsStore returns where credentials are stored: the --credentials_store flag if it's set, the
+// credentials.store setting otherwise, and the OS keychain by default.
+func CredentialsStore() string {
+	if store := viper.GetString("credentials_store"); store != "" {
+		return store
+	}
+	if store := Cfg().Credentials.Store; store != "" {
+		return store
+	}
+	return CredentialsStoreKeychain
+}
+
+func validateCredentialsStore(store string) error {
+	switch store {
+	case CredentialsStoreKeychain, CredentialsStoreFile:
+		return nil
+	default:
+		return fmt.Errorf("invalid credentials store %q, must be %s or %s", store, CredentialsStoreKeychain,
+			CredentialsStoreFile)
+	}
+}
+
+// SaveCredential stores the secret with the given name in the OS keychain, or in the file at path if credentials
+// are stored in files or the keychain isn't available. Once the secret is in the keychain, the file is removed.
+func SaveCredential(name, path string, secret []byte) error {
+	store := CredentialsStore()
+	if err := validateCredentialsStore(store); err != nil {
+		return err
+	}
+	if store == CredentialsStoreKeychain {
+		err := keychainSet(name, secret)
+		if err == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		if !errors.Is(err, errKeychainUnavailable) {
+			return fmt.Errorf("failed to store credentials in the OS keychain, pass --credentials_store=file to store them in a file: %w", err)
+		}
+	}
+	return os.WriteFile(path, secret, 0600)
+}
+
+// LoadCredential returns the secret with the given name from the OS keychain, or from the file at path. Secrets that
+// are found in the file while credentials are stored in the keychain are moved to the keychain, and the other way
+// around. The returned error matches os.ErrNotExist if there is no secret.
+func LoadCredential(name, path string) ([]byte, error) {
+	store := CredentialsStore()
+	if err := validateCredentialsStore(store); err != nil {
+		return nil, err
+	}
+	useKeychain := store == CredentialsStoreKeychain
+	if useKeychain {
+		secret, err := keychainGet(name)
+		switch {
+		case err == nil:
+			return secret, nil
+		case errors.Is(err, errKeychainUnavailable):
+			useKeychain = false
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("failed to read credentials from the OS keychain: %w", err)
+		}
+	}
+
+	secret, err := os.ReadFile(path)
+	if os.IsNotExist(err) && store == CredentialsStoreFile {
+		// Move credentials that were stored in the keychain before credentials were stored in files.
+		secret, kerr := keychainGet(name)
+		if kerr != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, secret, 0600); err != nil {
+			return nil, err
+		}
+		_ = keychainDelete(name)
+		return secret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if useKeychain {
+		// Move credentials that were stored before the keychain was used, so that they aren't left in plaintext.
+		if err := keychainSet(name, secret); err == nil {
+			_ = os.Remove(path)
+		}
+	}
+	return secret, nil
+}
+
+// DeleteCredential removes the secret with the given name from both the OS keychain and the file at path.
+func DeleteCredential(name, path string) error {
+	if err := keychainDelete(name); err != nil && !errors.Is(err, os.ErrNotExist) &&
+		!errors.Is(err, errKeychainUnavailable) {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+)
+
+// PassphraseEnvVar is the env var that the passphrase of an encrypted config file is read from. The user is prompted
+// for the passphrase if it isn't set.
+const PassphraseEnvVar = "PX_CONFIG_PASSPHRASE"
+
+const (
+	encryptionKDF    = "scrypt"
+	encryptionCipher = "aes-256-gcm"
+	// The scrypt parameters recommended for interactive logins.
+	scryptN    = 1 << 15
+	scryptR    = 8
+	scryptP    = 1
+	saltLength = 16
+	keyLength  = 32
+
+	minPassphraseLength = 8
+)
+
+// encryptedConfig is the layout of an encrypted config file. Nothing but the encryption parameters is stored in
+// plaintext.
+type encryptedConfig struct {
+	Encryption encryptionParams `json:"encryption"`
+	// Encrypted is the config file, encrypted with the key derived from the passphrase.
+	Encrypted []byte `json:"encrypted"`
+}
+
+// encryptionParams are the parameters that the key of an encrypted config file is derived and used with.
+type encryptionParams struct {
+	KDF    string `json:"kdf"`
+	Salt   []byte `json:"salt"`
+	N      int    `json:"n"`
+	R      int    `json:"r"`
+	P      int    `json:"p"`
+	Cipher string `json:"cipher"`
+	Nonce  []byte `json:"nonce"`
+}
+
+// configKey is the key that the config file is encrypted with, and configSalt is the salt that it was derived with.
+// configKey is nil if the config file isn't encrypted.
+var (
+	configKey  []byte
+	configSalt []byte
+)
+
+// ConfigEncrypted returns whether the config file is encrypted.
+func ConfigEncrypted() bool {
+	Cfg()
+	return configKey != nil
+}
+
+// EncryptConfig encrypts the config file with a key derived from the given passphrase. The config is decrypted when
+// it is read, with the passphrase from PassphraseEnvVar or a prompt.
+func EncryptConfig(passphrase string) error {
+	if len(passphrase) < minPassphraseLength {
+		return fmt.Errorf("the passphrase must be at least %d characters long", minPassphraseLength)
+	}
+	cfg := Cfg()
+	if configErr != nil {
+		return configErr
+	}
+	if err := setPassphrase(passphrase); err != nil {
+		return err
+	}
+	if err := Save(cfg); err != nil {
+		return err
+	}
+	// The backups that migrations keep of the config file are in plaintext.
+	backups, _ := filepath.Glob(configPath + ".v*.bak")
+	for _, b := range backups {
+		if err := os.Remove(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setPassphrase sets the key that the config file is encrypted with to one derived from the passphrase with a new
+// salt.
+func setPassphrase(passphrase string) error {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return err
+	}
+	configKey, configSalt = key, salt
+	return nil
+}
+
+// DecryptConfig stores the config file in plaintext again.
+func DecryptConfig() error {
+	cfg := Cfg()
+	if configErr != nil {
+		return configErr
+	}
+	configKey, configSalt = nil, nil
+	return Save(cfg)
+}
+
+// decryptConfig returns the plaintext of the given config file, which is returned as is if it isn't encrypted.
+func decryptConfig(b []byte) ([]byte, error) {
+	var enc encryptedConfig
+	if err := json.Unmarshal(b, &enc); err != nil || enc.Encrypted == nil {
+		return b, nil
+	}
+	p := enc.Encryption
+	if p.KDF != encryptionKDF || p.Cipher != encryptionCipher {
+		return nil, fmt.Errorf("unsupported encryption %s with %s", p.Cipher, p.KDF)
+	}
+	// Larger parameters than the CLI writes would make deriving the key take too much memory and time, and are only
+	// found in tampered files.
+	if p.N > scryptN || p.R > scryptR || p.P > scryptP {
+		return nil, fmt.Errorf("unsupported scrypt parameters N=%d, r=%d, p=%d", p.N, p.R, p.P)
+	}
+
+	key := configKey
+	if key == nil || !bytes.Equal(configSalt, p.Salt) {
+		passphrase, err := readPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		key, err = scrypt.Key([]byte(passphrase), p.Salt, p.N, p.R, p.P, keyLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	// Open panics on nonces of the wrong size.
+	if len(p.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce of %d bytes", len(p.Nonce))
+	}
+	plain, err := aead.Open(nil, p.Nonce, enc.Encrypted, nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase")
+	}
+	configKey, configSalt = key, p.Salt
+	return plain, nil
+}
+
+// encryptConfig encrypts the given config file if the config file is encrypted, or else returns it as is.
+func encryptConfig(plain []byte) ([]byte, error) {
+	if configKey == nil {
+		return plain, nil
+	}
+	aead, err := newAEAD(configKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return json.Marshal(&encryptedConfig{
+		Encryption: encryptionParams{
+			KDF:    encryptionKDF,
+			Salt:   configSalt,
+			N:      scryptN,
+			R:      scryptR,
+			P:      scryptP,
+			Cipher: encryptionCipher,
+			Nonce:  nonce,
+		},
+		Encrypted: aead.Seal(nil, nonce, plain, nil),
+	})
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readPassphrase returns the passphrase of the config file from PassphraseEnvVar, or else prompts for it.
+func readPassphrase() (string, error) {
+	if passphrase := os.Getenv(PassphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	// Reading the passphrase from stdin when it isn't a terminal would consume the input of the command.
+	if !components.IsTerminal(os.Stdin) {
+		return "", fmt.Errorf("the config file is encrypted, set %s to its passphrase", PassphraseEnvVar)
+	}
+	return components.SecretPrompt("Passphrase of the px config file")
+}
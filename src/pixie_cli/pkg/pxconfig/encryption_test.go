@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `{"version":1,"uniqueClientID":"1234"}`
+
+// encryptTestConfig encrypts testConfig with the passphrase, then forgets the key, so that decrypting the config
+// derives it from the passphrase in PassphraseEnvVar again.
+func encryptTestConfig(t *testing.T, passphrase string) []byte {
+	require.NoError(t, setPassphrase(passphrase))
+	t.Cleanup(func() {
+		configKey, configSalt = nil, nil
+	})
+	b, err := encryptConfig([]byte(testConfig))
+	require.NoError(t, err)
+	configKey, configSalt = nil, nil
+	return b
+}
+
+func TestEncryptConfig_RoundTrip(t *testing.T) {
+	b := encryptTestConfig(t, "correct horse")
+	assert.NotContains(t, string(b), "1234")
+
+	t.Setenv(PassphraseEnvVar, "correct horse")
+	plain, err := decryptConfig(b)
+	require.NoError(t, err)
+	assert.Equal(t, testConfig, string(plain))
+
+	// The key is kept, so that the config is encrypted again when it is saved.
+	again, err := encryptConfig(plain)
+	require.NoError(t, err)
+	assert.NotEqual(t, b, again)
+	plain, err = decryptConfig(again)
+	require.NoError(t, err)
+	assert.Equal(t, testConfig, string(plain))
+}
+
+func TestEncryptConfig_WrongPassphrase(t *testing.T) {
+	b := encryptTestConfig(t, "correct horse")
+
+	t.Setenv(PassphraseEnvVar, "battery staple")
+	_, err := decryptConfig(b)
+	assert.EqualError(t, err, "wrong passphrase")
+	assert.Nil(t, configKey)
+}
+
+func TestEncryptConfig_Plaintext(t *testing.T) {
+	plain, err := encryptConfig([]byte(testConfig))
+	require.NoError(t, err)
+	assert.Equal(t, testConfig, string(plain))
+
+	plain, err = decryptConfig([]byte(testConfig))
+	require.NoError(t, err)
+	assert.Equal(t, testConfig, string(plain))
+}
+
+func TestDecryptConfig_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(p *encryptionParams)
+		expected string
+	}{
+		{
+			name:     "larger N",
+			tamper:   func(p *encryptionParams) { p.N = 1 << 30 },
+			expected: "unsupported scrypt parameters N=1073741824, r=8, p=1",
+		},
+		{
+			name:     "larger r",
+			tamper:   func(p *encryptionParams) { p.R = 1 << 20 },
+			expected: "unsupported scrypt parameters N=32768, r=1048576, p=1",
+		},
+		{
+			name:     "larger p",
+			tamper:   func(p *encryptionParams) { p.P = 1 << 20 },
+			expected: "unsupported scrypt parameters N=32768, r=8, p=1048576",
+		},
+		{
+			name:     "other cipher",
+			tamper:   func(p *encryptionParams) { p.Cipher = "aes-128-cbc" },
+			expected: "unsupported encryption aes-128-cbc with scrypt",
+		},
+		{
+			name:     "short nonce",
+			tamper:   func(p *encryptionParams) { p.Nonce = p.Nonce[:4] },
+			expected: "invalid nonce of 4 bytes",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var enc encryptedConfig
+			require.NoError(t, json.Unmarshal(encryptTestConfig(t, "correct horse"), &enc))
+			test.tamper(&enc.Encryption)
+			b, err := json.Marshal(&enc)
+			require.NoError(t, err)
+
+			t.Setenv(PassphraseEnvVar, "correct horse")
+			_, err = decryptConfig(b)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
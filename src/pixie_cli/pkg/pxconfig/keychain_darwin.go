@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// securityItemNotFound is the exit code of the security tool if the item doesn't exist.
+const securityItemNotFound = 44
+
+// The secrets are stored base64 encoded, since the Keychain mangles some binary values.
+func keychainSet(name string, secret []byte) error {
+	if _, err := exec.LookPath("security"); err != nil {
+		return errKeychainUnavailable
+	}
+	encoded := base64.StdEncoding.EncodeToString(secret)
+	// The command is passed on stdin, rather than as arguments, so that the secret doesn't show up in the process
+	// list.
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %q -a %q -X %q\n",
+		keychainService, name, hex.EncodeToString([]byte(encoded))))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	// security -i doesn't fail if a command fails, but prints the error.
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func keychainGet(name string) ([]byte, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, errKeychainUnavailable
+	}
+	out, err := exec.Command("security", "find-generic-password", "-s", keychainService, "-a", name, "-w").Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return nil, os.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func keychainDelete(name string) error {
+	if _, err := exec.LookPath("security"); err != nil {
+		return errKeychainUnavailable
+	}
+	err := exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", name).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return os.ErrNotExist
+	}
+	return err
+}
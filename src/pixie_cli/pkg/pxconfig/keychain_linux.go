@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The Secret Service is used through secret-tool, which is part of libsecret. The secrets are stored base64
+// encoded, since secret-tool only handles text.
+
+func secretToolCmd(args ...string) (*exec.Cmd, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, errKeychainUnavailable
+	}
+	// The Secret Service is reached over the D-Bus session bus, which isn't available in SSH sessions or containers.
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return nil, errKeychainUnavailable
+	}
+	return exec.Command("secret-tool", args...), nil
+}
+
+func runSecretTool(cmd *exec.Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+	msg := strings.TrimSpace(stderr.String())
+	var exitErr *exec.ExitError
+	// secret-tool exits without an error message if the secret doesn't exist.
+	if errors.As(err, &exitErr) && msg == "" {
+		return nil, os.ErrNotExist
+	}
+	// Without a running keyring, for example on a headless machine, the Secret Service can't be used.
+	return nil, fmt.Errorf("%w: %s", errKeychainUnavailable, msg)
+}
+
+func keychainSet(name string, secret []byte) error {
+	cmd, err := secretToolCmd("store", "--label", "Pixie CLI "+name, "service", keychainService, "account", name)
+	if err != nil {
+		return err
+	}
+	// The secret is passed on stdin, so that it doesn't show up in the process list.
+	cmd.Stdin = strings.NewReader(base64.StdEncoding.EncodeToString(secret))
+	_, err = runSecretTool(cmd)
+	return err
+}
+
+func keychainGet(name string) ([]byte, error) {
+	cmd, err := secretToolCmd("lookup", "service", keychainService, "account", name)
+	if err != nil {
+		return nil, err
+	}
+	out, err := runSecretTool(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func keychainDelete(name string) error {
+	cmd, err := secretToolCmd("clear", "service", keychainService, "account", name)
+	if err != nil {
+		return err
+	}
+	_, err = runSecretTool(cmd)
+	return err
+}
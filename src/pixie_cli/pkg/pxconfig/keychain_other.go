@@ -0,0 +1,35 @@
+//go:build !darwin && !linux
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+// There is no keychain support on other OSes, so credentials are stored in files.
+
+func keychainSet(string, []byte) error {
+	return errKeychainUnavailable
+}
+
+func keychainGet(string) ([]byte, error) {
+	return nil, errKeychainUnavailable
+}
+
+func keychainDelete(string) error {
+	return errKeychainUnavailable
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// configVersion is the version of the layout of the config file that this version of the CLI writes. Changes to the
+// layout increase it, and add a migration from the previous version to configMigrations.
+const configVersion = 1
+
+// configMigrations migrate the config file from each version to the next: configMigrations[v] migrates it from
+// version v to version v+1. They work on the decoded JSON, since the layout that they migrate from doesn't match
+// ConfigInfo anymore.
+var configMigrations = [configVersion]func(cfg map[string]interface{}) error{
+	// Version 0 is the layout from before the config file had a version, which is the same as version 1.
+	func(cfg map[string]interface{}) error {
+		return nil
+	},
+}
+
+// migrateConfig migrates the given config file to configVersion. It also returns the version that the file had. Files
+// written by a newer version of the CLI are returned as is.
+func migrateConfig(b []byte) ([]byte, int, error) {
+	cfg := make(map[string]interface{})
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, 0, err
+	}
+	version := 0
+	if v, ok := cfg["version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 0 || f != math.Trunc(f) {
+			return nil, 0, fmt.Errorf("invalid config version %v", v)
+		}
+		version = int(f)
+	}
+	if version >= configVersion {
+		return b, version, nil
+	}
+
+	for v := version; v < configVersion; v++ {
+		if err := configMigrations[v](cfg); err != nil {
+			return nil, 0, fmt.Errorf("failed to migrate the config from version %d: %w", v, err)
+		}
+	}
+	cfg["version"] = configVersion
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	return b, version, nil
+}
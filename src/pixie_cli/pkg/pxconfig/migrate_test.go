@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          string
+		expected        string
+		expectedVersion int
+	}{
+		{
+			name:            "unversioned",
+			config:          `{"uniqueClientID":"1234"}`,
+			expected:        `{"uniqueClientID":"1234","version":1}`,
+			expectedVersion: 0,
+		},
+		{
+			name:            "current version",
+			config:          `{"version":1,"uniqueClientID":"1234"}`,
+			expected:        `{"version":1,"uniqueClientID":"1234"}`,
+			expectedVersion: 1,
+		},
+		{
+			name:            "newer version",
+			config:          `{"version":7,"uniqueClientID":"1234","newSetting":true}`,
+			expected:        `{"version":7,"uniqueClientID":"1234","newSetting":true}`,
+			expectedVersion: 7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, version, err := migrateConfig([]byte(test.config))
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(b))
+			assert.Equal(t, test.expectedVersion, version)
+		})
+	}
+}
+
+func TestMigrateConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name:     "negative version",
+			config:   `{"version":-1}`,
+			expected: "invalid config version -1",
+		},
+		{
+			name:     "fractional version",
+			config:   `{"version":1.5}`,
+			expected: "invalid config version 1.5",
+		},
+		{
+			name:     "string version",
+			config:   `{"version":"1"}`,
+			expected: "invalid config version 1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := migrateConfig([]byte(test.config))
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+
+	_, _, err := migrateConfig([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,628 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// Setting is a setting in the config file that can be managed with the px config commands.
+type Setting struct {
+	// Key is the name of the setting, for example "analytics.enabled".
+	Key string
+	// Description explains what the setting does.
+	Description string
+	// Local is whether the setting is specific to a workstation or a secret, so that it isn't shared through px config
+	// export.
+	Local bool
+	// Cluster is whether the setting can be overridden for the cluster of a kubeconfig context.
+	Cluster bool
+
+	get func(cfg *ConfigInfo) string
+	set func(cfg *ConfigInfo, value string) error
+	// unset restores the default of the setting.
+	unset func(cfg *ConfigInfo)
+}
+
+// Get returns the value of the setting in the given config.
+func (s *Setting) Get(cfg *ConfigInfo) string {
+	return s.get(cfg)
+}
+
+var settings = []*Setting{
+	{
+		Key:         "analytics.enabled",
+		Description: "Whether the CLI sends usage analytics",
+		// Each user decides whether to send usage analytics.
+		Local: true,
+		get: func(cfg *ConfigInfo) string {
+			return strconv.FormatBool(cfg.Analytics.Decided && !cfg.Analytics.Disabled)
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value %q, must be true or false", value)
+			}
+			// Events sent after opting back in can't be linked to the ones sent before opting out.
+			if enabled && cfg.Analytics.Decided && cfg.Analytics.Disabled {
+				clientID, err := newClientID()
+				if err != nil {
+					return err
+				}
+				cfg.UniqueClientID = clientID
+			}
+			cfg.Analytics.Disabled = !enabled
+			cfg.Analytics.Decided = true
+			return nil
+		},
+		// The user is asked whether to send usage analytics again.
+		unset: func(cfg *ConfigInfo) {
+			cfg.Analytics.Disabled = false
+			cfg.Analytics.Decided = false
+		},
+	},
+	{
+		Key:         "analytics.endpoint",
+		Description: `The URL of the Segment-compatible endpoint that analytics are sent to, or "none" to not send them anywhere`,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Analytics.Endpoint
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			if value != "" && value != AnalyticsEndpointNone {
+				u, err := url.Parse(value)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("invalid value %q, must be an http(s) URL or %q", value, AnalyticsEndpointNone)
+				}
+				value = strings.TrimSuffix(value, "/")
+			}
+			cfg.Analytics.Endpoint = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Analytics.Endpoint = ""
+		},
+	},
+	{
+		Key:         "analytics.write_key",
+		Description: "The key that analytics are sent with, if the endpoint doesn't serve one",
+		Local:       true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Analytics.WriteKey
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			cfg.Analytics.WriteKey = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Analytics.WriteKey = ""
+		},
+	},
+	{
+		Key:         "crash_reports.submit",
+		Description: "Whether reports of crashes of the CLI are submitted to the Pixie team: ask, to ask after each crash, always or never",
+		// Each user decides whether to submit crash reports.
+		Local: true,
+		get: func(cfg *ConfigInfo) string {
+			if cfg.CrashReports.Submit == "" {
+				return CrashReportsAsk
+			}
+			return cfg.CrashReports.Submit
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			value = strings.ToLower(value)
+			switch value {
+			case CrashReportsAsk, CrashReportsAlways, CrashReportsNever:
+				cfg.CrashReports.Submit = value
+				return nil
+			}
+			return fmt.Errorf("invalid value %q, must be one of: %s, %s, %s", value, CrashReportsAsk, CrashReportsAlways, CrashReportsNever)
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.CrashReports.Submit = ""
+		},
+	},
+	{
+		Key:         "credentials.store",
+		Description: "Where credentials are stored: keychain, for the OS keychain, or file, for plaintext files under ~/.config/pixie",
+		get: func(cfg *ConfigInfo) string {
+			if cfg.Credentials.Store == "" {
+				return CredentialsStoreKeychain
+			}
+			return cfg.Credentials.Store
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			if err := validateCredentialsStore(value); err != nil {
+				return err
+			}
+			cfg.Credentials.Store = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Credentials.Store = ""
+		},
+	},
+	{
+		Key:         "demo.artifacts",
+		Description: "The location of the demo apps, in the format of px demo --artifacts",
+		Cluster:     true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Demo.Artifacts
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			cfg.Demo.Artifacts = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Demo.Artifacts = ""
+		},
+	},
+	{
+		Key:         "demo.channel",
+		Description: "The release channel of the demo apps: one of: stable|beta|dev",
+		Cluster:     true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Demo.Channel
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			switch value {
+			case "stable", "beta", "dev":
+				cfg.Demo.Channel = value
+				return nil
+			default:
+				return fmt.Errorf("invalid value %q, must be one of: stable, beta, dev", value)
+			}
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Demo.Channel = ""
+		},
+	},
+	{
+		Key:         "deploy.labels",
+		Description: "Custom labels to apply to Pixie resources, in the format of px deploy --labels",
+		Cluster:     true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Deploy.Labels
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			if _, err := k8s.KeyValueStringToMap(value); err != nil {
+				return fmt.Errorf("invalid value %q: %w", value, err)
+			}
+			cfg.Deploy.Labels = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Deploy.Labels = ""
+		},
+	},
+	{
+		Key:         "kube.context",
+		Description: "The kubeconfig context that commands use, instead of the current context of the kubeconfig",
+		Local:       true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Kube.Context
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			cfg.Kube.Context = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Kube.Context = ""
+		},
+	},
+	{
+		Key:         "metrics.analytics",
+		Description: "Whether the recorded durations of commands are also sent with the usage analytics",
+		Local:       true,
+		get: func(cfg *ConfigInfo) string {
+			return strconv.FormatBool(cfg.Metrics.Analytics)
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value %q, must be true or false", value)
+			}
+			cfg.Metrics.Analytics = enabled
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Metrics.Analytics = false
+		},
+	},
+	{
+		Key:         "metrics.enabled",
+		Description: "Whether the CLI records how long commands and their tasks take, which px stats shows",
+		Local:       true,
+		get: func(cfg *ConfigInfo) string {
+			return strconv.FormatBool(cfg.Metrics.Enabled)
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value %q, must be true or false", value)
+			}
+			cfg.Metrics.Enabled = enabled
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Metrics.Enabled = false
+		},
+	},
+	{
+		Key:         "network.cacert",
+		Description: "The PEM file of CA certificates that Pixie Cloud, artifact and analytics servers are trusted with, in addition to the system's",
+		// The paths are specific to a workstation.
+		Local: true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Network.CACert
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			cfg.Network.CACert = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Network.CACert = ""
+		},
+	},
+	{
+		Key:         "network.client_cert",
+		Description: "The PEM file of the certificate that the CLI authenticates to Pixie Cloud, artifact and analytics servers with",
+		Local:       true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Network.ClientCert
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			cfg.Network.ClientCert = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Network.ClientCert = ""
+		},
+	},
+	{
+		Key:         "network.client_key",
+		Description: "The PEM file of the key of network.client_cert",
+		Local:       true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Network.ClientKey
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			cfg.Network.ClientKey = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Network.ClientKey = ""
+		},
+	},
+	{
+		Key:         "network.proxy",
+		Description: "The SOCKS5 proxy that connections to Pixie Cloud, artifacts and analytics go through, in the format of --proxy",
+		// Proxies are specific to the network of a workstation.
+		Local: true,
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Network.Proxy
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			if _, err := utils.ParseProxyURL(value); err != nil {
+				return err
+			}
+			cfg.Network.Proxy = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Network.Proxy = ""
+		},
+	},
+	{
+		Key:         "output.format",
+		Description: "The output format of commands that aren't given --output: one of: " + strings.Join(outputFormats, "|"),
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Output.Format
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			value = strings.ToLower(value)
+			for _, f := range outputFormats {
+				if value == f {
+					cfg.Output.Format = value
+					return nil
+				}
+			}
+			return fmt.Errorf("invalid value %q, must be one of: %s", value, strings.Join(outputFormats, ", "))
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Output.Format = ""
+		},
+	},
+	{
+		Key:         "prompts.timeout",
+		Description: "How long prompts wait for an answer before prompts.timeout_action is taken, for example: 10m. Prompts wait forever if it's unset",
+		get: func(cfg *ConfigInfo) string {
+			return cfg.Prompts.Timeout
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return fmt.Errorf("invalid value %q, must be a duration such as 10m", value)
+			}
+			cfg.Prompts.Timeout = value
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Prompts.Timeout = ""
+		},
+	},
+	{
+		Key:         "prompts.timeout_action",
+		Description: "What happens once a prompt times out: abort, to exit the command, or default, to take the default answer",
+		get: func(cfg *ConfigInfo) string {
+			if cfg.Prompts.TimeoutAction == "" {
+				return promptTimeoutActions[0]
+			}
+			return cfg.Prompts.TimeoutAction
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			value = strings.ToLower(value)
+			for _, a := range promptTimeoutActions {
+				if value == a {
+					cfg.Prompts.TimeoutAction = value
+					return nil
+				}
+			}
+			return fmt.Errorf("invalid value %q, must be one of: %s", value, strings.Join(promptTimeoutActions, ", "))
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.Prompts.TimeoutAction = ""
+		},
+	},
+	{
+		Key:         "update_notice.channel",
+		Description: "The release channel that is checked for newer releases of the CLI: one of: stable|beta|dev",
+		get: func(cfg *ConfigInfo) string {
+			if cfg.UpdateNotice.Channel == "" {
+				return "stable"
+			}
+			return cfg.UpdateNotice.Channel
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			switch value {
+			case "stable", "beta", "dev":
+				cfg.UpdateNotice.Channel = value
+				return nil
+			default:
+				return fmt.Errorf("invalid value %q, must be one of: stable, beta, dev", value)
+			}
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.UpdateNotice.Channel = ""
+		},
+	},
+	{
+		Key:         "update_notice.enabled",
+		Description: "Whether the CLI checks for newer releases once a day, and shows a notice after commands when one is available",
+		get: func(cfg *ConfigInfo) string {
+			return strconv.FormatBool(!cfg.UpdateNotice.Disabled)
+		},
+		set: func(cfg *ConfigInfo, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value %q, must be true or false", value)
+			}
+			cfg.UpdateNotice.Disabled = !enabled
+			return nil
+		},
+		unset: func(cfg *ConfigInfo) {
+			cfg.UpdateNotice.Disabled = false
+		},
+	},
+}
+
+// promptTimeoutActions are the values of the prompts.timeout_action setting. The first is the default.
+var promptTimeoutActions = []string{"abort", "default"}
+
+// outputFormats are the values of the output.format setting, which are the formats that most commands support.
+var outputFormats = []string{"table", "json", "csv", "csv-rfc4180", "yaml"}
+
+// AnalyticsEndpointNone is the value of the analytics.endpoint setting that turns off all outbound analytics calls.
+const AnalyticsEndpointNone = "none"
+
+// Settings returns the settings that can be managed with the px config commands.
+func Settings() []*Setting {
+	return settings
+}
+
+// LookupSetting returns the setting with the given key.
+func LookupSetting(key string) (*Setting, error) {
+	for _, s := range settings {
+		if s.Key == key {
+			return s, nil
+		}
+	}
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		keys[i] = s.Key
+	}
+	return nil, fmt.Errorf("unknown setting %q, must be one of: %s", key, strings.Join(keys, ", "))
+}
+
+// Set changes the setting with the given key in the config file.
+func Set(key, value string) error {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return err
+	}
+	cfg := Cfg()
+	if err := s.set(cfg, value); err != nil {
+		return err
+	}
+	return Save(cfg)
+}
+
+// Unset restores the default of the setting with the given key in the config file.
+func Unset(key string) error {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return err
+	}
+	cfg := Cfg()
+	s.unset(cfg)
+	return Save(cfg)
+}
+
+// Export returns the settings that can be shared with other users, so that a team can use the same settings. Local
+// settings and settings that have their default value are left out.
+func Export() map[string]string {
+	cfg := Cfg()
+	values := make(map[string]string)
+	for _, s := range settings {
+		if s.Local {
+			continue
+		}
+		if v := s.Get(cfg); v != "" && v != s.Get(&ConfigInfo{}) {
+			values[s.Key] = v
+		}
+	}
+	return values
+}
+
+// Import changes the given settings in the config file, as exported by Export. No setting is changed unless all of
+// them are valid. Local settings can't be imported.
+func Import(values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Validate the settings on a copy of the config, so that it is left unchanged if any of them are invalid.
+	cfg := *Cfg()
+	for _, key := range keys {
+		s, err := LookupSetting(key)
+		if err != nil {
+			return err
+		}
+		if s.Local {
+			return fmt.Errorf("setting %q is specific to each user and can't be imported", key)
+		}
+		if err := s.set(&cfg, values[key]); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	*config = cfg
+	return Save(config)
+}
+
+// ClusterCfg returns the config with the overrides for the cluster of the given kubeconfig context applied.
+func ClusterCfg(context string) *ConfigInfo {
+	cfg := Cfg()
+	c, ok := cfg.Clusters[context]
+	if !ok {
+		return cfg
+	}
+	merged := *cfg
+	overrides := c.settings()
+	for _, s := range settings {
+		if v := s.get(overrides); s.Cluster && v != "" {
+			// The overrides were validated when they were set.
+			_ = s.set(&merged, v)
+		}
+	}
+	return &merged
+}
+
+// SetForCluster changes the setting with the given key for the cluster of the given kubeconfig context.
+func SetForCluster(context, key, value string) error {
+	s, err := clusterSetting(key)
+	if err != nil {
+		return err
+	}
+	cfg := Cfg()
+	c, ok := cfg.Clusters[context]
+	if !ok {
+		c = &ClusterConfig{}
+	}
+	overrides := c.settings()
+	if err := s.set(overrides, value); err != nil {
+		return err
+	}
+	c.setSettings(overrides)
+	if cfg.Clusters == nil {
+		cfg.Clusters = make(map[string]*ClusterConfig)
+	}
+	cfg.Clusters[context] = c
+	return Save(cfg)
+}
+
+// UnsetForCluster removes the override of the setting with the given key for the cluster of the given kubeconfig
+// context, so that the setting for all clusters applies.
+func UnsetForCluster(context, key string) error {
+	s, err := clusterSetting(key)
+	if err != nil {
+		return err
+	}
+	cfg := Cfg()
+	c, ok := cfg.Clusters[context]
+	if !ok {
+		return nil
+	}
+	overrides := c.settings()
+	s.unset(overrides)
+	c.setSettings(overrides)
+	if *c == (ClusterConfig{}) {
+		delete(cfg.Clusters, context)
+	}
+	return Save(cfg)
+}
+
+// SetAlias makes the alias expand to the given command line in the config file.
+func SetAlias(name, command string) error {
+	cfg := Cfg()
+	if cfg.Aliases == nil {
+		cfg.Aliases = make(map[string]string)
+	}
+	cfg.Aliases[name] = command
+	return Save(cfg)
+}
+
+// UnsetAlias removes the alias from the config file.
+func UnsetAlias(name string) error {
+	cfg := Cfg()
+	if _, ok := cfg.Aliases[name]; !ok {
+		return fmt.Errorf("unknown alias %q", name)
+	}
+	delete(cfg.Aliases, name)
+	return Save(cfg)
+}
+
+func clusterSetting(key string) (*Setting, error) {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Cluster {
+		return nil, fmt.Errorf("setting %q applies to all clusters and can't be set for a single cluster", key)
+	}
+	return s, nil
+}
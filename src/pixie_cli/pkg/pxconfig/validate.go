@@ -0,0 +1,299 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// reachableTimeout is how long Validate waits for the URLs in the config to respond.
+const reachableTimeout = 5 * time.Second
+
+// Problem is a problem with the config file, found by Validate.
+type Problem struct {
+	// Key is where the problem is in the config file, for example "demo.channel".
+	Key string
+	// Message describes the problem.
+	Message string
+	// Warning is whether the problem may be temporary, such as a URL that can't be reached while offline.
+	Warning bool
+}
+
+// Validate checks the config file: that it only contains known keys of the right types, that the settings have valid
+// values, and that the files, URLs and kubeconfig contexts that it refers to exist. Unknown keys are otherwise
+// ignored, so that the settings silently fall back to their defaults.
+func Validate() ([]Problem, error) {
+	path, err := utils.EnsureDefaultConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b, err = decryptConfig(b); err != nil {
+		return []Problem{{Key: path, Message: fmt.Sprintf("can't decrypt: %v", err)}}, nil
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return []Problem{{Key: path, Message: fmt.Sprintf("invalid JSON: %v", err)}}, nil
+	}
+	problems := validateSchema("", raw, reflect.TypeOf(ConfigInfo{}))
+	if obj, ok := raw.(map[string]interface{}); ok {
+		if v, ok := obj["version"].(float64); ok && v > configVersion {
+			// The layout of the file may have changed in ways that this version doesn't know about.
+			for i := range problems {
+				problems[i].Warning = true
+			}
+			problems = append([]Problem{{Key: "version", Warning: true,
+				Message: "the config file was written by a newer version of px, upgrade px to validate it"}}, problems...)
+		}
+	}
+	if len(problems) > 0 {
+		// The values can't be checked if they don't have the right types.
+		return problems, nil
+	}
+
+	cfg := &ConfigInfo{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	problems = append(problems, validateSettings("", cfg, false)...)
+	contexts := make([]string, 0, len(cfg.Clusters))
+	for context := range cfg.Clusters {
+		contexts = append(contexts, context)
+	}
+	sort.Strings(contexts)
+	for _, context := range contexts {
+		prefix := fmt.Sprintf("clusters.%s.", context)
+		problems = append(problems, validateSettings(prefix, cfg.Clusters[context].settings(), true)...)
+	}
+	problems = append(problems, validateContexts(cfg, contexts)...)
+	return problems, nil
+}
+
+// validateSchema checks that the decoded JSON value v matches the type t of the config, and reports unknown keys.
+func validateSchema(path string, v interface{}, t reflect.Type) []Problem {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []Problem{typeProblem(path, "an object")}
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			fields[name] = t.Field(i).Type
+		}
+		var problems []Problem
+		for _, key := range sortedKeys(obj) {
+			fieldType, ok := fields[key]
+			if !ok {
+				problems = append(problems, unknownKeyProblem(path+key, key, fields))
+				continue
+			}
+			problems = append(problems, validateSchema(path+key+".", obj[key], fieldType)...)
+		}
+		return problems
+	case reflect.Map:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []Problem{typeProblem(path, "an object")}
+		}
+		var problems []Problem
+		for _, key := range sortedKeys(obj) {
+			problems = append(problems, validateSchema(path+key+".", obj[key], t.Elem())...)
+		}
+		return problems
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			return []Problem{typeProblem(path, "a string")}
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return []Problem{typeProblem(path, "true or false")}
+		}
+	case reflect.Int:
+		if f, ok := v.(float64); !ok || f != math.Trunc(f) {
+			return []Problem{typeProblem(path, "an integer")}
+		}
+	}
+	return nil
+}
+
+func typeProblem(path, want string) Problem {
+	return Problem{Key: strings.TrimSuffix(path, "."), Message: fmt.Sprintf("must be %s", want)}
+}
+
+// unknownKeyProblem reports an unknown key, suggesting the known key that is closest to it, if any is close enough to
+// be a typo.
+func unknownKeyProblem(path, key string, known map[string]reflect.Type) Problem {
+	best, bestDistance := "", len(key)/3+2
+	for k := range known {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(k)); d < bestDistance || (d == bestDistance && k < best) {
+			best, bestDistance = k, d
+		}
+	}
+	p := Problem{Key: path, Message: "unknown key, it is ignored"}
+	if best != "" {
+		p.Message = fmt.Sprintf("unknown key, it is ignored. Did you mean %q?", strings.TrimSuffix(path, key)+best)
+	}
+	return p
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateSettings checks the values of the settings that aren't set to their defaults, and that the files and URLs
+// that they refer to exist. If cluster is true, only the settings that can be overridden for a cluster are checked.
+func validateSettings(prefix string, cfg *ConfigInfo, cluster bool) []Problem {
+	var problems []Problem
+	defaults := &ConfigInfo{}
+	for _, s := range settings {
+		if cluster && !s.Cluster {
+			continue
+		}
+		v := s.get(cfg)
+		if v == "" || v == s.get(defaults) {
+			continue
+		}
+		if err := s.set(&ConfigInfo{}, v); err != nil {
+			problems = append(problems, Problem{Key: prefix + s.Key, Message: err.Error()})
+			continue
+		}
+		switch s.Key {
+		case "analytics.endpoint":
+			if v != AnalyticsEndpointNone {
+				problems = append(problems, checkReachable(prefix+s.Key, v)...)
+			}
+		case "demo.artifacts":
+			for _, mirror := range strings.Split(v, ",") {
+				problems = append(problems, checkArtifacts(prefix+s.Key, strings.TrimSpace(mirror))...)
+			}
+		}
+	}
+	return problems
+}
+
+// checkArtifacts checks that a location of the demo apps exists. Cloud storage locations aren't checked, since they
+// need credentials that are only looked up by px demo.
+func checkArtifacts(key, location string) []Problem {
+	u, err := url.Parse(location)
+	if err != nil {
+		return []Problem{{Key: key, Message: fmt.Sprintf("invalid URL %q: %v", location, err)}}
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return checkReachable(key, location)
+	case "file", "":
+		if _, err := os.Stat(u.Path); err != nil {
+			return []Problem{{Key: key, Message: fmt.Sprintf("%s doesn't exist", u.Path)}}
+		}
+	case "gs", "s3", "az":
+	default:
+		return []Problem{{Key: key, Message: fmt.Sprintf("unsupported URL scheme %q", u.Scheme)}}
+	}
+	return nil
+}
+
+// checkReachable checks that the server of the given URL responds. Any response counts, since the URL itself may
+// not serve anything.
+func checkReachable(key, u string) []Problem {
+	httpClient := utils.NewHTTPClient(utils.NewHTTPTransport(), reachableTimeout, nil)
+	resp, err := httpClient.Head(u)
+	if err != nil {
+		return []Problem{{Key: key, Message: fmt.Sprintf("%s can't be reached: %v", u, err), Warning: true}}
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// validateContexts checks that the kubeconfig contexts that the config refers to exist.
+func validateContexts(cfg *ConfigInfo, clusters []string) []Problem {
+	if cfg.Kube.Context == "" && len(clusters) == 0 {
+		return nil
+	}
+	contexts, err := k8s.ListContexts()
+	if err != nil {
+		return []Problem{{Key: "kube.context", Message: fmt.Sprintf("the kubeconfig contexts can't be checked: %v", err),
+			Warning: true}}
+	}
+	known := make(map[string]bool)
+	for _, c := range contexts {
+		known[c] = true
+	}
+	var problems []Problem
+	if cfg.Kube.Context != "" && !known[cfg.Kube.Context] {
+		problems = append(problems, Problem{Key: "kube.context",
+			Message: fmt.Sprintf("context %q isn't in the kubeconfig", cfg.Kube.Context)})
+	}
+	for _, c := range clusters {
+		if !known[c] {
+			// The context may be in the kubeconfig of another machine that the config was copied from.
+			problems = append(problems, Problem{Key: "clusters." + c,
+				Message: fmt.Sprintf("context %q isn't in the kubeconfig, so these settings aren't used", c), Warning: true})
+		}
+	}
+	return problems
+}
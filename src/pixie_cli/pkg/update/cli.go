@@ -22,7 +22,6 @@ import (
 	"context"
 	"encoding/hex"
 	"io"
-	"net/http"
 	"os"
 	"runtime"
 	"strconv"
@@ -33,23 +32,17 @@ import (
 	"github.com/kardianos/osext"
 	"github.com/vbauerster/mpb/v4"
 	"github.com/vbauerster/mpb/v4/decor"
-	"golang.org/x/sys/unix"
 	"google.golang.org/grpc"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	version "px.dev/pixie/src/shared/goversion"
-	"px.dev/pixie/src/shared/services"
 )
 
 func newATClient(cloudAddr string) (cloudpb.ArtifactTrackerClient, error) {
 	isInternal := strings.Contains(cloudAddr, "cluster.local")
 
-	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
-	if err != nil {
-		return nil, err
-	}
-
+	dialOpts := append(utils.ServerSideTLSDialOptions(isInternal), utils.GRPCDialOptions()...)
 	c, err := grpc.Dial(cloudAddr, dialOpts...)
 	if err != nil {
 		return nil, err
@@ -136,20 +129,7 @@ func (c *CLIUpdater) IsUpdatable() (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	err = unix.Access(executablePath, unix.W_OK)
-	if err == nil {
-		return true, nil
-	}
-	// File is not writable, check if the current user is owner.
-	s := &unix.Stat_t{}
-	err = unix.Stat(executablePath, s)
-	if err != nil {
-		return false, err
-	}
-	if int(s.Uid) != os.Getuid() {
-		return false, nil
-	}
-	return true, nil
+	return isReplaceable(executablePath)
 }
 
 // UpdateSelf updates the CLI to the specified version.
@@ -215,10 +195,11 @@ func newDownloadWithProgress(url, savePath string) *downloadWithProgress {
 }
 
 func (d *downloadWithProgress) getFileSize() (int64, error) {
-	hr, err := http.Head(d.url)
+	hr, err := utils.HTTPClient().Head(d.url)
 	if err != nil {
 		return 0, err
 	}
+	hr.Body.Close()
 	fileSize, err := strconv.Atoi(hr.Header.Get("Content-Length"))
 	if err != nil {
 		return 0, err
@@ -256,7 +237,7 @@ func (d *downloadWithProgress) Download() error {
 		return err
 	}
 
-	resp, err := http.Get(d.url)
+	resp, err := utils.HTTPClient().Get(d.url)
 	if err != nil {
 		return err
 	}
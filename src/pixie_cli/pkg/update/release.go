@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package update
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/inconshreveable/go-update"
+)
+
+// ReleaseFile is the name of the file that describes the latest CLI release of a channel, relative to the artifacts
+// of the channel.
+const ReleaseFile = "release.json"
+
+// Release is the latest CLI release of a channel, as published next to its binaries:
+//
+//	{
+//	  "version": "0.8.2",
+//	  "binaries": {
+//	    "linux_amd64": {"file": "px_linux_amd64", "sha256": "<hex>", "signature": "<base64>"}
+//	  }
+//	}
+type Release struct {
+	Version  string                    `json:"version"`
+	Binaries map[string]*ReleaseBinary `json:"binaries"`
+}
+
+// ReleaseBinary is the binary of a release for a single platform.
+type ReleaseBinary struct {
+	// File is the name of the binary, relative to the artifacts of the channel.
+	File string `json:"file"`
+	// SHA256 is the hex encoded SHA256 checksum of the binary.
+	SHA256 string `json:"sha256"`
+	// Signature is the base64 encoded ECDSA or RSA signature of the checksum of the binary. Releases that aren't
+	// signed leave it empty.
+	Signature string `json:"signature,omitempty"`
+}
+
+// ParseRelease parses the release file of a channel.
+func ParseRelease(b []byte) (*Release, error) {
+	r := &Release{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ReleaseFile, err)
+	}
+	if _, err := r.Semver(); err != nil {
+		return nil, fmt.Errorf("invalid version in %s: %w", ReleaseFile, err)
+	}
+	return r, nil
+}
+
+// Semver returns the version of the release.
+func (r *Release) Semver() (semver.Version, error) {
+	return semver.Parse(strings.TrimPrefix(r.Version, "v"))
+}
+
+// Binary returns the binary of the release for the platform that the CLI runs on.
+func (r *Release) Binary() (*ReleaseBinary, error) {
+	platform := fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH)
+	b, ok := r.Binaries[platform]
+	if !ok || b == nil || b.File == "" {
+		return nil, fmt.Errorf("release %s has no binary for %s", r.Version, platform)
+	}
+	return b, nil
+}
+
+// Signed returns whether the binary is signed.
+func (b *ReleaseBinary) Signed() bool {
+	return b.Signature != ""
+}
+
+// ReleasePublicKey is the PEM encoded public key that the binaries of the CLI releases are signed with. It is empty
+// until the public half of the Pixie release signing key is checked in, so releases can only be verified with a key
+// given in VerifyOptions until then.
+var ReleasePublicKey []byte
+
+// VerifyOptions configure how the binary of a release is verified.
+type VerifyOptions struct {
+	// PublicKeyPEM is the PEM encoded ECDSA or RSA public key that the binary must be signed with. It defaults to
+	// ReleasePublicKey.
+	PublicKeyPEM []byte
+	// InsecureSkipSignature only verifies the checksum of the binary. Since the checksum comes from the same release
+	// file as the binary, this doesn't protect against a tampered release.
+	InsecureSkipSignature bool
+}
+
+// VerifyBinary verifies that the binary of a release matches its checksum, and that the checksum is signed with the
+// public key, unless the signature check is skipped.
+func VerifyBinary(data []byte, b *ReleaseBinary, opts *VerifyOptions) error {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+	checksum, err := hex.DecodeString(b.SHA256)
+	if err != nil || len(checksum) != sha256.Size {
+		return fmt.Errorf("invalid checksum for %s: %q", b.File, b.SHA256)
+	}
+	actual := sha256.Sum256(data)
+	if !bytes.Equal(actual[:], checksum) {
+		return fmt.Errorf("%s doesn't match its checksum: expected %s, got %x", b.File, b.SHA256, actual)
+	}
+	if opts.InsecureSkipSignature {
+		return nil
+	}
+
+	if !b.Signed() {
+		return fmt.Errorf("%s is not signed", b.File)
+	}
+	publicKeyPEM := opts.PublicKeyPEM
+	if len(publicKeyPEM) == 0 {
+		publicKeyPEM = ReleasePublicKey
+	}
+	if len(publicKeyPEM) == 0 {
+		return fmt.Errorf("no public key to verify the signature of %s with", b.File)
+	}
+	var keyOpts update.Options
+	if err := keyOpts.SetPublicKeyPEM(publicKeyPEM); err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	var verifier update.Verifier
+	switch keyOpts.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		verifier = update.NewECDSAVerifier()
+	case *rsa.PublicKey:
+		verifier = update.NewRSAVerifier()
+	default:
+		return errors.New("the public key must be an ECDSA or RSA key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(b.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature for %s: %w", b.File, err)
+	}
+	if err := verifier.VerifySignature(checksum, signature, crypto.SHA256, keyOpts.PublicKey); err != nil {
+		return fmt.Errorf("invalid signature for %s: %w", b.File, err)
+	}
+	return nil
+}
+
+// ApplyBinary replaces the running binary with the given binary of a release, once it is verified. The binary is
+// replaced by renaming the new binary over it, so it is never left partially written.
+func ApplyBinary(data []byte, b *ReleaseBinary, opts *VerifyOptions) error {
+	if err := VerifyBinary(data, b, opts); err != nil {
+		return err
+	}
+	checksum, _ := hex.DecodeString(b.SHA256)
+	err := update.Apply(bytes.NewReader(data), update.Options{Checksum: checksum})
+	if rerr := update.RollbackError(err); rerr != nil {
+		return fmt.Errorf("failed to restore the previous binary after a failed update: %w", rerr)
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package update_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/update"
+)
+
+func generateKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func signedBinary(t *testing.T, key *ecdsa.PrivateKey, data []byte) *update.ReleaseBinary {
+	checksum := sha256.Sum256(data)
+	b := &update.ReleaseBinary{File: "cli_linux_amd64", SHA256: hex.EncodeToString(checksum[:])}
+	if key != nil {
+		sig, err := ecdsa.SignASN1(rand.Reader, key, checksum[:])
+		require.NoError(t, err)
+		b.Signature = base64.StdEncoding.EncodeToString(sig)
+	}
+	return b
+}
+
+func TestVerifyBinary(t *testing.T) {
+	key, publicKey := generateKey(t)
+	otherKey, _ := generateKey(t)
+	data := []byte("px binary")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		binary  *update.ReleaseBinary
+		opts    *update.VerifyOptions
+		wantErr string
+	}{
+		{
+			name:   "valid signature",
+			data:   data,
+			binary: signedBinary(t, key, data),
+			opts:   &update.VerifyOptions{PublicKeyPEM: publicKey},
+		},
+		{
+			name:    "checksum mismatch",
+			data:    []byte("tampered binary"),
+			binary:  signedBinary(t, key, data),
+			opts:    &update.VerifyOptions{PublicKeyPEM: publicKey},
+			wantErr: "doesn't match its checksum",
+		},
+		{
+			name:    "checksum mismatch without signature check",
+			data:    []byte("tampered binary"),
+			binary:  signedBinary(t, nil, data),
+			opts:    &update.VerifyOptions{InsecureSkipSignature: true},
+			wantErr: "doesn't match its checksum",
+		},
+		{
+			name:    "signed with another key",
+			data:    data,
+			binary:  signedBinary(t, otherKey, data),
+			opts:    &update.VerifyOptions{PublicKeyPEM: publicKey},
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "signature of another binary",
+			data:    data,
+			binary:  &update.ReleaseBinary{File: "cli_linux_amd64", SHA256: signedBinary(t, nil, data).SHA256, Signature: signedBinary(t, key, []byte("other")).Signature},
+			opts:    &update.VerifyOptions{PublicKeyPEM: publicKey},
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "unsigned",
+			data:    data,
+			binary:  signedBinary(t, nil, data),
+			opts:    &update.VerifyOptions{PublicKeyPEM: publicKey},
+			wantErr: "is not signed",
+		},
+		{
+			name:    "unsigned with the release key",
+			data:    data,
+			binary:  signedBinary(t, nil, data),
+			wantErr: "is not signed",
+		},
+		{
+			name:    "no release key",
+			data:    data,
+			binary:  signedBinary(t, key, data),
+			wantErr: "no public key to verify the signature",
+		},
+		{
+			name:   "unsigned without signature check",
+			data:   data,
+			binary: signedBinary(t, nil, data),
+			opts:   &update.VerifyOptions{InsecureSkipSignature: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := update.VerifyBinary(test.data, test.binary, test.opts)
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.wantErr)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package update
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// isReplaceable returns whether the executable can be replaced by an update: it is writable, or owned by the current
+// user.
+func isReplaceable(executablePath string) (bool, error) {
+	err := unix.Access(executablePath, unix.W_OK)
+	if err == nil {
+		return true, nil
+	}
+	// File is not writable, check if the current user is owner.
+	s := &unix.Stat_t{}
+	err = unix.Stat(executablePath, s)
+	if err != nil {
+		return false, err
+	}
+	if int(s.Uid) != os.Getuid() {
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package update
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// isReplaceable returns whether the executable can be replaced by an update. Windows doesn't allow writing to a
+// running executable, so updates rename it and write the new version next to it, which needs its folder to be
+// writable.
+func isReplaceable(executablePath string) (bool, error) {
+	f, err := os.CreateTemp(filepath.Dir(executablePath), ".px-update-check")
+	if os.IsPermission(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	f.Close()
+	return true, os.Remove(f.Name())
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+// AuditEntry is a change that the CLI made, or tried to make, to a cluster, as recorded in the audit log under
+// ~/.local/state/pixie. The audit log is append-only, so that it's a complete record of who changed what.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// User is the OS user that ran the CLI, and Host the machine it ran on.
+	User string `json:"user"`
+	Host string `json:"host,omitempty"`
+	// Command is the path of the command that made the change, such as "px demo deploy".
+	Command string `json:"command,omitempty"`
+	// Context, Cluster and KubeUser are the kubeconfig context that the change was made with, and its cluster and
+	// user.
+	Context  string `json:"context,omitempty"`
+	Cluster  string `json:"cluster,omitempty"`
+	KubeUser string `json:"kube_user,omitempty"`
+	// Server is the address of the API server of the cluster.
+	Server string `json:"server"`
+	// Verb is the change: create, update, patch, delete or deletecollection.
+	Verb string `json:"verb"`
+	// Resource is the type of the changed object, with its API group and subresource, such as deployments.apps or
+	// pods/eviction.
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Status is the HTTP status that the API server responded with, which is 0 if it couldn't be reached.
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Succeeded returns whether the change was made.
+func (e *AuditEntry) Succeeded() bool {
+	return e.Status >= 200 && e.Status < 300
+}
+
+// auditVerbs are the verbs of the HTTP methods that change objects.
+var auditVerbs = map[string]string{
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "patch",
+	http.MethodDelete: "delete",
+}
+
+// unauditedGroups are the API groups whose requests are created to ask the API server something, such as whether
+// the user has a permission, rather than to change the cluster.
+var unauditedGroups = map[string]bool{
+	"authorization.k8s.io":  true,
+	"authentication.k8s.io": true,
+}
+
+var (
+	auditMu      sync.Mutex
+	auditCommand string
+	auditOrigin  *AuditEntry
+	auditOnce    sync.Once
+)
+
+// SetAuditCommand sets the command that the changes recorded in the audit log are attributed to.
+func SetAuditCommand(command string) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditCommand = command
+}
+
+// newAuditEntry returns an entry with who made the change, from which machine and with which kubeconfig context.
+func newAuditEntry() *AuditEntry {
+	auditOnce.Do(func() {
+		origin := &AuditEntry{}
+		if u, err := user.Current(); err == nil {
+			origin.User = u.Username
+		} else if name := os.Getenv("USER"); name != "" {
+			origin.User = name
+		} else {
+			origin.User = os.Getenv("USERNAME")
+		}
+		origin.Host, _ = os.Hostname()
+		// The context was resolved by the client that makes the request.
+		if name, err := k8s.CurrentContext(); err == nil {
+			origin.Context = name
+			if c, ok := k8s.GetClientAPIConfig().Contexts[name]; ok {
+				origin.Cluster = c.Cluster
+				origin.KubeUser = c.AuthInfo
+			}
+		}
+		auditOrigin = origin
+	})
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	e := *auditOrigin
+	e.Time = time.Now()
+	e.Command = auditCommand
+	return &e
+}
+
+// parseAPIPath returns the resource, namespace and name of a request to the API server, such as
+// /apis/apps/v1/namespaces/px-sock-shop/deployments/front-end. ok is false if it isn't a request for an object.
+func parseAPIPath(path string) (group, resource, namespace, name string, ok bool) {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(segs) >= 3 && segs[0] == "api":
+		segs = segs[2:]
+	case len(segs) >= 4 && segs[0] == "apis":
+		group = segs[1]
+		segs = segs[3:]
+	default:
+		return "", "", "", "", false
+	}
+	// The subresources of namespaces, such as /api/v1/namespaces/pl/finalize, look like namespaced resources.
+	namespaceSubresource := len(segs) == 3 && segs[0] == "namespaces" && (segs[2] == "finalize" || segs[2] == "status")
+	if len(segs) >= 3 && segs[0] == "namespaces" && !namespaceSubresource {
+		namespace = segs[1]
+		segs = segs[2:]
+	}
+	if len(segs) == 0 || segs[0] == "" {
+		return "", "", "", "", false
+	}
+	resource = segs[0]
+	if group != "" {
+		resource += "." + group
+	}
+	if len(segs) > 1 {
+		name = segs[1]
+	}
+	if len(segs) > 2 {
+		resource += "/" + strings.Join(segs[2:], "/")
+	}
+	return group, resource, namespace, name, true
+}
+
+// nameFromBody returns the name of the object that a create request sends, if its body can be read again.
+func nameFromBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	var obj struct {
+		Metadata struct {
+			Name         string `json:"name"`
+			GenerateName string `json:"generateName"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(io.LimitReader(body, 1<<20)).Decode(&obj); err != nil {
+		return ""
+	}
+	if obj.Metadata.Name != "" {
+		return obj.Metadata.Name
+	}
+	return obj.Metadata.GenerateName
+}
+
+// auditTransport records the requests of the kubernetes clients that change the cluster in the audit log.
+type auditTransport struct {
+	rt http.RoundTripper
+}
+
+// NewAuditTransport wraps the transport of a kubernetes client to record the changes that it makes to the cluster
+// in the audit log. Dry runs aren't recorded.
+func NewAuditTransport(rt http.RoundTripper) http.RoundTripper {
+	if _, ok := rt.(*auditTransport); ok {
+		return rt
+	}
+	return &auditTransport{rt: rt}
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	verb, ok := auditVerbs[req.Method]
+	if !ok || req.URL.Query().Get("dryRun") != "" {
+		return t.rt.RoundTrip(req)
+	}
+	group, resource, namespace, name, ok := parseAPIPath(req.URL.Path)
+	if !ok || unauditedGroups[group] {
+		return t.rt.RoundTrip(req)
+	}
+	if verb == "delete" && name == "" {
+		verb = "deletecollection"
+	}
+	if verb == "create" && name == "" {
+		name = nameFromBody(req)
+	}
+
+	resp, err := t.rt.RoundTrip(req)
+
+	e := newAuditEntry()
+	e.Server = req.URL.Host
+	e.Verb = verb
+	e.Resource = resource
+	e.Namespace = namespace
+	e.Name = name
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Status = resp.StatusCode
+	}
+	if auditErr := appendAuditEntry(e); auditErr != nil {
+		log.WithError(auditErr).Debug("Failed to record the change in the audit log")
+	}
+	return resp, err
+}
+
+// appendAuditEntry appends the entry to the audit log. Unlike the other logs of the CLI, it's never truncated.
+func appendAuditEntry(e *AuditEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path, err := EnsureDefaultAuditFilePath()
+	if err != nil {
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	// The entry is written with a single write, so that the entries of CLIs that run at the same time don't mix.
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadAuditLog returns the entries of the audit log, from the oldest to the most recent. Lines that can't be parsed
+// are skipped.
+func ReadAuditLog() ([]*AuditEntry, error) {
+	path, err := EnsureDefaultAuditFilePath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e := &AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
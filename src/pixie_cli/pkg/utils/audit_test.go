@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test-cluster
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test-cluster
+    user: alice
+current-context: test
+users:
+- name: alice
+  user: {}
+`
+
+func TestAuditTransport(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(testKubeconfig), 0600))
+	require.NoError(t, pflag.Set("kubeconfig", kubeconfig))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/forbidden") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	utils.SetAuditCommand("px demo deploy")
+	c := &http.Client{Transport: utils.NewAuditTransport(http.DefaultTransport)}
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/api/v1/namespaces", `{"metadata":{"name":"px-sock-shop"}}`},
+		{http.MethodPatch, "/apis/apps/v1/namespaces/px-sock-shop/deployments/front-end", `{}`},
+		{http.MethodDelete, "/api/v1/namespaces/px-sock-shop/secrets/forbidden", ""},
+		// Reads, dry runs and permission checks aren't recorded.
+		{http.MethodGet, "/api/v1/namespaces/px-sock-shop", ""},
+		{http.MethodPatch, "/apis/apps/v1/namespaces/px-sock-shop/deployments/front-end?dryRun=All", `{}`},
+		{http.MethodPost, "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews", `{}`},
+	}
+	for _, r := range requests {
+		req, err := http.NewRequest(r.method, server.URL+r.path, strings.NewReader(r.body))
+		require.NoError(t, err)
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	entries, err := utils.ReadAuditLog()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "create", entries[0].Verb)
+	assert.Equal(t, "namespaces", entries[0].Resource)
+	assert.Equal(t, "px-sock-shop", entries[0].Name)
+	assert.Equal(t, "", entries[0].Namespace)
+	assert.Equal(t, "px demo deploy", entries[0].Command)
+	assert.Equal(t, "test", entries[0].Context)
+	assert.Equal(t, "test-cluster", entries[0].Cluster)
+	assert.Equal(t, "alice", entries[0].KubeUser)
+	assert.True(t, entries[0].Succeeded())
+
+	assert.Equal(t, "patch", entries[1].Verb)
+	assert.Equal(t, "deployments.apps", entries[1].Resource)
+	assert.Equal(t, "px-sock-shop", entries[1].Namespace)
+	assert.Equal(t, "front-end", entries[1].Name)
+
+	assert.Equal(t, "delete", entries[2].Verb)
+	assert.Equal(t, "secrets", entries[2].Resource)
+	assert.Equal(t, http.StatusForbidden, entries[2].Status)
+	assert.False(t, entries[2].Succeeded())
+}
@@ -20,12 +20,23 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
+	"sync"
+	"time"
+
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 )
 
+// cleanupTimeout is how long the cleanup functions of an interrupted run are given to complete.
+const cleanupTimeout = 2 * time.Minute
+
+// ErrInterrupted is returned by the task runners when they are interrupted with Ctrl+C.
+var ErrInterrupted = exitcodes.New(exitcodes.Aborted, "interrupted")
+
 // WithSignalCancellable returns a context that will automatically be cancelled
-// when Ctrl+C is pressed.
+// when Ctrl+C is pressed. Pressing Ctrl+C again terminates the process as usual.
 func WithSignalCancellable(ctx context.Context) (context.Context, func()) {
 	// trap Ctrl+C and call cancel on the context
 	newCtx, cancel := context.WithCancel(ctx)
@@ -40,9 +51,92 @@ func WithSignalCancellable(ctx context.Context) (context.Context, func()) {
 		select {
 		case <-c:
 			cancel()
+			signal.Stop(c)
 		case <-newCtx.Done():
 		}
 	}()
 
 	return newCtx, cleanup
 }
+
+type cleanupFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+type cleanupRegistry struct {
+	mu  sync.Mutex
+	fns []cleanupFunc
+}
+
+type cleanupRegistryKey struct{}
+
+// withCleanupRegistry returns a context that tasks can register cleanup functions with.
+func withCleanupRegistry(ctx context.Context) (context.Context, *cleanupRegistry) {
+	r := &cleanupRegistry{}
+	return context.WithValue(ctx, cleanupRegistryKey{}, r), r
+}
+
+// RegisterCleanup registers a function that undoes the work of a task, such as deleting a namespace that it
+// created. If the run is interrupted with Ctrl+C, or a task fails, the task runner rolls back the run by running
+// the registered functions in reverse order before returning. Since the rollback is automatic, the function must only
+// undo what the task created, and leave alone what already existed before the run. The function is ignored if the
+// context doesn't belong to a task runner.
+func RegisterCleanup(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	r, ok := ctx.Value(cleanupRegistryKey{}).(*cleanupRegistry)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fns = append(r.fns, cleanupFunc{name, fn})
+}
+
+// pending returns whether any cleanup functions are registered.
+func (r *cleanupRegistry) pending() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.fns) != 0
+}
+
+// SetConfirmRollback makes the runner call confirm when the run is interrupted with Ctrl+C, once its tasks have
+// stopped, to decide whether to roll back the completed tasks. If confirm returns false, the completed tasks are kept
+// even if the run can't be resumed. Otherwise, they are rolled back, and the run's checkpoint is removed.
+func (o *runnerOptions) SetConfirmRollback(confirm func() bool) {
+	o.confirmRollback = confirm
+}
+
+// finish returns the result of a run. If the run was interrupted or failed, the cleanup functions registered by the
+// tasks that ran are run first, unless keep is set because the run can be resumed. Tasks that an earlier run completed
+// aren't undone. Interrupted runs return ErrInterrupted.
+func (r *cleanupRegistry) finish(ctx context.Context, err error, newDisplay func() taskDisplay, keep bool) error {
+	interrupted := errors.Is(ctx.Err(), context.Canceled)
+	if interrupted {
+		err = ErrInterrupted
+	}
+	if err == nil || keep {
+		return err
+	}
+	r.mu.Lock()
+	fns := r.fns
+	r.fns = nil
+	r.mu.Unlock()
+	if len(fns) == 0 {
+		return err
+	}
+
+	if interrupted {
+		Info("Interrupted, cleaning up. Press Ctrl+C again to exit immediately.")
+	} else {
+		Info("A task failed, rolling back the completed tasks.")
+	}
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	st := newDisplay()
+	for i := len(fns) - 1; i >= 0; i-- {
+		ti := st.addTask(fns[i].name, false)
+		ti.Complete(fns[i].fn(cleanupCtx))
+	}
+	st.wait()
+	return err
+}
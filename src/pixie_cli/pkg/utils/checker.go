@@ -19,6 +19,7 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -86,7 +87,7 @@ func checkWrapper(check Checker) jobAdapter {
 	return jobAdapter{check}
 }
 
-func (j jobAdapter) Run() error {
+func (j jobAdapter) Run(ctx context.Context) error {
 	return j.Check()
 }
 
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNoCheckpoint is returned when there is no saved checkpoint to resume a run from.
+var ErrNoCheckpoint = errors.New("no checkpoint to resume from")
+
+// Checkpoint records which tasks of a run have completed, in a file under ~/.local/state/pixie/checkpoints, so that a
+// run that failed can be resumed without running those tasks again. Tasks are identified by their names. A task
+// runner with a checkpoint keeps the completed tasks when a task fails or the run is interrupted, rather than rolling
+// them back, and removes the checkpoint once the run succeeds.
+type Checkpoint struct {
+	path string
+
+	mu    sync.Mutex
+	state checkpointState
+	saved bool
+}
+
+type checkpointKey struct{}
+
+// SetCheckpoint makes the runner skip the tasks that the checkpoint records as completed, and record the tasks that
+// complete in it.
+func (o *runnerOptions) SetCheckpoint(c *Checkpoint) {
+	o.checkpoint = c
+}
+
+type checkpointState struct {
+	// Params are the parameters of the run, which a resumed run must reuse.
+	Params    map[string]string `json:"params"`
+	Completed []string          `json:"completed"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// NewCheckpoint creates an empty checkpoint with the given name, for a run with the given parameters. Any checkpoint
+// saved by an earlier run with the same name is removed. The checkpoint is saved once the first task completes.
+func NewCheckpoint(name string, params map[string]string) (*Checkpoint, error) {
+	path, err := EnsureDefaultCheckpointFilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoint{path: path, state: checkpointState{Params: params}}
+	if err := c.Remove(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadCheckpoint loads the checkpoint with the given name that was saved by an earlier run, or returns
+// ErrNoCheckpoint if there is none.
+func LoadCheckpoint(name string) (*Checkpoint, error) {
+	path, err := EnsureDefaultCheckpointFilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoCheckpoint
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoint{path: path, saved: true}
+	if err := json.Unmarshal(b, &c.state); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Params returns the parameters of the run that the checkpoint was created for.
+func (c *Checkpoint) Params() map[string]string {
+	return c.state.Params
+}
+
+// Completed returns whether the task with the given name has completed.
+func (c *Checkpoint) Completed(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, n := range c.state.Completed {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Saved returns whether the checkpoint has been saved, so that the run can be resumed.
+func (c *Checkpoint) Saved() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saved
+}
+
+// markCompleted records that the task with the given name has completed, and saves the checkpoint.
+func (c *Checkpoint) markCompleted(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Completed = append(c.state.Completed, name)
+	c.state.UpdatedAt = time.Now()
+	b, err := json.MarshalIndent(&c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, b, 0600); err != nil {
+		return err
+	}
+	c.saved = true
+	return nil
+}
+
+// Remove deletes the saved checkpoint, if there is one.
+func (c *Checkpoint) Remove() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	c.saved = false
+	return nil
+}
@@ -24,12 +24,18 @@ import (
 	"os"
 
 	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 )
 
 // CLIOutputEntry represents an output log entry.
 type CLIOutputEntry struct {
 	textColor *color.Color
 	err       error
+	// exitCode is the code that Fatal exits with. If it's unset, the code is derived from err.
+	exitCode int
 }
 
 var defaultCLIOutput = &CLIOutputEntry{
@@ -53,6 +59,14 @@ func WithError(err error) *CLIOutputEntry {
 	}
 }
 
+// WithExitCode returns a struct that can be used to log text to the CLI
+// and exit with a specific code from Fatal.
+func WithExitCode(code int) *CLIOutputEntry {
+	return &CLIOutputEntry{
+		exitCode: code,
+	}
+}
+
 // Infof prints the input string to stdout formatted with the input args.
 func Infof(format string, args ...interface{}) {
 	defaultCLIOutput.Infof(format, args...)
@@ -89,6 +103,7 @@ func (c *CLIOutputEntry) WithColor(textColor *color.Color) *CLIOutputEntry {
 	return &CLIOutputEntry{
 		err:       c.err,
 		textColor: textColor,
+		exitCode:  c.exitCode,
 	}
 }
 
@@ -98,6 +113,17 @@ func (c *CLIOutputEntry) WithError(err error) *CLIOutputEntry {
 	return &CLIOutputEntry{
 		err:       err,
 		textColor: c.textColor,
+		exitCode:  c.exitCode,
+	}
+}
+
+// WithExitCode returns a struct that can be used to log text to the CLI
+// and exit with a specific code from Fatal.
+func (c *CLIOutputEntry) WithExitCode(code int) *CLIOutputEntry {
+	return &CLIOutputEntry{
+		err:       c.err,
+		textColor: c.textColor,
+		exitCode:  code,
 	}
 }
 
@@ -134,14 +160,31 @@ func (c *CLIOutputEntry) Error(str string) {
 	c.write(os.Stderr, str)
 }
 
-// Fatalf prints the input string to stderr formatted with the input args.
+// Fatalf prints the input string to stderr formatted with the input args. If errors are rendered as JSON, it
+// prints a JSON object with the error code and remediation instead.
 func (c *CLIOutputEntry) Fatalf(format string, args ...interface{}) {
-	c.write(os.Stderr, format, args...)
-	os.Exit(1)
+	if components.JSONErrors() {
+		components.RenderErrorJSON(os.Stderr, fmt.Sprintf(format, args...), c.err, nil, c.code())
+	} else {
+		c.write(os.Stderr, format, args...)
+	}
+	// The message is logged at the debug level, so that it's in the log file without being printed twice.
+	log.WithError(c.err).Debugf("Exiting: "+format, args...)
+	exitcodes.ExitWith(c.code())
 }
 
 // Fatal prints the input string to stderr.
 func (c *CLIOutputEntry) Fatal(str string) {
-	c.write(os.Stderr, str)
-	os.Exit(1)
+	c.Fatalf(str)
+}
+
+// code returns the exit code of the entry, or else the code for its error, which is exitcodes.Error without one.
+func (c *CLIOutputEntry) code() int {
+	if c.exitCode != 0 {
+		return c.exitCode
+	}
+	if c.err != nil {
+		return exitcodes.ForError(c.err)
+	}
+	return exitcodes.Error
 }
@@ -19,22 +19,33 @@
 package utils
 
 import (
+	"crypto/tls"
 	"strings"
 
+	"github.com/spf13/viper"
 	"google.golang.org/grpc"
-
-	"px.dev/pixie/src/shared/services"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
 )
 
+// ServerSideTLSDialOptions returns the dial options of gRPC connections to a server with server-side TLS, like
+// services.GetGRPCClientDialOptsServerSideTLS. The CLI doesn't import the services package, which would link its
+// Sentry error reporting into builds with the notel tag.
+func ServerSideTLSDialOptions(isInternal bool) []grpc.DialOption {
+	dialOpts := []grpc.DialOption{grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name))}
+	if viper.GetBool("disable_ssl") {
+		return append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	creds := credentials.NewTLS(&tls.Config{InsecureSkipVerify: isInternal})
+	return append(dialOpts, grpc.WithTransportCredentials(creds))
+}
+
 // GetCloudClientConnection gets the GRPC connection based on the cloud addr.
 func GetCloudClientConnection(cloudAddr string) (*grpc.ClientConn, error) {
 	isInternal := strings.Contains(cloudAddr, "cluster.local")
 
-	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
-	if err != nil {
-		return nil, err
-	}
-
+	dialOpts := append(ServerSideTLSDialOptions(isInternal), GRPCDialOptions()...)
 	c, err := grpc.Dial(cloudAddr, dialOpts...)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	version "px.dev/pixie/src/shared/goversion"
+)
+
+// maxCrashReports is how many of the most recent crash reports are kept.
+const maxCrashReports = 10
+
+// CrashReport is the report of a panic of the CLI, as written under ~/.local/state/pixie/crashes.
+type CrashReport struct {
+	Time      time.Time `json:"time"`
+	Version   string    `json:"version"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	GoVersion string    `json:"goVersion"`
+	// Args is the command line, which should have its arguments and flag values scrubbed, since crash reports may be
+	// submitted.
+	Args []string `json:"args"`
+	// Panic is the value that the CLI panicked with.
+	Panic string `json:"panic"`
+	Stack string `json:"stack"`
+}
+
+// NewCrashReport returns the report of the panic with the given recovered value. It must be called by the deferred
+// function that recovered the panic, so that the stack trace leads to it.
+func NewCrashReport(recovered interface{}, args []string) *CrashReport {
+	report := &CrashReport{
+		Time:      time.Now().UTC(),
+		Version:   version.GetVersion().ToString(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		Args:      args,
+		Panic:     fmt.Sprint(recovered),
+		Stack:     string(debug.Stack()),
+	}
+	// Panics of the goroutines that run tasks are re-raised by the task runners, with the stack of the goroutine.
+	if p, ok := recovered.(*goroutinePanic); ok {
+		report.Panic = fmt.Sprint(p.value)
+		report.Stack = p.stack
+	}
+	return report
+}
+
+// errTaskPanicked is the error of a task that panicked in a goroutine of a task runner.
+var errTaskPanicked = errors.New("task panicked")
+
+// goroutinePanic is a panic of a goroutine that ran a task. The task runners re-raise it in the goroutine that runs
+// them, so that the command recovers it, since a panic that isn't recovered in its own goroutine kills the CLI
+// without a crash report.
+type goroutinePanic struct {
+	value interface{}
+	stack string
+}
+
+func (p *goroutinePanic) String() string {
+	return fmt.Sprint(p.value)
+}
+
+// newGoroutinePanic returns the goroutine panic for the recovered value, with the stack of the goroutine. It must be
+// called by the deferred function that recovered the panic.
+func newGoroutinePanic(recovered interface{}) *goroutinePanic {
+	if p, ok := recovered.(*goroutinePanic); ok {
+		return p
+	}
+	return &goroutinePanic{value: recovered, stack: string(debug.Stack())}
+}
+
+// panicCollector collects the panics of the goroutines that run tasks.
+type panicCollector struct {
+	mu    sync.Mutex
+	first *goroutinePanic
+}
+
+// run runs f, and returns errTaskPanicked if it panics, after collecting the panic.
+func (c *panicCollector) run(f func() error) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		p := newGoroutinePanic(recovered)
+		c.mu.Lock()
+		if c.first == nil {
+			c.first = p
+		}
+		c.mu.Unlock()
+		err = errTaskPanicked
+	}()
+	return f()
+}
+
+// repanic re-raises the first panic that was collected, if any.
+func (c *panicCollector) repanic() {
+	c.mu.Lock()
+	p := c.first
+	c.first = nil
+	c.mu.Unlock()
+	if p != nil {
+		panic(p)
+	}
+}
+
+// WriteCrashReport writes the crash report to the crashes folder, and returns its path. The oldest reports are removed
+// once there are more than maxCrashReports.
+func WriteCrashReport(r *CrashReport) (string, error) {
+	dir, err := EnsureDefaultCrashesDirPath()
+	if err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s.json", r.Time.Format("20060102T150405.000")))
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return "", err
+	}
+
+	if paths, err := CrashReportPaths(); err == nil && len(paths) > maxCrashReports {
+		for _, p := range paths[:len(paths)-maxCrashReports] {
+			_ = os.Remove(p)
+		}
+	}
+	return path, nil
+}
+
+// CrashReportPaths returns the paths of the crash reports, from the oldest to the most recent.
+func CrashReportPaths() ([]string, error) {
+	dir, err := EnsureDefaultCrashesDirPath()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "crash-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// The names start with the time of the crash, so they sort chronologically.
+	sort.Strings(paths)
+	return paths, nil
+}
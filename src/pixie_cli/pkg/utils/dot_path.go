@@ -19,32 +19,159 @@
 package utils
 
 import (
+	"io"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 const (
+	// pixieDotPath is the folder in the home directory that the CLI used before it followed the XDG base directory
+	// spec. Its files are moved to the XDG folders.
 	pixieDotPath    = ".pixie"
+	pixieDirName    = "pixie"
 	pixieConfigFile = "config.json"
 	pixieAuthFile   = "auth.json"
+
+	pixieDemoOverridesFile = "demo-overrides.json"
+	pixieCheckpointsDir    = "checkpoints"
+	pixieAnalyticsQueue    = "analytics-queue.jsonl"
+	pixieHistoryFile       = "history.jsonl"
+	pixieLogsDir           = "logs"
+	pixieMetricsFile       = "metrics.jsonl"
+	pixieAuditFile         = "audit.jsonl"
+	pixieCrashesDir        = "crashes"
 )
 
-// ensureDotFolderPath returns and creates the dot folder for cli config/auth.
+var migrateDotFolderOnce sync.Once
+
+// xdgDir returns the pixie folder in the XDG base directory that is set by the given env var, or else the default
+// pixie folder of the platform.
+func xdgDir(envVar string, defaultDir func() (string, error)) (string, error) {
+	if dir := os.Getenv(envVar); filepath.IsAbs(dir) {
+		return filepath.Join(dir, pixieDirName), nil
+	}
+	return defaultDir()
+}
+
+// configDir returns the folder for the config and credentials of the CLI: $XDG_CONFIG_HOME/pixie, which defaults to
+// ~/.config/pixie, or %AppData%\pixie on Windows.
+func configDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", defaultConfigDir)
+}
+
+// stateDir returns the folder for the state that the CLI keeps between runs, such as checkpoints:
+// $XDG_STATE_HOME/pixie, which defaults to ~/.local/state/pixie, or %LocalAppData%\pixie on Windows.
+func stateDir() (string, error) {
+	return xdgDir("XDG_STATE_HOME", defaultStateDir)
+}
+
+// cacheDir returns the folder for files that the CLI can download again, such as the demo manifest:
+// $XDG_CACHE_HOME/pixie, which defaults to ~/.cache/pixie, or %LocalAppData%\pixie\cache on Windows.
+func cacheDir() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", defaultCacheDir)
+}
+
+// ensureDir returns the given folder, creating it if needed. Files in the old ~/.pixie folder are moved to the XDG
+// folders first.
+func ensureDir(dir func() (string, error)) (string, error) {
+	migrateDotFolderOnce.Do(migrateDotFolder)
+	path, err := dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ensureDotFolderPath returns and creates the folder for cli config/auth.
 func ensureDotFolderPath() (string, error) {
+	return ensureDir(configDir)
+}
+
+// migrateDotFolder moves the files in ~/.pixie to the XDG folders, and removes ~/.pixie once it is empty. Files that
+// already exist in the XDG folders are left in place. Migration is best effort: if a file can't be moved, the CLI
+// behaves as if it didn't exist.
+func migrateDotFolder() {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return "", err
+		return
 	}
+	oldDir := filepath.Join(home, pixieDotPath)
+	entries, err := os.ReadDir(oldDir)
+	if err != nil {
+		return
+	}
+	cfgDir, err := configDir()
+	if err != nil {
+		return
+	}
+	stDir, err := stateDir()
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		dest := cfgDir
+		if e.Name() == pixieCheckpointsDir || e.Name() == pixieAnalyticsQueue {
+			dest = stDir
+		}
+		_ = movePath(filepath.Join(oldDir, e.Name()), filepath.Join(dest, e.Name()))
+	}
+	// Only succeeds if everything was moved.
+	_ = os.Remove(oldDir)
+}
 
-	pixieDirPath := filepath.Join(home, pixieDotPath)
-	if _, err := os.Stat(pixieDirPath); os.IsNotExist(err) {
-		err = os.Mkdir(pixieDirPath, 0744)
-		if err != nil {
-			return "", err
+// movePath moves a file or folder, unless the destination exists. Folders are merged into existing folders.
+func movePath(src, dest string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	if info.IsDir() {
+		if _, err := os.Stat(dest); err == nil {
+			entries, err := os.ReadDir(src)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				_ = movePath(filepath.Join(src, e.Name()), filepath.Join(dest, e.Name()))
+			}
+			return os.Remove(src)
 		}
+	} else if _, err := os.Stat(dest); err == nil {
+		return os.ErrExist
+	}
+	if err := os.Rename(src, dest); err == nil || info.IsDir() {
+		return err
 	}
+	// Renaming fails if the folders are on different file systems, so copy the file instead.
+	return copyFile(src, dest, info.Mode())
+}
 
-	return pixieDirPath, nil
+func copyFile(src, dest string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
 }
 
 // EnsureDefaultConfigFilePath returns the file path for the config file.
@@ -68,3 +195,120 @@ func EnsureDefaultAuthFilePath() (string, error) {
 	pixieAuthFilePath := filepath.Join(pixieDirPath, pixieAuthFile)
 	return pixieAuthFilePath, nil
 }
+
+// EnsureDefaultDemoOverridesFilePath returns the file path for the local demo manifest overrides.
+func EnsureDefaultDemoOverridesFilePath() (string, error) {
+	pixieDirPath, err := ensureDotFolderPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(pixieDirPath, pixieDemoOverridesFile), nil
+}
+
+// EnsureDefaultCheckpointFilePath returns the file path for the checkpoint with the given name, creating the
+// checkpoints folder if needed.
+func EnsureDefaultCheckpointFilePath(name string) (string, error) {
+	checkpointsPath, err := EnsureDefaultCheckpointsDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(checkpointsPath, name+".json"), nil
+}
+
+// EnsureDefaultCheckpointsDirPath returns the path of the folder that holds the checkpoints, creating it if needed.
+func EnsureDefaultCheckpointsDirPath() (string, error) {
+	pixieStatePath, err := ensureDir(stateDir)
+	if err != nil {
+		return "", err
+	}
+
+	checkpointsPath := filepath.Join(pixieStatePath, pixieCheckpointsDir)
+	if err := os.MkdirAll(checkpointsPath, 0700); err != nil {
+		return "", err
+	}
+	return checkpointsPath, nil
+}
+
+// EnsureDefaultAnalyticsQueueFilePath returns the file path for the analytics events that couldn't be sent yet.
+func EnsureDefaultAnalyticsQueueFilePath() (string, error) {
+	pixieStatePath, err := ensureDir(stateDir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(pixieStatePath, pixieAnalyticsQueue), nil
+}
+
+// EnsureDefaultHistoryFilePath returns the file path for the history of recent commands.
+func EnsureDefaultHistoryFilePath() (string, error) {
+	pixieStatePath, err := ensureDir(stateDir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(pixieStatePath, pixieHistoryFile), nil
+}
+
+// EnsureDefaultMetricsFilePath returns the file path for the recorded durations of commands.
+func EnsureDefaultMetricsFilePath() (string, error) {
+	pixieStatePath, err := ensureDir(stateDir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(pixieStatePath, pixieMetricsFile), nil
+}
+
+// EnsureDefaultAuditFilePath returns the file path for the audit log of the changes made to clusters.
+func EnsureDefaultAuditFilePath() (string, error) {
+	pixieStatePath, err := ensureDir(stateDir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(pixieStatePath, pixieAuditFile), nil
+}
+
+// EnsureDefaultLogsDirPath returns the path of the folder that holds the log files, creating it if needed.
+func EnsureDefaultLogsDirPath() (string, error) {
+	pixieStatePath, err := ensureDir(stateDir)
+	if err != nil {
+		return "", err
+	}
+
+	logsPath := filepath.Join(pixieStatePath, pixieLogsDir)
+	if err := os.MkdirAll(logsPath, 0700); err != nil {
+		return "", err
+	}
+	return logsPath, nil
+}
+
+// EnsureDefaultCrashesDirPath returns the path of the folder that holds the crash reports, creating it if needed.
+func EnsureDefaultCrashesDirPath() (string, error) {
+	pixieStatePath, err := ensureDir(stateDir)
+	if err != nil {
+		return "", err
+	}
+
+	crashesPath := filepath.Join(pixieStatePath, pixieCrashesDir)
+	if err := os.MkdirAll(crashesPath, 0700); err != nil {
+		return "", err
+	}
+	return crashesPath, nil
+}
+
+// EnsureDefaultCacheDirPath returns the path of the folder that holds the cached files, creating it if needed.
+func EnsureDefaultCacheDirPath() (string, error) {
+	return ensureDir(cacheDir)
+}
+
+// EnsureDefaultCacheFilePath returns the file path for the cached file with the given name.
+func EnsureDefaultCacheFilePath(name string) (string, error) {
+	pixieCachePath, err := EnsureDefaultCacheDirPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(pixieCachePath, name), nil
+}
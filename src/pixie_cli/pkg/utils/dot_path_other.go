@@ -0,0 +1,47 @@
+//go:build !windows
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// homeDir returns the pixie folder under the given folder of the home directory.
+func homeDir(dir ...string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append(append([]string{home}, dir...), pixieDirName)...), nil
+}
+
+func defaultConfigDir() (string, error) {
+	return homeDir(".config")
+}
+
+func defaultStateDir() (string, error) {
+	return homeDir(".local", "state")
+}
+
+func defaultCacheDir() (string, error) {
+	return homeDir(".cache")
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupDotFolder creates a home directory with the given files in ~/.pixie, and returns it and the XDG config and
+// state folders of the CLI.
+func setupDotFolder(t *testing.T, files map[string]string) (string, string, string) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(home, pixieDotPath, name), content)
+	}
+	return home, filepath.Join(home, "config", pixieDirName), filepath.Join(home, "state", pixieDirName)
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func readTestFile(t *testing.T, path string) string {
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestMigrateDotFolder(t *testing.T) {
+	home, cfgDir, stDir := setupDotFolder(t, map[string]string{
+		pixieConfigFile:     "config",
+		pixieAuthFile:       "auth",
+		pixieAnalyticsQueue: "queue",
+		filepath.Join(pixieCheckpointsDir, "demo.json"): "checkpoint",
+	})
+
+	migrateDotFolder()
+
+	assert.Equal(t, "config", readTestFile(t, filepath.Join(cfgDir, pixieConfigFile)))
+	assert.Equal(t, "auth", readTestFile(t, filepath.Join(cfgDir, pixieAuthFile)))
+	assert.Equal(t, "queue", readTestFile(t, filepath.Join(stDir, pixieAnalyticsQueue)))
+	assert.Equal(t, "checkpoint", readTestFile(t, filepath.Join(stDir, pixieCheckpointsDir, "demo.json")))
+	assert.NoDirExists(t, filepath.Join(home, pixieDotPath))
+}
+
+func TestMigrateDotFolder_KeepsExistingFiles(t *testing.T) {
+	home, cfgDir, stDir := setupDotFolder(t, map[string]string{
+		pixieConfigFile: "old config",
+		pixieAuthFile:   "auth",
+		filepath.Join(pixieCheckpointsDir, "demo.json"):  "old checkpoint",
+		filepath.Join(pixieCheckpointsDir, "other.json"): "other checkpoint",
+	})
+	writeTestFile(t, filepath.Join(cfgDir, pixieConfigFile), "new config")
+	writeTestFile(t, filepath.Join(stDir, pixieCheckpointsDir, "demo.json"), "new checkpoint")
+
+	migrateDotFolder()
+
+	assert.Equal(t, "new config", readTestFile(t, filepath.Join(cfgDir, pixieConfigFile)))
+	assert.Equal(t, "auth", readTestFile(t, filepath.Join(cfgDir, pixieAuthFile)))
+	// The checkpoints folder is merged into the existing one.
+	assert.Equal(t, "new checkpoint", readTestFile(t, filepath.Join(stDir, pixieCheckpointsDir, "demo.json")))
+	assert.Equal(t, "other checkpoint", readTestFile(t, filepath.Join(stDir, pixieCheckpointsDir, "other.json")))
+	// The files that weren't moved are kept in ~/.pixie.
+	assert.Equal(t, "old config", readTestFile(t, filepath.Join(home, pixieDotPath, pixieConfigFile)))
+	assert.Equal(t, "old checkpoint", readTestFile(t, filepath.Join(home, pixieDotPath, pixieCheckpointsDir, "demo.json")))
+	assert.NoFileExists(t, filepath.Join(home, pixieDotPath, pixieAuthFile))
+}
+
+func TestMigrateDotFolder_NoDotFolder(t *testing.T) {
+	home, cfgDir, _ := setupDotFolder(t, nil)
+
+	migrateDotFolder()
+
+	assert.NoDirExists(t, filepath.Join(home, pixieDotPath))
+	assert.NoDirExists(t, cfgDir)
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// TargetedTask is a task that reports what it acts on, such as a namespace or a file, which is shown in dry runs.
+type TargetedTask interface {
+	Task
+	Target() string
+}
+
+// WithTarget returns a task that runs the given task, and reports the given target.
+func WithTarget(t Task, target string) Task {
+	o := withOptions(t)
+	o.target = target
+	return o
+}
+
+type dryRunStep struct {
+	task Task
+	deps []Task
+}
+
+func dryRunSteps(tasks []Task) []dryRunStep {
+	steps := make([]dryRunStep, len(tasks))
+	for i, t := range tasks {
+		steps[i] = dryRunStep{task: t}
+	}
+	return steps
+}
+
+// printDryRun prints the numbered steps of a dry run to stdout, with their targets and the steps they wait for:
+//
+//	Dry run, no changes were made. The following tasks would run:
+//	  1. Creating namespace px-sock-shop
+//	  2. Deploying px-sock-shop YAMLs (target: namespace px-sock-shop, after: 1)
+func printDryRun(steps []dryRunStep) {
+	numbers := make(map[Task]int, len(steps))
+	for i, s := range steps {
+		numbers[s.task] = i + 1
+	}
+
+	fmt.Fprintln(os.Stdout, color.New(color.Bold).Sprint("Dry run, no changes were made. The following tasks would run:"))
+	for i, s := range steps {
+		var details []string
+		if tt, ok := s.task.(TargetedTask); ok && tt.Target() != "" {
+			details = append(details, "target: "+tt.Target())
+		}
+		if len(s.deps) > 0 {
+			after := make([]string, len(s.deps))
+			for j, d := range s.deps {
+				after[j] = strconv.Itoa(numbers[d])
+			}
+			details = append(details, "after: "+strings.Join(after, ", "))
+		}
+		line := fmt.Sprintf("  %d. %s", i+1, s.task.Name())
+		if len(details) > 0 {
+			line += color.New(color.Faint).Sprintf(" (%s)", strings.Join(details, ", "))
+		}
+		fmt.Fprintln(os.Stdout, line)
+	}
+}
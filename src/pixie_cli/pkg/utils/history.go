@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// maxHistoryEntries is how many of the most recent commands are kept in the history.
+const maxHistoryEntries = 100
+
+// HistoryEntry is a command that was run, as recorded in the history under ~/.local/state/pixie.
+type HistoryEntry struct {
+	Time time.Time `json:"time"`
+	// Args is the command line, which should have its arguments and flag values scrubbed, since the history is
+	// attached to bug reports.
+	Args []string `json:"args"`
+}
+
+// RecordHistory appends the command line to the history of recent commands, dropping the oldest commands once there
+// are more than maxHistoryEntries.
+func RecordHistory(args []string) error {
+	entries, err := ReadHistory()
+	if err != nil {
+		return err
+	}
+	entries = append(entries, &HistoryEntry{Time: time.Now(), Args: args})
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	path, err := EnsureDefaultHistoryFilePath()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// ReadHistory returns the recent commands, from the oldest to the most recent. Lines that can't be parsed are
+// skipped.
+func ReadHistory() ([]*HistoryEntry, error) {
+	path, err := EnsureDefaultHistoryFilePath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e := &HistoryEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+
+	version "px.dev/pixie/src/shared/goversion"
+)
+
+// HTTPOptions configure the connections of the CLI to Pixie Cloud, the artifact endpoints and the analytics endpoint.
+// Connections to Kubernetes clusters are configured by the kubeconfig instead.
+type HTTPOptions struct {
+	// Proxy is the URL of the SOCKS5 proxy that connections go through, such as socks5://bastion:1080. With the
+	// socks5h scheme, host names are resolved by the proxy. Connections go through the proxy of the HTTP_PROXY and
+	// HTTPS_PROXY env vars if it's empty.
+	Proxy string
+	// CACert is the path of a PEM file of CA certificates that servers are trusted with, in addition to the system's.
+	// TLS-intercepting proxies sign with such CAs.
+	CACert string
+	// ClientCert and ClientKey are the paths of the PEM files of the certificate and key that the CLI authenticates
+	// to servers with. They must be set together.
+	ClientCert string
+	ClientKey  string
+	// InsecureSkipTLSVerify turns off the verification of the certificates of servers. It's a last resort, since
+	// anyone on the network can then read and change the connections.
+	InsecureSkipTLSVerify bool
+}
+
+var (
+	httpOptionsMu sync.RWMutex
+	// proxyDialer dials through the proxy of the HTTP options, or is nil if there is none.
+	proxyDialer proxy.ContextDialer
+	proxyURL    *url.URL
+	// tlsConfig is the TLS config of the HTTP options, or nil if the defaults are used.
+	tlsConfig  *tls.Config
+	httpClient *http.Client
+)
+
+const (
+	// httpResponseHeaderTimeout is how long the CLI waits for a server to start responding. Requests aren't limited
+	// overall, since downloads may be large, so callers that need a deadline set one on the request's context.
+	httpResponseHeaderTimeout = 30 * time.Second
+	// httpMaxIdleConnsPerHost is the number of connections to each server that are kept open for reuse. The CLI
+	// often fetches several artifacts from the same server.
+	httpMaxIdleConnsPerHost = 8
+)
+
+// baseTransport is the default transport of net/http, before the CLI wraps it to log requests.
+var baseTransport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ResponseHeaderTimeout = httpResponseHeaderTimeout
+	t.MaxIdleConnsPerHost = httpMaxIdleConnsPerHost
+	return t
+}()
+
+// UserAgent returns the User-Agent that the CLI identifies itself to servers with, such as px/0.8.2 (linux/amd64).
+func UserAgent() string {
+	return fmt.Sprintf("px/%s (%s/%s)", version.GetVersion().ToString(), runtime.GOOS, runtime.GOARCH)
+}
+
+// userAgentTransport sets the User-Agent of requests that don't have one.
+type userAgentTransport struct {
+	rt http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") != "" {
+		return t.rt.RoundTrip(req)
+	}
+	// Round trippers must not change the request they're given.
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", UserAgent())
+	return t.rt.RoundTrip(req)
+}
+
+// ParseProxyURL parses the URL of a SOCKS5 proxy.
+func ParseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "socks5" && u.Scheme != "socks5h") || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q, must be a socks5:// or socks5h:// URL", s)
+	}
+	return u, nil
+}
+
+// SetHTTPOptions sets the options of the connections that the CLI makes.
+func SetHTTPOptions(opts HTTPOptions) error {
+	var u *url.URL
+	var dialer proxy.ContextDialer
+	if opts.Proxy != "" {
+		var err error
+		u, err = ParseProxyURL(opts.Proxy)
+		if err != nil {
+			return err
+		}
+		d, err := proxy.FromURL(u, &net.Dialer{})
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if dialer, ok = d.(proxy.ContextDialer); !ok {
+			return fmt.Errorf("proxy %s doesn't support contexts", u.Redacted())
+		}
+	}
+	tc, err := newTLSConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	httpOptionsMu.Lock()
+	defer httpOptionsMu.Unlock()
+	proxyURL = u
+	proxyDialer = dialer
+	tlsConfig = tc
+	httpClient = nil
+	return nil
+}
+
+// newTLSConfig returns the TLS config of the HTTP options, or nil if they don't change the defaults.
+func newTLSConfig(opts HTTPOptions) (*tls.Config, error) {
+	if opts.CACert == "" && opts.ClientCert == "" && opts.ClientKey == "" && !opts.InsecureSkipTLSVerify {
+		return nil, nil
+	}
+	c := &tls.Config{InsecureSkipVerify: opts.InsecureSkipTLSVerify}
+
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificates: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in %s", opts.CACert)
+		}
+		c.RootCAs = pool
+	}
+
+	if (opts.ClientCert == "") != (opts.ClientKey == "") {
+		return nil, errors.New("the client certificate and key must be given together")
+	}
+	if opts.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// NewHTTPTransport returns a transport that connects with the HTTP options, and reuses connections. Callers may
+// change the transport, for example to set shorter timeouts, and pass it to NewHTTPClient or WrapHTTPTransport.
+func NewHTTPTransport() *http.Transport {
+	httpOptionsMu.RLock()
+	defer httpOptionsMu.RUnlock()
+	t := baseTransport.Clone()
+	if proxyURL != nil {
+		// net/http connects through SOCKS5 proxies itself.
+		t.Proxy = http.ProxyURL(proxyURL)
+	}
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig.Clone()
+	}
+	return t
+}
+
+// WrapHTTPTransport wraps the transport to identify the CLI with its User-Agent, and to log requests at the debug
+// level. Idempotent requests are retried according to the policy, if it isn't nil.
+func WrapHTTPTransport(t *http.Transport, retry *RetryPolicy) http.RoundTripper {
+	var rt http.RoundTripper = NewLoggingTransport(t)
+	if retry != nil && retry.MaxAttempts > 1 {
+		rt = &retryTransport{rt: rt, policy: retry}
+	}
+	return &userAgentTransport{rt: rt}
+}
+
+// NewHTTPClient returns a client that makes requests with the wrapped transport, see WrapHTTPTransport. Requests
+// fail after the timeout, if it isn't zero.
+func NewHTTPClient(t *http.Transport, timeout time.Duration, retry *RetryPolicy) *http.Client {
+	return &http.Client{Transport: WrapHTTPTransport(t, retry), Timeout: timeout}
+}
+
+// HTTPClient returns the client that the CLI makes HTTP requests with. It connects with the HTTP options, and
+// retries idempotent requests that fail transiently according to the DefaultRetryPolicy. Since the client is shared,
+// its connections are reused across requests.
+func HTTPClient() *http.Client {
+	httpOptionsMu.RLock()
+	c := httpClient
+	httpOptionsMu.RUnlock()
+	if c != nil {
+		return c
+	}
+
+	c = NewHTTPClient(NewHTTPTransport(), 0, DefaultRetryPolicy)
+	httpOptionsMu.Lock()
+	defer httpOptionsMu.Unlock()
+	if httpClient == nil {
+		httpClient = c
+	}
+	return httpClient
+}
+
+// GRPCDialOptions returns the dial options of gRPC connections to Pixie Cloud, which connect with the HTTP options.
+func GRPCDialOptions() []grpc.DialOption {
+	httpOptionsMu.RLock()
+	dialer := proxyDialer
+	httpOptionsMu.RUnlock()
+	if dialer == nil {
+		return nil
+	}
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", addr)
+		}),
+		// The proxy of the env vars would be used in addition to the SOCKS5 proxy otherwise.
+		grpc.WithNoProxy(),
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// secretHeader matches the names of headers whose values are secrets, and aren't logged.
+var secretHeader = regexp.MustCompile(`(?i)(AUTHORIZATION|KEY|TOKEN|SECRET|COOKIE)`)
+
+// loggingTransport logs each request and its response at the debug level, and their headers at the trace level.
+type loggingTransport struct {
+	rt http.RoundTripper
+}
+
+// NewLoggingTransport wraps the transport to log its requests, once the log level is debug or trace.
+func NewLoggingTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if _, ok := rt.(*loggingTransport); ok {
+		return rt
+	}
+	return &loggingTransport{rt: rt}
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !log.IsLevelEnabled(log.DebugLevel) {
+		return t.rt.RoundTrip(req)
+	}
+
+	log.Debugf("HTTP %s %s", req.Method, req.URL.Redacted())
+	logHeaders("HTTP request header", req.Header)
+	start := time.Now()
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		log.WithError(err).Debugf("HTTP %s %s failed after %s", req.Method, req.URL.Redacted(), time.Since(start))
+		return nil, err
+	}
+	log.Debugf("HTTP %s %s: %s in %s", req.Method, req.URL.Redacted(), resp.Status, time.Since(start))
+	logHeaders("HTTP response header", resp.Header)
+	return resp, nil
+}
+
+func logHeaders(prefix string, h http.Header) {
+	if !log.IsLevelEnabled(log.TraceLevel) {
+		return
+	}
+	for name, values := range h {
+		for _, v := range values {
+			if secretHeader.MatchString(name) {
+				v = "<hidden>"
+			}
+			log.Tracef("%s %s: %s", prefix, name, v)
+		}
+	}
+}
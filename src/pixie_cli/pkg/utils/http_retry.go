@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// retryableStatus are the response statuses of temporary server problems, after which a request may succeed.
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// retryTransport retries idempotent requests that fail with transient errors or retryable statuses.
+type retryTransport struct {
+	rt     http.RoundTripper
+	policy *RetryPolicy
+}
+
+// canRetry returns whether the request can be sent again: it must be idempotent, and its body must be replayable.
+func canRetry(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryAfter returns the delay that the response asks for in its Retry-After header, if it has one in seconds.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !canRetry(req) {
+		return t.rt.RoundTrip(req)
+	}
+	retryable := t.policy.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+
+	interval := t.policy.InitialInterval
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.rt.RoundTrip(req)
+		if attempt == t.policy.MaxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+		delay := interval
+		switch {
+		case err != nil:
+			if !retryable(err) {
+				return nil, err
+			}
+			log.WithError(err).Debugf("Retrying HTTP %s %s in %s", req.Method, req.URL.Redacted(), delay)
+		case retryableStatus[resp.StatusCode]:
+			if d, ok := retryAfter(resp); ok {
+				delay = d
+			}
+			if t.policy.MaxInterval > 0 && delay > t.policy.MaxInterval {
+				// Waiting longer than the policy allows isn't worth it, so the response is returned as is.
+				return resp, nil
+			}
+			log.Debugf("Retrying HTTP %s %s in %s: %s", req.Method, req.URL.Redacted(), delay, resp.Status)
+			// The body is drained so that the connection can be reused.
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		interval *= 2
+		if t.policy.MaxInterval > 0 && interval > t.policy.MaxInterval {
+			interval = t.policy.MaxInterval
+		}
+	}
+}
@@ -19,19 +19,214 @@
 package utils
 
 import (
-	"golang.org/x/sync/errgroup"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
 
-	"px.dev/pixie/src/pixie_cli/pkg/components"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 )
 
 // Task is an entity that can be run.
 type Task interface {
 	Name() string
-	Run() error
+	// Run runs the task. The context is canceled when the run is interrupted with Ctrl+C, and can be used to
+	// RegisterCleanup functions that undo the task.
+	Run(ctx context.Context) error
+}
+
+// taskWithOptions wraps a task to configure how the task runners run it. The options are set by functions such as
+// WithRetry and WithTimeout, which can be combined.
+type taskWithOptions struct {
+	Task
+	retryPolicy *RetryPolicy
+	timeout     time.Duration
+	target      string
+	undo        func(ctx context.Context) error
+	progress    bool
+}
+
+func withOptions(t Task) *taskWithOptions {
+	if o, ok := t.(*taskWithOptions); ok {
+		c := *o
+		return &c
+	}
+	o := &taskWithOptions{Task: t}
+	if rt, ok := t.(RetryableTask); ok {
+		o.retryPolicy = rt.RetryPolicy()
+	}
+	if tt, ok := t.(TimeoutTask); ok {
+		o.timeout = tt.Timeout()
+	}
+	if tt, ok := t.(TargetedTask); ok {
+		o.target = tt.Target()
+	}
+	if ut, ok := t.(UndoableTask); ok {
+		o.undo = ut.Undo()
+	}
+	if pt, ok := t.(ProgressTask); ok {
+		o.progress = pt.ReportsProgress()
+	}
+	return o
+}
+
+func (t *taskWithOptions) RetryPolicy() *RetryPolicy {
+	return t.retryPolicy
+}
+
+func (t *taskWithOptions) Timeout() time.Duration {
+	return t.timeout
+}
+
+func (t *taskWithOptions) Target() string {
+	return t.target
+}
+
+func (t *taskWithOptions) Undo() func(ctx context.Context) error {
+	return t.undo
+}
+
+func (t *taskWithOptions) ReportsProgress() bool {
+	return t.progress
+}
+
+// runnerOptions are the options shared by the task runners.
+type runnerOptions struct {
+	timeout     time.Duration
+	events      io.Writer
+	handler     func(e *TaskEvent)
+	dryRun      bool
+	showSummary bool
+	durations   taskDurations
+	checkpoint  *Checkpoint
+	// confirmRollback decides whether an interrupted run is rolled back, if it's set.
+	confirmRollback func() bool
+}
+
+// SetDryRun makes the runner print the tasks that it would run in order, without running them.
+func (o *runnerOptions) SetDryRun(dryRun bool) {
+	o.dryRun = dryRun
+}
+
+// SetEventWriter makes the runner write task events to w, instead of showing spinners.
+func (o *runnerOptions) SetEventWriter(w io.Writer) {
+	o.events = w
+}
+
+// SetEventHandler makes the runner pass task events to handler, instead of showing spinners. The handler is called
+// from the goroutines that run the tasks, one event at a time.
+func (o *runnerOptions) SetEventHandler(handler func(e *TaskEvent)) {
+	o.handler = handler
+}
+
+// SetTimeout sets how long the tasks may take to complete altogether. Tasks that are still running when the
+// timeout expires fail, and no more tasks are started.
+func (o *runnerOptions) SetTimeout(timeout time.Duration) {
+	o.timeout = timeout
+}
+
+// startRun returns the context that the tasks of a run are passed, which is canceled when Ctrl+C is pressed or
+// the run times out, and a function that ends the run and returns its result. The function must be called once
+// the display of the run has stopped, since it prints the output captured from the tasks.
+func (o *runnerOptions) startRun() (context.Context, func(err error) error) {
+	ctx, cancel := WithSignalCancellable(context.Background())
+	if o.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = withRunTimeout(ctx, o.timeout)
+		cleanup := cancel
+		cancel = func() {
+			cancelTimeout()
+			cleanup()
+		}
+	}
+	ctx, cleanups := withCleanupRegistry(ctx)
+	if o.checkpoint != nil {
+		ctx = context.WithValue(ctx, checkpointKey{}, o.checkpoint)
+	}
+	o.durations.reset()
+	// Task events are written one per line, so the output of tasks is only captured while spinners are shown.
+	var logs *logCapture
+	if o.showsSpinners() {
+		logs = startLogCapture()
+		ctx = context.WithValue(ctx, logCaptureKey{}, logs)
+	}
+	return ctx, func(err error) error {
+		defer cancel()
+		if logs != nil {
+			logs.stop()
+			logs.print(os.Stderr, shouldShowTaskLogs())
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = &TimeoutError{Timeout: o.timeout}
+		}
+		// A run with a saved checkpoint keeps its completed tasks, so that it can be resumed, unless it was
+		// interrupted and the rollback is confirmed.
+		keep := o.checkpoint != nil && o.checkpoint.Saved()
+		if errors.Is(ctx.Err(), context.Canceled) && o.confirmRollback != nil && cleanups.pending() {
+			keep = !o.confirmRollback()
+		}
+		err = cleanups.finish(ctx, err, o.newTaskDisplay, keep)
+		if (err == nil || !keep) && o.checkpoint != nil {
+			if cpErr := o.checkpoint.Remove(); cpErr != nil {
+				log.WithError(cpErr).Warn("Failed to remove the checkpoint of the run")
+			}
+		}
+		o.durations.stop()
+		recordTaskMetrics(o.Durations())
+		if o.showSummary && o.showsSpinners() {
+			printDurationSummary(os.Stderr, o.Durations(), o.Elapsed())
+		}
+		return err
+	}
+}
+
+// runTask adds the task to the table, and runs it.
+func runTask(ctx context.Context, st taskDisplay, t Task) error {
+	cp, _ := ctx.Value(checkpointKey{}).(*Checkpoint)
+	if cp != nil && cp.Completed(t.Name()) {
+		ti := st.addTask(t.Name(), false)
+		ti.SetDetail("completed by an earlier run")
+		ti.Complete(nil)
+		return nil
+	}
+
+	pt, ok := t.(ProgressTask)
+	ti := st.addTask(t.Name(), ok && pt.ReportsProgress())
+	ctx = context.WithValue(ctx, taskStatusKey{}, ti)
+	ctx, logDone := withTaskLog(ctx, t)
+	defer func() {
+		// A task that panics is shown as failed, so that the display of the run can stop before the panic is
+		// reported.
+		if recovered := recover(); recovered != nil {
+			p := newGoroutinePanic(recovered)
+			logDone(errTaskPanicked)
+			ti.Complete(errTaskPanicked)
+			panic(p)
+		}
+	}()
+	err := runWithTimeout(ctx, t, func(ctx context.Context) error {
+		return runWithRetries(ctx, t, ti)
+	})
+	logDone(err)
+	ti.Complete(err)
+	if ut, ok := t.(UndoableTask); ok && err == nil && ut.Undo() != nil {
+		RegisterCleanup(ctx, fmt.Sprintf("Undoing: %s", t.Name()), ut.Undo())
+	}
+	if cp != nil && err == nil {
+		if cpErr := cp.markCompleted(t.Name()); cpErr != nil {
+			log.WithError(cpErr).Warnf("Failed to save the checkpoint, a resumed run will repeat %q", t.Name())
+		}
+	}
+	return err
 }
 
 // SerialTaskRunner runs tasks in serial and displays them in a table.
 type SerialTaskRunner struct {
+	runnerOptions
 	tasks []Task
 }
 
@@ -44,45 +239,234 @@ func NewSerialTaskRunner(tasks []Task) *SerialTaskRunner {
 
 // RunAndMonitor runs tasks and shows output in a table.
 func (s *SerialTaskRunner) RunAndMonitor() error {
-	st := components.NewSpinnerTable()
-	defer st.Wait()
+	if s.dryRun {
+		printDryRun(dryRunSteps(s.tasks))
+		return nil
+	}
+	ctx, finish := s.startRun()
+	st := s.newTaskDisplay()
+	var panics panicCollector
+	var err error
 	for _, t := range s.tasks {
-		ti := st.AddTask(t.Name())
-		err := t.Run()
-		ti.Complete(err)
-		if err != nil {
-			return err
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if err = panics.run(func() error { return runTask(ctx, st, t) }); err != nil {
+			break
 		}
 	}
-	return nil
+	st.wait()
+	err = finish(err)
+	panics.repanic()
+	return err
 }
 
 // ParallelTaskRunner runs tasks in parallel and displays them in a table.
 type ParallelTaskRunner struct {
-	tasks []Task
+	runnerOptions
+	tasks          []Task
+	maxConcurrency int
 }
 
-// NewParallelTaskRunner creates a new ParallelTaskRunner
-func NewParallelTaskRunner(tasks []Task) *ParallelTaskRunner {
+// NewParallelTaskRunner creates a new ParallelTaskRunner that runs at most maxConcurrency tasks at a time.
+// If maxConcurrency is zero or less, all tasks are run at once.
+func NewParallelTaskRunner(tasks []Task, maxConcurrency int) *ParallelTaskRunner {
 	return &ParallelTaskRunner{
-		tasks: tasks,
+		tasks:          tasks,
+		maxConcurrency: maxConcurrency,
 	}
 }
 
-// RunAndMonitor runs tasks and shows output in a table.
+// RunAndMonitor runs tasks and shows output in a table. Tasks are only added to the table once they start,
+// so the table shows the tasks that are running and the tasks that have completed.
 func (s *ParallelTaskRunner) RunAndMonitor() error {
-	st := components.NewSpinnerTable()
+	if s.dryRun {
+		printDryRun(dryRunSteps(s.tasks))
+		return nil
+	}
+	ctx, finish := s.startRun()
+	st := s.newTaskDisplay()
 	g := errgroup.Group{}
+	if s.maxConcurrency > 0 {
+		g.SetLimit(s.maxConcurrency)
+	}
+	var panics panicCollector
 	for _, t := range s.tasks {
 		boundTask := t
 		g.Go(func() error {
-			ti := st.AddTask(boundTask.Name())
-			err := boundTask.Run()
-			ti.Complete(err)
-			return err
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return panics.run(func() error { return runTask(ctx, st, boundTask) })
 		})
 	}
 	err := g.Wait()
-	st.Wait()
+	st.wait()
+	err = finish(err)
+	panics.repanic()
+	return err
+}
+
+// DAGTaskRunner runs tasks that depend on each other, and displays them in a table. A task is started once all of
+// the tasks it depends on have completed, so independent tasks run in parallel, while dependent tasks run in order.
+// Once a task fails, no more tasks are started.
+type DAGTaskRunner struct {
+	runnerOptions
+	tasks          []Task
+	deps           map[Task][]Task
+	maxConcurrency int
+}
+
+// NewDAGTaskRunner creates a new DAGTaskRunner that runs at most maxConcurrency tasks at a time. If maxConcurrency
+// is zero or less, all tasks whose dependencies have completed are run at once.
+func NewDAGTaskRunner(maxConcurrency int) *DAGTaskRunner {
+	return &DAGTaskRunner{
+		deps:           make(map[Task][]Task),
+		maxConcurrency: maxConcurrency,
+	}
+}
+
+// AddTask adds a task that is run after the given tasks have completed. The dependencies must also be added to the
+// runner. Tasks that are ready at the same time are started in the order they were added.
+func (s *DAGTaskRunner) AddTask(t Task, dependsOn ...Task) {
+	if _, ok := s.deps[t]; !ok {
+		s.tasks = append(s.tasks, t)
+	}
+	s.deps[t] = append(s.deps[t], dependsOn...)
+}
+
+// order returns the index of each task and the tasks in an order that they can run in, or an error if a dependency
+// is missing or the dependencies form a cycle.
+func (s *DAGTaskRunner) order() (map[Task]int, []Task, error) {
+	index := make(map[Task]int, len(s.tasks))
+	for i, t := range s.tasks {
+		index[t] = i
+	}
+	for _, t := range s.tasks {
+		for _, d := range s.deps[t] {
+			if _, ok := index[d]; !ok {
+				return nil, nil, fmt.Errorf("task %q depends on task %q, which wasn't added", t.Name(), d.Name())
+			}
+		}
+	}
+
+	// Check for cycles by repeatedly removing the tasks that have no remaining dependencies.
+	remaining := make(map[Task]bool, len(s.tasks))
+	for _, t := range s.tasks {
+		remaining[t] = true
+	}
+	sorted := make([]Task, 0, len(s.tasks))
+	for len(remaining) > 0 {
+		removed := false
+		for _, t := range s.tasks {
+			if !remaining[t] {
+				continue
+			}
+			ready := true
+			for _, d := range s.deps[t] {
+				if remaining[d] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				delete(remaining, t)
+				sorted = append(sorted, t)
+				removed = true
+			}
+		}
+		if !removed {
+			for _, t := range s.tasks {
+				if remaining[t] {
+					return nil, nil, fmt.Errorf("task %q is part of a dependency cycle", t.Name())
+				}
+			}
+		}
+	}
+	return index, sorted, nil
+}
+
+type dagTaskResult struct {
+	task Task
+	err  error
+}
+
+// RunAndMonitor runs tasks and shows output in a table. It returns the error of the first task that failed, once
+// the tasks that were already running have completed.
+func (s *DAGTaskRunner) RunAndMonitor() error {
+	index, sorted, err := s.order()
+	if err != nil {
+		return err
+	}
+	if s.dryRun {
+		steps := make([]dryRunStep, len(sorted))
+		for i, t := range sorted {
+			steps[i] = dryRunStep{task: t, deps: s.deps[t]}
+		}
+		printDryRun(steps)
+		return nil
+	}
+
+	pending := make(map[Task]int, len(s.tasks))
+	dependents := make(map[Task][]Task)
+	var ready []Task
+	for _, t := range s.tasks {
+		seen := make(map[Task]bool)
+		for _, d := range s.deps[t] {
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			pending[t]++
+			dependents[d] = append(dependents[d], t)
+		}
+		if pending[t] == 0 {
+			ready = append(ready, t)
+		}
+	}
+
+	ctx, finish := s.startRun()
+	st := s.newTaskDisplay()
+	var panics panicCollector
+	results := make(chan dagTaskResult)
+	running := 0
+	var firstErr error
+	for {
+		if firstErr == nil && ctx.Err() != nil {
+			firstErr = ctx.Err()
+		}
+		for firstErr == nil && len(ready) > 0 && (s.maxConcurrency <= 0 || running < s.maxConcurrency) {
+			t := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				results <- dagTaskResult{t, panics.run(func() error { return runTask(ctx, st, t) })}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		res := <-results
+		running--
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			continue
+		}
+		for _, d := range dependents[res.task] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+		sort.SliceStable(ready, func(i, j int) bool {
+			return index[ready[i]] < index[ready[j]]
+		})
+	}
+	st.wait()
+	err = finish(firstErr)
+	panics.repanic()
 	return err
 }
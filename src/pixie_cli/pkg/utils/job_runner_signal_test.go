@@ -0,0 +1,118 @@
+//go:build !windows
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// The interrupt tests signal the test process with SIGINT, which Windows doesn't support.
+
+func TestSerialTaskRunner_InterruptRunsCleanup(t *testing.T) {
+	cleanedUp := false
+	ranNext := false
+	tasks := []utils.Task{
+		&testTask{name: "interrupted", run: func(ctx context.Context) error {
+			utils.RegisterCleanup(ctx, "cleanup", func(ctx context.Context) error {
+				cleanedUp = true
+				return nil
+			})
+			if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+				return err
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		&testTask{name: "next", run: func(ctx context.Context) error {
+			ranNext = true
+			return nil
+		}},
+	}
+
+	err := utils.NewSerialTaskRunner(tasks).RunAndMonitor()
+	assert.ErrorIs(t, err, utils.ErrInterrupted)
+	assert.True(t, cleanedUp)
+	assert.False(t, ranNext)
+}
+
+// interruptingTasks returns a task that completes and registers a cleanup, followed by a task that is interrupted.
+func interruptingTasks(cleanedUp *bool) []utils.Task {
+	return []utils.Task{
+		&testTask{name: "completed", run: func(ctx context.Context) error {
+			utils.RegisterCleanup(ctx, "cleanup", func(ctx context.Context) error {
+				*cleanedUp = true
+				return nil
+			})
+			return nil
+		}},
+		&testTask{name: "interrupted", run: func(ctx context.Context) error {
+			if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+				return err
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	}
+}
+
+func TestSerialTaskRunner_InterruptKeepsDeclinedRollback(t *testing.T) {
+	cleanedUp := false
+	asked := 0
+	tr := utils.NewSerialTaskRunner(interruptingTasks(&cleanedUp))
+	tr.SetConfirmRollback(func() bool {
+		asked++
+		return false
+	})
+
+	err := tr.RunAndMonitor()
+	assert.ErrorIs(t, err, utils.ErrInterrupted)
+	assert.Equal(t, 1, asked)
+	assert.False(t, cleanedUp)
+}
+
+func TestSerialTaskRunner_InterruptRollsBackResumableRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+
+	cp, err := utils.NewCheckpoint("test", nil)
+	require.NoError(t, err)
+	cleanedUp := false
+	tr := utils.NewSerialTaskRunner(interruptingTasks(&cleanedUp))
+	tr.SetCheckpoint(cp)
+	tr.SetConfirmRollback(func() bool { return true })
+
+	err = tr.RunAndMonitor()
+	assert.ErrorIs(t, err, utils.ErrInterrupted)
+	assert.True(t, cleanedUp)
+	// The rolled back run can't be resumed.
+	assert.False(t, cp.Saved())
+	_, err = utils.LoadCheckpoint("test")
+	assert.ErrorIs(t, err, utils.ErrNoCheckpoint)
+}
@@ -0,0 +1,499 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+type testTask struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (t *testTask) Name() string {
+	return t.name
+}
+
+func (t *testTask) Run(ctx context.Context) error {
+	return t.run(ctx)
+}
+
+func TestParallelTaskRunner_MaxConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	tasks := make([]utils.Task, 10)
+	for i := range tasks {
+		tasks[i] = &testTask{
+			name: fmt.Sprintf("task %d", i),
+			run: func(ctx context.Context) error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			},
+		}
+	}
+
+	err := utils.NewParallelTaskRunner(tasks, 3).RunAndMonitor()
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, maxRunning, int32(3))
+	assert.Greater(t, maxRunning, int32(1))
+}
+
+func TestParallelTaskRunner_Error(t *testing.T) {
+	errFailed := errors.New("failed")
+	var ran int32
+	tasks := []utils.Task{
+		&testTask{name: "ok", run: func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return nil
+		}},
+		&testTask{name: "fails", run: func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return errFailed
+		}},
+	}
+
+	err := utils.NewParallelTaskRunner(tasks, 0).RunAndMonitor()
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, int32(2), ran)
+}
+
+func TestDAGTaskRunner_Order(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	newTask := func(name string) *testTask {
+		return &testTask{name: name, run: func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}}
+	}
+	download := newTask("download")
+	namespace := newTask("namespace")
+	crds := newTask("crds")
+	workloads := newTask("workloads")
+	smoke := newTask("smoke tests")
+
+	r := utils.NewDAGTaskRunner(0)
+	r.AddTask(download)
+	r.AddTask(namespace)
+	r.AddTask(crds, download, namespace)
+	r.AddTask(workloads, crds)
+	r.AddTask(smoke, workloads)
+	require.NoError(t, r.RunAndMonitor())
+
+	require.Len(t, order, 5)
+	assert.ElementsMatch(t, []string{"download", "namespace"}, order[:2])
+	assert.Equal(t, []string{"crds", "workloads", "smoke tests"}, order[2:])
+}
+
+func TestDAGTaskRunner_StopsOnError(t *testing.T) {
+	errFailed := errors.New("failed")
+	ranDependent := false
+	failing := &testTask{name: "fails", run: func(ctx context.Context) error {
+		return errFailed
+	}}
+	dependent := &testTask{name: "dependent", run: func(ctx context.Context) error {
+		ranDependent = true
+		return nil
+	}}
+
+	r := utils.NewDAGTaskRunner(0)
+	r.AddTask(failing)
+	r.AddTask(dependent, failing)
+	assert.ErrorIs(t, r.RunAndMonitor(), errFailed)
+	assert.False(t, ranDependent)
+}
+
+func TestDAGTaskRunner_Invalid(t *testing.T) {
+	a := &testTask{name: "a", run: func(ctx context.Context) error { return nil }}
+	b := &testTask{name: "b", run: func(ctx context.Context) error { return nil }}
+
+	r := utils.NewDAGTaskRunner(0)
+	r.AddTask(a, b)
+	assert.EqualError(t, r.RunAndMonitor(), `task "a" depends on task "b", which wasn't added`)
+
+	r = utils.NewDAGTaskRunner(0)
+	r.AddTask(a, b)
+	r.AddTask(b, a)
+	assert.EqualError(t, r.RunAndMonitor(), `task "a" is part of a dependency cycle`)
+}
+
+func TestSerialTaskRunner_Retry(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+	policy := &utils.RetryPolicy{
+		MaxAttempts:     3,
+		InitialInterval: time.Millisecond,
+		Retryable: func(err error) bool {
+			return errors.Is(err, errTransient)
+		},
+	}
+
+	attempts := 0
+	succeedsLater := utils.WithRetry(&testTask{name: "succeeds later", run: func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errTransient
+		}
+		return nil
+	}}, policy)
+	require.NoError(t, utils.NewSerialTaskRunner([]utils.Task{succeedsLater}).RunAndMonitor())
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	permanent := utils.WithRetry(&testTask{name: "permanent", run: func(ctx context.Context) error {
+		attempts++
+		return errPermanent
+	}}, policy)
+	assert.ErrorIs(t, utils.NewSerialTaskRunner([]utils.Task{permanent}).RunAndMonitor(), errPermanent)
+	assert.Equal(t, 1, attempts)
+
+	attempts = 0
+	exhausted := utils.WithRetry(&testTask{name: "exhausted", run: func(ctx context.Context) error {
+		attempts++
+		return errTransient
+	}}, policy)
+	assert.ErrorIs(t, utils.NewSerialTaskRunner([]utils.Task{exhausted}).RunAndMonitor(), errTransient)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestSerialTaskRunner_RollbackOnFailure(t *testing.T) {
+	var undone []string
+	undo := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			undone = append(undone, name)
+			return nil
+		}
+	}
+	succeed := func(ctx context.Context) error { return nil }
+	failErr := errors.New("failed")
+	tasks := []utils.Task{
+		utils.WithUndo(&testTask{name: "first", run: succeed}, undo("first")),
+		utils.WithUndo(&testTask{name: "second", run: succeed}, undo("second")),
+		utils.WithUndo(&testTask{name: "failing", run: func(ctx context.Context) error {
+			return failErr
+		}}, undo("failing")),
+	}
+
+	err := utils.NewSerialTaskRunner(tasks).RunAndMonitor()
+	assert.ErrorIs(t, err, failErr)
+	assert.Equal(t, []string{"second", "first"}, undone)
+}
+
+func TestSerialTaskRunner_TaskTimeout(t *testing.T) {
+	stuck := utils.WithTimeout(&testTask{name: "stuck", run: func(ctx context.Context) error {
+		// Ignores the context, like a stuck API call.
+		time.Sleep(time.Second)
+		return nil
+	}}, 10*time.Millisecond)
+
+	err := utils.NewSerialTaskRunner([]utils.Task{stuck}).RunAndMonitor()
+	assert.EqualError(t, err, "timed out after 10ms")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSerialTaskRunner_RunTimeout(t *testing.T) {
+	ranSecond := false
+	tasks := []utils.Task{
+		&testTask{name: "slow", run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		&testTask{name: "second", run: func(ctx context.Context) error {
+			ranSecond = true
+			return nil
+		}},
+	}
+
+	r := utils.NewSerialTaskRunner(tasks)
+	r.SetTimeout(10 * time.Millisecond)
+	assert.EqualError(t, r.RunAndMonitor(), "timed out after 10ms")
+	assert.False(t, ranSecond)
+}
+
+func TestSerialTaskRunner_Events(t *testing.T) {
+	errFailed := errors.New("failed")
+	tasks := []utils.Task{
+		&testTask{name: "ok", run: func(ctx context.Context) error { return nil }},
+		&testTask{name: "fails", run: func(ctx context.Context) error { return errFailed }},
+	}
+
+	var buf bytes.Buffer
+	r := utils.NewSerialTaskRunner(tasks)
+	r.SetEventWriter(&buf)
+	assert.ErrorIs(t, r.RunAndMonitor(), errFailed)
+
+	var events []utils.TaskEvent
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e utils.TaskEvent
+		require.NoError(t, dec.Decode(&e))
+		events = append(events, e)
+	}
+	require.Len(t, events, 4)
+	assert.Equal(t, utils.TaskStarted, events[0].Type)
+	assert.Equal(t, "ok", events[0].Task)
+	assert.Equal(t, utils.TaskSucceeded, events[1].Type)
+	assert.Equal(t, utils.TaskStarted, events[2].Type)
+	assert.Equal(t, utils.TaskFailed, events[3].Type)
+	assert.Equal(t, "fails", events[3].Task)
+	assert.Equal(t, "failed", events[3].Error)
+}
+
+func TestDAGTaskRunner_DryRun(t *testing.T) {
+	ran := false
+	newTask := func(name string) utils.Task {
+		return &testTask{name: name, run: func(ctx context.Context) error {
+			ran = true
+			return nil
+		}}
+	}
+	namespace := utils.WithTarget(newTask("namespace"), "namespace px-sock-shop")
+	yamls := newTask("yamls")
+
+	r := utils.NewDAGTaskRunner(0)
+	r.AddTask(yamls, namespace)
+	r.AddTask(namespace)
+	r.SetDryRun(true)
+	require.NoError(t, r.RunAndMonitor())
+	assert.False(t, ran)
+}
+
+func TestSerialTaskRunner_SubtaskEvents(t *testing.T) {
+	errFailed := errors.New("failed")
+	tasks := []utils.Task{
+		&testTask{name: "deploy", run: func(ctx context.Context) error {
+			utils.StartSubtask(ctx, "a.yaml").Complete(nil)
+			return utils.RunSubtask(ctx, "b.yaml", func(ctx context.Context) error {
+				return errFailed
+			})
+		}},
+	}
+
+	var buf bytes.Buffer
+	r := utils.NewSerialTaskRunner(tasks)
+	r.SetEventWriter(&buf)
+	assert.ErrorIs(t, r.RunAndMonitor(), errFailed)
+
+	var events []utils.TaskEvent
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e utils.TaskEvent
+		require.NoError(t, dec.Decode(&e))
+		events = append(events, e)
+	}
+	require.Len(t, events, 6)
+	assert.Equal(t, utils.TaskEvent{Type: utils.TaskStarted, Task: "a.yaml", Parent: "deploy"},
+		utils.TaskEvent{Type: events[1].Type, Task: events[1].Task, Parent: events[1].Parent})
+	assert.Equal(t, utils.TaskFailed, events[4].Type)
+	assert.Equal(t, "b.yaml", events[4].Task)
+	assert.Equal(t, "deploy", events[4].Parent)
+	assert.Equal(t, utils.TaskFailed, events[5].Type)
+	assert.Equal(t, "deploy", events[5].Task)
+}
+
+func TestSerialTaskRunner_Durations(t *testing.T) {
+	failErr := errors.New("failed")
+	tasks := []utils.Task{
+		&testTask{name: "slow", run: func(ctx context.Context) error {
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}},
+		&testTask{name: "failing", run: func(ctx context.Context) error {
+			return failErr
+		}},
+	}
+
+	tr := utils.NewSerialTaskRunner(tasks)
+	tr.SetEventWriter(&bytes.Buffer{})
+	err := tr.RunAndMonitor()
+	assert.ErrorIs(t, err, failErr)
+
+	durations := tr.Durations()
+	require.Len(t, durations, 2)
+	assert.Equal(t, "slow", durations[0].Name)
+	assert.GreaterOrEqual(t, durations[0].Duration, 20*time.Millisecond)
+	assert.NoError(t, durations[0].Err)
+	assert.Equal(t, "failing", durations[1].Name)
+	assert.ErrorIs(t, durations[1].Err, failErr)
+	assert.GreaterOrEqual(t, tr.Elapsed(), durations[0].Duration+durations[1].Duration)
+}
+
+func TestSerialTaskRunner_CapturesLogs(t *testing.T) {
+	var out bytes.Buffer
+	prevOut := log.StandardLogger().Out
+	log.SetOutput(&out)
+	defer log.SetOutput(prevOut)
+
+	tasks := []utils.Task{
+		&testTask{name: "logging", run: func(ctx context.Context) error {
+			log.Info("logged by the task")
+			fmt.Fprintln(utils.TaskOutput(ctx), "written by the task")
+			return nil
+		}},
+	}
+	err := utils.NewSerialTaskRunner(tasks).RunAndMonitor()
+	require.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	log.Info("logged after the run")
+	assert.Contains(t, out.String(), "logged after the run")
+}
+
+func TestSerialTaskRunner_ProgressEvents(t *testing.T) {
+	task := utils.WithProgress(&testTask{name: "download", run: func(ctx context.Context) error {
+		utils.ReportProgress(ctx, 0.5)
+		// Progress within the same percent isn't reported again.
+		utils.ReportProgress(ctx, 0.501)
+		utils.ReportProgress(ctx, 0.75)
+		return nil
+	}})
+	pt, ok := task.(utils.ProgressTask)
+	require.True(t, ok)
+	assert.True(t, pt.ReportsProgress())
+
+	var buf bytes.Buffer
+	r := utils.NewSerialTaskRunner([]utils.Task{task})
+	r.SetEventWriter(&buf)
+	require.NoError(t, r.RunAndMonitor())
+
+	var progress []float64
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e utils.TaskEvent
+		require.NoError(t, dec.Decode(&e))
+		if e.Type == utils.TaskProgress {
+			progress = append(progress, e.Progress)
+		}
+	}
+	assert.Equal(t, []float64{0.5, 0.75}, progress)
+}
+
+func TestSerialTaskRunner_ResumeFromCheckpoint(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+
+	firstRuns, secondRuns := 0, 0
+	undone := false
+	failSecond := true
+	tasks := []utils.Task{
+		utils.WithUndo(&testTask{name: "first", run: func(ctx context.Context) error {
+			firstRuns++
+			return nil
+		}}, func(ctx context.Context) error {
+			undone = true
+			return nil
+		}),
+		&testTask{name: "second", run: func(ctx context.Context) error {
+			secondRuns++
+			if failSecond {
+				return errors.New("failed")
+			}
+			return nil
+		}},
+	}
+
+	cp, err := utils.NewCheckpoint("test", map[string]string{"param": "value"})
+	require.NoError(t, err)
+	tr := utils.NewSerialTaskRunner(tasks)
+	tr.SetCheckpoint(cp)
+	require.Error(t, tr.RunAndMonitor())
+	assert.True(t, cp.Saved())
+	// The completed task is kept, so that the run can be resumed.
+	assert.False(t, undone)
+
+	cp, err = utils.LoadCheckpoint("test")
+	require.NoError(t, err)
+	assert.Equal(t, "value", cp.Params()["param"])
+	assert.True(t, cp.Completed("first"))
+	failSecond = false
+	tr = utils.NewSerialTaskRunner(tasks)
+	tr.SetCheckpoint(cp)
+	require.NoError(t, tr.RunAndMonitor())
+	assert.Equal(t, 1, firstRuns)
+	assert.Equal(t, 2, secondRuns)
+
+	_, err = utils.LoadCheckpoint("test")
+	assert.ErrorIs(t, err, utils.ErrNoCheckpoint)
+}
+
+func TestTaskRunners_ReraisePanicsOfTasks(t *testing.T) {
+	panicking := func() utils.Task {
+		return &testTask{name: "panics", run: func(ctx context.Context) error {
+			panic("task panic")
+		}}
+	}
+	runners := map[string]func() error{
+		"serial": func() error {
+			return utils.NewSerialTaskRunner([]utils.Task{panicking()}).RunAndMonitor()
+		},
+		"parallel": func() error {
+			return utils.NewParallelTaskRunner([]utils.Task{panicking(), panicking()}, 0).RunAndMonitor()
+		},
+		"dag": func() error {
+			tr := utils.NewDAGTaskRunner(0)
+			tr.AddTask(panicking())
+			return tr.RunAndMonitor()
+		},
+		"timeout": func() error {
+			return utils.NewSerialTaskRunner([]utils.Task{utils.WithTimeout(panicking(), time.Minute)}).RunAndMonitor()
+		},
+	}
+	for name, run := range runners {
+		t.Run(name, func(t *testing.T) {
+			var report *utils.CrashReport
+			func() {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						report = utils.NewCrashReport(recovered, nil)
+					}
+				}()
+				_ = run()
+			}()
+			require.NotNil(t, report, "the panic of the task wasn't re-raised")
+			assert.Equal(t, "task panic", report.Panic)
+			// The stack trace leads to the task, rather than to the runner that re-raised the panic.
+			assert.Contains(t, report.Stack, "job_runner_test.go")
+		})
+	}
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
+)
+
+const (
+	// logFileName is the name of the current log file. Rotated files get a numbered suffix, such as px.log.1.
+	logFileName = "px.log"
+	// maxLogFileSize is the size at which the log file is rotated.
+	maxLogFileSize = 10 * 1024 * 1024
+	// maxLogFiles is the number of log files that are kept, including the current one.
+	maxLogFiles = 5
+	// fileLogLevel is the level of the messages that are written to the log file, whatever the level of the console.
+	fileLogLevel = log.DebugLevel
+)
+
+var (
+	logFileMu   sync.Mutex
+	logFilePath string
+)
+
+// LogFilePath returns the path of the log file of this run, or "" if there is none.
+func LogFilePath() string {
+	logFileMu.Lock()
+	defer logFileMu.Unlock()
+	return logFilePath
+}
+
+// consoleFormatter formats the log messages of the console, dropping the messages that are more verbose than its
+// level. The logger itself is at the level of the log file, so that the log file gets debug messages even when the
+// console doesn't show them.
+type consoleFormatter struct {
+	log.Formatter
+	level log.Level
+}
+
+func (f *consoleFormatter) Format(entry *log.Entry) ([]byte, error) {
+	if entry.Level > f.level {
+		return nil, nil
+	}
+	if entry.Level <= log.FatalLevel && components.JSONErrors() {
+		// Fatal errors are rendered as JSON objects like the CLI's other errors, so that automation can parse them.
+		err, _ := entry.Data[log.ErrorKey].(error)
+		code := exitcodes.Error
+		if err != nil {
+			code = exitcodes.ForError(err)
+		}
+		var buf bytes.Buffer
+		components.RenderErrorJSON(&buf, entry.Message, err, nil, code)
+		return buf.Bytes(), nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// SetConsoleLogLevel sets the level of the log messages shown on the console. The log file keeps getting debug
+// messages if the level is less verbose.
+func SetConsoleLogLevel(level log.Level) {
+	logger := log.StandardLogger()
+	formatter := logger.Formatter
+	if f, ok := formatter.(*consoleFormatter); ok {
+		formatter = f.Formatter
+	}
+	logger.SetFormatter(&consoleFormatter{Formatter: formatter, level: level})
+	if LogFilePath() != "" && level < fileLogLevel {
+		level = fileLogLevel
+	}
+	logger.SetLevel(level)
+}
+
+// logFileHook writes the log messages of every level up to debug to the log file, as JSON.
+type logFileHook struct {
+	mu        sync.Mutex
+	path      string
+	f         *os.File
+	size      int64
+	formatter log.Formatter
+}
+
+func (h *logFileHook) Levels() []log.Level {
+	var levels []log.Level
+	for _, l := range log.AllLevels {
+		if l <= fileLogLevel {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+func (h *logFileHook) Fire(entry *log.Entry) error {
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.size+int64(len(b)) > maxLogFileSize {
+		h.f.Close()
+		if err := rotateLogFiles(h.path); err != nil {
+			return err
+		}
+		if h.f, err = os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+			return err
+		}
+		h.size = 0
+	}
+	n, err := h.f.Write(b)
+	h.size += int64(n)
+	return err
+}
+
+// rotateLogFiles renames px.log to px.log.1, px.log.1 to px.log.2 and so on, dropping the oldest file.
+func rotateLogFiles(path string) error {
+	for i := maxLogFiles - 1; i > 0; i-- {
+		src := path
+		if i > 1 {
+			src = fmt.Sprintf("%s.%d", path, i-1)
+		}
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", path, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartLogFile writes a structured debug log of this run to the log file, which is rotated once it grows too big.
+// Errors that make the CLI exit refer to the log file.
+func StartLogFile(args []string) error {
+	dir, err := EnsureDefaultLogsDirPath()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, logFileName)
+	if info, err := os.Stat(path); err == nil && info.Size() >= maxLogFileSize {
+		if err := rotateLogFiles(path); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	logger := log.StandardLogger()
+	logger.AddHook(&logFileHook{
+		path:      path,
+		f:         f,
+		size:      info.Size(),
+		formatter: &log.JSONFormatter{},
+	})
+	logFileMu.Lock()
+	logFilePath = path
+	logFileMu.Unlock()
+	SetConsoleLogLevel(logger.GetLevel())
+	components.SetLogFilePath(path)
+
+	exitcodes.OnExit(func(code int) {
+		if code != exitcodes.Success {
+			printLogFileHint()
+		}
+	})
+
+	log.WithField("args", args).WithField("pid", os.Getpid()).Debug("Exec started")
+	return nil
+}
+
+// printLogFileHint tells the user where the log of this run is, so that they can find out why it failed. JSON errors
+// have the path in their log_file field instead.
+func printLogFileHint() {
+	if components.JSONErrors() {
+		return
+	}
+	if path := LogFilePath(); path != "" {
+		fmt.Fprintf(os.Stderr, "The debug log of this run is in %s\n", path)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxMetricsEntries is how many of the most recent commands the metrics are kept for.
+const maxMetricsEntries = 1000
+
+// TaskMetric is how long a task of a command took.
+type TaskMetric struct {
+	Name string `json:"name"`
+	// Parent is the name of the task that started the task, if it is a subtask.
+	Parent   string        `json:"parent,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Failed   bool          `json:"failed,omitempty"`
+}
+
+// CommandMetrics is how long a command and its tasks took, as recorded under ~/.local/state/pixie once metrics are
+// enabled.
+type CommandMetrics struct {
+	Time time.Time `json:"time"`
+	// Command is the path of the command, such as "px demo deploy". Arguments aren't recorded.
+	Command  string        `json:"command"`
+	Duration time.Duration `json:"duration"`
+	ExitCode int           `json:"exit_code"`
+	Tasks    []TaskMetric  `json:"tasks,omitempty"`
+}
+
+var (
+	metricsMu sync.Mutex
+	// currentMetrics are the metrics of the running command, or nil if metrics aren't enabled.
+	currentMetrics *CommandMetrics
+)
+
+// StartCommandMetrics starts recording how long the command and the tasks that it runs take.
+func StartCommandMetrics(command string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	currentMetrics = &CommandMetrics{Time: time.Now(), Command: command}
+}
+
+// recordTaskMetrics adds the durations of the tasks of a run to the metrics of the command, if they are recorded.
+func recordTaskMetrics(durations []TaskDuration) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if currentMetrics == nil {
+		return
+	}
+	for _, d := range durations {
+		currentMetrics.Tasks = append(currentMetrics.Tasks, TaskMetric{
+			Name:     d.Name,
+			Parent:   d.Parent,
+			Duration: d.Duration,
+			Failed:   d.Err != nil,
+		})
+	}
+}
+
+// FinishCommandMetrics stops recording the metrics of the command, and appends them to the recorded metrics. It
+// returns nil if StartCommandMetrics wasn't called.
+func FinishCommandMetrics(exitCode int) (*CommandMetrics, error) {
+	metricsMu.Lock()
+	m := currentMetrics
+	currentMetrics = nil
+	metricsMu.Unlock()
+	if m == nil {
+		return nil, nil
+	}
+	m.Duration = time.Since(m.Time)
+	m.ExitCode = exitCode
+
+	entries, err := ReadMetrics()
+	if err != nil {
+		return m, err
+	}
+	entries = append(entries, m)
+	if len(entries) > maxMetricsEntries {
+		entries = entries[len(entries)-maxMetricsEntries:]
+	}
+	return m, writeMetrics(entries)
+}
+
+func writeMetrics(entries []*CommandMetrics) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	path, err := EnsureDefaultMetricsFilePath()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// ReadMetrics returns the recorded metrics, from the oldest command to the most recent. Lines that can't be parsed
+// are skipped.
+func ReadMetrics() ([]*CommandMetrics, error) {
+	path, err := EnsureDefaultMetricsFilePath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*CommandMetrics
+	scanner := bufio.NewScanner(f)
+	// Commands that run many tasks have long lines.
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		e := &CommandMetrics{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// ClearMetrics removes the recorded metrics.
+func ClearMetrics() error {
+	path, err := EnsureDefaultMetricsFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
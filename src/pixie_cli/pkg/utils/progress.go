@@ -0,0 +1,46 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+)
+
+// ProgressTask is a task that reports how much of its work is done with ReportProgress, such as a download or a
+// wait for a known number of resources. The task runners show it with a progress bar and the estimated time until
+// it completes, rather than a spinner.
+type ProgressTask interface {
+	Task
+	ReportsProgress() bool
+}
+
+// WithProgress returns a task that runs the given task, which reports its progress with ReportProgress.
+func WithProgress(t Task) Task {
+	o := withOptions(t)
+	o.progress = true
+	return o
+}
+
+// ReportProgress reports the fraction of the work of the running task that is done, from 0 to 1. Tasks that
+// aren't a ProgressTask show the progress after their name. Outside of a task runner, the progress isn't shown.
+func ReportProgress(ctx context.Context, fraction float64) {
+	if st, ok := ctx.Value(taskStatusKey{}).(taskStatus); ok {
+		st.SetProgress(fraction)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// RetryPolicy configures how a task is retried when it fails.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times the task is run, including the first attempt.
+	MaxAttempts int
+	// InitialInterval is the delay before the second attempt. The delay doubles after each attempt.
+	InitialInterval time.Duration
+	// MaxInterval is the longest delay between attempts.
+	MaxInterval time.Duration
+	// Retryable returns whether the task should be retried after failing with the error. Defaults to
+	// IsTransientError.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy retries transient errors twice, after 1s and 2s.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts:     3,
+	InitialInterval: time.Second,
+	MaxInterval:     10 * time.Second,
+}
+
+// RetryableTask is a task that is retried by the task runners when it fails.
+type RetryableTask interface {
+	Task
+	RetryPolicy() *RetryPolicy
+}
+
+// WithRetry returns a task that runs the given task, and is retried according to the policy.
+func WithRetry(t Task, policy *RetryPolicy) Task {
+	o := withOptions(t)
+	o.retryPolicy = policy
+	return o
+}
+
+// IsTransientError returns whether the error is likely caused by a temporary problem with the network or the
+// Kubernetes API server, so that retrying the operation may succeed.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if k8serrors.IsServerTimeout(err) || k8serrors.IsTimeout(err) || k8serrors.IsTooManyRequests(err) ||
+		k8serrors.IsServiceUnavailable(err) || k8serrors.IsInternalError(err) || k8serrors.IsUnexpectedServerError(err) {
+		return true
+	}
+	if utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// runWithRetries runs the task, retrying it if it has a retry policy, and shows the attempt in the task's
+// status line.
+func runWithRetries(ctx context.Context, t Task, ti taskStatus) error {
+	rt, ok := t.(RetryableTask)
+	if !ok || rt.RetryPolicy() == nil || rt.RetryPolicy().MaxAttempts <= 1 {
+		return t.Run(ctx)
+	}
+	policy := rt.RetryPolicy()
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+
+	interval := policy.InitialInterval
+	var err error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			ti.SetDetail(fmt.Sprintf("attempt %d/%d", attempt, policy.MaxAttempts))
+		}
+		err = t.Run(ctx)
+		if err == nil || !retryable(err) || attempt == policy.MaxAttempts || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if policy.MaxInterval > 0 && interval > policy.MaxInterval {
+			interval = policy.MaxInterval
+		}
+	}
+	ti.SetDetail("")
+	return err
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+)
+
+type taskStatusKey struct{}
+
+// Subtask is a child of a running task, such as one of the files that a task applies. Subtasks are shown with
+// their own status, indented below their parent task.
+type Subtask struct {
+	status taskStatus
+}
+
+// StartSubtask shows a subtask below the running task that the context was passed to. Outside of a task runner,
+// the subtask isn't shown.
+func StartSubtask(ctx context.Context, name string) *Subtask {
+	parent, ok := ctx.Value(taskStatusKey{}).(taskStatus)
+	if !ok {
+		return &Subtask{}
+	}
+	return &Subtask{parent.addSubtask(name)}
+}
+
+// Context returns a context that starts subtasks below this subtask.
+func (s *Subtask) Context(ctx context.Context) context.Context {
+	if s.status == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, taskStatusKey{}, s.status)
+}
+
+// SetDetail shows details about the progress of the subtask.
+func (s *Subtask) SetDetail(detail string) {
+	if s.status != nil {
+		s.status.SetDetail(detail)
+	}
+}
+
+// SetProgress shows the fraction of the work of the subtask that is done, from 0 to 1.
+func (s *Subtask) SetProgress(fraction float64) {
+	if s.status != nil {
+		s.status.SetProgress(fraction)
+	}
+}
+
+// Complete finishes the subtask, showing whether it succeeded.
+func (s *Subtask) Complete(err error) {
+	if s.status != nil {
+		s.status.Complete(err)
+	}
+}
+
+// RunSubtask runs fn as a subtask of the running task, and returns its error.
+func RunSubtask(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	s := StartSubtask(ctx, name)
+	err := fn(s.Context(ctx))
+	s.Complete(err)
+	return err
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+)
+
+// TaskDuration is how long a task of a run took.
+type TaskDuration struct {
+	Name string
+	// Parent is the name of the task that started the task, if it is a subtask.
+	Parent   string
+	Duration time.Duration
+	// Err is the error that the task failed with, if any.
+	Err error
+}
+
+// taskDurations records how long the tasks of a run take.
+type taskDurations struct {
+	mu        sync.Mutex
+	start     time.Time
+	elapsed   time.Duration
+	durations []TaskDuration
+}
+
+func (d *taskDurations) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.start = time.Now()
+	d.elapsed = 0
+	d.durations = nil
+}
+
+func (d *taskDurations) add(td TaskDuration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.durations = append(d.durations, td)
+}
+
+func (d *taskDurations) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.elapsed = time.Since(d.start)
+}
+
+// SetShowSummary makes the runner print how long each task took once the run completes. The summary isn't printed
+// when the runner writes task events, which already include the durations.
+func (o *runnerOptions) SetShowSummary(show bool) {
+	o.showSummary = show
+}
+
+// Durations returns how long each task of the last run took, in the order that the tasks completed. Subtasks are
+// included, with the name of their parent task.
+func (o *runnerOptions) Durations() []TaskDuration {
+	o.durations.mu.Lock()
+	defer o.durations.mu.Unlock()
+	return append([]TaskDuration(nil), o.durations.durations...)
+}
+
+// Elapsed returns the wall-clock time that the last run took. It is less than the sum of the durations of the tasks
+// if they ran in parallel.
+func (o *runnerOptions) Elapsed() time.Duration {
+	o.durations.mu.Lock()
+	defer o.durations.mu.Unlock()
+	return o.durations.elapsed
+}
+
+// durationDisplay records the durations of the tasks shown by a display.
+type durationDisplay struct {
+	taskDisplay
+	durations *taskDurations
+}
+
+func (d *durationDisplay) addTask(name string, showsProgress bool) taskStatus {
+	return &durationStatus{
+		taskStatus: d.taskDisplay.addTask(name, showsProgress),
+		durations:  d.durations,
+		name:       name,
+		start:      time.Now(),
+	}
+}
+
+type durationStatus struct {
+	taskStatus
+	durations *taskDurations
+	name      string
+	parent    string
+	start     time.Time
+}
+
+func (s *durationStatus) addSubtask(name string) taskStatus {
+	return &durationStatus{
+		taskStatus: s.taskStatus.addSubtask(name),
+		durations:  s.durations,
+		name:       name,
+		parent:     s.name,
+		start:      time.Now(),
+	}
+}
+
+func (s *durationStatus) Complete(err error) {
+	s.durations.add(TaskDuration{Name: s.name, Parent: s.parent, Duration: time.Since(s.start), Err: err})
+	s.taskStatus.Complete(err)
+}
+
+// formatTaskDuration rounds the duration to a precision that is readable, but still tells fast tasks apart.
+func formatTaskDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// printDurationSummary prints how long each task took, followed by the wall-clock time of the run:
+//
+//	Task durations:
+//	  Creating namespace px-sock-shop  312ms
+//	  Deploying px-sock-shop YAMLs     1m12.4s
+//	  Total                            1m12.7s
+//
+// Subtasks are left out to keep the summary short, but are included in Durations.
+func printDurationSummary(w io.Writer, durations []TaskDuration, elapsed time.Duration) {
+	const totalName = "Total"
+	width := runewidth.StringWidth(totalName)
+	for _, d := range durations {
+		if nameWidth := runewidth.StringWidth(d.Name); d.Parent == "" && nameWidth > width {
+			width = nameWidth
+		}
+	}
+
+	fmt.Fprintln(w, color.New(color.Bold).Sprint("Task durations:"))
+	for _, d := range durations {
+		if d.Parent != "" {
+			continue
+		}
+		line := fmt.Sprintf("  %s  %s", runewidth.FillRight(d.Name, width), formatTaskDuration(d.Duration))
+		if d.Err != nil {
+			line += color.RedString("  failed")
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "  %s  %s\n", runewidth.FillRight(totalName, width), formatTaskDuration(elapsed))
+}
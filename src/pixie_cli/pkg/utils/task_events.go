@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+)
+
+// TaskEventType is the type of a task event.
+type TaskEventType string
+
+const (
+	// TaskStarted is emitted when a task starts.
+	TaskStarted TaskEventType = "task_started"
+	// TaskProgress is emitted when a task reports details about its progress, such as the attempt it is on.
+	TaskProgress TaskEventType = "task_progress"
+	// TaskSucceeded is emitted when a task completes successfully.
+	TaskSucceeded TaskEventType = "task_succeeded"
+	// TaskFailed is emitted when a task fails.
+	TaskFailed TaskEventType = "task_failed"
+)
+
+// TaskEvent is a machine-readable record of a change in the status of a task. Events are written as JSON, one
+// event per line.
+type TaskEvent struct {
+	Time time.Time     `json:"time"`
+	Type TaskEventType `json:"type"`
+	Task string        `json:"task"`
+	// Parent is the name of the task that started the task, if it is a subtask.
+	Parent string `json:"parent,omitempty"`
+	// Detail is the detail reported by a TaskProgress event.
+	Detail string `json:"detail,omitempty"`
+	// Progress is the fraction of the work of the task that is done, from 0 to 1, reported by a TaskProgress event.
+	Progress float64 `json:"progress,omitempty"`
+	// Error is the error that a task failed with.
+	Error string `json:"error,omitempty"`
+	// DurationMs is how long the task ran for, for TaskSucceeded and TaskFailed events.
+	DurationMs int64 `json:"duration_ms,omitempty"`
+}
+
+var (
+	taskEventWriterMu sync.Mutex
+	taskEventWriter   io.Writer
+)
+
+// SetTaskEventWriter makes all task runners write task events to w instead of showing spinners, for example when
+// the CLI outputs JSON. Passing nil restores the spinners.
+func SetTaskEventWriter(w io.Writer) {
+	taskEventWriterMu.Lock()
+	defer taskEventWriterMu.Unlock()
+	taskEventWriter = w
+}
+
+func defaultTaskEventWriter() io.Writer {
+	taskEventWriterMu.Lock()
+	defer taskEventWriterMu.Unlock()
+	return taskEventWriter
+}
+
+// taskStatus shows the status of a running task.
+type taskStatus interface {
+	SetDetail(detail string)
+	SetProgress(fraction float64)
+	Complete(err error)
+	addSubtask(name string) taskStatus
+}
+
+// taskDisplay shows the status of the tasks of a run.
+type taskDisplay interface {
+	// addTask shows a task, with a progress bar rather than a spinner if the task reports its progress.
+	addTask(name string, showsProgress bool) taskStatus
+	wait()
+}
+
+// newTaskDisplay returns the display for a run, which writes events if an event writer is set, and shows spinners
+// otherwise. The display records the durations of the tasks.
+func (o *runnerOptions) newTaskDisplay() taskDisplay {
+	var d taskDisplay = &spinnerDisplay{components.NewSpinnerTable()}
+	if o.handler != nil {
+		d = &eventDisplay{handle: o.handler}
+	} else if w := o.eventWriter(); w != nil {
+		enc := json.NewEncoder(w)
+		d = &eventDisplay{handle: func(e *TaskEvent) { _ = enc.Encode(e) }}
+	}
+	return &durationDisplay{taskDisplay: d, durations: &o.durations}
+}
+
+// showsSpinners returns whether the runner shows spinners, rather than passing on task events.
+func (o *runnerOptions) showsSpinners() bool {
+	return o.handler == nil && o.eventWriter() == nil
+}
+
+// eventWriter returns the writer that the runner writes task events to, or nil if it shows spinners.
+func (o *runnerOptions) eventWriter() io.Writer {
+	if o.events != nil {
+		return o.events
+	}
+	return defaultTaskEventWriter()
+}
+
+type spinnerDisplay struct {
+	st *components.SpinnerTable
+}
+
+func (d *spinnerDisplay) addTask(name string, showsProgress bool) taskStatus {
+	if showsProgress {
+		return &spinnerStatus{d.st.AddProgressTask(name)}
+	}
+	return &spinnerStatus{d.st.AddTask(name)}
+}
+
+func (d *spinnerDisplay) wait() {
+	d.st.Wait()
+}
+
+type spinnerStatus struct {
+	*components.TaskInfo
+}
+
+func (s *spinnerStatus) addSubtask(name string) taskStatus {
+	return &spinnerStatus{s.AddSubtask(name)}
+}
+
+type eventDisplay struct {
+	mu     sync.Mutex
+	handle func(e *TaskEvent)
+}
+
+func (d *eventDisplay) emit(e *TaskEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e.Time = time.Now()
+	d.handle(e)
+}
+
+func (d *eventDisplay) addTask(name string, showsProgress bool) taskStatus {
+	return d.start(name, "")
+}
+
+func (d *eventDisplay) start(name, parent string) *eventStatus {
+	d.emit(&TaskEvent{Type: TaskStarted, Task: name, Parent: parent})
+	return &eventStatus{d: d, name: name, parent: parent, start: time.Now(), lastPercent: -1}
+}
+
+func (d *eventDisplay) wait() {}
+
+type eventStatus struct {
+	d      *eventDisplay
+	name   string
+	parent string
+	start  time.Time
+
+	mu sync.Mutex
+	// lastPercent is the whole percentage of the last progress event, which limits progress events to one per
+	// percent.
+	lastPercent int
+}
+
+func (s *eventStatus) SetDetail(detail string) {
+	if detail != "" {
+		s.d.emit(&TaskEvent{Type: TaskProgress, Task: s.name, Parent: s.parent, Detail: detail})
+	}
+}
+
+func (s *eventStatus) SetProgress(fraction float64) {
+	s.mu.Lock()
+	percent := int(fraction * 100)
+	changed := percent != s.lastPercent
+	s.lastPercent = percent
+	s.mu.Unlock()
+	if changed {
+		s.d.emit(&TaskEvent{Type: TaskProgress, Task: s.name, Parent: s.parent, Progress: fraction})
+	}
+}
+
+func (s *eventStatus) addSubtask(name string) taskStatus {
+	return s.d.start(name, s.name)
+}
+
+func (s *eventStatus) Complete(err error) {
+	e := &TaskEvent{Type: TaskSucceeded, Task: s.name, Parent: s.parent, DurationMs: time.Since(s.start).Milliseconds()}
+	if err != nil {
+		e.Type = TaskFailed
+		e.Error = err.Error()
+	}
+	s.d.emit(e)
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	showTaskLogsMu sync.Mutex
+	showTaskLogs   bool
+)
+
+// SetShowTaskLogs makes the task runners print the output captured from all tasks once a run completes, rather
+// than only the output of the tasks that failed.
+func SetShowTaskLogs(show bool) {
+	showTaskLogsMu.Lock()
+	defer showTaskLogsMu.Unlock()
+	showTaskLogs = show
+}
+
+func shouldShowTaskLogs() bool {
+	showTaskLogsMu.Lock()
+	defer showTaskLogsMu.Unlock()
+	return showTaskLogs
+}
+
+// taskLog is the output captured from a task.
+type taskLog struct {
+	name string
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	err  error
+}
+
+func (l *taskLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+type taskLogKey struct{}
+
+// TaskOutput returns the writer that a task should write its output to, instead of stdout or stderr. While a task
+// runner shows spinners, the output is captured, and printed below the name of the task once the run completes if
+// the task failed, or if SetShowTaskLogs is set. Otherwise, the output is written to stderr.
+func TaskOutput(ctx context.Context) io.Writer {
+	if l, ok := ctx.Value(taskLogKey{}).(*taskLog); ok {
+		return l
+	}
+	return os.Stderr
+}
+
+type logCaptureKey struct{}
+
+// logCapture captures the output of the tasks of a run, including the output of the standard logrus logger, so
+// that it doesn't garble the spinners. Log output is attributed to the task that is running when it is logged, or
+// to the run as a whole while several tasks are running.
+type logCapture struct {
+	mu      sync.Mutex
+	tasks   []*taskLog
+	running []*taskLog
+	run     *taskLog
+
+	prevOut  io.Writer
+	prevExit func(int)
+}
+
+// startLogCapture redirects the standard logrus logger to the capture until stop is called. If a task calls
+// log.Fatal, the captured output is printed before the process exits.
+func startLogCapture() *logCapture {
+	c := &logCapture{run: &taskLog{}}
+	logger := log.StandardLogger()
+	c.prevOut = logger.Out
+	c.prevExit = logger.ExitFunc
+	if c.prevExit == nil {
+		c.prevExit = os.Exit
+	}
+	logger.SetOutput(c)
+	logger.ExitFunc = func(code int) {
+		c.stop()
+		c.print(os.Stderr, true)
+		c.prevExit(code)
+	}
+	return c
+}
+
+func (c *logCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.running) == 1 {
+		return c.running[0].Write(p)
+	}
+	return c.run.Write(p)
+}
+
+func (c *logCapture) start(name string) *taskLog {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := &taskLog{name: name}
+	c.tasks = append(c.tasks, l)
+	c.running = append(c.running, l)
+	return l
+}
+
+func (c *logCapture) done(l *taskLog, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l.err = err
+	for i, r := range c.running {
+		if r == l {
+			c.running = append(c.running[:i], c.running[i+1:]...)
+			break
+		}
+	}
+}
+
+// stop restores the output of the standard logrus logger.
+func (c *logCapture) stop() {
+	logger := log.StandardLogger()
+	logger.SetOutput(c.prevOut)
+	logger.ExitFunc = c.prevExit
+}
+
+// print writes the output captured from each task below the name of the task, if the task failed or all is set.
+// Output that couldn't be attributed to a single task is printed last, if any task failed or all is set.
+func (c *logCapture) print(w io.Writer, all bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	failed := false
+	for _, l := range c.tasks {
+		if l.err != nil {
+			failed = true
+		}
+		if all || l.err != nil {
+			printTaskLog(w, fmt.Sprintf("Output of %q:", l.name), l)
+		}
+	}
+	if all || failed {
+		printTaskLog(w, "Output of the run:", c.run)
+	}
+}
+
+func printTaskLog(w io.Writer, heading string, l *taskLog) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	output := strings.TrimRight(l.buf.String(), "\n")
+	if output == "" {
+		return
+	}
+	fmt.Fprintln(w, color.New(color.Bold).Sprint(heading))
+	for _, line := range strings.Split(output, "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
+
+// withTaskLog starts capturing the output of the task, if the run captures output. The returned function records
+// the result of the task.
+func withTaskLog(ctx context.Context, t Task) (context.Context, func(err error)) {
+	c, ok := ctx.Value(logCaptureKey{}).(*logCapture)
+	if !ok {
+		return ctx, func(error) {}
+	}
+	l := c.start(t.Name())
+	return context.WithValue(ctx, taskLogKey{}, l), func(err error) {
+		c.done(l, err)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned by a task or a task runner that didn't complete in time.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
+}
+
+// Is makes timeout errors match context.DeadlineExceeded.
+func (e *TimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// TimeoutTask is a task that fails if it doesn't complete within its timeout.
+type TimeoutTask interface {
+	Task
+	Timeout() time.Duration
+}
+
+// WithTimeout returns a task that runs the given task, and fails if it doesn't complete within the timeout. The
+// timeout includes the time spent on retries.
+func WithTimeout(t Task, timeout time.Duration) Task {
+	o := withOptions(t)
+	o.timeout = timeout
+	return o
+}
+
+type runTimeoutKey struct{}
+
+// withRunTimeout returns a context that expires after the timeout of the whole run.
+func withRunTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return context.WithValue(ctx, runTimeoutKey{}, timeout), cancel
+}
+
+// runWithTimeout runs the task with its timeout, if it has one. Tasks that don't stop once their context expires
+// are abandoned, so that a stuck task can't block the runner forever.
+func runWithTimeout(ctx context.Context, t Task, run func(ctx context.Context) error) error {
+	tt, ok := t.(TimeoutTask)
+	if !ok || tt.Timeout() <= 0 {
+		runTimeout, ok := ctx.Value(runTimeoutKey{}).(time.Duration)
+		if !ok {
+			return run(ctx)
+		}
+		// The run has an overall timeout, which stuck tasks must not outlast either.
+		return abandonAfterDeadline(ctx, run, runTimeout)
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, tt.Timeout())
+	defer cancel()
+	err := abandonAfterDeadline(taskCtx, run, tt.Timeout())
+	if errors.Is(taskCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return &TimeoutError{Timeout: tt.Timeout()}
+	}
+	return err
+}
+
+func abandonAfterDeadline(ctx context.Context, run func(ctx context.Context) error, timeout time.Duration) error {
+	// A panic of the task is re-raised in the goroutine that runs it, unless the task was abandoned.
+	var panics panicCollector
+	done := make(chan error, 1)
+	go func() {
+		done <- panics.run(func() error { return run(ctx) })
+	}()
+	select {
+	case err := <-done:
+		panics.repanic()
+		return err
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// Interrupted tasks are waited on, so that their cleanup doesn't race with them.
+			err := <-done
+			panics.repanic()
+			return err
+		}
+		return &TimeoutError{Timeout: timeout}
+	}
+}
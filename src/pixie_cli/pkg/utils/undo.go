@@ -0,0 +1,40 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+)
+
+// UndoableTask is a task that can be undone. When a later task of the run fails, or the run is interrupted, the
+// task runners undo the tasks that completed, in the reverse order that they completed in.
+type UndoableTask interface {
+	Task
+	// Undo returns the function that undoes the task, or nil if the task can't be undone.
+	Undo() func(ctx context.Context) error
+}
+
+// WithUndo returns a task that runs the given task, and is undone by the undo function if a later task fails.
+// Tasks that only know what to undo while they run, such as whether they created a resource, should use
+// RegisterCleanup instead.
+func WithUndo(t Task, undo func(ctx context.Context) error) Task {
+	o := withOptions(t)
+	o.undo = undo
+	return o
+}
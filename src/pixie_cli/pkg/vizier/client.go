@@ -24,17 +24,13 @@ import (
 	"google.golang.org/grpc"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
-	"px.dev/pixie/src/shared/services"
+	cliUtils "px.dev/pixie/src/pixie_cli/pkg/utils"
 )
 
 func newVizierClusterInfoClient(cloudAddr string) (cloudpb.VizierClusterInfoClient, error) {
 	isInternal := strings.Contains(cloudAddr, "cluster.local")
 
-	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
-	if err != nil {
-		return nil, err
-	}
-
+	dialOpts := append(cliUtils.ServerSideTLSDialOptions(isInternal), cliUtils.GRPCDialOptions()...)
 	c, err := grpc.Dial(cloudAddr, dialOpts...)
 	if err != nil {
 		return nil, err
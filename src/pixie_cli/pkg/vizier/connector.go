@@ -40,7 +40,6 @@ import (
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
 	cliUtils "px.dev/pixie/src/pixie_cli/pkg/utils"
-	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/script"
 )
@@ -104,11 +103,7 @@ func (c *Connector) connect(addr string) error {
 	}()
 	isInternal := strings.Contains(addr, "cluster.local")
 
-	dialOpts, err := services.GetGRPCClientDialOptsServerSideTLS(isInternal)
-	if err != nil {
-		return err
-	}
-
+	dialOpts := append(cliUtils.ServerSideTLSDialOptions(isInternal), cliUtils.GRPCDialOptions()...)
 	dialOpts = append(dialOpts, grpc.WithBlock())
 	// Try to dial with a time out (ctrl-c can be used to cancel)
 	conn, err := grpc.DialContext(ctx, addr, dialOpts...)
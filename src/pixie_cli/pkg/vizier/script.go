@@ -26,18 +26,26 @@ import (
 	"strings"
 	"time"
 
-	"github.com/segmentio/analytics-go/v3"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 
 	apiutils "px.dev/pixie/src/api/go/pxapi/utils"
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
-	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/utils/script"
 )
 
+// scriptEventSampleRate is the fraction of script executions that are tracked after the first, since live views
+// re-run their scripts every few seconds.
+const scriptEventSampleRate = 0.1
+
+func init() {
+	pxanalytics.SampleEvent("Script Execution Started", scriptEventSampleRate)
+	pxanalytics.SampleEvent("Script Execution Failed", scriptEventSampleRate)
+	pxanalytics.SampleEvent("Script Execution Success", scriptEventSampleRate)
+}
+
 type taskWrapper struct {
 	name string
 	run  func() error
@@ -54,7 +62,7 @@ func (t *taskWrapper) Name() string {
 	return t.name
 }
 
-func (t *taskWrapper) Run() error {
+func (t *taskWrapper) Run(ctx context.Context) error {
 	return t.run()
 }
 
@@ -121,7 +129,7 @@ func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScri
 		return errors.New("Could not prepare schema")
 	}))
 
-	vzJr := utils.NewParallelTaskRunner(tasks)
+	vzJr := utils.NewParallelTaskRunner(tasks, 0)
 
 	// Run retries.
 	go func() {
@@ -198,13 +206,9 @@ func runScript(ctx context.Context, conns []*Connector, execScript *script.Execu
 // RunScript runs the script and return the data channel
 func RunScript(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, encOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions) (chan *ExecData, error) {
 	// TODO(zasgar): Refactor this when we change to the new API to make analytics cleaner.
-	_ = pxanalytics.Client().Enqueue(&analytics.Track{
-		UserId: pxconfig.Cfg().UniqueClientID,
-		Event:  "Script Execution Started",
-		Properties: analytics.NewProperties().
-			Set("scriptName", execScript.ScriptName).
-			Set("scriptString", execScript.ScriptString),
-	})
+	pxanalytics.Track("Script Execution Started", pxanalytics.NewProperties().
+		Set("scriptName", execScript.ScriptName).
+		Set("scriptString", execScript.ScriptString))
 
 	mergedResponses := make(chan *ExecData)
 	var eg errgroup.Group
@@ -239,19 +243,11 @@ func RunScript(ctx context.Context, conns []*Connector, execScript *script.Execu
 		close(mergedResponses)
 
 		if err != nil {
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Script Execution Failed",
-				Properties: analytics.NewProperties().
-					Set("scriptString", execScript.ScriptString),
-			})
+			pxanalytics.Track("Script Execution Failed", pxanalytics.NewProperties().
+				Set("scriptString", execScript.ScriptString))
 		} else {
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Script Execution Success",
-				Properties: analytics.NewProperties().
-					Set("scriptString", execScript.ScriptString),
-			})
+			pxanalytics.Track("Script Execution Success", pxanalytics.NewProperties().
+				Set("scriptString", execScript.ScriptString))
 		}
 	}()
 	return mergedResponses, nil
@@ -23,63 +23,49 @@ package main
 
 import (
 	"os"
-	"runtime"
 	"strings"
-	"time"
 
-	"github.com/getsentry/sentry-go"
-	"github.com/segmentio/analytics-go/v3"
 	log "github.com/sirupsen/logrus"
 
 	"px.dev/pixie/src/pixie_cli/pkg/cmd"
+	"px.dev/pixie/src/pixie_cli/pkg/exitcodes"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
-	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
-	"px.dev/pixie/src/pixie_cli/pkg/sentryhook"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
-	version "px.dev/pixie/src/shared/goversion"
 )
 
-const sentryDSN = "https://ef3a781b5e7b42e282706fc541077f3a@sentry.io/4090453"
-
 func main() {
-	// Disable Sentry in dev mode.
-	selectedDSN := sentryDSN
-	if version.GetVersion().IsDev() {
-		selectedDSN = ""
-	}
+	// Panics of the command are handled by Execute, and those of the code that runs before it here.
+	defer cmd.RecoverCrash()
+	cmd.ExpandAliases()
 
-	err := sentry.Init(sentry.ClientOptions{
-		Dsn:              selectedDSN,
-		AttachStacktrace: true,
-		Release:          version.GetVersion().ToString(),
-		Environment:      runtime.GOOS,
-		MaxBreadcrumbs:   10,
-	})
-	if err != nil {
-		log.WithError(err).Trace("Cannot initialize sentry")
-	} else {
-		tags := map[string]string{
-			"version":  version.GetVersion().ToString(),
-			"clientID": pxconfig.Cfg().UniqueClientID,
-		}
-		hook := sentryhook.New([]log.Level{
-			log.ErrorLevel, log.PanicLevel, log.FatalLevel,
-		}, sentryhook.WithTags(tags))
-		log.AddHook(hook)
+	// Shell completions must not print anything but the completions, or prompt the user.
+	if cmd.IsCompletion(os.Args) {
+		cmd.Execute()
+		return
 	}
-	defer sentry.Flush(2 * time.Second)
-	defer pxanalytics.Client().Close()
 
-	pxanalytics.Client().Enqueue(&analytics.Track{
-		UserId: pxconfig.Cfg().UniqueClientID,
-		Event:  "Exec Started",
-		Properties: analytics.NewProperties().
-			Set("cmd", strings.Join(os.Args, ",")),
-	})
+	// log.Fatal exits with the exit code for the error that it logs, like the CLI's own errors.
+	exitcodes.HandleLogFatal(log.StandardLogger())
 
-	defer pxanalytics.Client().Enqueue(&analytics.Track{
-		UserId: pxconfig.Cfg().UniqueClientID,
-		Event:  "Exec Complete",
+	defer initSentry()()
+
+	// The events are batched until the client is closed, including those tracked by the exit hooks of commands that
+	// exit rather than return.
+	exitcodes.OnExitLast(func(int) { _ = pxanalytics.Close() })
+
+	scrubbedArgs := cmd.ScrubbedArgs(os.Args)
+	pxanalytics.Track("Exec Started", pxanalytics.NewProperties().
+		Set("cmd", strings.Join(scrubbedArgs, ",")))
+	// The history is attached to support bundles, so it only records the scrubbed command line.
+	_ = utils.RecordHistory(scrubbedArgs)
+	if err := utils.StartLogFile(scrubbedArgs); err != nil {
+		log.WithError(err).Debug("Cannot write the log file")
+	}
+
+	exitcodes.OnExit(func(code int) {
+		if code == exitcodes.Success {
+			pxanalytics.Track("Exec Complete", nil)
+		}
 	})
 
 	log.SetOutput(os.Stderr)
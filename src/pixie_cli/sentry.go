@@ -0,0 +1,101 @@
+//go:build !notel
+
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package main
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/pixie_cli/pkg/cmd"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/sentryhook"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	version "px.dev/pixie/src/shared/goversion"
+)
+
+const sentryDSN = "https://ef3a781b5e7b42e282706fc541077f3a@sentry.io/4090453"
+
+// crashReportTimeout is how long to wait for Sentry to receive a crash report.
+const crashReportTimeout = 10 * time.Second
+
+// initSentry reports errors that are logged with logrus to Sentry, and returns a function that flushes the reports
+// before the CLI exits. Crash reports are submitted to Sentry too, if the user agrees to.
+func initSentry() func() {
+	// Disable Sentry in dev mode.
+	selectedDSN := sentryDSN
+	if version.GetVersion().IsDev() {
+		selectedDSN = ""
+	}
+
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              selectedDSN,
+		AttachStacktrace: true,
+		Release:          version.GetVersion().ToString(),
+		Environment:      runtime.GOOS,
+		MaxBreadcrumbs:   10,
+	})
+	if err != nil {
+		log.WithError(err).Trace("Cannot initialize sentry")
+	} else {
+		tags := map[string]string{
+			"version":  version.GetVersion().ToString(),
+			"clientID": pxconfig.Cfg().UniqueClientID,
+		}
+		hook := sentryhook.New([]log.Level{
+			log.ErrorLevel, log.PanicLevel, log.FatalLevel,
+		}, sentryhook.WithTags(tags))
+		log.AddHook(hook)
+		if selectedDSN != "" {
+			cmd.SetCrashReportSubmitter(submitCrashReport)
+		}
+	}
+	return func() {
+		sentry.Flush(2 * time.Second)
+	}
+}
+
+// submitCrashReport sends the crash report to Sentry.
+func submitCrashReport(r *utils.CrashReport) error {
+	event := sentry.NewEvent()
+	event.Level = sentry.LevelFatal
+	event.Message = r.Panic
+	event.Tags = map[string]string{
+		"version": r.Version,
+		"type":    "crash",
+	}
+	event.Extra = map[string]interface{}{
+		"args":      strings.Join(r.Args, " "),
+		"arch":      r.Arch,
+		"goVersion": r.GoVersion,
+		"stack":     r.Stack,
+	}
+	if sentry.CaptureEvent(event) == nil {
+		return errors.New("the crash report was dropped")
+	}
+	if !sentry.Flush(crashReportTimeout) {
+		return errors.New("timed out submitting the crash report")
+	}
+	return nil
+}
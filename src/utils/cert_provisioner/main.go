@@ -112,7 +112,7 @@ func main() {
 		log.WithError(err).Fatal("Failed to fill in templated deployment YAMLs")
 	}
 
-	err = k8s.ApplyYAML(clientset, kubeConfig, ns, strings.NewReader(yamls[0].YAML), false)
+	_, err = k8s.ApplyYAML(clientset, kubeConfig, ns, strings.NewReader(yamls[0].YAML), false)
 	if err != nil {
 		log.WithError(err).Fatalf("Failed deploy cert YAMLs")
 	}
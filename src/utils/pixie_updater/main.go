@@ -221,7 +221,7 @@ func main() {
 		}
 	}
 
-	err = k8s.ApplyYAML(clientset, kubeConfig, ns, strings.NewReader(yamlMap[vzYaml]), true)
+	_, err = k8s.ApplyYAML(clientset, kubeConfig, ns, strings.NewReader(yamlMap[vzYaml]), true)
 	if err != nil {
 		log.WithError(err).Fatalf("Failed to install vizier")
 	}
@@ -241,7 +241,7 @@ func retryDeploy(clientset *kubernetes.Clientset, config *rest.Config, namespace
 	tries := 12
 	var err error
 	for tries > 0 {
-		err = k8s.ApplyYAML(clientset, config, namespace, strings.NewReader(yamlContents), false)
+		_, err = k8s.ApplyYAML(clientset, config, namespace, strings.NewReader(yamlContents), false)
 		if err == nil {
 			return nil
 		}
@@ -31,6 +31,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -75,10 +76,34 @@ func ConvertResourceToYAML(obj runtime.Object) (string, error) {
 	return buf.String(), nil
 }
 
-// ApplyYAML does the equivalent of a kubectl apply for the given yaml. If allowUpdate is true, then we update the resource
-// if it already exists.
-func ApplyYAML(clientset kubernetes.Interface, config *rest.Config, namespace string, yamlFile io.Reader, allowUpdate bool) error {
-	return ApplyYAMLForResourceTypes(clientset, config, namespace, yamlFile, []string{}, allowUpdate)
+// ApplyYAML does the equivalent of a kubectl apply for the given yaml, and returns the outcome for each resource.
+// If allowUpdate is true, then we update the resource if it already exists.
+func ApplyYAML(clientset kubernetes.Interface, config *rest.Config, namespace string, yamlFile io.Reader, allowUpdate bool) ([]*AppliedResource, error) {
+	return ApplyYAMLWithOptions(clientset, config, namespace, yamlFile, &ApplyOptions{AllowUpdate: allowUpdate})
+}
+
+// ApplyOptions configures how ApplyYAMLWithOptions applies resources.
+type ApplyOptions struct {
+	// AllowedResources, if set, restricts the applied resources to the given types, such as "deployments".
+	AllowedResources []string
+	// AllowUpdate updates resources that already exist.
+	AllowUpdate bool
+	// RespectObjectNamespaces applies objects to the namespace in their own metadata, using the namespace
+	// parameter only for objects that don't declare one. Namespaces that don't exist yet are created, and
+	// Namespace objects in the YAML are applied before anything else.
+	RespectObjectNamespaces bool
+	// NamespaceLabels are set on the namespaces that are created for the objects.
+	NamespaceLabels map[string]string
+}
+
+// ApplyYAMLWithOptions does the equivalent of a kubectl apply for the given yaml, and returns the outcome
+// for each applied resource.
+func ApplyYAMLWithOptions(clientset kubernetes.Interface, config *rest.Config, namespace string, yamlFile io.Reader, opts *ApplyOptions) ([]*AppliedResource, error) {
+	resources, err := GetResourcesFromYAML(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyResourcesWithOptions(clientset, config, resources, namespace, opts)
 }
 
 // KeyValueStringToMap converts a user-inputted label string (label1=value,label2=value2) into a string map.
@@ -142,65 +167,154 @@ type Resource struct {
 	GVK    *schema.GroupVersionKind
 }
 
-// GetResourcesFromYAML parses the YAMLs into K8s resource objects that can be passed to the API.
+// GetResourcesFromYAML parses the YAMLs into K8s resource objects that can be passed to the API. If the
+// reader is a file, parse errors refer to its name.
 func GetResourcesFromYAML(yamlFile io.Reader) ([]*Resource, error) {
+	source := ""
+	if f, ok := yamlFile.(interface{ Name() string }); ok {
+		source = f.Name()
+	}
+	return GetResourcesFromNamedYAML(source, yamlFile)
+}
+
+// GetResourcesFromNamedYAML parses the resources in the given yaml. Parse errors are returned as a
+// YAMLParseError that refers to the source name.
+func GetResourcesFromNamedYAML(source string, yamlFile io.Reader) ([]*Resource, error) {
+	data, err := io.ReadAll(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+
 	resources := make([]*Resource, 0)
+	for i, doc := range splitYAMLDocuments(data) {
+		parseErr := func(err error) error {
+			return &YAMLParseError{Source: source, Document: i + 1, Line: yamlErrorLine(err, doc.line), Err: err}
+		}
 
-	decodedYAML := yaml.NewYAMLOrJSONDecoder(yamlFile, 4096)
+		decodedYAML := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(doc.data), 4096)
+		for {
+			ext := runtime.RawExtension{}
+			err := decodedYAML.Decode(&ext)
 
-	for {
-		ext := runtime.RawExtension{}
-		err := decodedYAML.Decode(&ext)
+			if err != nil && err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, parseErr(err)
+			}
+			if ext.Raw == nil {
+				continue
+			}
 
-		if err != nil && err == io.EOF {
-			break
-		} else if err != nil {
-			return nil, err
-		}
-		if ext.Raw == nil {
-			continue
-		}
+			_, _, err = unstructured.UnstructuredJSONScheme.Decode(ext.Raw, nil, nil)
+			if err != nil {
+				return nil, parseErr(err)
+			}
 
-		_, gvk, err := unstructured.UnstructuredJSONScheme.Decode(ext.Raw, nil, nil)
-		if err != nil {
-			log.WithError(err).Fatalf(err.Error())
-			return nil, err
+			var unstructBlob interface{}
+			err = json.Unmarshal(ext.Raw, &unstructBlob)
+			if err != nil {
+				return nil, parseErr(err)
+			}
+
+			items, err := expandListResources(&unstructured.Unstructured{Object: unstructBlob.(map[string]interface{})})
+			if err != nil {
+				return nil, parseErr(err)
+			}
+			resources = append(resources, items...)
 		}
+	}
 
-		var unstructRes unstructured.Unstructured
-		unstructRes.Object = make(map[string]interface{})
-		var unstructBlob interface{}
+	return resources, nil
+}
 
-		err = json.Unmarshal(ext.Raw, &unstructBlob)
-		if err != nil {
-			return nil, err
-		}
+// Outcomes of applying a resource, reported in AppliedResource.Status.
+const (
+	StatusCreated    = "created"
+	StatusConfigured = "configured"
+	StatusUnchanged  = "unchanged"
+	StatusPruned     = "pruned"
+	StatusFailed     = "failed"
+)
 
-		unstructRes.Object = unstructBlob.(map[string]interface{})
+// appliedStatuses is the order in which statuses are summarized.
+var appliedStatuses = []string{StatusCreated, StatusConfigured, StatusUnchanged, StatusPruned, StatusFailed}
+
+// AppliedResource describes the outcome of applying a single resource.
+type AppliedResource struct {
+	Kind      string
+	Name      string
+	Namespace string
+	// Status is one of StatusCreated, StatusConfigured, StatusUnchanged, StatusPruned or StatusFailed.
+	Status string
+	// Err is the reason the resource failed to apply, if the status is StatusFailed.
+	Err error
+	// Warnings are the warnings returned by the API server while applying the resource, such as deprecation
+	// notices for its API version.
+	Warnings []string
+}
 
-		resources = append(resources, &Resource{
-			Object: &unstructRes,
-			GVK:    gvk,
-		})
+// CountAppliedResources returns the number of resources with each status.
+func CountAppliedResources(applied []*AppliedResource) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range applied {
+		counts[r.Status]++
 	}
+	return counts
+}
 
-	return resources, nil
+// SummarizeAppliedResources returns a summary of the number of resources with each status, such as
+// "3 created, 1 unchanged".
+func SummarizeAppliedResources(applied []*AppliedResource) string {
+	counts := CountAppliedResources(applied)
+	var parts []string
+	for _, status := range appliedStatuses {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	if len(parts) == 0 {
+		return "no resources applied"
+	}
+	return strings.Join(parts, ", ")
 }
 
 // ApplyResources applies the following resources to the give namespace/cluster.
 func ApplyResources(clientset kubernetes.Interface, config *rest.Config, resources []*Resource, namespace string, allowedResources []string, allowUpdate bool) error {
-	discoveryClient := clientset.Discovery()
+	_, err := ApplyResourcesWithResults(clientset, config, resources, namespace, allowedResources, allowUpdate)
+	return err
+}
+
+// ApplyResourcesWithResults applies the following resources to the given namespace/cluster, and returns
+// the outcome for each resource that was applied. Resources of any kind served by the cluster, including
+// custom resources whose CRDs are applied earlier in the same call, are supported.
+func ApplyResourcesWithResults(clientset kubernetes.Interface, config *rest.Config, resources []*Resource, namespace string, allowedResources []string, allowUpdate bool) ([]*AppliedResource, error) {
+	return ApplyResourcesWithOptions(clientset, config, resources, namespace, &ApplyOptions{
+		AllowedResources: allowedResources,
+		AllowUpdate:      allowUpdate,
+	})
+}
 
-	apiGroupResources, err := restmapper.GetAPIGroupResources(discoveryClient)
+// ApplyResourcesWithOptions applies the resources like ApplyResourcesWithResults, configured by the given options.
+func ApplyResourcesWithOptions(clientset kubernetes.Interface, config *rest.Config, resources []*Resource, namespace string, opts *ApplyOptions) ([]*AppliedResource, error) {
+	var applied []*AppliedResource
+	rm := newResourceMapper(clientset)
+	namespaces := newNamespaceEnsurer(clientset, opts.NamespaceLabels)
+	allowedResources := opts.AllowedResources
+	allowUpdate := opts.AllowUpdate
+	if opts.RespectObjectNamespaces {
+		resources = NamespacesFirst(resources)
+	}
+
+	warnings := &WarningCollector{}
+	dynamicClient, err := dynamic.NewForConfig(withWarningCollector(config, warnings))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	rm := restmapper.NewDiscoveryRESTMapper(apiGroupResources)
 
 	for _, resource := range resources {
-		mapping, err := rm.RESTMapping(resource.GVK.GroupKind(), resource.GVK.Version)
+		mapping, err := rm.RESTMapping(resource.GVK)
 		if err != nil {
-			return err
+			return applied, err
 		}
 
 		k8sRes := mapping.Resource.Resource
@@ -216,45 +330,94 @@ func ApplyResources(clientset kubernetes.Interface, config *rest.Config, resourc
 			}
 		}
 
-		restconfig := config
-		restconfig.GroupVersion = &schema.GroupVersion{
-			Group:   mapping.GroupVersionKind.Group,
-			Version: mapping.GroupVersionKind.Version,
-		}
-		dynamicClient, err := dynamic.NewForConfig(restconfig)
-		if err != nil {
-			return err
-		}
-
-		res := dynamicClient.Resource(mapping.Resource)
-		objNS := namespace
-		if objNS == "" { // If no namespace specified, use the namespace from the resource.
-			if nestedNS, ok, _ := unstructured.NestedString(resource.Object.Object, "metadata", "namespace"); ok {
-				objNS = nestedNS
+		createRes, objNS := resourceInterface(dynamicClient, mapping, namespace, resource, opts.RespectObjectNamespaces)
+		if opts.RespectObjectNamespaces {
+			created, err := namespaces.ensure(objNS)
+			if err != nil {
+				return applied, err
+			}
+			if created != nil {
+				applied = append(applied, created)
 			}
 		}
-		nsRes := res.Namespace(objNS)
-
-		createRes := nsRes
-		if k8sRes == "validatingwebhookconfigurations" || k8sRes == "mutatingwebhookconfigurations" || k8sRes == "namespaces" || k8sRes == "configmap" || k8sRes == "clusterrolebindings" || k8sRes == "clusterroles" || k8sRes == "customresourcedefinitions" {
-			createRes = res
+		result := &AppliedResource{
+			Kind:      resource.GVK.Kind,
+			Name:      resource.Object.GetName(),
+			Namespace: objNS,
+			Status:    StatusCreated,
 		}
 
 		_, err = createRes.Create(context.Background(), resource.Object, metav1.CreateOptions{})
+		result.Warnings = warnings.Drain()
 		if err != nil {
 			if !k8serrors.IsAlreadyExists(err) {
-				return err
-			} else if (k8sRes == "clusterroles" || k8sRes == "cronjobs") || allowUpdate {
+				result.Status = StatusFailed
+				result.Err = wrapAPIError(err, "create", k8sRes, objNS, result.Name)
+				return append(applied, result), result.Err
+			}
+			result.Status = StatusUnchanged
+			if (k8sRes == "clusterroles" || k8sRes == "cronjobs") || allowUpdate {
 				// TODO(michelle,vihang,philkuz) Update() fails on services and PVCs that are already running on the
 				// cluster. We will need to fix this before we can successfully update those resources. K8s is unhappy
 				// that we don't specify resourceVersion and clusterIP for services.
-				_, err = createRes.Update(context.Background(), resource.Object, metav1.UpdateOptions{})
+				desc := fmt.Sprintf("updating %s %s", resource.GVK.Kind, resource.Object.GetName())
+				err = RetryOnConflict(DefaultConflictRetries, desc, func() error {
+					existing, err := createRes.Get(context.Background(), resource.Object.GetName(), metav1.GetOptions{})
+					if err != nil {
+						return err
+					}
+					resource.Object.SetResourceVersion(existing.GetResourceVersion())
+					_, err = createRes.Update(context.Background(), resource.Object, metav1.UpdateOptions{})
+					return err
+				})
+				result.Warnings = append(result.Warnings, warnings.Drain()...)
 				if err != nil {
 					log.WithError(err).Info("Could not update K8s resource")
+					result.Status = StatusFailed
+					result.Err = wrapAPIError(err, "update", k8sRes, objNS, result.Name)
+				} else {
+					result.Status = StatusConfigured
 				}
 			}
 		}
+		applied = append(applied, result)
 	}
 
-	return nil
+	return applied, nil
+}
+
+// resourceMapper maps resource kinds to their REST resources using discovery. Discovery is refreshed
+// when a kind is not found, so that custom resources can be mapped once their CRD has been created.
+type resourceMapper struct {
+	rm *restmapper.DeferredDiscoveryRESTMapper
+}
+
+func newResourceMapper(clientset kubernetes.Interface) *resourceMapper {
+	return &resourceMapper{
+		rm: restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscoveryFor(clientset)),
+	}
+}
+
+// RESTMapping returns the REST mapping for the given kind.
+func (m *resourceMapper) RESTMapping(gvk *schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := m.rm.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		m.rm.Reset()
+		mapping, err = m.rm.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	return mapping, err
+}
+
+// resourceInterface returns the dynamic client for the given resource, and the namespace of the resource
+// (empty for cluster-scoped resources). If namespace is empty, the namespace from the resource is used. If
+// respectObjectNamespace is set, the namespace from the resource wins, and namespace is only the fallback.
+func resourceInterface(dynamicClient dynamic.Interface, mapping *meta.RESTMapping, namespace string, resource *Resource, respectObjectNamespace bool) (dynamic.ResourceInterface, string) {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return dynamicClient.Resource(mapping.Resource), ""
+	}
+	objNS := namespace
+	if objNS == "" || (respectObjectNamespace && resource.Object.GetNamespace() != "") {
+		objNS = resource.Object.GetNamespace()
+	}
+	return dynamicClient.Resource(mapping.Resource).Namespace(objNS), objNS
 }
@@ -86,3 +86,31 @@ func TestKeyValueStringToMap(t *testing.T) {
 		})
 	}
 }
+
+func TestSummarizeAppliedResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		expected string
+	}{
+		{
+			name:     "empty",
+			expected: "no resources applied",
+		},
+		{
+			name:     "mixed",
+			statuses: []string{k8s.StatusUnchanged, k8s.StatusCreated, k8s.StatusFailed, k8s.StatusCreated},
+			expected: "2 created, 1 unchanged, 1 failed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var applied []*k8s.AppliedResource
+			for _, s := range test.statuses {
+				applied = append(applied, &k8s.AppliedResource{Kind: "Deployment", Name: "frontend", Status: s})
+			}
+			assert.Equal(t, test.expected, k8s.SummarizeAppliedResources(applied))
+		})
+	}
+}
@@ -21,7 +21,9 @@ package k8s
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/pflag"
@@ -40,6 +42,12 @@ import (
 
 var kubeconfig *string
 
+// kubeContext is the kubeconfig context to use instead of the current context, if set.
+var kubeContext string
+
+// inClusterContext is the name of the context reported when running inside a pod without a kubeconfig.
+const inClusterContext = "in-cluster"
+
 // fileExists checks if a file exists and is not a directory before we
 // try using it to prevent further errors.
 func fileExists(filename string) bool {
@@ -54,7 +62,7 @@ func init() {
 	defaultKubeConfig := ""
 	optionalStr := "(optional) "
 	if k := os.Getenv("KUBECONFIG"); k != "" {
-		for _, config := range strings.Split(k, ":") {
+		for _, config := range filepath.SplitList(k) {
 			if fileExists(config) {
 				defaultKubeConfig = config
 				break
@@ -71,7 +79,101 @@ func init() {
 		optionalStr = ""
 	}
 
-	kubeconfig = pflag.String("kubeconfig", defaultKubeConfig, fmt.Sprintf("%sabsolute path to the kubeconfig file", optionalStr))
+	kubeconfig = pflag.String("kubeconfig", defaultKubeConfig, fmt.Sprintf("%sabsolute path to the kubeconfig file. Defaults to the files of the KUBECONFIG env var, which are merged like kubectl does", optionalStr))
+}
+
+// kubeconfigLoadingRules returns the rules that the kubeconfig is loaded with: the --kubeconfig file if it was given,
+// or else the files of the KUBECONFIG env var, which are merged like kubectl does, or else ~/.kube/config.
+func kubeconfigLoadingRules() *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if f := pflag.CommandLine.Lookup("kubeconfig"); f == nil || f.Changed || len(rules.Precedence) <= 1 {
+		rules.ExplicitPath = *kubeconfig
+	}
+	return rules
+}
+
+// hasKubeconfigFile returns whether any of the kubeconfig files of the rules exists.
+func hasKubeconfigFile(rules *clientcmd.ClientConfigLoadingRules) bool {
+	if rules.ExplicitPath != "" {
+		return fileExists(rules.ExplicitPath)
+	}
+	for _, file := range rules.Precedence {
+		if fileExists(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextCandidate is a context that a kubeconfig file of the KUBECONFIG env var sets as its current context.
+type ContextCandidate struct {
+	Name string
+	File string
+}
+
+// ContextResolver picks the context to use when the kubeconfig files of the KUBECONFIG env var set different current
+// contexts, which would otherwise silently be the one of the first file. The candidates are in the order of the files.
+type ContextResolver func(candidates []ContextCandidate) (string, error)
+
+var (
+	contextResolver    ContextResolver
+	contextResolved    bool
+	contextResolverErr error
+)
+
+// SetContextResolver sets the resolver that picks the context when the kubeconfig files set different current
+// contexts, unless a context was selected with SetContext.
+func SetContextResolver(r ContextResolver) {
+	contextResolver = r
+	contextResolved = false
+	contextResolverErr = nil
+}
+
+// resolveContext picks the context with the resolver, once, if no context was selected and the current contexts of
+// the kubeconfig files are ambiguous.
+func resolveContext() error {
+	if kubeContext != "" || contextResolver == nil || contextResolved {
+		return contextResolverErr
+	}
+	contextResolved = true
+	candidates := ambiguousCurrentContexts()
+	if len(candidates) < 2 {
+		return nil
+	}
+	name, err := contextResolver(candidates)
+	if err != nil {
+		contextResolverErr = err
+		return err
+	}
+	kubeContext = name
+	resetClientCache()
+	return nil
+}
+
+// ambiguousCurrentContexts returns the distinct current contexts of the kubeconfig files of the KUBECONFIG env var,
+// if there are several of them.
+func ambiguousCurrentContexts() []ContextCandidate {
+	rules := kubeconfigLoadingRules()
+	if rules.ExplicitPath != "" {
+		return nil
+	}
+	var candidates []ContextCandidate
+	seen := make(map[string]bool)
+	for _, file := range rules.Precedence {
+		if !fileExists(file) {
+			continue
+		}
+		config, err := clientcmd.LoadFromFile(file)
+		if err != nil || config.CurrentContext == "" || seen[config.CurrentContext] {
+			continue
+		}
+		seen[config.CurrentContext] = true
+		candidates = append(candidates, ContextCandidate{Name: config.CurrentContext, File: file})
+	}
+	if len(candidates) < 2 {
+		return nil
+	}
+	return candidates
 }
 
 // GetClientset gets the clientset for the current kubernetes cluster.
@@ -97,10 +199,16 @@ func GetDiscoveryClient(config *rest.Config) *discovery.DiscoveryClient {
 	return discoveryClient
 }
 
+// SetContext selects the kubeconfig context that GetConfig and GetClientAPIConfig use instead of the
+// current context. An empty name selects the current context.
+func SetContext(name string) {
+	kubeContext = name
+	resetClientCache()
+}
+
 // GetConfig gets the kubernetes rest config.
 func GetConfig() *rest.Config {
-	// use the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	config, err := LoadConfig()
 	if err != nil {
 		// Don't use log.Fatal, because it will send an error to Sentry when invoked from the CLI.
 		fmt.Printf("Could not build kubeconfig: %s\n", err.Error())
@@ -110,9 +218,155 @@ func GetConfig() *rest.Config {
 	return config
 }
 
-// GetClientAPIConfig gets the config used for reading the current kube contexts.
+// LoadConfig gets the kubernetes rest config like GetConfig, but returns an error instead of exiting if
+// there is no usable config. The config is rate limited and timed out according to the client options.
+func LoadConfig() (*rest.Config, error) {
+	if err := resolveContext(); err != nil {
+		return nil, err
+	}
+	config, err := configForContext(kubeconfigLoadingRules(), kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	if err := clientOptions.validate(); err != nil {
+		return nil, err
+	}
+	clientOptions.apply(config)
+	return config, nil
+}
+
+// GetConfigForContext gets the kubernetes rest config for a context of the given kubeconfig file. The current
+// context is used if contextName is empty. If the kubeconfig file doesn't exist and we are running inside a pod,
+// the pod's service account is used instead.
+func GetConfigForContext(kubeconfigPath, contextName string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfigPath
+	return configForContext(rules, contextName)
+}
+
+func configForContext(rules *clientcmd.ClientConfigLoadingRules, contextName string) (*rest.Config, error) {
+	if contextName == "" && !hasKubeconfigFile(rules) {
+		if config, err := rest.InClusterConfig(); err == nil {
+			return config, nil
+		}
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	// Credentials from exec plugins are refreshed by client-go when they expire, so only check that the
+	// plugin is installed, rather than failing on the first request that needs new credentials.
+	if err := checkExecPlugin(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// execPluginHints are install instructions for common exec credential plugins that don't declare an install hint.
+var execPluginHints = map[string]string{
+	"aws":                    "install the AWS CLI, see https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html",
+	"aws-iam-authenticator":  "see https://github.com/kubernetes-sigs/aws-iam-authenticator#4-set-up-kubectl-to-use-authentication-tokens-provided-by-aws-iam-authenticator-for-kubernetes",
+	"gke-gcloud-auth-plugin": "run `gcloud components install gke-gcloud-auth-plugin`",
+	"kubelogin":              "see https://github.com/Azure/kubelogin",
+}
+
+// checkExecPlugin returns a descriptive error if the config authenticates with an exec credential plugin
+// that is not installed.
+func checkExecPlugin(config *rest.Config) error {
+	if config.ExecProvider == nil {
+		return nil
+	}
+	command := config.ExecProvider.Command
+	if _, err := exec.LookPath(command); err == nil {
+		return nil
+	}
+	hint := strings.TrimSpace(config.ExecProvider.InstallHint)
+	if hint == "" {
+		hint = execPluginHints[filepath.Base(command)]
+	}
+	if hint == "" {
+		return fmt.Errorf("the kubeconfig requires the credential plugin %q, which was not found in PATH", command)
+	}
+	return fmt.Errorf("the kubeconfig requires the credential plugin %q, which was not found in PATH: %s", command, hint)
+}
+
+// GetClientAPIConfig gets the config used for reading the current kube contexts. If a context was selected
+// with SetContext, it is reported as the current context.
 func GetClientAPIConfig() *clientcmdapi.Config {
-	return clientcmd.GetConfigFromFileOrDie(*kubeconfig)
+	err := resolveContext()
+	var config *clientcmdapi.Config
+	if err == nil {
+		config, err = loadClientAPIConfig()
+	}
+	if err != nil {
+		// Don't use log.Fatal, because it will send an error to Sentry when invoked from the CLI.
+		fmt.Printf("Could not load kubeconfig: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if kubeContext != "" {
+		config.CurrentContext = kubeContext
+	}
+	return config
+}
+
+// CurrentContext returns the name of the context that GetConfig uses: the context selected with SetContext, or else
+// the current context of the kubeconfig.
+func CurrentContext() (string, error) {
+	if err := resolveContext(); err != nil {
+		return "", err
+	}
+	if kubeContext != "" {
+		return kubeContext, nil
+	}
+	config, err := loadClientAPIConfig()
+	if err != nil {
+		return "", err
+	}
+	return config.CurrentContext, nil
+}
+
+// ListContexts returns the names of all contexts in the kubeconfig, sorted by name.
+func ListContexts() ([]string, error) {
+	config, err := loadClientAPIConfig()
+	if err != nil {
+		return nil, err
+	}
+	contexts := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
+// loadClientAPIConfig loads the kubeconfig, merging the files of the KUBECONFIG env var. If it doesn't exist and we
+// are running inside a pod, it returns a config with a single context for the pod's cluster.
+func loadClientAPIConfig() (*clientcmdapi.Config, error) {
+	rules := kubeconfigLoadingRules()
+	if !hasKubeconfigFile(rules) {
+		if restConfig, err := rest.InClusterConfig(); err == nil {
+			return inClusterAPIConfig(restConfig), nil
+		}
+	}
+	return rules.Load()
+}
+
+func inClusterAPIConfig(restConfig *rest.Config) *clientcmdapi.Config {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[inClusterContext] = &clientcmdapi.Cluster{
+		Server:               restConfig.Host,
+		CertificateAuthority: restConfig.TLSClientConfig.CAFile,
+	}
+	config.AuthInfos[inClusterContext] = &clientcmdapi.AuthInfo{
+		TokenFile: restConfig.BearerTokenFile,
+	}
+	config.Contexts[inClusterContext] = &clientcmdapi.Context{
+		Cluster:  inClusterContext,
+		AuthInfo: inClusterContext,
+	}
+	config.CurrentContext = inClusterContext
+	return config
 }
 
 func GetKubeconfigPath() string {
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+- name: gke
+  context:
+    cluster: prod
+    user: gke
+current-context: dev
+users:
+- name: admin
+  user:
+    token: abc
+- name: gke
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: /nonexistent/gke-gcloud-auth-plugin
+      provideClusterInfo: true
+`
+
+func TestGetConfigForContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(testKubeconfig), 0o600))
+
+	tests := []struct {
+		name         string
+		context      string
+		expectedHost string
+		expectError  string
+	}{
+		{
+			name:         "current context",
+			expectedHost: "https://dev.example.com",
+		},
+		{
+			name:         "explicit context",
+			context:      "prod",
+			expectedHost: "https://prod.example.com",
+		},
+		{
+			name:        "missing context",
+			context:     "staging",
+			expectError: "staging",
+		},
+		{
+			name:        "missing exec plugin",
+			context:     "gke",
+			expectError: "gcloud components install gke-gcloud-auth-plugin",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := k8s.GetConfigForContext(path, test.context)
+			if test.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedHost, config.Host)
+		})
+	}
+}
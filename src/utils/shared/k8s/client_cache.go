@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"sync"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// clientCache holds the config, clientset and discovery client shared by all callers of GetSharedClientset, so
+// that commands that make many calls reuse connections and only run discovery once.
+var clientCache struct {
+	sync.Mutex
+	config    *rest.Config
+	clientset *kubernetes.Clientset
+	discovery discovery.CachedDiscoveryInterface
+}
+
+// GetSharedConfig returns the rest config of GetConfig, loading it only once. The returned config is shared,
+// so callers that need to modify it must copy it with rest.CopyConfig.
+func GetSharedConfig() *rest.Config {
+	clientCache.Lock()
+	defer clientCache.Unlock()
+	if clientCache.config == nil {
+		clientCache.config = GetConfig()
+	}
+	return clientCache.config
+}
+
+// GetSharedClientset returns a clientset for the shared config, creating it only once.
+func GetSharedClientset() *kubernetes.Clientset {
+	config := GetSharedConfig()
+
+	clientCache.Lock()
+	defer clientCache.Unlock()
+	if clientCache.clientset == nil {
+		clientCache.clientset = GetClientset(config)
+		clientCache.discovery = memory.NewMemCacheClient(clientCache.clientset.Discovery())
+	}
+	return clientCache.clientset
+}
+
+// GetSharedDiscoveryClient returns a discovery client for the shared config, which caches the server's
+// resources after they are first fetched. Call Invalidate on it to refetch them, for example after creating CRDs.
+func GetSharedDiscoveryClient() discovery.CachedDiscoveryInterface {
+	GetSharedClientset()
+
+	clientCache.Lock()
+	defer clientCache.Unlock()
+	return clientCache.discovery
+}
+
+// cachedDiscoveryFor returns a cached discovery client for the clientset. The shared clientset uses the shared
+// discovery cache, while other clientsets get a new cache.
+func cachedDiscoveryFor(clientset kubernetes.Interface) discovery.CachedDiscoveryInterface {
+	clientCache.Lock()
+	defer clientCache.Unlock()
+	if cs, ok := clientset.(*kubernetes.Clientset); ok && cs == clientCache.clientset {
+		return clientCache.discovery
+	}
+	return memory.NewMemCacheClient(clientset.Discovery())
+}
+
+// resetClientCache drops the shared clients, so that they are recreated with the current settings.
+func resetClientCache() {
+	clientCache.Lock()
+	defer clientCache.Unlock()
+	clientCache.config = nil
+	clientCache.clientset = nil
+	clientCache.discovery = nil
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+)
+
+// Client defaults used when no flags or config are set. The QPS and burst are higher than client-go's
+// defaults of 5 and 10, which throttle applying the dozens of objects in a typical manifest.
+const (
+	DefaultClientQPS            = 50
+	DefaultClientBurst          = 100
+	DefaultClientRequestTimeout = 30 * time.Second
+)
+
+// ClientOptions configures the rate limiting and timeouts of the clients built from GetConfig.
+type ClientOptions struct {
+	// QPS is the maximum sustained queries per second to the API server.
+	QPS float32
+	// Burst is the maximum number of queries allowed above QPS for short periods.
+	Burst int
+	// RequestTimeout is the timeout of a single request to the API server, other than long-running requests such as
+	// watches. Zero disables the timeout.
+	RequestTimeout time.Duration
+	// WrapTransport wraps the transport of the clients, for example to log their requests, if set.
+	WrapTransport func(rt http.RoundTripper) http.RoundTripper
+}
+
+var clientOptions = &ClientOptions{
+	QPS:            DefaultClientQPS,
+	Burst:          DefaultClientBurst,
+	RequestTimeout: DefaultClientRequestTimeout,
+}
+
+func init() {
+	pflag.Float32Var(&clientOptions.QPS, "kube_qps", DefaultClientQPS, "(optional) maximum queries per second to the kubernetes API server")
+	pflag.IntVar(&clientOptions.Burst, "kube_burst", DefaultClientBurst, "(optional) maximum burst of queries to the kubernetes API server")
+	pflag.DurationVar(&clientOptions.RequestTimeout, "kube_request_timeout", DefaultClientRequestTimeout, "(optional) timeout of a single request to the kubernetes API server, other than watches and log streams, 0 to disable")
+}
+
+// SetClientOptions overrides the rate limiting and timeouts used by GetConfig and LoadConfig, for example with
+// values read from config.
+func SetClientOptions(opts *ClientOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	*clientOptions = *opts
+	resetClientCache()
+	return nil
+}
+
+func (o *ClientOptions) validate() error {
+	if o.QPS <= 0 {
+		return fmt.Errorf("kube_qps must be positive, got %v", o.QPS)
+	}
+	if o.Burst < 1 {
+		return fmt.Errorf("kube_burst must be at least 1, got %d", o.Burst)
+	}
+	if o.RequestTimeout < 0 {
+		return fmt.Errorf("kube_request_timeout must not be negative, got %s", o.RequestTimeout)
+	}
+	return nil
+}
+
+// apply sets the rate limiting and timeouts on the config.
+func (o *ClientOptions) apply(config *rest.Config) {
+	config.QPS = o.QPS
+	config.Burst = o.Burst
+	if o.RequestTimeout > 0 {
+		timeout := o.RequestTimeout
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return WithRequestTimeout(rt, timeout)
+		})
+	}
+	if o.WrapTransport != nil {
+		config.Wrap(o.WrapTransport)
+	}
+}
+
+// WithRequestTimeout wraps the transport so that requests to the API server time out after the given duration,
+// including reading their response. Unlike rest.Config.Timeout, the timeout doesn't apply to long-running requests,
+// such as watches and followed logs, which would otherwise end silently once it passes.
+func WithRequestTimeout(rt http.RoundTripper, timeout time.Duration) http.RoundTripper {
+	return &requestTimeoutRoundTripper{rt: rt, timeout: timeout}
+}
+
+type requestTimeoutRoundTripper struct {
+	rt      http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *requestTimeoutRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isLongRunningRequest(req) {
+		return t.rt.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t *requestTimeoutRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return t.rt
+}
+
+// isLongRunningRequest returns whether the request stays open for as long as the client wants, such as a watch, a
+// followed log stream, or a connection upgraded for exec or port-forwarding.
+func isLongRunningRequest(req *http.Request) bool {
+	q := req.URL.Query()
+	return q.Get("watch") == "true" || q.Get("watch") == "1" || q.Get("follow") == "true" || req.Header.Get("Upgrade") != ""
+}
+
+// cancelOnClose cancels the context of a request once its response has been read.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
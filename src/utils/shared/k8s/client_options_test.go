@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const requestTimeout = 100 * time.Millisecond
+
+// newSlowAPIServer returns an API server that answers requests for config maps after three request timeouts. Watches
+// send an event once that time has passed.
+func newSlowAPIServer(t *testing.T) *kubernetes.Clientset {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") == "true" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-time.After(3 * requestTimeout):
+		case <-r.Context().Done():
+			return
+		}
+		cm := `{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"slow","namespace":"default"}}`
+		if r.URL.Query().Get("watch") == "true" {
+			fmt.Fprintf(w, `{"type":"ADDED","object":%s}`+"\n", cm)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, cm)
+	}))
+	t.Cleanup(srv.Close)
+
+	config := &rest.Config{Host: srv.URL}
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return k8s.WithRequestTimeout(rt, requestTimeout)
+	})
+	clientset, err := kubernetes.NewForConfig(config)
+	require.NoError(t, err)
+	return clientset
+}
+
+func TestWithRequestTimeout_TimesOutRequests(t *testing.T) {
+	clientset := newSlowAPIServer(t)
+
+	_, err := clientset.CoreV1().ConfigMaps("default").Get(context.Background(), "slow", metav1.GetOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWithRequestTimeout_WatchOutlivesTimeout(t *testing.T) {
+	clientset := newSlowAPIServer(t)
+
+	w, err := clientset.CoreV1().ConfigMaps("default").Watch(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	defer w.Stop()
+
+	select {
+	case e, ok := <-w.ResultChan():
+		require.True(t, ok, "the watch ended before its first event")
+		assert.Equal(t, watch.Added, e.Type)
+	case <-time.After(10 * requestTimeout):
+		t.Fatal("no event received from the watch")
+	}
+}
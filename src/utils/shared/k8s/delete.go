@@ -19,7 +19,9 @@
 package k8s
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"strings"
 	"sync"
@@ -47,7 +49,7 @@ import (
 // ObjectDeleter has methods to delete K8s objects and wait for them. This code is adopted from `kubectl delete`.
 type ObjectDeleter struct {
 	Namespace  string
-	Clientset  *kubernetes.Clientset
+	Clientset  kubernetes.Interface
 	RestConfig *rest.Config
 	Timeout    time.Duration
 
@@ -110,7 +112,7 @@ func (o *ObjectDeleter) DeleteNamespace() error {
 	return err
 }
 
-func (o *ObjectDeleter) getDeletableResourceTypes() ([]string, error) {
+func (o *ObjectDeleter) getDeletableResourceTypes(namespacedOnly bool) ([]string, error) {
 	discoveryClient, err := o.rcg.ToDiscoveryClient()
 	if err != nil {
 		return nil, err
@@ -131,6 +133,9 @@ func (o *ObjectDeleter) getDeletableResourceTypes() ([]string, error) {
 			if len(resource.Verbs) == 0 {
 				continue
 			}
+			if namespacedOnly && !resource.Namespaced {
+				continue
+			}
 			if !sets.NewString(resource.Verbs...).HasAll("delete") {
 				continue
 			}
@@ -148,7 +153,7 @@ func (o *ObjectDeleter) DeleteByLabel(selector string, resourceKinds ...string)
 	b := resource.NewBuilder(o.rcg)
 
 	if len(resourceKinds) == 0 {
-		allKinds, err := o.getDeletableResourceTypes()
+		allKinds, err := o.getDeletableResourceTypes(false)
 		if err != nil {
 			return 0, err
 		}
@@ -176,12 +181,93 @@ func (o *ObjectDeleter) DeleteByLabel(selector string, resourceKinds ...string)
 	return o.runDelete(r)
 }
 
+// DeleteByLabel deletes the objects of all namespaced kinds in the given namespace that match the label
+// selector, leaving the namespace itself and everything else in it intact. Waits for deletion.
+func DeleteByLabel(clientset kubernetes.Interface, config *rest.Config, namespace, selector string, timeout time.Duration) (int, error) {
+	if namespace == "" {
+		return 0, fmt.Errorf("a namespace is required to delete by label")
+	}
+	od := &ObjectDeleter{
+		Namespace:  namespace,
+		Clientset:  clientset,
+		RestConfig: config,
+		Timeout:    timeout,
+	}
+	if err := od.initRestClientGetter(); err != nil {
+		return 0, err
+	}
+	kinds, err := od.getDeletableResourceTypes(true)
+	if err != nil {
+		return 0, err
+	}
+	return od.DeleteByLabel(selector, kinds...)
+}
+
+// DeleteInstance deletes the objects of all kinds, in all namespaces, that were applied for the given instance.
+// Waits for deletion.
+func DeleteInstance(clientset kubernetes.Interface, config *rest.Config, instance string, timeout time.Duration) (int, error) {
+	if instance == "" {
+		return 0, fmt.Errorf("an instance is required to delete by instance")
+	}
+	od := &ObjectDeleter{
+		Clientset:  clientset,
+		RestConfig: config,
+		Timeout:    timeout,
+	}
+	selector := InstanceLabelSelector(instance)
+	return od.DeleteByLabel(metav1.FormatLabelSelector(&selector))
+}
+
+// DeleteResources deletes the given objects, which are identified by their kind, namespace and name, and waits for
+// them to be removed. Namespaced objects must have their namespace set, as ServerSideApplyResources does. Objects that
+// don't exist are skipped.
+func DeleteResources(clientset kubernetes.Interface, config *rest.Config, resources []*Resource, timeout time.Duration) (int, error) {
+	if len(resources) == 0 {
+		return 0, nil
+	}
+	var objects bytes.Buffer
+	for _, r := range resources {
+		data, err := r.Object.MarshalJSON()
+		if err != nil {
+			return 0, err
+		}
+		objects.Write(data)
+		objects.WriteByte('\n')
+	}
+
+	od := &ObjectDeleter{
+		Clientset:  clientset,
+		RestConfig: config,
+		Timeout:    timeout,
+	}
+	if err := od.initRestClientGetter(); err != nil {
+		return 0, err
+	}
+	r := resource.NewBuilder(od.rcg).
+		Unstructured().
+		ContinueOnError().
+		Stream(&objects, "resources").
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return 0, err
+	}
+	if err := od.initDynamicClient(); err != nil {
+		return 0, err
+	}
+	return od.runDelete(r)
+}
+
 func (o *ObjectDeleter) runDelete(r *resource.Result) (int, error) {
-	r = r.IgnoreErrors(errors.IsNotFound)
+	return o.deleteVisited(r.IgnoreErrors(errors.IsNotFound))
+}
+
+// deleteVisited deletes all objects visited by v, and waits for them to be removed.
+func (o *ObjectDeleter) deleteVisited(v resource.Visitor) (int, error) {
 	deletedInfos := []*resource.Info{}
 	uidMap := cmdwait.UIDMap{}
 	found := 0
-	err := r.Visit(func(info *resource.Info, err error) error {
+	err := v.Visit(func(info *resource.Info, err error) error {
 		if err != nil {
 			return err
 		}
@@ -398,7 +484,7 @@ func DeletePods(clientset kubernetes.Interface, namespace string, selectors stri
 }
 
 type restClientGetter struct {
-	clientset  *kubernetes.Clientset
+	clientset  kubernetes.Interface
 	restConfig *rest.Config
 
 	discoveryClientLock sync.Mutex
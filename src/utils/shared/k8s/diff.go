@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pmezard/go-difflib/difflib"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+// ResourceDiff is the difference between the live and the desired state of a single resource.
+type ResourceDiff struct {
+	Kind      string
+	Name      string
+	Namespace string
+	// Exists is false if the resource does not exist on the cluster yet.
+	Exists bool
+	// Diff is the unified diff from the live to the desired resource. It is empty if the resource is up to date.
+	Diff string
+	// Err is the reason the resource couldn't be compared, such as a kind the cluster doesn't serve or a denied get.
+	// Diff is empty then.
+	Err error
+}
+
+// Changed returns whether applying the desired resource would change the cluster.
+func (d *ResourceDiff) Changed() bool {
+	return d.Diff != ""
+}
+
+// Diff compares the resources in the given yaml against the live objects on the cluster, similar to
+// `kubectl diff`.
+func Diff(clientset kubernetes.Interface, config *rest.Config, namespace string, yamlFile io.Reader) ([]*ResourceDiff, error) {
+	resources, err := GetResourcesFromYAML(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+	return DiffResources(clientset, config, resources, namespace)
+}
+
+// DiffResources compares the given resources against the live objects in the given namespace/cluster. Fields that
+// are not set in the desired resources, such as those defaulted or populated by the server, are ignored. A resource
+// that can't be compared is reported with its error in its ResourceDiff, and the remaining resources are still
+// compared.
+func DiffResources(clientset kubernetes.Interface, config *rest.Config, resources []*Resource, namespace string) ([]*ResourceDiff, error) {
+	rm := newResourceMapper(clientset)
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]*ResourceDiff, 0, len(resources))
+	for _, resource := range resources {
+		d := &ResourceDiff{
+			Kind: resource.GVK.Kind,
+			Name: resource.Object.GetName(),
+		}
+		d.Err = diffResource(dynamicClient, rm, resource, namespace, d)
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// diffResource compares a single resource against its live object, filling in the diff.
+func diffResource(dynamicClient dynamic.Interface, rm *resourceMapper, resource *Resource, namespace string, d *ResourceDiff) error {
+	mapping, err := rm.RESTMapping(resource.GVK)
+	if err != nil {
+		return err
+	}
+
+	ri, objNS := resourceInterface(dynamicClient, mapping, namespace, resource, false)
+	if objNS != "" {
+		resource.Object.SetNamespace(objNS)
+	}
+	d.Namespace = objNS
+
+	desiredBytes, err := yaml.Marshal(resource.Object.Object)
+	if err != nil {
+		return err
+	}
+
+	var liveBytes []byte
+	live, err := ri.Get(context.Background(), resource.Object.GetName(), metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+	case err != nil:
+		return wrapAPIError(err, "get", mapping.Resource.Resource, objNS, d.Name)
+	default:
+		d.Exists = true
+		liveBytes, err = yaml.Marshal(pruneToDesired(live.Object, resource.Object.Object))
+		if err != nil {
+			return err
+		}
+	}
+
+	resourceName := fmt.Sprintf("%s/%s", d.Kind, d.Name)
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(liveBytes)),
+		B:        difflib.SplitLines(string(desiredBytes)),
+		FromFile: "live/" + resourceName,
+		ToFile:   "desired/" + resourceName,
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	d.Diff = diff
+	return nil
+}
+
+// pruneToDesired removes all fields from live that are not set in desired, so that fields
+// defaulted or populated by the server do not show up as drift.
+func pruneToDesired(live, desired interface{}) interface{} {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		pruned := make(map[string]interface{}, len(d))
+		for k, dv := range d {
+			if lv, ok := l[k]; ok {
+				pruned[k] = pruneToDesired(lv, dv)
+			}
+		}
+		return pruned
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return live
+		}
+		pruned := make([]interface{}, len(l))
+		for i := range l {
+			pruned[i] = pruneToDesired(l[i], d[i])
+		}
+		return pruned
+	default:
+		return live
+	}
+}
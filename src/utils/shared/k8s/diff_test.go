@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const diffYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: live
+data:
+  key: desired
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: denied
+data:
+  key: desired
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: unknown
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: missing
+data:
+  key: desired
+`
+
+func TestDiff_ReportsErrorsPerResource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/namespaces/px/configmaps/live":
+			fmt.Fprint(w, `{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"live","namespace":"px","uid":"1"},"data":{"key":"live"}}`)
+		case "/api/v1/namespaces/px/configmaps/denied":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	clientset := fake.NewSimpleClientset()
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+	}}
+
+	diffs, err := k8s.Diff(clientset, &rest.Config{Host: srv.URL}, "px", strings.NewReader(diffYAML))
+	require.NoError(t, err)
+	require.Len(t, diffs, 4)
+
+	assert.NoError(t, diffs[0].Err)
+	assert.True(t, diffs[0].Exists)
+	assert.Contains(t, diffs[0].Diff, "-  key: live\n+  key: desired\n")
+
+	assert.ErrorIs(t, diffs[1].Err, k8s.ErrForbidden)
+	assert.False(t, diffs[1].Changed())
+
+	assert.Equal(t, "Widget", diffs[2].Kind)
+	assert.Error(t, diffs[2].Err)
+	assert.False(t, diffs[2].Changed())
+
+	assert.NoError(t, diffs[3].Err)
+	assert.False(t, diffs[3].Exists)
+	assert.True(t, diffs[3].Changed())
+}
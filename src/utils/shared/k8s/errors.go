@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Kinds of errors returned by the helpers in this package. Use errors.Is to check the kind of an error, for
+// example errors.Is(err, ErrForbidden).
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+	ErrConflict  = errors.New("conflict")
+	ErrTimeout   = errors.New("timed out")
+)
+
+// APIError is a failed request to the API server, with the operation that was attempted.
+type APIError struct {
+	// Verb is the attempted operation, such as "create" or "delete".
+	Verb string
+	// Resource is the resource type, such as "deployments".
+	Resource  string
+	Namespace string
+	Name      string
+	Err       error
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	if errors.Is(e, ErrForbidden) {
+		fmt.Fprintf(&sb, "you lack permission to %s %s", e.Verb, e.Resource)
+	} else {
+		fmt.Fprintf(&sb, "failed to %s %s", e.Verb, e.Resource)
+		if e.Name != "" {
+			fmt.Fprintf(&sb, " %s", e.Name)
+		}
+	}
+	if e.Namespace != "" {
+		fmt.Fprintf(&sb, " in namespace %s", e.Namespace)
+	}
+	fmt.Fprintf(&sb, ": %s", e.Err)
+	return sb.String()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is of the given kind.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return k8serrors.IsNotFound(e.Err)
+	case ErrForbidden:
+		return k8serrors.IsForbidden(e.Err) || k8serrors.IsUnauthorized(e.Err)
+	case ErrConflict:
+		return k8serrors.IsConflict(e.Err) || k8serrors.IsAlreadyExists(e.Err)
+	case ErrTimeout:
+		return k8serrors.IsTimeout(e.Err) || k8serrors.IsServerTimeout(e.Err) ||
+			errors.Is(e.Err, context.DeadlineExceeded) || errors.Is(e.Err, wait.ErrWaitTimeout)
+	default:
+		return false
+	}
+}
+
+// wrapAPIError wraps an error from the API server in an APIError. It returns nil if err is nil, and leaves errors
+// that are already wrapped unchanged.
+func wrapAPIError(err error, verb, resource, namespace, name string) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return err
+	}
+	return &APIError{Verb: verb, Resource: resource, Namespace: namespace, Name: name, Err: err}
+}
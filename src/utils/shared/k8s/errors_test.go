@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestAPIError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "deployments"}
+	tests := []struct {
+		name     string
+		err      error
+		kind     error
+		expected string
+	}{
+		{
+			name:     "not found",
+			err:      &k8s.APIError{Verb: "get", Resource: "deployments", Namespace: "px-sock-shop", Name: "frontend", Err: k8serrors.NewNotFound(gr, "frontend")},
+			kind:     k8s.ErrNotFound,
+			expected: `failed to get deployments frontend in namespace px-sock-shop: deployments "frontend" not found`,
+		},
+		{
+			name:     "forbidden",
+			err:      &k8s.APIError{Verb: "create", Resource: "deployments", Namespace: "px-sock-shop", Name: "frontend", Err: k8serrors.NewForbidden(gr, "frontend", errors.New("RBAC denied"))},
+			kind:     k8s.ErrForbidden,
+			expected: `you lack permission to create deployments in namespace px-sock-shop: deployments "frontend" is forbidden: RBAC denied`,
+		},
+		{
+			name: "conflict",
+			err:  &k8s.APIError{Verb: "update", Resource: "deployments", Name: "frontend", Err: k8serrors.NewConflict(gr, "frontend", errors.New("modified"))},
+			kind: k8s.ErrConflict,
+		},
+		{
+			name: "timeout",
+			err:  &k8s.APIError{Verb: "wait for", Resource: "deployments", Name: "frontend", Err: k8serrors.NewTimeoutError("slow", 1)},
+			kind: k8s.ErrTimeout,
+		},
+	}
+
+	kinds := []error{k8s.ErrNotFound, k8s.ErrForbidden, k8s.ErrConflict, k8s.ErrTimeout}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, kind := range kinds {
+				assert.Equal(t, kind == test.kind, errors.Is(test.err, kind), kind.Error())
+			}
+			if test.expected != "" {
+				assert.Equal(t, test.expected, test.err.Error())
+			}
+		})
+	}
+}
+
+func TestGrantSCCForbidden(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "rolebindings", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8serrors.NewForbidden(schema.GroupResource{Resource: "rolebindings"}, "pixie-scc-anyuid", errors.New("RBAC denied"))
+	})
+
+	err := k8s.GrantSCC(clientset, "px-sock-shop", "anyuid", nil)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, k8s.ErrForbidden))
+	assert.Contains(t, err.Error(), "you lack permission to create rolebindings in namespace px-sock-shop")
+}
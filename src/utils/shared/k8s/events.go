@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Event is a Kubernetes event, deduplicated across repeated occurrences.
+type Event struct {
+	// Type is either "Normal" or "Warning".
+	Type string
+	// Reason is a short reason for the event, such as "FailedScheduling" or "BackOff".
+	Reason string
+	// Object is the kind and name of the object the event is about, such as "Pod/frontend-5d8f".
+	Object  string
+	Message string
+	// Count is the number of times the event occurred.
+	Count int32
+	// LastSeen is when the event last occurred.
+	LastSeen time.Time
+}
+
+// NamespaceEventsOptions configures GetNamespaceEvents.
+type NamespaceEventsOptions struct {
+	// WarningsOnly drops events of type "Normal".
+	WarningsOnly bool
+	// Since drops events that last occurred longer ago than this, if set.
+	Since time.Duration
+	// Limit keeps only the most recent events, if set.
+	Limit int
+}
+
+// GetNamespaceEvents returns the events of the namespace, deduplicated by object, reason and message, and sorted
+// from the oldest to the most recent.
+func GetNamespaceEvents(ctx context.Context, clientset kubernetes.Interface, namespace string, opts *NamespaceEventsOptions) ([]*Event, error) {
+	list, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var cutoff time.Time
+	if opts.Since > 0 {
+		cutoff = time.Now().Add(-opts.Since)
+	}
+
+	byKey := make(map[string]*Event)
+	var events []*Event
+	for i := range list.Items {
+		ev := &list.Items[i]
+		if opts.WarningsOnly && ev.Type != v1.EventTypeWarning {
+			continue
+		}
+		lastSeen := eventTime(ev)
+		if lastSeen.Before(cutoff) {
+			continue
+		}
+
+		object := fmt.Sprintf("%s/%s", ev.InvolvedObject.Kind, ev.InvolvedObject.Name)
+		key := fmt.Sprintf("%s/%s/%s/%s", ev.Type, object, ev.Reason, ev.Message)
+		if e, ok := byKey[key]; ok {
+			e.Count += eventCount(ev)
+			if lastSeen.After(e.LastSeen) {
+				e.LastSeen = lastSeen
+			}
+			continue
+		}
+		e := &Event{
+			Type:     ev.Type,
+			Reason:   ev.Reason,
+			Object:   object,
+			Message:  ev.Message,
+			Count:    eventCount(ev),
+			LastSeen: lastSeen,
+		}
+		byKey[key] = e
+		events = append(events, e)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastSeen.Before(events[j].LastSeen)
+	})
+	if opts.Limit > 0 && len(events) > opts.Limit {
+		events = events[len(events)-opts.Limit:]
+	}
+	return events, nil
+}
+
+// eventTime returns when the event last occurred, falling back to older fields for events that don't set it.
+func eventTime(ev *v1.Event) time.Time {
+	switch {
+	case ev.Series != nil:
+		return ev.Series.LastObservedTime.Time
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	case !ev.FirstTimestamp.IsZero():
+		return ev.FirstTimestamp.Time
+	default:
+		return ev.CreationTimestamp.Time
+	}
+}
+
+func eventCount(ev *v1.Event) int32 {
+	switch {
+	case ev.Series != nil:
+		return ev.Series.Count
+	case ev.Count > 0:
+		return ev.Count
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func testEvent(name, eventType, reason, pod, message string, count int32, lastSeen time.Time) *v1.Event {
+	return &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "px-sock-shop"},
+		Type:           eventType,
+		Reason:         reason,
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: pod},
+		Message:        message,
+		Count:          count,
+		LastTimestamp:  metav1.NewTime(lastSeen),
+	}
+}
+
+func TestGetNamespaceEvents(t *testing.T) {
+	now := time.Now()
+	clientset := fake.NewSimpleClientset(
+		testEvent("a", v1.EventTypeWarning, "FailedScheduling", "carts-0", "0/3 nodes are available", 2, now.Add(-5*time.Minute)),
+		testEvent("b", v1.EventTypeWarning, "FailedScheduling", "carts-0", "0/3 nodes are available", 3, now.Add(-time.Minute)),
+		testEvent("c", v1.EventTypeNormal, "Pulled", "frontend-0", "Successfully pulled image", 1, now.Add(-2*time.Minute)),
+		testEvent("d", v1.EventTypeWarning, "BackOff", "orders-0", "Back-off pulling image", 1, now.Add(-2*time.Hour)),
+	)
+
+	tests := []struct {
+		name            string
+		opts            *k8s.NamespaceEventsOptions
+		expectedReasons []string
+	}{
+		{
+			name:            "all events",
+			opts:            &k8s.NamespaceEventsOptions{},
+			expectedReasons: []string{"BackOff", "Pulled", "FailedScheduling"},
+		},
+		{
+			name:            "recent warnings",
+			opts:            &k8s.NamespaceEventsOptions{WarningsOnly: true, Since: time.Hour},
+			expectedReasons: []string{"FailedScheduling"},
+		},
+		{
+			name:            "limit",
+			opts:            &k8s.NamespaceEventsOptions{Limit: 1},
+			expectedReasons: []string{"FailedScheduling"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := k8s.GetNamespaceEvents(context.Background(), clientset, "px-sock-shop", test.opts)
+			require.NoError(t, err)
+			reasons := make([]string, len(events))
+			for i, e := range events {
+				reasons[i] = e.Reason
+			}
+			assert.Equal(t, test.expectedReasons, reasons)
+		})
+	}
+
+	events, err := k8s.GetNamespaceEvents(context.Background(), clientset, "px-sock-shop", &k8s.NamespaceEventsOptions{WarningsOnly: true})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "Pod/carts-0", events[1].Object)
+	assert.Equal(t, int32(5), events[1].Count)
+}
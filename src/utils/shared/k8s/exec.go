@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// ExecOptions are the streams attached to a command run with Exec. Nil streams are not attached.
+type ExecOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// TTY allocates a terminal for the command, in which case Stderr is merged into Stdout.
+	TTY bool
+}
+
+// ExecExitError is returned by Exec when the command runs but exits with a non-zero code.
+type ExecExitError struct {
+	Pod       string
+	Container string
+	Code      int
+}
+
+func (e *ExecExitError) Error() string {
+	return fmt.Sprintf("command in %s/%s exited with code %d", e.Pod, e.Container, e.Code)
+}
+
+// Exec runs the command in the container of the given pod, similar to `kubectl exec`. If container is empty, the
+// pod's first container is used. Exec blocks until the command exits or the context is done.
+func Exec(ctx context.Context, clientset kubernetes.Interface, config *rest.Config, namespace, pod, container string, command []string, opts *ExecOptions) error {
+	if container == "" {
+		p, err := clientset.CoreV1().Pods(namespace).Get(ctx, pod, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if len(p.Spec.Containers) == 0 {
+			return fmt.Errorf("pod %s has no containers", pod)
+		}
+		container = p.Spec.Containers[0].Name
+	}
+
+	req := clientset.CoreV1().RESTClient().
+		Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil && !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+	streamOpts := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Tty:    opts.TTY,
+	}
+	if !opts.TTY {
+		streamOpts.Stderr = opts.Stderr
+	}
+
+	err = executor.StreamWithContext(ctx, streamOpts)
+	var exitErr exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return &ExecExitError{Pod: pod, Container: container, Code: exitErr.ExitStatus()}
+	}
+	return err
+}
@@ -23,10 +23,16 @@ import (
 	"os/exec"
 )
 
+// KubectlCmd returns a kubectl command with the given arguments, for the kubeconfig and the context that the clients
+// of this package use.
 func KubectlCmd(args ...string) *exec.Cmd {
+	if kubeContext != "" {
+		args = append([]string{"--context", kubeContext}, args...)
+	}
 	cmd := exec.Command("kubectl", args...)
-	if *kubeconfig != "" {
-		cmd.Env = append(cmd.Environ(), fmt.Sprintf("KUBECONFIG=%s", *kubeconfig))
+	// Without --kubeconfig, kubectl merges the files of KUBECONFIG itself.
+	if rules := kubeconfigLoadingRules(); rules.ExplicitPath != "" {
+		cmd.Env = append(cmd.Environ(), fmt.Sprintf("KUBECONFIG=%s", rules.ExplicitPath))
 	}
 	return cmd
 }
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NamespacesFirst returns the resources with the Namespace objects moved to the front, so that the namespaces
+// exist before the objects in them are applied. The order is otherwise preserved.
+func NamespacesFirst(resources []*Resource) []*Resource {
+	ordered := make([]*Resource, 0, len(resources))
+	for _, r := range resources {
+		if isNamespace(r) {
+			ordered = append(ordered, r)
+		}
+	}
+	for _, r := range resources {
+		if !isNamespace(r) {
+			ordered = append(ordered, r)
+		}
+	}
+	return ordered
+}
+
+func isNamespace(r *Resource) bool {
+	return r.GVK.Group == "" && r.GVK.Kind == "Namespace"
+}
+
+// EnsureNamespace creates the namespace with the given labels if it doesn't exist, and returns whether it was
+// created. The labels of an existing namespace are left alone.
+func EnsureNamespace(clientset kubernetes.Interface, name string, labels map[string]string) (bool, error) {
+	_, err := clientset.CoreV1().Namespaces().Get(context.Background(), name, metav1.GetOptions{})
+	if err == nil {
+		return false, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return false, wrapAPIError(err, "get", "namespaces", "", name)
+	}
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	_, err = clientset.CoreV1().Namespaces().Create(context.Background(), ns, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	return err == nil, wrapAPIError(err, "create", "namespaces", "", name)
+}
+
+// namespaceEnsurer creates the namespaces that objects are applied to, checking each namespace only once. The
+// namespaces it creates get the labels of the objects it applies, so that they can be found and deleted with them.
+type namespaceEnsurer struct {
+	clientset kubernetes.Interface
+	labels    map[string]string
+	checked   map[string]bool
+}
+
+func newNamespaceEnsurer(clientset kubernetes.Interface, labels map[string]string) *namespaceEnsurer {
+	return &namespaceEnsurer{clientset: clientset, labels: labels, checked: make(map[string]bool)}
+}
+
+// ensure creates the namespace if needed. It returns the result to report if the namespace was created.
+func (e *namespaceEnsurer) ensure(namespace string) (*AppliedResource, error) {
+	if namespace == "" || e.checked[namespace] {
+		return nil, nil
+	}
+	created, err := EnsureNamespace(e.clientset, namespace, e.labels)
+	if err != nil {
+		return nil, err
+	}
+	e.checked[namespace] = true
+	if !created {
+		return nil, nil
+	}
+	return &AppliedResource{Kind: "Namespace", Name: namespace, Status: StatusCreated}, nil
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func testResource(kind, name string) *k8s.Resource {
+	obj := &unstructured.Unstructured{}
+	obj.SetName(name)
+	return &k8s.Resource{Object: obj, GVK: &schema.GroupVersionKind{Version: "v1", Kind: kind}}
+}
+
+func TestNamespacesFirst(t *testing.T) {
+	resources := []*k8s.Resource{
+		testResource("Service", "frontend"),
+		testResource("Namespace", "backend"),
+		testResource("ConfigMap", "config"),
+		testResource("Namespace", "frontend"),
+	}
+
+	var names []string
+	for _, r := range k8s.NamespacesFirst(resources) {
+		names = append(names, r.GVK.Kind+"/"+r.Object.GetName())
+	}
+	assert.Equal(t, []string{"Namespace/backend", "Namespace/frontend", "Service/frontend", "ConfigMap/config"}, names)
+}
+
+func TestEnsureNamespace(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "existing"}})
+
+	labels := map[string]string{k8s.InstanceLabel: "px-sock-shop"}
+	created, err := k8s.EnsureNamespace(clientset, "existing", labels)
+	require.NoError(t, err)
+	assert.False(t, created)
+	ns, err := clientset.CoreV1().Namespaces().Get(context.Background(), "existing", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, ns.Labels)
+
+	created, err = k8s.EnsureNamespace(clientset, "missing", labels)
+	require.NoError(t, err)
+	assert.True(t, created)
+	ns, err = clientset.CoreV1().Namespaces().Get(context.Background(), "missing", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, labels, ns.Labels)
+
+	created, err = k8s.EnsureNamespace(clientset, "missing", nil)
+	require.NoError(t, err)
+	assert.False(t, created)
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+)
+
+// openShiftSecurityGroup is the API group of OpenShift's SecurityContextConstraints, which is only served by
+// OpenShift clusters.
+const openShiftSecurityGroup = "security.openshift.io"
+
+// IsOpenShift returns whether the cluster is an OpenShift cluster.
+func IsOpenShift(discoveryClient discovery.DiscoveryInterface) (bool, error) {
+	groups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return false, err
+	}
+	for _, g := range groups.Groups {
+		if g.Name == openShiftSecurityGroup {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SCCGrantCommand returns the `oc` command that grants the service accounts of the namespace the given
+// SecurityContextConstraints, for users that have to ask a cluster admin to run it.
+func SCCGrantCommand(namespace, scc string) string {
+	return fmt.Sprintf("oc adm policy add-scc-to-group %s system:serviceaccounts:%s", scc, namespace)
+}
+
+// GrantSCC allows all service accounts in the namespace to use the given SecurityContextConstraints, such as
+// "anyuid", by binding them to the SCC's cluster role. This is the equivalent of SCCGrantCommand. The role binding is
+// created with the given labels, so that it can be deleted along with the objects that needed it.
+func GrantSCC(clientset kubernetes.Interface, namespace, scc string, labels map[string]string) error {
+	rb := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("pixie-scc-%s", scc),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     fmt.Sprintf("system:openshift:scc:%s", scc),
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				APIGroup: rbacv1.GroupName,
+				Kind:     rbacv1.GroupKind,
+				Name:     fmt.Sprintf("system:serviceaccounts:%s", namespace),
+			},
+		},
+	}
+	_, err := clientset.RbacV1().RoleBindings(namespace).Create(context.Background(), rb, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return wrapAPIError(err, "create", "rolebindings", namespace, rb.Name)
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestIsOpenShift(t *testing.T) {
+	tests := []struct {
+		name     string
+		groups   []string
+		expected bool
+	}{
+		{
+			name:     "kubernetes",
+			groups:   []string{"v1", "apps/v1"},
+			expected: false,
+		},
+		{
+			name:     "openshift",
+			groups:   []string{"v1", "apps/v1", "security.openshift.io/v1"},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			for _, gv := range test.groups {
+				clientset.Resources = append(clientset.Resources, &metav1.APIResourceList{GroupVersion: gv})
+			}
+			isOpenShift, err := k8s.IsOpenShift(clientset.Discovery())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, isOpenShift)
+		})
+	}
+}
+
+func TestGrantSCC(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	labels := map[string]string{k8s.InstanceLabel: "px-sock-shop"}
+	require.NoError(t, k8s.GrantSCC(clientset, "px-sock-shop", "anyuid", labels))
+	// Granting again is a no-op.
+	require.NoError(t, k8s.GrantSCC(clientset, "px-sock-shop", "anyuid", labels))
+
+	rb, err := clientset.RbacV1().RoleBindings("px-sock-shop").Get(context.Background(), "pixie-scc-anyuid", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "system:openshift:scc:anyuid", rb.RoleRef.Name)
+	assert.Equal(t, labels, rb.Labels)
+	require.Len(t, rb.Subjects, 1)
+	assert.Equal(t, "system:serviceaccounts:px-sock-shop", rb.Subjects[0].Name)
+}
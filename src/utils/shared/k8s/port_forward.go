@@ -0,0 +1,200 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForwarder forwards a local port to a port of a pod, similar to `kubectl port-forward`.
+type PortForwarder struct {
+	Namespace string
+	Pod       string
+
+	pf        *portforward.PortForwarder
+	stopCh    chan struct{}
+	readyCh   chan struct{}
+	errCh     chan error
+	localPort uint16
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewPortForwarder creates a forwarder from the local port to the remote port of the given pod. If localPort
+// is 0, a free local port is chosen when the forwarder is started.
+func NewPortForwarder(clientset kubernetes.Interface, config *rest.Config, namespace, pod string, localPort, remotePort int) (*PortForwarder, error) {
+	req := clientset.CoreV1().RESTClient().
+		Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+
+	fw := &PortForwarder{
+		Namespace: namespace,
+		Pod:       pod,
+		stopCh:    make(chan struct{}),
+		readyCh:   make(chan struct{}),
+		errCh:     make(chan error, 1),
+	}
+	ports := []string{fmt.Sprintf("%d:%d", localPort, remotePort)}
+	pf, err := portforward.New(dialer, ports, fw.stopCh, fw.readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	fw.pf = pf
+	return fw, nil
+}
+
+// NewServicePortForwarder creates a forwarder from the local port to the given port of a Service, by forwarding
+// to a running pod that backs the Service.
+func NewServicePortForwarder(ctx context.Context, clientset kubernetes.Interface, config *rest.Config, namespace, service string, localPort, servicePort int) (*PortForwarder, error) {
+	pod, podPort, err := servicePodPort(ctx, clientset, namespace, service, servicePort)
+	if err != nil {
+		return nil, err
+	}
+	return NewPortForwarder(clientset, config, namespace, pod, localPort, podPort)
+}
+
+// Start starts forwarding in the background, and returns once the forwarder is ready to accept connections.
+func (fw *PortForwarder) Start() error {
+	fw.wg.Add(1)
+	go func() {
+		defer fw.wg.Done()
+		fw.errCh <- fw.pf.ForwardPorts()
+		close(fw.errCh)
+	}()
+
+	select {
+	case err := <-fw.errCh:
+		if err == nil {
+			err = errors.New("port forwarder stopped before becoming ready")
+		}
+		return err
+	case <-fw.readyCh:
+	}
+
+	ports, err := fw.pf.GetPorts()
+	if err != nil {
+		fw.Close()
+		return err
+	}
+	if len(ports) != 1 {
+		fw.Close()
+		return errors.New("couldn't get local port from port forwarder")
+	}
+	fw.localPort = ports[0].Local
+	return nil
+}
+
+// LocalPort returns the local port that is forwarded. It is only valid after Start returns.
+func (fw *PortForwarder) LocalPort() int {
+	return int(fw.localPort)
+}
+
+// Done returns a channel that receives the error, if any, once forwarding stops. Forwarding stops when
+// Close is called or the connection to the pod is lost.
+func (fw *PortForwarder) Done() <-chan error {
+	return fw.errCh
+}
+
+// Close stops forwarding and waits for the forwarder to shut down.
+func (fw *PortForwarder) Close() {
+	fw.closeOnce.Do(func() {
+		close(fw.stopCh)
+	})
+	fw.wg.Wait()
+}
+
+// servicePodPort returns a running pod that backs the Service, and the pod port that the given Service port targets.
+func servicePodPort(ctx context.Context, clientset kubernetes.Interface, namespace, service string, port int) (string, int, error) {
+	svc, err := clientset.CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, err
+	}
+	var svcPort *v1.ServicePort
+	for i, p := range svc.Spec.Ports {
+		if int(p.Port) == port {
+			svcPort = &svc.Spec.Ports[i]
+			break
+		}
+	}
+	if svcPort == nil {
+		return "", 0, fmt.Errorf("service %s does not expose port %d", service, port)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("service %s has no pod selector", service)
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		podPort, err := containerPort(&pod, svcPort)
+		if err != nil {
+			return "", 0, err
+		}
+		return pod.Name, podPort, nil
+	}
+	return "", 0, fmt.Errorf("no running pods found for service %s", service)
+}
+
+// containerPort resolves the target port of the Service port to a port on the pod.
+func containerPort(pod *v1.Pod, svcPort *v1.ServicePort) (int, error) {
+	switch {
+	case svcPort.TargetPort.Type == intstr.Int && svcPort.TargetPort.IntValue() == 0:
+		return int(svcPort.Port), nil
+	case svcPort.TargetPort.Type == intstr.Int:
+		return svcPort.TargetPort.IntValue(), nil
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == svcPort.TargetPort.StrVal {
+				return int(p.ContainerPort), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s has no port named %s", pod.Name, svcPort.TargetPort.StrVal)
+}
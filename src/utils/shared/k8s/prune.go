@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// PruneOptions configures an apply with prune.
+type PruneOptions struct {
+	// Selector is the label selector that matches all objects managed by the apply. Objects in the namespace
+	// that match the selector but are not part of the applied set are deleted.
+	Selector string
+	// Timeout is how long to wait for pruned objects to be deleted.
+	Timeout time.Duration
+}
+
+// ApplyWithPrune server-side applies the given resources to the namespace, then deletes all objects that match
+// the prune selector but were not applied, similar to `kubectl apply --prune`. The pruned objects are returned
+// with StatusPruned.
+func ApplyWithPrune(clientset *kubernetes.Clientset, config *rest.Config, resources []*Resource, namespace string, opts *ServerSideApplyOptions, pruneOpts *PruneOptions) ([]*AppliedResource, error) {
+	applied, err := ServerSideApplyResources(clientset, config, resources, namespace, opts)
+	if err != nil {
+		return applied, err
+	}
+	pruned, err := Prune(clientset, config, namespace, applied, pruneOpts)
+	return append(applied, pruned...), err
+}
+
+// Prune deletes the objects in the namespace that match the prune selector and are missing from the applied set.
+// Objects owned by another object, such as the ReplicaSets of a Deployment, are never pruned.
+func Prune(clientset kubernetes.Interface, config *rest.Config, namespace string, applied []*AppliedResource, opts *PruneOptions) ([]*AppliedResource, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("a namespace is required to prune")
+	}
+	if opts.Selector == "" {
+		return nil, fmt.Errorf("a label selector is required to prune")
+	}
+
+	keep := make(map[string]bool, len(applied))
+	for _, a := range applied {
+		keep[appliedResourceKey(a.Kind, a.Namespace, a.Name)] = true
+	}
+
+	od := &ObjectDeleter{
+		Namespace:  namespace,
+		Clientset:  clientset,
+		RestConfig: config,
+		Timeout:    opts.Timeout,
+	}
+	if err := od.initRestClientGetter(); err != nil {
+		return nil, err
+	}
+	kinds, err := od.getDeletableResourceTypes(true)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := resource.NewBuilder(od.rcg).
+		Unstructured().
+		ContinueOnError().
+		NamespaceParam(namespace).
+		LabelSelector(opts.Selector).
+		ResourceTypeOrNameArgs(false, strings.Join(kinds, ",")).
+		Flatten().
+		Do().
+		IgnoreErrors(errors.IsNotFound).
+		Infos()
+	if err != nil {
+		return nil, err
+	}
+
+	var toPrune []*resource.Info
+	var pruned []*AppliedResource
+	for _, info := range infos {
+		kind := info.Mapping.GroupVersionKind.Kind
+		if keep[appliedResourceKey(kind, info.Namespace, info.Name)] {
+			continue
+		}
+		if obj, err := meta.Accessor(info.Object); err == nil && len(obj.GetOwnerReferences()) > 0 {
+			continue
+		}
+		toPrune = append(toPrune, info)
+		pruned = append(pruned, &AppliedResource{
+			Kind:      kind,
+			Name:      info.Name,
+			Namespace: info.Namespace,
+			Status:    StatusPruned,
+		})
+	}
+	if len(toPrune) == 0 {
+		return nil, nil
+	}
+
+	if err := od.initDynamicClient(); err != nil {
+		return nil, err
+	}
+	if _, err := od.deleteVisited(resource.InfoListVisitor(toPrune)); err != nil {
+		return pruned, err
+	}
+	return pruned, nil
+}
+
+func appliedResourceKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
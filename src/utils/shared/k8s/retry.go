@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// DefaultConflictRetries is the number of attempts made for a mutating call that fails with a conflict.
+const DefaultConflictRetries = 5
+
+// RetryOnConflict calls fn until it succeeds, fails with an error other than a conflict, or attempts run out.
+// fn should fetch the latest version of the object on every call. Conflicts with the fields of other field managers
+// aren't retried, since they persist until the fields are forced or released. The final conflict error is annotated
+// with the description of the operation.
+func RetryOnConflict(attempts int, description string, fn func() error) error {
+	if attempts <= 0 {
+		attempts = DefaultConflictRetries
+	}
+	backoff := wait.Backoff{
+		Steps:    attempts,
+		Duration: 10 * time.Millisecond,
+		Factor:   5.0,
+		Jitter:   0.1,
+	}
+	err := retry.OnError(backoff, isStaleConflict, fn)
+	if isStaleConflict(err) {
+		return fmt.Errorf("%s: still conflicting after %d attempts: %w", description, attempts, err)
+	}
+	return err
+}
+
+// isStaleConflict returns whether the error is a conflict that retrying with the latest version of the object can
+// resolve, rather than a conflict with the fields of another field manager.
+func isStaleConflict(err error) bool {
+	if !k8serrors.IsConflict(err) {
+		return false
+	}
+	var status k8serrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Details == nil {
+		return true
+	}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type == metav1.CauseTypeFieldManagerConflict {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestRetryOnConflict(t *testing.T) {
+	conflict := k8serrors.NewConflict(schema.GroupResource{Resource: "deployments"}, "frontend", errors.New("object was modified"))
+	fieldConflict := k8serrors.NewApplyConflict([]metav1.StatusCause{{
+		Type:    metav1.CauseTypeFieldManagerConflict,
+		Message: `conflict with "kubectl"`,
+		Field:   ".spec.replicas",
+	}}, "Apply failed with 1 conflict")
+
+	tests := []struct {
+		name          string
+		failures      int
+		err           error
+		expectedCalls int
+		expectError   bool
+	}{
+		{
+			name:          "succeeds immediately",
+			failures:      0,
+			expectedCalls: 1,
+		},
+		{
+			name:          "succeeds after conflicts",
+			failures:      2,
+			err:           conflict,
+			expectedCalls: 3,
+		},
+		{
+			name:          "gives up after attempts",
+			failures:      10,
+			err:           conflict,
+			expectedCalls: 3,
+			expectError:   true,
+		},
+		{
+			name:          "does not retry field manager conflicts",
+			failures:      10,
+			err:           fieldConflict,
+			expectedCalls: 1,
+			expectError:   true,
+		},
+		{
+			name:          "does not retry wrapped field manager conflicts",
+			failures:      10,
+			err:           fmt.Errorf("applying deployment frontend: %w", fieldConflict),
+			expectedCalls: 1,
+			expectError:   true,
+		},
+		{
+			name:          "does not retry other errors",
+			failures:      10,
+			err:           errors.New("forbidden"),
+			expectedCalls: 1,
+			expectError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			err := k8s.RetryOnConflict(3, "updating deployment frontend", func() error {
+				calls++
+				if calls <= test.failures {
+					return test.err
+				}
+				return nil
+			})
+			assert.Equal(t, test.expectedCalls, calls)
+			if test.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Workload kinds supported by the rollout helpers.
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+)
+
+// RolloutStatus is the rollout progress of a single workload.
+type RolloutStatus struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Done is true once the rollout has completed.
+	Done bool
+	// Message describes the progress of the rollout, in the same format as `kubectl rollout status`.
+	Message string
+}
+
+// DeploymentRolloutStatus returns the rollout status of the given Deployment.
+func DeploymentRolloutStatus(d *appsv1.Deployment) *RolloutStatus {
+	s := &RolloutStatus{Kind: KindDeployment, Namespace: d.Namespace, Name: d.Name}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	switch {
+	case d.Generation > d.Status.ObservedGeneration:
+		s.Message = fmt.Sprintf("Waiting for deployment %q spec update to be observed...", d.Name)
+	case d.Status.UpdatedReplicas < replicas:
+		s.Message = fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...", d.Name, d.Status.UpdatedReplicas, replicas)
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		s.Message = fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination...", d.Name, d.Status.Replicas-d.Status.UpdatedReplicas)
+	case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+		s.Message = fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...", d.Name, d.Status.AvailableReplicas, d.Status.UpdatedReplicas)
+	default:
+		s.Done = true
+		s.Message = fmt.Sprintf("deployment %q successfully rolled out", d.Name)
+	}
+	return s
+}
+
+// StatefulSetRolloutStatus returns the rollout status of the given StatefulSet.
+func StatefulSetRolloutStatus(sts *appsv1.StatefulSet) *RolloutStatus {
+	s := &RolloutStatus{Kind: KindStatefulSet, Namespace: sts.Namespace, Name: sts.Name}
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	switch {
+	case sts.Status.ObservedGeneration == 0 || sts.Generation > sts.Status.ObservedGeneration:
+		s.Message = fmt.Sprintf("Waiting for statefulset %q spec update to be observed...", sts.Name)
+	case sts.Status.ReadyReplicas < replicas:
+		s.Message = fmt.Sprintf("Waiting for %d pods to be ready...", replicas-sts.Status.ReadyReplicas)
+	case sts.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType && sts.Status.UpdateRevision != sts.Status.CurrentRevision:
+		s.Message = fmt.Sprintf("Waiting for statefulset %q rolling update to complete %d pods at revision %s...", sts.Name, sts.Status.UpdatedReplicas, sts.Status.UpdateRevision)
+	default:
+		s.Done = true
+		s.Message = fmt.Sprintf("statefulset %q successfully rolled out", sts.Name)
+	}
+	return s
+}
+
+// DaemonSetRolloutStatus returns the rollout status of the given DaemonSet.
+func DaemonSetRolloutStatus(ds *appsv1.DaemonSet) *RolloutStatus {
+	s := &RolloutStatus{Kind: KindDaemonSet, Namespace: ds.Namespace, Name: ds.Name}
+
+	switch {
+	case ds.Generation > ds.Status.ObservedGeneration:
+		s.Message = fmt.Sprintf("Waiting for daemon set %q spec update to be observed...", ds.Name)
+	case ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled:
+		s.Message = fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d out of %d new pods have been updated...", ds.Name, ds.Status.UpdatedNumberScheduled, ds.Status.DesiredNumberScheduled)
+	case ds.Status.NumberAvailable < ds.Status.DesiredNumberScheduled:
+		s.Message = fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d of %d updated pods are available...", ds.Name, ds.Status.NumberAvailable, ds.Status.DesiredNumberScheduled)
+	default:
+		s.Done = true
+		s.Message = fmt.Sprintf("daemon set %q successfully rolled out", ds.Name)
+	}
+	return s
+}
+
+// GetRolloutStatus returns the rollout status of the given workload.
+func GetRolloutStatus(ctx context.Context, clientset kubernetes.Interface, kind, namespace, name string) (*RolloutStatus, error) {
+	switch kind {
+	case KindDeployment:
+		d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, wrapAPIError(err, "get", "deployments", namespace, name)
+		}
+		return DeploymentRolloutStatus(d), nil
+	case KindStatefulSet:
+		sts, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, wrapAPIError(err, "get", "statefulsets", namespace, name)
+		}
+		return StatefulSetRolloutStatus(sts), nil
+	case KindDaemonSet:
+		ds, err := clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, wrapAPIError(err, "get", "daemonsets", namespace, name)
+		}
+		return DaemonSetRolloutStatus(ds), nil
+	default:
+		return nil, fmt.Errorf("rollout status is not supported for kind %s", kind)
+	}
+}
+
+// ListRolloutStatuses returns the rollout status of all Deployments, StatefulSets and DaemonSets in the namespace.
+func ListRolloutStatuses(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]*RolloutStatus, error) {
+	var statuses []*RolloutStatus
+
+	deps, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range deps.Items {
+		statuses = append(statuses, DeploymentRolloutStatus(&deps.Items[i]))
+	}
+
+	sets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range sets.Items {
+		statuses = append(statuses, StatefulSetRolloutStatus(&sets.Items[i]))
+	}
+
+	daemonSets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		statuses = append(statuses, DaemonSetRolloutStatus(&daemonSets.Items[i]))
+	}
+	return statuses, nil
+}
+
+// WaitForRollout polls the given workload until its rollout completes or the context is done. If progress is
+// non-nil, it is called with the status after every poll.
+func WaitForRollout(ctx context.Context, clientset kubernetes.Interface, kind, namespace, name string, interval time.Duration, progress func(*RolloutStatus)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		s, err := GetRolloutStatus(ctx, clientset, kind, namespace, name)
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(s)
+		}
+		if s.Done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%s: %w", s.Message, ErrTimeout)
+			}
+			return fmt.Errorf("%s: %w", s.Message, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
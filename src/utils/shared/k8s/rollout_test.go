@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestDeploymentRolloutStatus(t *testing.T) {
+	replicas := int32(3)
+	tests := []struct {
+		name     string
+		status   appsv1.DeploymentStatus
+		gen      int64
+		expected bool
+	}{
+		{
+			name:     "spec not observed",
+			gen:      2,
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3},
+			expected: false,
+		},
+		{
+			name:     "replicas being updated",
+			gen:      1,
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 1},
+			expected: false,
+		},
+		{
+			name:     "old replicas terminating",
+			gen:      1,
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 4, UpdatedReplicas: 3, AvailableReplicas: 3},
+			expected: false,
+		},
+		{
+			name:     "updated replicas unavailable",
+			gen:      1,
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 2},
+			expected: false,
+		},
+		{
+			name:     "rolled out",
+			gen:      1,
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "frontend", Generation: test.gen},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status:     test.status,
+			}
+			s := k8s.DeploymentRolloutStatus(d)
+			assert.Equal(t, test.expected, s.Done)
+			assert.Equal(t, "frontend", s.Name)
+			assert.NotEmpty(t, s.Message)
+		})
+	}
+}
@@ -22,6 +22,14 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ManagedByLabel is set on every object applied by the CLI to the field manager that applied it.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// InstanceLabel is set on every object applied by the CLI as part of a named instance, such as a demo app,
+	// so that all its objects can be found and deleted regardless of their namespace.
+	InstanceLabel = "px.dev/instance"
+)
+
 // VizierLabelSelector returns a K8s selector that matches labels of all Pixie managed resources.
 func VizierLabelSelector() metav1.LabelSelector {
 	return metav1.LabelSelector{
@@ -50,3 +58,13 @@ func OperatorLabelSelector() metav1.LabelSelector {
 		},
 	}
 }
+
+// InstanceLabelSelector returns a K8s selector that matches the labels of all objects applied by the CLI for
+// the given instance.
+func InstanceLabelSelector(instance string) metav1.LabelSelector {
+	return metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			InstanceLabel: instance,
+		},
+	}
+}
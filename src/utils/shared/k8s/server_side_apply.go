@@ -0,0 +1,352 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// DefaultFieldManager is the field manager used for server-side applies that don't specify one.
+const DefaultFieldManager = "pixie-cli"
+
+// FieldConflict is a field that could not be applied because it is owned by another field manager.
+type FieldConflict struct {
+	// Field is the path of the conflicting field, such as ".spec.replicas".
+	Field string
+	// Message describes the conflict, including the manager that owns the field.
+	Message string
+}
+
+// ApplyConflictError is returned when a server-side apply fails because fields are owned by other managers.
+type ApplyConflictError struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Conflicts []*FieldConflict
+}
+
+// Is reports whether target is ErrConflict.
+func (e *ApplyConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *ApplyConflictError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "apply of %s %s has %d conflict(s) with other field managers:", e.Kind, e.Name, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		fmt.Fprintf(&sb, "\n  %s: %s", c.Field, c.Message)
+	}
+	return sb.String()
+}
+
+// applyConflictError converts a server-side apply conflict into an ApplyConflictError. It returns nil if err is
+// not caused by fields owned by other managers.
+func applyConflictError(err error, result *AppliedResource) *ApplyConflictError {
+	var statusErr *k8serrors.StatusError
+	if !errors.As(err, &statusErr) || !k8serrors.IsConflict(err) || statusErr.ErrStatus.Details == nil {
+		return nil
+	}
+	var conflicts []*FieldConflict
+	for _, cause := range statusErr.ErrStatus.Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		conflicts = append(conflicts, &FieldConflict{Field: cause.Field, Message: cause.Message})
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return &ApplyConflictError{
+		Kind:      result.Kind,
+		Name:      result.Name,
+		Namespace: result.Namespace,
+		Conflicts: conflicts,
+	}
+}
+
+// ServerSideApplyOptions configures a server-side apply.
+type ServerSideApplyOptions struct {
+	// FieldManager is the name of the manager that owns the applied fields. Defaults to DefaultFieldManager.
+	FieldManager string
+	// Force takes ownership of fields that are owned by other managers instead of failing on conflicts.
+	Force bool
+	// ConflictRetries is the number of attempts made to apply a resource that fails with a conflict.
+	// Defaults to DefaultConflictRetries.
+	ConflictRetries int
+	// Instance, if set, is stamped on every applied resource with the InstanceLabel.
+	Instance string
+	// RespectObjectNamespaces applies objects to the namespace in their own metadata, like
+	// ApplyOptions.RespectObjectNamespaces.
+	RespectObjectNamespaces bool
+	// NamespaceLabels are set on the namespaces that are created for the objects, along with the labels that are
+	// stamped on every applied object.
+	NamespaceLabels map[string]string
+	// PreviousFieldManagers are the managers that the objects were applied with before the field manager was renamed.
+	// Their fields are taken over by the field manager before it applies an object, so that they don't conflict with
+	// the new applies, and fields that were removed from the objects are still removed from the cluster.
+	PreviousFieldManagers []string
+}
+
+// ServerSideApplyYAML does the equivalent of a `kubectl apply --server-side` for the given yaml. Unlike ApplyYAML,
+// resources that already exist are reconciled to the given yaml.
+func ServerSideApplyYAML(clientset kubernetes.Interface, config *rest.Config, namespace string, yamlFile io.Reader, opts *ServerSideApplyOptions) ([]*AppliedResource, error) {
+	resources, err := GetResourcesFromYAML(yamlFile)
+	if err != nil {
+		return nil, err
+	}
+	return ServerSideApplyResources(clientset, config, resources, namespace, opts)
+}
+
+// ServerSideApplyResources server-side applies the given resources to the given namespace/cluster, and returns
+// the outcome for each resource.
+func ServerSideApplyResources(clientset kubernetes.Interface, config *rest.Config, resources []*Resource, namespace string, opts *ServerSideApplyOptions) ([]*AppliedResource, error) {
+	return ServerSideApplyResourcesContext(context.Background(), clientset, config, resources, namespace, opts)
+}
+
+// ServerSideApplyResourcesContext is like ServerSideApplyResources, but stops applying resources once the context is
+// done, and returns the context's error with the outcomes of the resources that were applied until then. The requests
+// of the resource that is being applied when the context is done are cancelled, and it is reported as failed.
+func ServerSideApplyResourcesContext(ctx context.Context, clientset kubernetes.Interface, config *rest.Config, resources []*Resource, namespace string, opts *ServerSideApplyOptions) ([]*AppliedResource, error) {
+	rm := newResourceMapper(clientset)
+
+	warnings := &WarningCollector{}
+	dynamicClient, err := dynamic.NewForConfig(withWarningCollector(config, warnings))
+	if err != nil {
+		return nil, err
+	}
+
+	fieldManager := opts.FieldManager
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+	namespaceLabels := map[string]string{ManagedByLabel: fieldManager}
+	if opts.Instance != "" {
+		namespaceLabels[InstanceLabel] = opts.Instance
+	}
+	for k, v := range opts.NamespaceLabels {
+		namespaceLabels[k] = v
+	}
+	namespaces := newNamespaceEnsurer(clientset, namespaceLabels)
+	if opts.RespectObjectNamespaces {
+		resources = NamespacesFirst(resources)
+	}
+
+	var applied []*AppliedResource
+	for _, resource := range resources {
+		if err := ctx.Err(); err != nil {
+			return applied, err
+		}
+		mapping, err := rm.RESTMapping(resource.GVK)
+		if err != nil {
+			return applied, err
+		}
+
+		ri, objNS := resourceInterface(dynamicClient, mapping, namespace, resource, opts.RespectObjectNamespaces)
+		if objNS != "" {
+			resource.Object.SetNamespace(objNS)
+		}
+		if opts.RespectObjectNamespaces {
+			created, err := namespaces.ensure(objNS)
+			if err != nil {
+				return applied, err
+			}
+			if created != nil {
+				applied = append(applied, created)
+			}
+		}
+		stampLabels(resource, fieldManager, opts.Instance)
+
+		result := &AppliedResource{
+			Kind:      resource.GVK.Kind,
+			Name:      resource.Object.GetName(),
+			Namespace: objNS,
+		}
+
+		data, err := resource.Object.MarshalJSON()
+		if err != nil {
+			return applied, err
+		}
+
+		prevVersion := ""
+		var patched *unstructured.Unstructured
+		desc := fmt.Sprintf("applying %s %s", result.Kind, result.Name)
+		err = RetryOnConflict(opts.ConflictRetries, desc, func() error {
+			prevVersion = ""
+			existing, err := ri.Get(ctx, resource.Object.GetName(), metav1.GetOptions{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return err
+			}
+			if err == nil {
+				// Taking over the fields changes the version of the object, so the apply is compared with the
+				// version after the takeover.
+				existing, err = takeOverFields(ctx, ri, existing, fieldManager, opts.PreviousFieldManagers)
+				if err != nil {
+					return err
+				}
+				prevVersion = existing.GetResourceVersion()
+			}
+
+			force := opts.Force
+			patched, err = ri.Patch(ctx, resource.Object.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+				FieldManager: fieldManager,
+				Force:        &force,
+			})
+			// Conflicts with other managers won't resolve by retrying, so they aren't returned as conflicts.
+			if conflictErr := applyConflictError(err, result); conflictErr != nil {
+				return conflictErr
+			}
+			return err
+		})
+		result.Warnings = warnings.Drain()
+		var conflictErr *ApplyConflictError
+		if err != nil && !errors.As(err, &conflictErr) {
+			err = wrapAPIError(err, "apply", mapping.Resource.Resource, objNS, result.Name)
+		}
+		if err != nil {
+			result.Status = StatusFailed
+			result.Err = err
+			return append(applied, result), err
+		}
+
+		switch {
+		case prevVersion == "":
+			result.Status = StatusCreated
+		case prevVersion == patched.GetResourceVersion():
+			result.Status = StatusUnchanged
+		default:
+			result.Status = StatusConfigured
+		}
+		applied = append(applied, result)
+	}
+	return applied, nil
+}
+
+// stampLabels labels the resource with the manager that applies it and the instance it belongs to.
+func stampLabels(resource *Resource, fieldManager, instance string) {
+	labels := resource.Object.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[ManagedByLabel] = fieldManager
+	if instance != "" {
+		labels[InstanceLabel] = instance
+	}
+	resource.Object.SetLabels(labels)
+}
+
+// takeOverFields transfers the fields that the previous managers applied on the object to the field manager, by
+// rewriting the managed fields of the object, and returns the updated object. The managed fields of the other managers
+// are kept. The update fails with a conflict if the object changed since it was read.
+func takeOverFields(ctx context.Context, ri dynamic.ResourceInterface, obj *unstructured.Unstructured, fieldManager string, previous []string) (*unstructured.Unstructured, error) {
+	managedFields, changed, err := mergeManagedFields(obj.GetManagedFields(), fieldManager, previous)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return obj, nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": obj.GetResourceVersion(),
+			"managedFields":   managedFields,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ri.Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+// mergeManagedFields returns the managed fields with the applied fields of the previous managers merged into the
+// applied fields of the field manager with the same API version and subresource, and whether they changed.
+func mergeManagedFields(entries []metav1.ManagedFieldsEntry, fieldManager string, previous []string) ([]metav1.ManagedFieldsEntry, bool, error) {
+	isPrevious := make(map[string]bool)
+	for _, m := range previous {
+		isPrevious[m] = true
+	}
+	var merged []metav1.ManagedFieldsEntry
+	changed := false
+	for _, e := range entries {
+		if e.Operation == metav1.ManagedFieldsOperationApply && isPrevious[e.Manager] {
+			e.Manager = fieldManager
+			changed = true
+		}
+		i := -1
+		if e.Manager == fieldManager && e.Operation == metav1.ManagedFieldsOperationApply {
+			for j, m := range merged {
+				if m.Manager == e.Manager && m.Operation == e.Operation && m.APIVersion == e.APIVersion && m.Subresource == e.Subresource {
+					i = j
+					break
+				}
+			}
+		}
+		if i == -1 {
+			merged = append(merged, e)
+			continue
+		}
+		union, err := unionFields(merged[i].FieldsV1, e.FieldsV1)
+		if err != nil {
+			return nil, false, err
+		}
+		merged[i].FieldsV1 = union
+	}
+	if !changed {
+		return entries, false, nil
+	}
+	return merged, true, nil
+}
+
+// unionFields returns the union of two sets of managed fields.
+func unionFields(a, b *metav1.FieldsV1) (*metav1.FieldsV1, error) {
+	set := func(f *metav1.FieldsV1) (*fieldpath.Set, error) {
+		s := &fieldpath.Set{}
+		if f == nil || len(f.Raw) == 0 {
+			return s, nil
+		}
+		return s, s.FromJSON(bytes.NewReader(f.Raw))
+	}
+	sa, err := set(a)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := set(b)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := sa.Union(sb).ToJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &metav1.FieldsV1{Raw: raw}, nil
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const previousManagerConfigMap = `{
+  "kind": "ConfigMap",
+  "apiVersion": "v1",
+  "metadata": {
+    "name": "%s",
+    "namespace": "px",
+    "resourceVersion": "1",
+    "managedFields": [%s]
+  },
+  "data": {"key": "value"}
+}`
+
+const (
+	previousManagerFields = `{"manager":"px-demo","operation":"Apply","apiVersion":"v1","fieldsType":"FieldsV1",` +
+		`"fieldsV1":{"f:data":{"f:key":{}}}}`
+	newManagerFields = `{"manager":"pixie-cli","operation":"Apply","apiVersion":"v1","fieldsType":"FieldsV1",` +
+		`"fieldsV1":{"f:data":{"f:other":{}}}}`
+	otherManagerFields = `{"manager":"kubectl","operation":"Update","apiVersion":"v1","fieldsType":"FieldsV1",` +
+		`"fieldsV1":{"f:metadata":{"f:annotations":{}}}}`
+)
+
+type patchRequest struct {
+	name      string
+	patchType types.PatchType
+	body      []byte
+}
+
+// newApplyAPIServer returns an API server that serves the given config maps, and records the patches it receives.
+func newApplyAPIServer(t *testing.T, configMaps map[string]string) (*rest.Config, *fake.Clientset, func() []patchRequest) {
+	var mu sync.Mutex
+	var patches []patchRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		cm, ok := configMaps[name]
+		if r.Method == http.MethodPatch {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			patches = append(patches, patchRequest{name, types.PatchType(r.Header.Get("Content-Type")), body})
+			mu.Unlock()
+			if !ok {
+				cm = fmt.Sprintf(previousManagerConfigMap, name, "")
+			}
+			fmt.Fprint(w, strings.Replace(cm, `"resourceVersion": "1"`, `"resourceVersion": "2"`, 1))
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+			return
+		}
+		fmt.Fprint(w, cm)
+	}))
+	t.Cleanup(srv.Close)
+
+	clientset := fake.NewSimpleClientset()
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+	}}
+	return &rest.Config{Host: srv.URL}, clientset, func() []patchRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return patches
+	}
+}
+
+func managedFieldsOfPatch(t *testing.T, p patchRequest) []metav1.ManagedFieldsEntry {
+	var patch struct {
+		Metadata struct {
+			ResourceVersion string                      `json:"resourceVersion"`
+			ManagedFields   []metav1.ManagedFieldsEntry `json:"managedFields"`
+		} `json:"metadata"`
+	}
+	require.NoError(t, json.Unmarshal(p.body, &patch))
+	assert.Equal(t, "1", patch.Metadata.ResourceVersion)
+	return patch.Metadata.ManagedFields
+}
+
+func TestServerSideApply_TakesOverPreviousFieldManagers(t *testing.T) {
+	config, clientset, patches := newApplyAPIServer(t, map[string]string{
+		"previous": fmt.Sprintf(previousManagerConfigMap, "previous", previousManagerFields+","+otherManagerFields),
+		"both":     fmt.Sprintf(previousManagerConfigMap, "both", newManagerFields+","+previousManagerFields),
+		"current":  fmt.Sprintf(previousManagerConfigMap, "current", newManagerFields),
+	})
+
+	var yamls []string
+	for _, name := range []string{"previous", "both", "current", "created"} {
+		yamls = append(yamls, fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\ndata:\n  key: value\n", name))
+	}
+	applied, err := k8s.ServerSideApplyYAML(clientset, config, "px", strings.NewReader(strings.Join(yamls, "---\n")),
+		&k8s.ServerSideApplyOptions{PreviousFieldManagers: []string{"px-demo"}})
+	require.NoError(t, err)
+	require.Len(t, applied, 4)
+	// Every patch leaves the objects at version 2, so the apply doesn't change the objects that were taken over.
+	assert.Equal(t, k8s.StatusUnchanged, applied[0].Status)
+	assert.Equal(t, k8s.StatusUnchanged, applied[1].Status)
+	assert.Equal(t, k8s.StatusConfigured, applied[2].Status)
+	assert.Equal(t, k8s.StatusCreated, applied[3].Status)
+
+	var takeOvers, applies []patchRequest
+	for _, p := range patches() {
+		if p.patchType == types.MergePatchType {
+			takeOvers = append(takeOvers, p)
+		} else {
+			applies = append(applies, p)
+		}
+	}
+	assert.Len(t, applies, 4)
+	require.Len(t, takeOvers, 2)
+
+	// The fields of the previous manager are renamed, and the fields of other managers are kept.
+	assert.Equal(t, "previous", takeOvers[0].name)
+	fields := managedFieldsOfPatch(t, takeOvers[0])
+	require.Len(t, fields, 2)
+	assert.Equal(t, "pixie-cli", fields[0].Manager)
+	assert.Equal(t, metav1.ManagedFieldsOperationApply, fields[0].Operation)
+	assert.JSONEq(t, `{"f:data":{"f:key":{}}}`, string(fields[0].FieldsV1.Raw))
+	assert.Equal(t, "kubectl", fields[1].Manager)
+
+	// The fields of the previous manager are merged into the fields of the new manager.
+	assert.Equal(t, "both", takeOvers[1].name)
+	fields = managedFieldsOfPatch(t, takeOvers[1])
+	require.Len(t, fields, 1)
+	assert.Equal(t, "pixie-cli", fields[0].Manager)
+	assert.JSONEq(t, `{"f:data":{"f:key":{},"f:other":{}}}`, string(fields[0].FieldsV1.Raw))
+}
+
+func TestServerSideApplyContext_CancelsRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The API server never answers, so only cancelling the request ends it.
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	clientset := fake.NewSimpleClientset()
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+	}}
+	resources, err := k8s.GetResourcesFromYAML(strings.NewReader("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	applied, err := k8s.ServerSideApplyResourcesContext(ctx, clientset, &rest.Config{Host: srv.URL}, resources, "px",
+		&k8s.ServerSideApplyOptions{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, applied, 1)
+	assert.Equal(t, k8s.StatusFailed, applied[0].Status)
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// LogLine is a single line of logs from a container.
+type LogLine struct {
+	Namespace string
+	Pod       string
+	Container string
+	Line      string
+	// Err is set if streaming the logs of the container failed, in which case Line is empty.
+	Err error
+}
+
+// StreamLogsOptions configures StreamLogs.
+type StreamLogsOptions struct {
+	// Follow keeps streaming new logs, including from pods that are created or restarted while streaming.
+	Follow bool
+	// TailLines is the number of recent lines to show for each container. All lines are shown if it is nil.
+	TailLines *int64
+}
+
+// StreamLogs fans in the logs of all containers in the pods that match the label selector onto a single channel.
+// The channel is closed once all streams end, or the context is done when following.
+func StreamLogs(ctx context.Context, clientset kubernetes.Interface, namespace, selector string, opts *StreamLogsOptions) (<-chan *LogLine, error) {
+	s := &logStreamer{
+		ctx:       ctx,
+		clientset: clientset,
+		opts:      opts,
+		lines:     make(chan *LogLine),
+		started:   make(map[string]bool),
+	}
+
+	if !opts.Follow {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			s.startPod(&pods.Items[i])
+		}
+		go func() {
+			s.wg.Wait()
+			close(s.lines)
+		}()
+		return s.lines, nil
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.LabelSelector = selector
+		}))
+	informer := factory.Core().V1().Pods().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*v1.Pod); ok {
+				s.startPod(pod)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if pod, ok := obj.(*v1.Pod); ok {
+				s.startPod(pod)
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		informer.Run(ctx.Done())
+	}()
+	go func() {
+		s.wg.Wait()
+		close(s.lines)
+	}()
+	return s.lines, nil
+}
+
+type logStreamer struct {
+	ctx       context.Context
+	clientset kubernetes.Interface
+	opts      *StreamLogsOptions
+	lines     chan *LogLine
+	wg        sync.WaitGroup
+
+	mu sync.Mutex
+	// started records the container instances that are already being streamed.
+	started map[string]bool
+}
+
+// startPod starts streaming the logs of all started containers in the pod that aren't streamed yet.
+func (s *logStreamer) startPod(pod *v1.Pod) {
+	if pod.DeletionTimestamp != nil {
+		return
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		switch {
+		case status.State.Running != nil:
+		case status.State.Terminated != nil && !s.opts.Follow:
+		default:
+			continue
+		}
+
+		key := containerInstanceKey(pod, status.Name, status.RestartCount)
+		s.mu.Lock()
+		if s.started[key] {
+			s.mu.Unlock()
+			continue
+		}
+		s.started[key] = true
+		s.mu.Unlock()
+
+		logOpts := &v1.PodLogOptions{
+			Container: status.Name,
+			Follow:    s.opts.Follow,
+			TailLines: s.opts.TailLines,
+		}
+		if s.opts.Follow && status.RestartCount > 0 && s.restarted(pod, status) {
+			// Show all logs of a container that restarted while streaming.
+			logOpts.TailLines = nil
+		}
+
+		s.wg.Add(1)
+		go func(namespace, podName, container string) {
+			defer s.wg.Done()
+			s.streamContainer(namespace, podName, container, logOpts)
+		}(pod.Namespace, pod.Name, status.Name)
+	}
+}
+
+// restarted returns whether an earlier instance of the container was already streamed.
+func (s *logStreamer) restarted(pod *v1.Pod, status v1.ContainerStatus) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started[containerInstanceKey(pod, status.Name, status.RestartCount-1)]
+}
+
+// containerInstanceKey identifies a single run of a container, so that restarted containers are streamed again.
+func containerInstanceKey(pod *v1.Pod, container string, restartCount int32) string {
+	return fmt.Sprintf("%s/%s/%s/%d", pod.Name, pod.UID, container, restartCount)
+}
+
+func (s *logStreamer) streamContainer(namespace, pod, container string, opts *v1.PodLogOptions) {
+	stream, err := s.clientset.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream(s.ctx)
+	if err != nil {
+		s.send(&LogLine{Namespace: namespace, Pod: pod, Container: container, Err: err})
+		return
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		if !s.send(&LogLine{Namespace: namespace, Pod: pod, Container: container, Line: scanner.Text()}) {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && s.ctx.Err() == nil {
+		s.send(&LogLine{Namespace: namespace, Pod: pod, Container: container, Err: err})
+	}
+}
+
+// send sends the line unless the context is done, and returns whether it was sent.
+func (s *logStreamer) send(l *LogLine) bool {
+	select {
+	case s.lines <- l:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
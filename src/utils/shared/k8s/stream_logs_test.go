@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func demoPod(name string, labels map[string]string, state v1.ContainerState) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "px-sock-shop", Labels: labels},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{{Name: "app", State: state}},
+		},
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	waiting := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}}
+	clientset := fake.NewSimpleClientset(
+		demoPod("frontend", map[string]string{"name": "frontend"}, running),
+		demoPod("carts", map[string]string{"name": "carts"}, running),
+		demoPod("orders", map[string]string{"name": "orders"}, waiting),
+	)
+
+	tests := []struct {
+		name         string
+		selector     string
+		expectedPods []string
+	}{
+		{
+			name:         "all pods",
+			expectedPods: []string{"carts", "frontend"},
+		},
+		{
+			name:         "selector",
+			selector:     "name=frontend",
+			expectedPods: []string{"frontend"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, err := k8s.StreamLogs(context.Background(), clientset, "px-sock-shop", test.selector, &k8s.StreamLogsOptions{})
+			require.NoError(t, err)
+
+			var pods []string
+			for l := range lines {
+				require.NoError(t, l.Err)
+				assert.Equal(t, "app", l.Container)
+				assert.Equal(t, "fake logs", l.Line)
+				pods = append(pods, l.Pod)
+			}
+			sort.Strings(pods)
+			assert.Equal(t, test.expectedPods, pods)
+		})
+	}
+}
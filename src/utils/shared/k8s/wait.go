@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// ConditionFunc returns whether an object satisfies a condition. obj is nil if the object does not exist.
+type ConditionFunc func(obj *unstructured.Unstructured) (bool, error)
+
+// Deleted is satisfied once the object no longer exists.
+func Deleted(obj *unstructured.Unstructured) (bool, error) {
+	return obj == nil, nil
+}
+
+// HasCondition returns a ConditionFunc that is satisfied once the object reports the status condition with the
+// given type as "True", such as "Established" for CRDs or "Complete" for Jobs.
+func HasCondition(conditionType string) ConditionFunc {
+	return func(obj *unstructured.Unstructured) (bool, error) {
+		if obj == nil {
+			return false, nil
+		}
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			cond, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if cond["type"] == conditionType && cond["status"] == "True" {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// WaitForCondition watches the object with the given name until it satisfies the condition or the timeout
+// passes. Use an empty namespace for cluster-scoped resources.
+func WaitForCondition(config *rest.Config, gvr schema.GroupVersionResource, namespace, name string, condition ConditionFunc, timeout time.Duration) error {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	ri := dynamicClient.Resource(gvr).Namespace(namespace)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.FieldSelector = fieldSelector
+			return ri.List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.FieldSelector = fieldSelector
+			return ri.Watch(ctx, opts)
+		},
+	}
+
+	key := name
+	if namespace != "" {
+		key = fmt.Sprintf("%s/%s", namespace, name)
+	}
+	precondition := func(store cache.Store) (bool, error) {
+		item, exists, err := store.GetByKey(key)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return condition(nil)
+		}
+		obj, ok := item.(*unstructured.Unstructured)
+		if !ok {
+			return false, nil
+		}
+		return condition(obj)
+	}
+
+	_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, precondition, func(e watch.Event) (bool, error) {
+		switch e.Type {
+		case watch.Deleted:
+			return condition(nil)
+		case watch.Added, watch.Modified:
+			obj, ok := e.Object.(*unstructured.Unstructured)
+			if !ok {
+				return false, nil
+			}
+			return condition(obj)
+		default:
+			return false, nil
+		}
+	})
+	return wrapAPIError(err, "wait for", gvr.Resource, namespace, name)
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestHasCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		obj        *unstructured.Unstructured
+		conditions []interface{}
+		expected   bool
+	}{
+		{
+			name:     "missing object",
+			expected: false,
+		},
+		{
+			name:     "no conditions",
+			obj:      &unstructured.Unstructured{Object: map[string]interface{}{}},
+			expected: false,
+		},
+		{
+			name: "condition false",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Established", "status": "False"},
+					},
+				},
+			}},
+			expected: false,
+		},
+		{
+			name: "condition true",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+						map[string]interface{}{"type": "Established", "status": "True"},
+					},
+				},
+			}},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok, err := k8s.HasCondition("Established")(test.obj)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ok)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"sync"
+
+	"k8s.io/client-go/rest"
+)
+
+// WarningCollector is a rest.WarningHandler that records the warnings returned by the API server, such as
+// deprecation and admission warnings, so that they can be shown to the user.
+type WarningCollector struct {
+	mu       sync.Mutex
+	warnings []string
+	seen     map[string]bool
+}
+
+// HandleWarningHeader records the warning. Only warnings with the code 299 are recorded, and each distinct
+// warning is recorded once until the collector is drained.
+func (c *WarningCollector) HandleWarningHeader(code int, agent string, text string) {
+	if code != 299 || text == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen == nil {
+		c.seen = make(map[string]bool)
+	}
+	if c.seen[text] {
+		return
+	}
+	c.seen[text] = true
+	c.warnings = append(c.warnings, text)
+}
+
+// Drain returns the warnings recorded since the last call to Drain.
+func (c *WarningCollector) Drain() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	warnings := c.warnings
+	c.warnings = nil
+	c.seen = nil
+	return warnings
+}
+
+// withWarningCollector returns a copy of the config whose clients report warnings to the collector.
+func withWarningCollector(config *rest.Config, collector *WarningCollector) *rest.Config {
+	c := rest.CopyConfig(config)
+	c.WarningHandler = collector
+	return c
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestWarningCollector(t *testing.T) {
+	c := &k8s.WarningCollector{}
+	c.HandleWarningHeader(299, "-", "apps/v1beta1 Deployment is deprecated")
+	c.HandleWarningHeader(299, "-", "apps/v1beta1 Deployment is deprecated")
+	c.HandleWarningHeader(199, "-", "miscellaneous warning")
+	c.HandleWarningHeader(299, "-", "")
+
+	assert.Equal(t, []string{"apps/v1beta1 Deployment is deprecated"}, c.Drain())
+	assert.Empty(t, c.Drain())
+
+	c.HandleWarningHeader(299, "-", "apps/v1beta1 Deployment is deprecated")
+	assert.Equal(t, []string{"apps/v1beta1 Deployment is deprecated"}, c.Drain())
+}
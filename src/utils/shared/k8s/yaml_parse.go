@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// YAMLParseError is returned when a document of a multi-document YAML can't be decoded.
+type YAMLParseError struct {
+	// Source is the name of the file the YAML was read from, if known.
+	Source string
+	// Document is the 1-based index of the document in the YAML.
+	Document int
+	// Line is the line of the YAML that the problem was found on, or 0 if unknown.
+	Line int
+	Err  error
+}
+
+func (e *YAMLParseError) Error() string {
+	source := e.Source
+	if source == "" {
+		source = "yaml"
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%s: document %d, line %d: %v", source, e.Document, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: document %d: %v", source, e.Document, e.Err)
+}
+
+func (e *YAMLParseError) Unwrap() error {
+	return e.Err
+}
+
+// yamlDocument is a single document of a multi-document YAML.
+type yamlDocument struct {
+	data []byte
+	// line is the line of the YAML that the document starts on.
+	line int
+}
+
+// splitYAMLDocuments splits a multi-document YAML on "---" separators, keeping track of where each document starts.
+func splitYAMLDocuments(data []byte) []*yamlDocument {
+	var docs []*yamlDocument
+	cur := &yamlDocument{line: 1}
+	for i, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("---")) && len(bytes.TrimSpace(line[3:])) == 0 {
+			docs = append(docs, cur)
+			cur = &yamlDocument{line: i + 2}
+			continue
+		}
+		cur.data = append(cur.data, line...)
+	}
+	return append(docs, cur)
+}
+
+var yamlErrLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// yamlErrorLine returns the line of the YAML that a decode error refers to, given the line the document starts on.
+func yamlErrorLine(err error, docLine int) int {
+	m := yamlErrLineRegex.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	line, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return docLine + line - 1
+}
+
+// expandListResources returns the resource for the object, or the resources for the items of the object if it is a
+// List, such as a v1.List. Lists nested in lists are expanded too.
+func expandListResources(obj *unstructured.Unstructured) ([]*Resource, error) {
+	if !obj.IsList() {
+		gvk := obj.GroupVersionKind()
+		return []*Resource{{Object: obj, GVK: &gvk}}, nil
+	}
+
+	list, err := obj.ToList()
+	if err != nil {
+		return nil, err
+	}
+	var resources []*Resource
+	for i := range list.Items {
+		item := &list.Items[i]
+		if item.GetKind() == "" {
+			return nil, fmt.Errorf("item %d of %s has no kind", i, obj.GetKind())
+		}
+		expanded, err := expandListResources(item)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, expanded...)
+	}
+	return resources, nil
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8s_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+func TestGetResourcesFromNamedYAML(t *testing.T) {
+	tests := []struct {
+		name          string
+		yaml          string
+		expectedNames []string
+		expectedDoc   int
+		expectedLine  int
+	}{
+		{
+			name: "multiple documents",
+			yaml: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+`,
+			expectedNames: []string{"a", "b"},
+		},
+		{
+			name: "list",
+			yaml: `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+- apiVersion: v1
+  kind: List
+  items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: b
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: c
+`,
+			expectedNames: []string{"a", "b", "c"},
+		},
+		{
+			name: "list item missing kind",
+			yaml: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  metadata:
+    name: b
+`,
+			expectedDoc: 2,
+		},
+		{
+			name: "invalid yaml",
+			yaml: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+  labels: [a
+`,
+			expectedDoc:  2,
+			expectedLine: 10,
+		},
+		{
+			name: "missing kind",
+			yaml: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+apiVersion: v1
+metadata:
+  name: b
+`,
+			expectedDoc: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resources, err := k8s.GetResourcesFromNamedYAML("configmaps.yaml", strings.NewReader(test.yaml))
+			if test.expectedDoc != 0 {
+				var parseErr *k8s.YAMLParseError
+				require.True(t, errors.As(err, &parseErr))
+				assert.Equal(t, "configmaps.yaml", parseErr.Source)
+				assert.Equal(t, test.expectedDoc, parseErr.Document)
+				assert.Equal(t, test.expectedLine, parseErr.Line)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, len(resources))
+			for i, r := range resources {
+				names[i] = r.Object.GetName()
+			}
+			assert.Equal(t, test.expectedNames, names)
+		})
+	}
+}